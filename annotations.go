@@ -0,0 +1,38 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// annotationsAPIVersion is the first API version which passes the
+// annotations of a container to the runtime.
+const annotationsAPIVersion = "1.43"
+
+// ErrAnnotationsUnsupported is returned when a container with annotations is
+// created on a daemon which would silently drop them.
+var ErrAnnotationsUnsupported = errors.New("OCI annotations require API version " + annotationsAPIVersion)
+
+// Annotations returns the OCI annotations of the container. Containers of
+// daemons older than API version 1.43 have none.
+func (c *Client) Annotations(ctx context.Context, id string) (map[string]string, error) {
+	cj, err := c.inspectContainer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("inspect container %s: %w", id, err)
+	}
+	return cj.HostConfig.Annotations, nil
+}
+
+// checkAnnotations fails with ErrAnnotationsUnsupported if the daemon does
+// not support annotations.
+func (c *Client) checkAnnotations(ctx context.Context) error {
+	v, err := c.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if !v.APIAtLeast(annotationsAPIVersion) {
+		return fmt.Errorf("%w, daemon supports %s", ErrAnnotationsUnsupported, v.APIVersion)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_Annotations(t *testing.T) {
+	spec := ContainerSpec{Name: "plc", Image: "sim", Annotations: map[string]string{"io.grid-x.slaves": "10"}}
+	body, err := spec.createBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.route(map[string]mockResponse{
+		"GET /version": {Body: `{"Version": "20.10.7", "ApiVersion": "1.41"}`},
+	})
+	defer srv.route(nil)
+	if _, err := client.createContainer(context.Background(), spec.Name, body); !errors.Is(err, ErrAnnotationsUnsupported) {
+		t.Fatalf("expected ErrAnnotationsUnsupported, got %v", err)
+	}
+
+	srv.route(map[string]mockResponse{
+		"GET /version":            {Body: `{"Version": "24.0.2", "ApiVersion": "1.43"}`},
+		"POST /containers/create": {StatusCode: 201, Body: `{"Id": "c1"}`},
+		"GET /containers/c1/json": {Body: `{"Id": "c1", "Name": "/plc", "State": {"Status": "created"},
+			"HostConfig": {"Annotations": {"io.grid-x.slaves": "10"}}}`},
+	})
+	res, err := client.createContainer(context.Background(), spec.Name, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := client.Annotations(context.Background(), res.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, spec.Annotations) {
+		t.Errorf("want annotations %v, got %v", spec.Annotations, got)
+	}
+}
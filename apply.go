@@ -0,0 +1,612 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Operation is a single change of the daemon's state planned or executed by
+// Apply or TeardownRun.
+type Operation struct {
+	// Action is one of create, start, remove, connect and disconnect.
+	// StackDeploy also reports update.
+	Action string `json:"action"`
+	// Resource is one of container, network, volume and service.
+	Resource string `json:"resource"`
+	Name     string `json:"name"`
+	// Network is set for connect and disconnect.
+	Network string `json:"network,omitempty"`
+}
+
+func (o Operation) String() string {
+	switch o.Action {
+	case "connect":
+		return fmt.Sprintf("connect %s %s to %s", o.Resource, o.Name, o.Network)
+	case "disconnect":
+		return fmt.Sprintf("disconnect %s %s from %s", o.Resource, o.Name, o.Network)
+	}
+	return fmt.Sprintf("%s %s %s", o.Action, o.Resource, o.Name)
+}
+
+// ApplyOption changes the behaviour of Apply and TeardownRun.
+type ApplyOption func(*applier)
+
+// DryRun plans the operations without executing them. The daemon is only
+// queried for its current state.
+func DryRun() ApplyOption {
+	return func(a *applier) {
+		a.dryRun = true
+	}
+}
+
+// Parallel sets up at most n containers at the same time. A container is
+// only set up after its dependencies, so independent branches of the
+// dependency graph start in parallel. The default is 1, which sets up the
+// containers one after another.
+func Parallel(n int) ApplyOption {
+	return func(a *applier) {
+		a.parallel = n
+	}
+}
+
+// RollbackMode defines what Apply undoes if it fails.
+type RollbackMode int
+
+const (
+	// NoRollback leaves the resources as they are, which is the default.
+	NoRollback RollbackMode = iota
+	// RollbackStop stops the containers started by Apply.
+	RollbackStop
+	// RollbackRemove removes the volumes, networks and containers created by
+	// Apply and stops the existing containers it started. Resources which
+	// were recreated because their configuration changed are removed too.
+	// Resources are removed by the ID returned when Apply created them,
+	// volumes which existed before are never removed.
+	RollbackRemove
+)
+
+// Rollback sets what Apply undoes if it fails, so a partially created
+// environment does not linger. The rollback is best effort and runs even if
+// the context of Apply was canceled. It is not part of the returned
+// operations.
+func Rollback(mode RollbackMode) ApplyOption {
+	return func(a *applier) {
+		a.rollback = mode
+	}
+}
+
+// applier executes or plans operations and records them.
+type applier struct {
+	c        *Client
+	dryRun   bool
+	parallel int
+	rollback RollbackMode
+
+	mu  sync.Mutex
+	ops []Operation
+	// undoable are the executed operations a rollback undoes with the IDs
+	// of their resources.
+	undoable []undoable
+}
+
+// undoable is an operation which created or started the resource with ID.
+type undoable struct {
+	op Operation
+	id string
+}
+
+func (c *Client) newApplier(opts []ApplyOption) *applier {
+	a := &applier{c: c, parallel: 1}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.parallel < 1 {
+		a.parallel = 1
+	}
+	return a
+}
+
+// run executes fn unless this is a dry run and records the operation once
+// it succeeded.
+func (a *applier) run(op Operation, fn func() error) error {
+	return a.runUndoable(op, func() (string, error) {
+		return "", fn()
+	})
+}
+
+// runUndoable is run for operations which are undone by a rollback. fn
+// returns the ID of the resource it created or started, which the rollback
+// acts on, so a resource of the same name created by somebody else is left
+// alone.
+func (a *applier) runUndoable(op Operation, fn func() (string, error)) error {
+	var id string
+	if !a.dryRun {
+		var err error
+		if id, err = fn(); err != nil {
+			return err
+		}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.ops = append(a.ops, op)
+	if id != "" {
+		a.undoable = append(a.undoable, undoable{op: op, id: id})
+	}
+	return nil
+}
+
+// undo rolls back the executed operations according to the rollback mode.
+func (a *applier) undo() {
+	if a.dryRun || a.rollback == NoRollback {
+		return
+	}
+	ctx := context.Background()
+	created := make(map[string]bool)
+	for _, u := range a.undoable {
+		if u.op.Action == "create" && u.op.Resource == "container" {
+			created[u.id] = true
+		}
+	}
+	for i := len(a.undoable) - 1; i >= 0; i-- {
+		u := a.undoable[i]
+		if u.op.Action != "start" || (a.rollback == RollbackRemove && created[u.id]) {
+			continue
+		}
+		a.c.stopContainer(ctx, u.id)
+	}
+	if a.rollback == RollbackRemove {
+		a.c.rollback(ctx, a.undoable)
+	}
+}
+
+// Apply reconciles the state of the daemon with the given spec:
+// Missing volumes, networks and containers are created. Networks and
+// containers whose configuration changed are recreated, network attachments
+// are connected and disconnected as needed and stopped containers are
+// started. Networks and containers of the run which are not part of the spec
+// anymore are removed. Volumes are never removed by Apply, use the run label
+// to clean them up.
+// The operations which succeeded are returned in the order they finished,
+// a failed operation is not part of them. With the DryRun option they are
+// only planned.
+// Containers are set up after their dependencies, see Parallel. Once a
+// container failed no further containers are set up. If several containers
+// failed independently, a BatchError keyed by name is returned. See Rollback
+// for undoing the operations of a failed Apply.
+func (c *Client) Apply(ctx context.Context, spec *Spec, opts ...ApplyOption) ([]Operation, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	a := c.newApplier(opts)
+	err := a.apply(ctx, spec)
+	if err != nil {
+		a.undo()
+	}
+	return a.ops, err
+}
+
+func (a *applier) apply(ctx context.Context, spec *Spec) error {
+	owned := map[string][]string{"label": {LabelRun + "=" + spec.Run}}
+	if err := a.applyVolumes(ctx, spec, owned); err != nil {
+		return err
+	}
+	networks, recreated, err := a.applyNetworks(ctx, spec, owned)
+	if err != nil {
+		return err
+	}
+	if err := a.applyContainers(ctx, spec, owned, networks, recreated); err != nil {
+		return err
+	}
+	return a.pruneNetworks(ctx, spec, owned)
+}
+
+func (a *applier) applyVolumes(ctx context.Context, spec *Spec, owned map[string][]string) error {
+	vols, err := a.c.listVolumes(ctx, owned)
+	if err != nil {
+		return fmt.Errorf("list volumes: %w", err)
+	}
+	exists := make(map[string]bool, len(vols))
+	for _, v := range vols {
+		exists[v.Name] = true
+	}
+
+	for _, v := range spec.Volumes {
+		if exists[v.Name] {
+			continue
+		}
+		// the daemon answers the creation of an existing volume with the
+		// volume, which must neither be reported as created nor removed
+		// by a rollback
+		if _, err := a.c.inspectVolume(ctx, v.Name); err == nil {
+			continue
+		} else if !isNotFound(err) {
+			return fmt.Errorf("inspect volume %s: %w", v.Name, err)
+		}
+		v := v
+		labels := copyLabels(v.Labels)
+		labels[LabelRun] = spec.Run
+		err := a.runUndoable(Operation{Action: "create", Resource: "volume", Name: v.Name}, func() (string, error) {
+			res, err := a.c.createVolume(ctx, v.Name, v.Driver, labels)
+			if err != nil {
+				return "", err
+			}
+			return res.Name, nil
+		})
+		if err != nil {
+			return fmt.Errorf("create volume %s: %w", v.Name, err)
+		}
+	}
+	return nil
+}
+
+// applyNetworks creates and recreates the networks of the spec. It returns
+// the IDs of all networks referenced by the spec by name and the names of the
+// recreated networks.
+func (a *applier) applyNetworks(ctx context.Context, spec *Spec, owned map[string][]string) (map[string]string, map[string]bool, error) {
+	existing, err := a.c.listNetworks(ctx, owned)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list networks: %w", err)
+	}
+	byName := make(map[string]networkSummary, len(existing))
+	for _, n := range existing {
+		byName[n.Name] = n
+	}
+
+	ids := make(map[string]string)
+	recreated := make(map[string]bool)
+	for _, n := range spec.Networks {
+		hash := n.hash()
+		if cur, ok := byName[n.Name]; ok {
+			if cur.Labels[LabelConfigHash] == hash {
+				ids[n.Name] = cur.ID
+				continue
+			}
+			if err := a.removeNetwork(ctx, cur); err != nil {
+				return nil, nil, fmt.Errorf("remove changed network %s: %w", n.Name, err)
+			}
+			recreated[n.Name] = true
+		}
+
+		body := n.createBody()
+		body.Labels[LabelRun] = spec.Run
+		body.Labels[LabelConfigHash] = hash
+		err := a.runUndoable(Operation{Action: "create", Resource: "network", Name: n.Name}, func() (string, error) {
+			res, err := a.c.createNetwork(ctx, body)
+			if err != nil {
+				return "", err
+			}
+			ids[n.Name] = res.ID
+			return res.ID, nil
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("create network %s: %w", n.Name, err)
+		}
+	}
+
+	// networks which are referenced but not part of the spec have to exist
+	for _, cs := range spec.Containers {
+		for _, att := range cs.Networks {
+			if _, ok := ids[att.Network]; ok || spec.network(att.Network) != nil {
+				continue
+			}
+			id, err := a.c.networkByName(ctx, att.Network)
+			if err != nil {
+				return nil, nil, fmt.Errorf("container %s: %w", cs.Name, err)
+			}
+			ids[att.Network] = id
+		}
+	}
+	return ids, recreated, nil
+}
+
+// removeNetwork removes the network including all its endpoints.
+func (a *applier) removeNetwork(ctx context.Context, n networkSummary) error {
+	return a.run(Operation{Action: "remove", Resource: "network", Name: n.Name}, func() error {
+		return a.c.recycleNetwork(ctx, n.ID)
+	})
+}
+
+// recycleNetwork disconnects all containers from the network and removes it.
+func (c *Client) recycleNetwork(ctx context.Context, id string) error {
+	nw, err := c.inspectNetwork(ctx, id)
+	if err != nil {
+		return err
+	}
+	for cid := range nw.Containers {
+		if err := c.disconnectNetwork(ctx, id, cid, true); err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+	return c.removeNetwork(ctx, id)
+}
+
+// networkByName returns the ID of the network with exactly the given name.
+func (c *Client) networkByName(ctx context.Context, name string) (string, error) {
+	// the key differs from NetworkIDByName, which matches substrings
+	key := "=" + name
+	id, gen, ok := c.names.get("network", key)
+	if ok {
+		return id, nil
+	}
+	nws, err := c.listNetworks(ctx, map[string][]string{"name": {name}})
+	if err != nil {
+		return "", err
+	}
+	for _, n := range nws {
+		if n.Name == name {
+			c.names.put("network", key, n.ID, gen)
+			return n.ID, nil
+		}
+	}
+	return "", fmt.Errorf("network %s does not exist", name)
+}
+
+func (a *applier) applyContainers(ctx context.Context, spec *Spec, owned map[string][]string,
+	networks map[string]string, recreated map[string]bool) error {
+
+	existing, err := a.c.listContainers(ctx, true, owned)
+	if err != nil {
+		return fmt.Errorf("list containers: %w", err)
+	}
+	byName := make(map[string]containerSummary, len(existing))
+	for _, cs := range existing {
+		byName[cs.Name()] = cs
+	}
+
+	order, err := spec.startOrder()
+	if err != nil {
+		return err
+	}
+	err = a.startGraph(ctx, order, func(cs ContainerSpec) error {
+		cur, ok := byName[cs.Name]
+		if ok && cur.Labels[LabelConfigHash] != cs.hash() {
+			if err := a.removeContainer(ctx, cur); err != nil {
+				return fmt.Errorf("remove changed container %s: %w", cs.Name, err)
+			}
+			ok = false
+		}
+		if !ok {
+			return a.createFromSpec(ctx, spec.Run, cs, networks)
+		}
+		return a.reconcileContainer(ctx, cur.ID, cs, networks, recreated)
+	})
+	if err != nil {
+		return err
+	}
+
+	for name, cur := range byName {
+		if spec.container(name) != nil {
+			continue
+		}
+		if err := a.removeContainer(ctx, cur); err != nil && !isNotFound(err) {
+			return fmt.Errorf("remove container %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// startGraph calls setup for the containers, which are ordered by their
+// dependencies, with at most a.parallel calls at the same time. A container
+// is set up after all its dependencies of the spec were set up. Once a call
+// failed, the containers which did not begin yet are skipped.
+func (a *applier) startGraph(ctx context.Context, order []ContainerSpec, setup func(ContainerSpec) error) error {
+	errs := forEachInGraph(ctx, order, graphOptions{
+		concurrency: a.parallel,
+		deps:        ContainerSpec.dependencies,
+		failFast:    true,
+	}, setup)
+
+	// only the failed calls are reported, not the skipped containers
+	skipped := false
+	for name, err := range errs {
+		if _, ok := err.(*skipError); ok {
+			skipped = true
+			delete(errs, name)
+		}
+	}
+	switch {
+	case len(errs) == 1:
+		for _, err := range errs {
+			return err
+		}
+	case len(errs) > 1:
+		return errs
+	case skipped:
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (a *applier) removeContainer(ctx context.Context, cs containerSummary) error {
+	return a.run(Operation{Action: "remove", Resource: "container", Name: cs.Name()}, func() error {
+		return a.c.removeContainer(ctx, cs.ID, true)
+	})
+}
+
+// createFromSpec creates, connects and starts the container.
+func (a *applier) createFromSpec(ctx context.Context, run string, cs ContainerSpec, networks map[string]string) error {
+	body, err := cs.createBody()
+	if err != nil {
+		return err
+	}
+	body.Labels[LabelRun] = run
+	body.Labels[LabelConfigHash] = cs.hash()
+
+	var id string
+	err = a.runUndoable(Operation{Action: "create", Resource: "container", Name: cs.Name}, func() (string, error) {
+		res, err := a.c.createContainer(ctx, cs.Name, body)
+		if err != nil {
+			return "", err
+		}
+		id = res.ID
+		return id, nil
+	})
+	if err != nil {
+		return fmt.Errorf("create container %s: %w", cs.Name, err)
+	}
+	for i, att := range cs.Networks {
+		if i == 0 {
+			continue
+		}
+		if err := a.connect(ctx, cs.Name, id, att, networks); err != nil {
+			return err
+		}
+	}
+	if err := a.waitHealthy(ctx, cs); err != nil {
+		return err
+	}
+	return a.start(ctx, cs.Name, id)
+}
+
+func (a *applier) connect(ctx context.Context, name, id string, att NetworkAttachment, networks map[string]string) error {
+	op := Operation{Action: "connect", Resource: "container", Name: name, Network: att.Network}
+	err := a.run(op, func() error {
+		return a.c.connectNetwork(ctx, networks[att.Network], id, att.endpointConfig())
+	})
+	if err != nil {
+		return fmt.Errorf("connect container %s to %s: %w", name, att.Network, err)
+	}
+	return nil
+}
+
+func (a *applier) start(ctx context.Context, name, id string) error {
+	err := a.runUndoable(Operation{Action: "start", Resource: "container", Name: name}, func() (string, error) {
+		return id, a.c.startContainer(ctx, id)
+	})
+	if err != nil {
+		return fmt.Errorf("start container %s: %w", name, err)
+	}
+	return nil
+}
+
+// reconcileContainer connects and disconnects networks of an existing
+// container and starts it if it is not running.
+func (a *applier) reconcileContainer(ctx context.Context, id string, cs ContainerSpec,
+	networks map[string]string, recreated map[string]bool) error {
+
+	cur, err := a.c.inspectContainer(ctx, id)
+	if err != nil {
+		return fmt.Errorf("inspect container %s: %w", cs.Name, err)
+	}
+
+	if len(cs.Networks) > 0 {
+		want := make(map[string]bool, len(cs.Networks))
+		for _, att := range cs.Networks {
+			want[att.Network] = true
+			if _, ok := cur.NetworkSettings.Networks[att.Network]; ok && !recreated[att.Network] {
+				continue
+			}
+			if err := a.connect(ctx, cs.Name, id, att, networks); err != nil {
+				return err
+			}
+		}
+		for name, ep := range cur.NetworkSettings.Networks {
+			if want[name] {
+				continue
+			}
+			ep := ep
+			op := Operation{Action: "disconnect", Resource: "container", Name: cs.Name, Network: name}
+			err := a.run(op, func() error {
+				return a.c.disconnectNetwork(ctx, ep.NetworkID, id, true)
+			})
+			if err != nil {
+				return fmt.Errorf("disconnect container %s from %s: %w", cs.Name, name, err)
+			}
+		}
+	}
+
+	if cur.State.Running {
+		return nil
+	}
+	if err := a.waitHealthy(ctx, cs); err != nil {
+		return err
+	}
+	return a.start(ctx, cs.Name, id)
+}
+
+// waitHealthy waits until the dependencies of the container named by
+// DependsOnHealthy passed their healthcheck. Unlike Run, which keeps waiting,
+// it fails as soon as a dependency is unhealthy or exited, because the
+// container would be started against a broken dependency otherwise.
+func (a *applier) waitHealthy(ctx context.Context, cs ContainerSpec) error {
+	if a.dryRun {
+		return nil
+	}
+	backoff := a.c.backoffOr(ConstantBackoff(defaultPollInterval))
+	for _, dep := range cs.DependsOnHealthy {
+		for n := 0; ; n++ {
+			cj, err := a.c.inspectContainer(ctx, dep)
+			if err != nil {
+				return fmt.Errorf("container %s: inspect dependency %s: %w", cs.Name, dep, err)
+			}
+			if !cj.State.Running && !cj.State.Restarting {
+				return fmt.Errorf("container %s: dependency %s is not running", cs.Name, dep)
+			}
+			if cj.State.Running && (cj.State.Health == nil || cj.State.Health.Status == "healthy") {
+				break
+			}
+			if cj.State.Health != nil && cj.State.Health.Status == "unhealthy" {
+				return fmt.Errorf("container %s: dependency %s is unhealthy", cs.Name, dep)
+			}
+			if err := sleepBackoff(ctx, backoff, n); err != nil {
+				return fmt.Errorf("container %s: wait for dependency %s to be healthy: %w", cs.Name, dep, err)
+			}
+		}
+	}
+	return nil
+}
+
+// pruneNetworks removes networks of the run which are not part of the spec.
+func (a *applier) pruneNetworks(ctx context.Context, spec *Spec, owned map[string][]string) error {
+	existing, err := a.c.listNetworks(ctx, owned)
+	if err != nil {
+		return fmt.Errorf("list networks: %w", err)
+	}
+	for _, n := range existing {
+		if spec.network(n.Name) != nil {
+			continue
+		}
+		if err := a.removeNetwork(ctx, n); err != nil && !isNotFound(err) {
+			return fmt.Errorf("remove network %s: %w", n.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Spec) container(name string) *ContainerSpec {
+	for i := range s.Containers {
+		if s.Containers[i].Name == name {
+			return &s.Containers[i]
+		}
+	}
+	return nil
+}
+
+func (s *Spec) network(name string) *NetworkSpec {
+	for i := range s.Networks {
+		if s.Networks[i].Name == name {
+			return &s.Networks[i]
+		}
+	}
+	return nil
+}
+
+// rollback removes the created resources in reverse order. Failures are
+// ignored, the rollback removes as much as possible.
+func (c *Client) rollback(ctx context.Context, ops []undoable) {
+	for i := len(ops) - 1; i >= 0; i-- {
+		u := ops[i]
+		if u.op.Action != "create" {
+			continue
+		}
+		switch u.op.Resource {
+		case "container":
+			c.removeContainer(ctx, u.id, true)
+		case "network":
+			c.recycleNetwork(ctx, u.id)
+		case "volume":
+			c.removeVolume(ctx, u.id, true)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_Apply(t *testing.T) {
+	spec := &Spec{
+		Run:      "test",
+		Networks: []NetworkSpec{{Name: "subnet"}},
+		Containers: []ContainerSpec{
+			{
+				Name:     "plc",
+				Image:    "nginxdemos/hello:plain-text",
+				Networks: []NetworkAttachment{{Network: "subnet", Aliases: []string{"plc"}}},
+			},
+		},
+	}
+
+	tt := []struct {
+		name    string
+		routes  map[string]mockResponse
+		expect  []string
+		wantErr bool
+	}{
+		{
+			name: "create",
+			routes: map[string]mockResponse{
+				"GET /volumes":              {Body: `{"Volumes": []}`},
+				"GET /networks":             {Body: `[]`},
+				"POST /networks/create":     {StatusCode: http.StatusCreated, Body: `{"Id": "n1"}`},
+				"GET /containers/json":      {Body: `[]`},
+				"POST /containers/create":   {StatusCode: http.StatusCreated, Body: `{"Id": "c1"}`},
+				"POST /containers/c1/start": {StatusCode: http.StatusNoContent},
+			},
+			expect: []string{
+				"GET /volumes",
+				"GET /networks",
+				"POST /networks/create",
+				"GET /containers/json",
+				"POST /containers/create",
+				"POST /containers/c1/start",
+				"GET /networks",
+			},
+		},
+		{
+			name: "recreate changed",
+			routes: map[string]mockResponse{
+				"GET /volumes": {Body: `{"Volumes": []}`},
+				"GET /networks": {Body: fmt.Sprintf(`[{"Id": "n1", "Name": "subnet", "Labels": {%q: %q}}]`,
+					LabelConfigHash, spec.Networks[0].hash())},
+				"GET /containers/json": {Body: `[
+					{"Id": "c1", "Names": ["/plc"], "Labels": {"` + LabelConfigHash + `": "old"}},
+					{"Id": "c2", "Names": ["/gone"], "Labels": {}}
+				]`},
+				"DELETE /containers/c1":     {StatusCode: http.StatusNoContent},
+				"POST /containers/create":   {StatusCode: http.StatusCreated, Body: `{"Id": "c3"}`},
+				"POST /containers/c3/start": {StatusCode: http.StatusNoContent},
+				"DELETE /containers/c2":     {StatusCode: http.StatusNoContent},
+			},
+			expect: []string{
+				"GET /volumes",
+				"GET /networks",
+				"GET /containers/json",
+				"DELETE /containers/c1",
+				"POST /containers/create",
+				"POST /containers/c3/start",
+				"DELETE /containers/c2",
+				"GET /networks",
+			},
+		},
+		{
+			name: "fail",
+			routes: map[string]mockResponse{
+				"GET /volumes": {StatusCode: http.StatusInternalServerError, Body: `{"message": "boom"}`},
+			},
+			expect:  []string{"GET /volumes"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			srv.route(tc.routes)
+			defer srv.route(nil)
+
+			_, err := client.Apply(context.Background(), spec)
+			if err != nil && !tc.wantErr {
+				t.Error(err)
+			}
+			if err == nil && tc.wantErr {
+				t.Error("expected error")
+			}
+			if got := srv.Requests(); !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("got: %v, want: %v", got, tc.expect)
+			}
+		})
+	}
+}
+
+func Test_SpecValidate(t *testing.T) {
+	tt := []struct {
+		name    string
+		spec    Spec
+		wantErr bool
+	}{
+		{
+			name: "expected",
+			spec: Spec{Run: "r", Containers: []ContainerSpec{{Name: "a", Image: "i", Ports: []string{"8080:80"}}}},
+		},
+		{
+			name:    "missing run",
+			spec:    Spec{},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate container",
+			spec:    Spec{Run: "r", Containers: []ContainerSpec{{Name: "a", Image: "i"}, {Name: "a", Image: "i"}}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid mount",
+			spec:    Spec{Run: "r", Containers: []ContainerSpec{{Name: "a", Image: "i", Mounts: []string{"/tmp"}}}},
+			wantErr: true,
+		},
+		{
+			name: "dependency cycle",
+			spec: Spec{Run: "r", Containers: []ContainerSpec{
+				{Name: "a", Image: "i", DependsOn: []string{"b"}},
+				{Name: "b", Image: "i", DependsOnHealthy: []string{"a"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "external dependency",
+			spec: Spec{Run: "r", Containers: []ContainerSpec{{Name: "a", Image: "i", DependsOnHealthy: []string{"broker"}}}},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.spec.Validate()
+			if err != nil && !tc.wantErr {
+				t.Error(err)
+			}
+			if err == nil && tc.wantErr {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func Test_ApplyDryRun(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /volumes":         {Body: `{"Volumes": []}`},
+		"GET /networks":        {Body: `[]`},
+		"GET /containers/json": {Body: `[{"Id": "c2", "Names": ["/gone"], "Labels": {}}]`},
+	})
+	defer srv.route(nil)
+
+	spec := &Spec{
+		Run:      "test",
+		Networks: []NetworkSpec{{Name: "subnet"}, {Name: "other"}},
+		Containers: []ContainerSpec{
+			{
+				Name:     "plc",
+				Image:    "nginxdemos/hello:plain-text",
+				Networks: []NetworkAttachment{{Network: "subnet"}, {Network: "other"}},
+			},
+		},
+	}
+	ops, err := client.Apply(context.Background(), spec, DryRun())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, op := range ops {
+		got = append(got, op.String())
+	}
+	expect := []string{
+		"create network subnet",
+		"create network other",
+		"create container plc",
+		"connect container plc to other",
+		"start container plc",
+		"remove container gone",
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("got: %v, want: %v", got, expect)
+	}
+	for _, r := range srv.Requests() {
+		if r[:4] != "GET " {
+			t.Errorf("unexpected request %s", r)
+		}
+	}
+}
+
+func Test_ApplyHealthyDependencies(t *testing.T) {
+	spec := &Spec{
+		Run: "test",
+		Containers: []ContainerSpec{
+			{Name: "app", Image: "sim/app", DependsOnHealthy: []string{"db"}},
+			{Name: "db", Image: "postgres"},
+		},
+	}
+	routes := func(health string) map[string]mockResponse {
+		return map[string]mockResponse{
+			"GET /volumes":                     {Body: `{"Volumes": []}`},
+			"GET /networks":                    {Body: `[]`},
+			"GET /containers/json":             {Body: `[]`},
+			"POST /containers/create?name=db":  {StatusCode: http.StatusCreated, Body: `{"Id": "c1"}`},
+			"POST /containers/c1/start":        {StatusCode: http.StatusNoContent},
+			"POST /containers/create?name=app": {StatusCode: http.StatusCreated, Body: `{"Id": "c2"}`},
+			"POST /containers/c2/start":        {StatusCode: http.StatusNoContent},
+			"GET /containers/db/json": {
+				Body: `{"Id": "c1", "State": {"Running": true, "Health": {"Status": "starting"}}}`,
+				Next: &mockResponse{Body: fmt.Sprintf(`{"Id": "c1", "State": {"Running": true, "Health": {"Status": %q}}}`, health)},
+			},
+		}
+	}
+	c := NewClient(sockPath)
+	c.SetBackoff(ConstantBackoff(time.Millisecond))
+
+	tt := []struct {
+		name    string
+		health  string
+		expect  []string
+		wantErr bool
+	}{
+		{
+			name:   "healthy",
+			health: "healthy",
+			expect: []string{
+				"GET /volumes",
+				"GET /networks",
+				"GET /containers/json",
+				"POST /containers/create",
+				"POST /containers/c1/start",
+				"POST /containers/create",
+				"GET /containers/db/json",
+				"GET /containers/db/json",
+				"POST /containers/c2/start",
+				"GET /networks",
+			},
+		},
+		{
+			name:   "unhealthy",
+			health: "unhealthy",
+			expect: []string{
+				"GET /volumes",
+				"GET /networks",
+				"GET /containers/json",
+				"POST /containers/create",
+				"POST /containers/c1/start",
+				"POST /containers/create",
+				"GET /containers/db/json",
+				"GET /containers/db/json",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			srv.route(routes(tc.health))
+			defer srv.route(nil)
+
+			_, err := c.Apply(context.Background(), spec)
+			if err != nil && !tc.wantErr {
+				t.Error(err)
+			}
+			if err == nil && tc.wantErr {
+				t.Error("expected error")
+			}
+			if got := srv.Requests(); !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("got: %v, want: %v", got, tc.expect)
+			}
+		})
+	}
+}
+
+func Test_ApplyParallel(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /volumes":                       {Body: `{"Volumes": []}`},
+		"GET /networks":                      {Body: `[]`},
+		"GET /containers/json":               {Body: `[]`},
+		"POST /containers/create?name=db":    {StatusCode: http.StatusCreated, Body: `{"Id": "c1"}`},
+		"POST /containers/create?name=cache": {StatusCode: http.StatusCreated, Body: `{"Id": "c2"}`},
+		"POST /containers/create?name=app":   {StatusCode: http.StatusCreated, Body: `{"Id": "c3"}`},
+		"POST /containers/c1/start":          {StatusCode: http.StatusNoContent, Delay: 100 * time.Millisecond},
+		"POST /containers/c2/start":          {StatusCode: http.StatusNoContent, Delay: 100 * time.Millisecond},
+		"POST /containers/c3/start":          {StatusCode: http.StatusNoContent},
+	})
+	defer srv.route(nil)
+
+	spec := &Spec{
+		Run: "test",
+		Containers: []ContainerSpec{
+			{Name: "app", Image: "sim/app", DependsOn: []string{"db", "cache"}},
+			{Name: "db", Image: "postgres"},
+			{Name: "cache", Image: "redis"},
+		},
+	}
+	start := time.Now()
+	ops, err := client.Apply(context.Background(), spec, Parallel(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d >= 200*time.Millisecond {
+		t.Errorf("independent containers were not started in parallel, took %v", d)
+	}
+	if len(ops) != 6 {
+		t.Fatalf("got %d operations, want: 6", len(ops))
+	}
+	if got := ops[4].String() + ", " + ops[5].String(); got != "create container app, start container app" {
+		t.Errorf("app was not set up after its dependencies: %v", ops)
+	}
+}
+
+func Test_ApplyRollback(t *testing.T) {
+	spec := &Spec{
+		Run: "test",
+		Containers: []ContainerSpec{
+			{Name: "db", Image: "postgres"},
+			{Name: "app", Image: "sim/app", DependsOn: []string{"db"}},
+		},
+	}
+	routes := map[string]mockResponse{
+		"GET /volumes":                     {Body: `{"Volumes": []}`},
+		"GET /networks":                    {Body: `[]`},
+		"GET /containers/json":             {Body: `[]`},
+		"POST /containers/create?name=db":  {StatusCode: http.StatusCreated, Body: `{"Id": "c1"}`},
+		"POST /containers/c1/start":        {StatusCode: http.StatusNoContent},
+		"POST /containers/create?name=app": {StatusCode: http.StatusCreated, Body: `{"Id": "c2"}`},
+		"POST /containers/c2/start":        {StatusCode: http.StatusInternalServerError, Body: `{"message": "boom"}`},
+		"POST /containers/c1/stop":         {StatusCode: http.StatusNoContent},
+		"DELETE /containers/c2":            {StatusCode: http.StatusNoContent},
+		"DELETE /containers/c1":            {StatusCode: http.StatusNoContent},
+	}
+	applied := []string{
+		"GET /volumes",
+		"GET /networks",
+		"GET /containers/json",
+		"POST /containers/create",
+		"POST /containers/c1/start",
+		"POST /containers/create",
+		"POST /containers/c2/start",
+	}
+
+	tt := []struct {
+		name   string
+		mode   RollbackMode
+		expect []string
+	}{
+		{
+			name:   "none",
+			mode:   NoRollback,
+			expect: applied,
+		},
+		{
+			name: "stop",
+			mode: RollbackStop,
+			// app failed to start, it is not stopped
+			expect: append(append([]string(nil), applied...), "POST /containers/c1/stop"),
+		},
+		{
+			name:   "remove",
+			mode:   RollbackRemove,
+			expect: append(append([]string(nil), applied...), "DELETE /containers/c2", "DELETE /containers/c1"),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			srv.route(routes)
+			defer srv.route(nil)
+
+			ops, err := client.Apply(context.Background(), spec, Rollback(tc.mode))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			// the failed start of app is neither reported nor undone
+			if got := fmt.Sprint(ops); got != "[create container db start container db create container app]" {
+				t.Errorf("got operations %s", got)
+			}
+			if got := srv.Requests(); !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("got: %v, want: %v", got, tc.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package docker
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Backoff is the policy of the waits between attempts, both of retries and
+// of polling. Delay returns the wait after the attempt n, starting at 0.
+type Backoff interface {
+	Delay(n int) time.Duration
+}
+
+// BackoffFunc adapts a function to a Backoff.
+type BackoffFunc func(n int) time.Duration
+
+// Delay calls f.
+func (f BackoffFunc) Delay(n int) time.Duration {
+	return f(n)
+}
+
+// ConstantBackoff waits d between all attempts.
+func ConstantBackoff(d time.Duration) Backoff {
+	return BackoffFunc(func(int) time.Duration {
+		return d
+	})
+}
+
+// ExponentialBackoff waits base after the first attempt and doubles the
+// wait for every further one, up to max if it is not zero. Every wait is
+// randomized by the fraction jitter, e.g. with 0.2 it is between 80% and
+// 120% of the computed one, so clients started together spread out.
+func ExponentialBackoff(base, max time.Duration, jitter float64) Backoff {
+	return BackoffFunc(func(n int) time.Duration {
+		d := base
+		for i := 0; i < n && (max <= 0 || d < max) && d < 1<<61; i++ {
+			d *= 2
+		}
+		if max > 0 && d > max {
+			d = max
+		}
+		if jitter > 0 {
+			d += time.Duration((rand.Float64()*2 - 1) * jitter * float64(d))
+		}
+		return d
+	})
+}
+
+// SetBackoff sets the policy of all waits of the client between attempts:
+// the retries of rate limited pulls and of conflicts, the polling of
+// containers and of dind daemons and the resubscription to events. Waits
+// set explicitly by options, e.g. PullOptions.Backoff or
+// RunOptions.PollInterval, take precedence. nil restores the defaults of
+// every wait. SetBackoff has to be called before the client is used.
+func (c *Client) SetBackoff(b Backoff) {
+	c.backoff = b
+}
+
+// backoffOr returns the policy of the client or def if none is set.
+func (c *Client) backoffOr(def Backoff) Backoff {
+	if c.backoff != nil {
+		return c.backoff
+	}
+	return def
+}
+
+// sleepBackoff waits for the delay of the attempt n or until the context is
+// done.
+func sleepBackoff(ctx context.Context, b Backoff, n int) error {
+	t := time.NewTimer(b.Delay(n))
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
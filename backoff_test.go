@@ -0,0 +1,90 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_Backoff(t *testing.T) {
+	tt := []struct {
+		name   string
+		b      Backoff
+		expect []time.Duration
+	}{
+		{
+			name:   "constant",
+			b:      ConstantBackoff(time.Second),
+			expect: []time.Duration{time.Second, time.Second, time.Second},
+		},
+		{
+			name:   "exponential",
+			b:      ExponentialBackoff(time.Second, 0, 0),
+			expect: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second},
+		},
+		{
+			name:   "exponential with max",
+			b:      ExponentialBackoff(time.Second, 3*time.Second, 0),
+			expect: []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second},
+		},
+		{
+			name:   "func",
+			b:      BackoffFunc(func(n int) time.Duration { return time.Duration(n) * time.Millisecond }),
+			expect: []time.Duration{0, time.Millisecond, 2 * time.Millisecond},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []time.Duration
+			for n := range tc.expect {
+				got = append(got, tc.b.Delay(n))
+			}
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("got: %v, want: %v", got, tc.expect)
+			}
+		})
+	}
+
+	// the delays do not overflow
+	if d := ExponentialBackoff(time.Second, 0, 0).Delay(100); d <= 0 {
+		t.Errorf("expected a positive delay, got %v", d)
+	}
+	// the jitter stays within its fraction
+	b := ExponentialBackoff(time.Second, 0, 0.2)
+	for i := 0; i < 100; i++ {
+		if d := b.Delay(1); d < 1600*time.Millisecond || d > 2400*time.Millisecond {
+			t.Fatalf("expected a delay between 1.6s and 2.4s, got %v", d)
+		}
+	}
+}
+
+func Test_SetBackoff(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"POST /containers/create":   {StatusCode: http.StatusCreated, Body: `{"Id": "c1"}`},
+		"POST /containers/c1/start": {StatusCode: http.StatusNoContent},
+		"GET /containers/c1/json": {Body: `{"Id": "c1", "State": {"Status": "running", "Running": true}}`,
+			Next: &mockResponse{Body: `{"Id": "c1", "State": {"Status": "running", "Running": true}}`,
+				Next: &mockResponse{Body: `{"Id": "c1", "State": {"Status": "exited", "ExitCode": 0}}`}}},
+	})
+	defer srv.route(nil)
+
+	c := NewClient(sockPath)
+	var attempts []int
+	c.SetBackoff(BackoffFunc(func(n int) time.Duration {
+		attempts = append(attempts, n)
+		return time.Millisecond
+	}))
+
+	// the default poll interval would exceed the timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if _, err := c.Run(ctx, ContainerSpec{Image: "busybox"}, RunOptions{Wait: WaitExited}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 1}; !reflect.DeepEqual(attempts, want) {
+		t.Errorf("got attempts: %v, want: %v", attempts, want)
+	}
+}
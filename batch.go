@@ -0,0 +1,212 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BatchError holds the errors of a batch operation by the ID of the failed
+// container.
+type BatchError map[string]error
+
+func (e BatchError) Error() string {
+	ids := make([]string, 0, len(e))
+	for id := range e {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	ss := make([]string, len(ids))
+	for i, id := range ids {
+		ss[i] = fmt.Sprintf("%s: %v", id, e[id])
+	}
+	return fmt.Sprintf("%d of the operations failed: %s", len(e), strings.Join(ss, "; "))
+}
+
+// StartAll starts the containers with at most concurrency requests in
+// parallel. Containers which are running already are not treated as error.
+// If starting fails for some containers, a BatchError is returned.
+func (c *Client) StartAll(ctx context.Context, ids []string, concurrency int) error {
+	return forEach(ctx, ids, concurrency, c.startContainer)
+}
+
+// StopAll stops the containers with at most concurrency requests in parallel.
+// Containers which are stopped already are not treated as error. If stopping
+// fails for some containers, a BatchError is returned.
+func (c *Client) StopAll(ctx context.Context, ids []string, concurrency int) error {
+	return forEach(ctx, ids, concurrency, c.stopContainer)
+}
+
+// ConnectSpec is a container to connect by ConnectAll.
+type ConnectSpec struct {
+	// Container is the name or ID of the container.
+	Container string
+	ConnectOptions
+}
+
+// ConnectAll connects the containers to the network with at most
+// concurrency requests in parallel. If connecting fails for some containers,
+// a BatchError keyed by container is returned.
+func (c *Client) ConnectAll(ctx context.Context, networkID string, containers []ConnectSpec, concurrency int) error {
+	opts := make(map[string]ConnectOptions, len(containers))
+	ids := make([]string, 0, len(containers))
+	for _, cs := range containers {
+		if _, ok := opts[cs.Container]; ok {
+			return fmt.Errorf("connect to %s: duplicate container %s", networkID, cs.Container)
+		}
+		opts[cs.Container] = cs.ConnectOptions
+		ids = append(ids, cs.Container)
+	}
+	return forEach(ctx, ids, concurrency, func(ctx context.Context, id string) error {
+		return c.connectNetwork(ctx, networkID, id, opts[id].endpointConfig())
+	})
+}
+
+// forEach calls fn for every ID by a pool of concurrency workers. If the
+// context is done, the remaining IDs are not processed and fail with the
+// error of the context.
+func forEach(ctx context.Context, ids []string, concurrency int, fn func(context.Context, string) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		mu   sync.Mutex
+		errs = make(BatchError)
+		wg   sync.WaitGroup
+		work = make(chan string)
+	)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range work {
+				err := ctx.Err()
+				if err == nil {
+					err = fn(ctx, id)
+				}
+				if err != nil {
+					mu.Lock()
+					errs[id] = err
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, id := range ids {
+		work <- id
+	}
+	close(work)
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// graphOptions configures forEachInGraph.
+type graphOptions struct {
+	// concurrency limits the calls at the same time. With 1 the containers
+	// are handled one after the other in the given order, which has to
+	// follow the dependencies.
+	concurrency int
+	// deps returns the dependencies of a container. Those which are not
+	// part of the graph are ignored.
+	deps func(ContainerSpec) []string
+	// failFast skips the containers which did not begin yet once a call
+	// failed.
+	failFast bool
+}
+
+// skipError is the error of a container forEachInGraph did not call fn for.
+type skipError struct {
+	err error
+}
+
+func (e *skipError) Error() string { return e.err.Error() }
+func (e *skipError) Unwrap() error { return e.err }
+
+// errSkippedAfterFailure is the error of the containers skipped by
+// failFast.
+var errSkippedAfterFailure = errors.New("skipped after a failure")
+
+// forEachInGraph calls fn for the containers. A container is handled after
+// all its dependencies. Containers whose dependency failed, and those which
+// did not begin before the context was done, fail with a *skipError. The
+// errors are returned by container name, the BatchError is empty if all
+// calls succeeded.
+func forEachInGraph(ctx context.Context, order []ContainerSpec, opts graphOptions, fn func(ContainerSpec) error) BatchError {
+	if opts.concurrency < 1 {
+		opts.concurrency = 1
+	}
+	var (
+		sem    = make(chan struct{}, opts.concurrency)
+		done   = make(map[string]chan struct{}, len(order))
+		mu     sync.Mutex
+		errs   = make(BatchError)
+		failed bool
+		wg     sync.WaitGroup
+	)
+	for _, cs := range order {
+		done[cs.Name] = make(chan struct{})
+	}
+	// skip returns the error of a container which shall not be handled
+	skip := func(cs ContainerSpec) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, dep := range opts.deps(cs) {
+			if _, ok := errs[dep]; ok {
+				return &skipError{fmt.Errorf("dependency %s failed", dep)}
+			}
+		}
+		if opts.failFast && failed {
+			return &skipError{errSkippedAfterFailure}
+		}
+		return nil
+	}
+
+	for _, cs := range order {
+		wg.Add(1)
+		go func(cs ContainerSpec) {
+			defer wg.Done()
+			defer close(done[cs.Name])
+
+			err := func() error {
+				for _, dep := range opts.deps(cs) {
+					if ch, ok := done[dep]; ok {
+						<-ch
+					}
+				}
+				if err := skip(cs); err != nil {
+					return err
+				}
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					return &skipError{ctx.Err()}
+				}
+				defer func() { <-sem }()
+				if err := skip(cs); err != nil {
+					return err
+				}
+				return fn(cs)
+			}()
+			if err != nil {
+				mu.Lock()
+				errs[cs.Name] = err
+				failed = true
+				mu.Unlock()
+			}
+		}(cs)
+		if opts.concurrency == 1 {
+			<-done[cs.Name]
+		}
+	}
+	wg.Wait()
+	return errs
+}
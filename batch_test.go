@@ -0,0 +1,121 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_forEach(t *testing.T) {
+	var (
+		running, max int32
+		ids          = []string{"a", "b", "c", "d", "e", "f"}
+	)
+	err := forEach(context.Background(), ids, 2, func(_ context.Context, id string) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		if id == "c" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	be, ok := err.(BatchError)
+	if !ok || len(be) != 1 || be["c"] == nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if max > 2 {
+		t.Errorf("concurrency exceeded: %d", max)
+	}
+}
+
+func Test_forEachInGraph(t *testing.T) {
+	order := []ContainerSpec{
+		{Name: "db"},
+		{Name: "cache"},
+		{Name: "web", DependsOn: []string{"db", "cache"}},
+	}
+	boom := errors.New("boom")
+	tt := []struct {
+		name     string
+		failFast bool
+		expect   map[string]string
+	}{
+		{name: "dependency failed", expect: map[string]string{"db": "boom", "web": "dependency db failed"}},
+		{name: "fail fast", failFast: true, expect: map[string]string{"db": "boom", "cache": "skipped after a failure",
+			"web": "dependency db failed"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := forEachInGraph(context.Background(), order, graphOptions{
+				concurrency: 1,
+				deps:        func(cs ContainerSpec) []string { return cs.DependsOn },
+				failFast:    tc.failFast,
+			}, func(cs ContainerSpec) error {
+				if cs.Name == "db" {
+					return boom
+				}
+				return nil
+			})
+			got := make(map[string]string, len(errs))
+			for name, err := range errs {
+				got[name] = err.Error()
+			}
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("got: %v, want: %v", got, tc.expect)
+			}
+			if _, ok := errs["web"].(*skipError); !ok {
+				t.Errorf("expected web to be skipped, got %v", errs["web"])
+			}
+		})
+	}
+}
+
+func Test_StartAll(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"POST /containers/c1/start": {StatusCode: http.StatusNoContent},
+		"POST /containers/c2/start": {StatusCode: http.StatusNotModified},
+	})
+	defer srv.route(nil)
+
+	err := client.StartAll(context.Background(), []string{"c1", "c2", "c3"}, 2)
+	be, ok := err.(BatchError)
+	if !ok || len(be) != 1 || !isNotFound(be["c3"]) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func Test_ConnectAll(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"POST /networks/field/connect": {Next: &mockResponse{StatusCode: http.StatusInternalServerError, Body: `{"message": "boom"}`}},
+	})
+	defer srv.route(nil)
+
+	containers := []ConnectSpec{
+		{Container: "a", ConnectOptions: ConnectOptions{IPv4Address: "10.0.0.2"}},
+		{Container: "b", ConnectOptions: ConnectOptions{Aliases: []string{"meter"}}},
+		{Container: "c"},
+	}
+	err := client.ConnectAll(context.Background(), "field", containers, 1)
+	be, ok := err.(BatchError)
+	if !ok || len(be) != 2 || be["b"] == nil || be["c"] == nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if n := len(srv.Requests()); n != 3 {
+		t.Errorf("got %d requests, want: 3", n)
+	}
+
+	err = client.ConnectAll(context.Background(), "field", []ConnectSpec{{Container: "a"}, {Container: "a"}}, 1)
+	if err == nil {
+		t.Error("expected error for duplicate container")
+	}
+}
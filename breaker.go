@@ -0,0 +1,118 @@
+package docker
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrDaemonUnavailable is returned without contacting the daemon while the
+// circuit breaker of the client is open, see SetBreaker.
+var ErrDaemonUnavailable = errors.New("docker daemon unavailable")
+
+// BreakerOptions configures the circuit breaker of a client.
+type BreakerOptions struct {
+	// Threshold is the number of consecutive connection failures which
+	// open the breaker. Defaults to 5.
+	Threshold int
+	// Cooldown is the time the breaker stays open before a single probe
+	// request is let through. Defaults to 10s.
+	Cooldown time.Duration
+}
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 10 * time.Second
+)
+
+// SetBreaker enables a circuit breaker for the client, nil disables it.
+// Once the daemon could not be connected to Threshold times in a row, e.g.
+// because it restarts, requests fail immediately with ErrDaemonUnavailable.
+// After the cooldown the next request probes the daemon: if it succeeds the
+// breaker closes, otherwise it stays open for another cooldown. Responses
+// of the daemon count as success whatever their status, requests canceled
+// by their context are not counted. SetBreaker has to be called before the
+// client is used.
+func (c *Client) SetBreaker(opts *BreakerOptions) {
+	if opts == nil {
+		c.breaker = nil
+		c.chainTransport()
+		return
+	}
+	b := &breaker{
+		threshold: opts.Threshold,
+		cooldown:  opts.Cooldown,
+		now:       time.Now,
+	}
+	if b.threshold <= 0 {
+		b.threshold = defaultBreakerThreshold
+	}
+	if b.cooldown <= 0 {
+		b.cooldown = defaultBreakerCooldown
+	}
+	c.breaker = b
+	c.chainTransport()
+}
+
+// breaker is a RoundTripper which implements the circuit breaker.
+type breaker struct {
+	next      http.RoundTripper
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	// openedAt is zero while the breaker is closed.
+	openedAt time.Time
+	probing  bool
+}
+
+func (b *breaker) RoundTrip(req *http.Request) (*http.Response, error) {
+	probe, err := b.allow()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	res, err := b.next.RoundTrip(req)
+	b.record(probe, err != nil && req.Context().Err() == nil, err == nil)
+	return res, err
+}
+
+// allow reports whether a request may be sent and whether it is the probe
+// of a half-open breaker.
+func (b *breaker) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openedAt.IsZero() {
+		return false, nil
+	}
+	if b.probing || b.now().Sub(b.openedAt) < b.cooldown {
+		return false, ErrDaemonUnavailable
+	}
+	b.probing = true
+	return true, nil
+}
+
+// record updates the state by the outcome of a request. Requests which
+// neither failed nor succeeded, like canceled ones, only end a probe.
+func (b *breaker) record(probe, failed, succeeded bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	}
+	switch {
+	case succeeded:
+		b.failures = 0
+		b.openedAt = time.Time{}
+	case failed:
+		b.failures++
+		if probe || b.failures >= b.threshold {
+			b.openedAt = b.now()
+		}
+	}
+}
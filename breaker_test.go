@@ -0,0 +1,67 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func Test_Breaker(t *testing.T) {
+	c := NewClient("/nonexistent/docker.sock")
+	c.SetBreaker(&BreakerOptions{Threshold: 2, Cooldown: time.Minute})
+	b := c.breaker
+	now := time.Now()
+	b.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	ping := func() error {
+		_, err := c.Ping(ctx)
+		return err
+	}
+	for i := 0; i < 2; i++ {
+		if err := ping(); err == nil || errors.Is(err, ErrDaemonUnavailable) {
+			t.Fatalf("expected connection error, got %v", err)
+		}
+	}
+	if err := ping(); !errors.Is(err, ErrDaemonUnavailable) {
+		t.Fatalf("expected ErrDaemonUnavailable, got %v", err)
+	}
+
+	// the failed probe opens the breaker again
+	now = now.Add(time.Minute)
+	if err := ping(); err == nil || errors.Is(err, ErrDaemonUnavailable) {
+		t.Fatalf("expected probe to fail, got %v", err)
+	}
+	if err := ping(); !errors.Is(err, ErrDaemonUnavailable) {
+		t.Fatalf("expected ErrDaemonUnavailable, got %v", err)
+	}
+
+	// the daemon is back
+	b.next = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("OK")), Header: http.Header{}}, nil
+	})
+	now = now.Add(time.Minute)
+	for i := 0; i < 2; i++ {
+		if err := ping(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// canceled requests are not counted
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	c.SetBreaker(&BreakerOptions{Threshold: 1})
+	for i := 0; i < 2; i++ {
+		if _, err := c.Ping(canceled); errors.Is(err, ErrDaemonUnavailable) {
+			t.Fatal("breaker opened by canceled request")
+		}
+	}
+}
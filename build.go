@@ -0,0 +1,318 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// BuildOptions configure BuildImage.
+type BuildOptions struct {
+	// Tags are the references of the built image, e.g. "sim/plc:latest".
+	Tags []string
+	// Dockerfile is the path of the Dockerfile within the context. It
+	// defaults to Dockerfile.
+	Dockerfile string
+	BuildArgs  map[string]string
+	Labels     map[string]string
+	// Target is the stage of a multi-stage build to build.
+	Target string
+	// Platform e.g.: "linux/arm64"
+	Platform string
+	NoCache  bool
+	// Pull pulls newer versions of the base images.
+	Pull bool
+	// Squash squashes the layers of the build into a single layer on top of
+	// the base image. It requires a daemon with experimental features and
+	// the legacy builder, otherwise use FlattenImage after the build.
+	Squash bool
+	// Compress gzips the context while it is uploaded. The daemon detects
+	// the compression, it only pays off for remote daemons.
+	Compress bool
+	// UploadProgress is called with the number of bytes of the context sent
+	// so far, compressed if Compress is set.
+	UploadProgress func(sent int64)
+	// ContextDir transfers the context from the directory through a
+	// BuildKit session instead of the buildContext argument of BuildImage,
+	// which has to be nil then. BuildKit keeps the context of earlier builds
+	// of the directory and only requests the files which changed since, which
+	// speeds up repeated builds over slow connections considerably.
+	ContextDir string
+	// Secrets are served to RUN instructions which mount them. They
+	// require BuildKit, the build is run by BuildKit if they are set.
+	Secrets []BuildSecret
+	// SSH are the SSH agents forwarded to RUN instructions which mount
+	// them, like --ssh default. They require BuildKit as well.
+	SSH []BuildSSH
+	// Output receives the build output, e.g. the steps and the output of
+	// their commands. BuildKit reports its progress in a binary format
+	// which is not passed.
+	Output io.Writer
+}
+
+// BuildImage builds an image from the tar archive of the build context, e.g.
+// of BuildContext, and returns its ID. The context is streamed to the daemon.
+// The long timeout of the client applies to builds, see SetTimeouts.
+// docs.: https://docs.docker.com/engine/api/v1.41/#operation/ImageBuild
+func (c *Client) BuildImage(ctx context.Context, buildContext io.Reader, opts BuildOptions) (string, error) {
+	q, err := opts.query()
+	if err != nil {
+		return "", fmt.Errorf("build image: %w", err)
+	}
+	if sess, err := opts.session(); err != nil {
+		return "", fmt.Errorf("build image: %w", err)
+	} else if sess != nil {
+		stop, err := c.runSession(ctx, sess)
+		if err != nil {
+			return "", fmt.Errorf("build image: %w", err)
+		}
+		defer stop()
+		q.Set("version", "2")
+		q.Set("session", sess.id)
+		if opts.ContextDir != "" {
+			q.Set("remote", "client-session")
+			q.Set("dockerfile", filepath.Base(opts.dockerfile()))
+		}
+	}
+	body := buildContext
+	if opts.Compress && body != nil {
+		zr := gzipStream(body)
+		defer zr.Close()
+		body = zr
+	}
+	if opts.UploadProgress != nil && body != nil {
+		body = &progressReader{r: body, progress: opts.UploadProgress}
+	}
+	header := http.Header{"Content-Type": {"application/x-tar"}}
+	r, err := c.streamHeader(ctx, http.MethodPost, "build", q, body, header)
+	if err != nil {
+		return "", fmt.Errorf("build image: %w", err)
+	}
+	defer r.Body.Close()
+	if err := checkResponse(r, http.StatusOK); err != nil {
+		return "", fmt.Errorf("build image: %w", err)
+	}
+
+	var id string
+	err = ReadJSONMessages(r.Body, func(msg *JSONMessage) error {
+		// Aux is the ID of the image or a base64 encoded progress message
+		// of BuildKit
+		if len(msg.Aux) > 0 && msg.Aux[0] == '{' {
+			var aux struct {
+				ID string `json:"ID"`
+			}
+			if err := json.Unmarshal(msg.Aux, &aux); err == nil && aux.ID != "" {
+				id = aux.ID
+			}
+		}
+		if msg.Stream != "" && opts.Output != nil {
+			io.WriteString(opts.Output, msg.Stream)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("build image: %w", err)
+	}
+	if id == "" {
+		return "", fmt.Errorf("build image: daemon did not report the image ID")
+	}
+	return id, nil
+}
+
+func (o BuildOptions) query() (url.Values, error) {
+	q := url.Values{"rm": {"1"}}
+	for _, t := range o.Tags {
+		q.Add("t", t)
+	}
+	if o.Dockerfile != "" {
+		q.Set("dockerfile", o.Dockerfile)
+	}
+	for name, m := range map[string]map[string]string{"buildargs": o.BuildArgs, "labels": o.Labels} {
+		if len(m) == 0 {
+			continue
+		}
+		b, err := json.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		q.Set(name, string(b))
+	}
+	if o.Target != "" {
+		q.Set("target", o.Target)
+	}
+	if o.Platform != "" {
+		q.Set("platform", o.Platform)
+	}
+	if o.NoCache {
+		q.Set("nocache", "1")
+	}
+	if o.Pull {
+		q.Set("pull", "1")
+	}
+	if o.Squash {
+		q.Set("squash", "1")
+	}
+	return q, nil
+}
+
+func (o BuildOptions) dockerfile() string {
+	if o.Dockerfile == "" {
+		return "Dockerfile"
+	}
+	return filepath.FromSlash(o.Dockerfile)
+}
+
+// session returns the BuildKit session the build needs, nil if it needs
+// none.
+func (o BuildOptions) session() (*buildSession, error) {
+	if len(o.Secrets) == 0 && len(o.SSH) == 0 && o.ContextDir == "" {
+		return nil, nil
+	}
+	s := newBuildSession()
+	if o.ContextDir != "" {
+		s.sharedKey = contextSharedKey(o.ContextDir)
+		s.addFileSync(map[string]string{
+			"context":    o.ContextDir,
+			"dockerfile": filepath.Dir(filepath.Join(o.ContextDir, o.dockerfile())),
+		})
+	}
+	if len(o.Secrets) > 0 {
+		if err := s.addSecrets(o.Secrets); err != nil {
+			return nil, err
+		}
+	}
+	if len(o.SSH) > 0 {
+		if err := s.addSSH(o.SSH); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// FlattenImage squashes all layers of the image into one by exporting the
+// filesystem of a container of the image and importing it as ref. The
+// configuration of the image, like its command and environment, is carried
+// over. Unlike BuildOptions.Squash it works with every daemon, but the base
+// layers are not shared with other images anymore. It returns the ID of the
+// new image.
+func (c *Client) FlattenImage(ctx context.Context, image, ref string) (string, error) {
+	img, err := c.inspectImage(ctx, image)
+	if err != nil {
+		return "", fmt.Errorf("inspect image %s: %w", image, err)
+	}
+	// the container is never started, but the daemon refuses to create
+	// containers without command
+	create := &containerCreate{Image: image}
+	if len(img.Config.Cmd) == 0 && len(img.Config.Entrypoint) == 0 {
+		create.Cmd = []string{"true"}
+	}
+	changes, err := configChanges(img.Config)
+	if err != nil {
+		return "", fmt.Errorf("flatten image %s: %w", image, err)
+	}
+	res, err := c.createContainer(ctx, "", create)
+	if err != nil {
+		return "", fmt.Errorf("flatten image %s: %w", image, err)
+	}
+	defer c.removeContainer(context.Background(), res.ID, true)
+
+	export, err := c.streamHeader(ctx, http.MethodGet, "containers/"+pathName(res.ID)+"/export", nil, nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("flatten image %s: %w", image, err)
+	}
+	defer export.Body.Close()
+	if err := checkResponse(export, http.StatusOK); err != nil {
+		return "", fmt.Errorf("flatten image %s: %w", image, err)
+	}
+
+	q := url.Values{"fromSrc": {"-"}, "changes": changes}
+	repo, tag := repoTag(ref)
+	q.Set("repo", repo)
+	if tag != "" {
+		q.Set("tag", tag)
+	}
+	header := http.Header{"Content-Type": {"application/x-tar"}}
+	r, err := c.streamHeader(ctx, http.MethodPost, "images/create", q, export.Body, header)
+	if err != nil {
+		return "", fmt.Errorf("flatten image %s: %w", image, err)
+	}
+	defer r.Body.Close()
+	if err := checkResponse(r, http.StatusOK); err != nil {
+		return "", fmt.Errorf("flatten image %s: %w", image, err)
+	}
+	if err := readProgress(r.Body); err != nil {
+		return "", fmt.Errorf("flatten image %s: %w", image, err)
+	}
+
+	flat, err := c.inspectImage(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("inspect image %s: %w", ref, err)
+	}
+	return flat.ID, nil
+}
+
+// configChanges returns the Dockerfile instructions which restore the
+// configuration on import. Values with line breaks can not be expressed.
+func configChanges(cfg containerConfig) ([]string, error) {
+	var changes []string
+	for _, kv := range cfg.Env {
+		ss := strings.SplitN(kv, "=", 2)
+		if len(ss) == 1 {
+			ss = append(ss, "")
+		}
+		if strings.ContainsAny(ss[1], "\r\n") {
+			return nil, fmt.Errorf("environment variable %s contains a line break", ss[0])
+		}
+		changes = append(changes, "ENV "+ss[0]+"="+dockerfileQuote(ss[1]))
+	}
+	keys := make([]string, 0, len(cfg.Labels))
+	for k := range cfg.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if strings.ContainsAny(k+cfg.Labels[k], "\r\n") {
+			return nil, fmt.Errorf("label %s contains a line break", k)
+		}
+		changes = append(changes, "LABEL "+dockerfileQuote(k)+"="+dockerfileQuote(cfg.Labels[k]))
+	}
+	ports := make([]string, 0, len(cfg.ExposedPorts))
+	for p := range cfg.ExposedPorts {
+		ports = append(ports, p)
+	}
+	sort.Strings(ports)
+	for _, p := range ports {
+		changes = append(changes, "EXPOSE "+p)
+	}
+	if cfg.WorkingDir != "" {
+		changes = append(changes, "WORKDIR "+cfg.WorkingDir)
+	}
+	if cfg.User != "" {
+		changes = append(changes, "USER "+cfg.User)
+	}
+	if cfg.StopSignal != "" {
+		changes = append(changes, "STOPSIGNAL "+cfg.StopSignal)
+	}
+	if len(cfg.Entrypoint) > 0 {
+		b, _ := json.Marshal(cfg.Entrypoint)
+		changes = append(changes, "ENTRYPOINT "+string(b))
+	}
+	if len(cfg.Cmd) > 0 {
+		b, _ := json.Marshal(cfg.Cmd)
+		changes = append(changes, "CMD "+string(b))
+	}
+	return changes, nil
+}
+
+// dockerfileQuote quotes s for ENV and LABEL instructions, so it may contain
+// spaces. Backslashes and quotes are escaped and so are dollar signs, which
+// would substitute variables otherwise.
+func dockerfileQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`).Replace(s) + `"`
+}
@@ -0,0 +1,143 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_BuildImage(t *testing.T) {
+	q := url.Values{
+		"rm":        {"1"},
+		"t":         {"sim/plc:latest", "sim/plc:1.2"},
+		"buildargs": {`{"VERSION":"1.2"}`},
+		"squash":    {"1"},
+	}
+	srv.route(map[string]mockResponse{
+		"POST /build?" + q.Encode(): {Body: `{"stream": "Step 1/2 : FROM alpine\n"}
+			{"stream": "Step 2/2 : COPY sim /sim\n"}
+			{"aux": {"ID": "sha256:abc"}}
+			{"stream": "Successfully built abc\n"}`},
+	})
+	defer srv.route(nil)
+
+	var out bytes.Buffer
+	id, err := client.BuildImage(context.Background(), strings.NewReader(""), BuildOptions{
+		Tags:      []string{"sim/plc:latest", "sim/plc:1.2"},
+		BuildArgs: map[string]string{"VERSION": "1.2"},
+		Squash:    true,
+		Output:    &out,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "sha256:abc" {
+		t.Errorf("unexpected image ID %s", id)
+	}
+	if !strings.HasPrefix(out.String(), "Step 1/2") || !strings.HasSuffix(out.String(), "built abc\n") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	srv.route(map[string]mockResponse{
+		"POST /build": {Body: `{"stream": "Step 1/1 : RUN false\n"}
+			{"error": "The command '/bin/sh -c false' returned a non-zero code: 1"}`},
+	})
+	if _, err := client.BuildImage(context.Background(), strings.NewReader(""), BuildOptions{}); err == nil ||
+		!strings.Contains(err.Error(), "non-zero code") {
+		t.Errorf("expected build error, got %v", err)
+	}
+}
+
+func Test_FlattenImage(t *testing.T) {
+	q := url.Values{
+		"fromSrc": {"-"},
+		"repo":    {"sim/plc"},
+		"tag":     {"flat"},
+		"changes": {`ENV PATH="/usr/bin:/bin"`, `LABEL "vendor"="grid-x"`, "EXPOSE 502/tcp", `CMD ["/sim"]`},
+	}
+	srv.route(map[string]mockResponse{
+		"GET /images/sim/plc:latest/json": {Body: `{"Id": "sha256:abc", "Config": {"Env": ["PATH=/usr/bin:/bin"],
+			"Labels": {"vendor": "grid-x"}, "ExposedPorts": {"502/tcp": {}}, "Cmd": ["/sim"]}}`},
+		"POST /containers/create":           {StatusCode: 201, Body: `{"Id": "c1"}`},
+		"GET /containers/c1/export":         {Body: "tar"},
+		"POST /images/create?" + q.Encode(): {Body: `{"status": "sha256:def"}`},
+		"GET /images/sim/plc:flat/json":     {Body: `{"Id": "sha256:def"}`},
+		"DELETE /containers/c1":             {StatusCode: 204},
+	})
+	defer srv.route(nil)
+
+	id, err := client.FlattenImage(context.Background(), "sim/plc:latest", "sim/plc:flat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "sha256:def" {
+		t.Errorf("unexpected image ID %s", id)
+	}
+	want := []string{
+		"GET /images/sim/plc:latest/json",
+		"POST /containers/create",
+		"GET /containers/c1/export",
+		"POST /images/create",
+		"GET /images/sim/plc:flat/json",
+		"DELETE /containers/c1",
+	}
+	if got := srv.Requests(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want requests %v, got %v", want, got)
+	}
+}
+
+func Test_FlattenImageWithoutCmd(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /images/scratch-data:latest/json": {Body: `{"Id": "sha256:abc", "Config": {}}`},
+		"POST /containers/create":              {StatusCode: 201, Body: `{"Id": "c1"}`},
+		"GET /containers/c1/export":            {Body: "tar"},
+		"POST /images/create":                  {Body: `{"status": "sha256:def"}`},
+		"GET /images/scratch-data:flat/json":   {Body: `{"Id": "sha256:def"}`},
+		"DELETE /containers/c1":                {StatusCode: 204},
+	})
+	defer srv.route(nil)
+
+	if _, err := client.FlattenImage(context.Background(), "scratch-data:latest", "scratch-data:flat"); err != nil {
+		t.Fatal(err)
+	}
+	// the daemon needs a command to create the container
+	if body := srv.Bodies()[1]; !strings.Contains(body, `"Cmd":["true"]`) {
+		t.Errorf("expected a command, got %s", body)
+	}
+}
+
+func Test_configChanges(t *testing.T) {
+	changes, err := configChanges(containerConfig{
+		Env:    []string{`GREETING=say "hi"`, `PATH=C:\bin;$PATH`, "EMPTY"},
+		Labels: map[string]string{"desc": "100$ tool"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`ENV GREETING="say \"hi\""`, `ENV PATH="C:\\bin;\$PATH"`, `ENV EMPTY=""`, `LABEL "desc"="100\$ tool"`}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("want changes %q, got %q", want, changes)
+	}
+	if _, err := configChanges(containerConfig{Env: []string{"MOTD=a\nb"}}); err == nil {
+		t.Error("expected error for a line break")
+	}
+}
+
+func Test_repoTag(t *testing.T) {
+	digest := "@sha256:4bcff63911fcb4448bd4fdacec207030997caf25e9bea4045fa6c8c44de311d1"
+	tests := []struct{ ref, repo, tag string }{
+		{ref: "sim/plc", repo: "sim/plc"},
+		{ref: "sim/plc:1.0", repo: "sim/plc", tag: "1.0"},
+		{ref: "localhost:5000/sim/plc", repo: "localhost:5000/sim/plc"},
+		{ref: "sim/plc" + digest, repo: "sim/plc"},
+		{ref: "localhost:5000/sim/plc:1.0" + digest, repo: "localhost:5000/sim/plc", tag: "1.0"},
+	}
+	for _, tc := range tests {
+		if repo, tag := repoTag(tc.ref); repo != tc.repo || tag != tc.tag {
+			t.Errorf("%s: want %s, %s, got %s, %s", tc.ref, tc.repo, tc.tag, repo, tag)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// BuildContext returns the directory as tar archive for BuildImage. Files
+// matching the patterns of the .dockerignore file in the directory are
+// excluded, except the Dockerfile and the .dockerignore file itself which the
+// daemon always needs. The archive is written while it is read, the
+// directory is never buffered. The caller has to close the returned reader.
+func BuildContext(dir string) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeBuildContext(pw, dir))
+	}()
+	return pr
+}
+
+func writeBuildContext(w io.Writer, dir string) error {
+	ignore, err := readDockerignore(dir)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(w)
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if ignore.excludes(rel) {
+			if info.IsDir() && !ignore.hasExceptions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return writeContextFile(tw, p, rel, info)
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// writeContextFile adds the file p as name to the archive.
+func writeContextFile(tw *tar.Writer, p, name string, info os.FileInfo) error {
+	var link string
+	if info.Mode()&os.ModeSymlink != 0 {
+		var err error
+		if link, err = os.Readlink(p); err != nil {
+			return err
+		}
+	}
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if info.IsDir() {
+		hdr.Name += "/"
+	}
+	// the owner on the host is meaningless in the image
+	hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// dockerignore holds the patterns of a .dockerignore file. Patterns starting
+// with ! re-include files, the last matching pattern wins.
+// docs.: https://docs.docker.com/engine/reference/builder/#dockerignore-file
+type dockerignore []string
+
+func readDockerignore(dir string) (dockerignore, error) {
+	f, err := os.Open(filepath.Join(dir, ".dockerignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var patterns dockerignore
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		neg := strings.HasPrefix(line, "!")
+		line = path.Clean(strings.TrimPrefix(strings.TrimPrefix(line, "!"), "/"))
+		if neg {
+			line = "!" + line
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, s.Err()
+}
+
+// excludes reports whether the file with the slash separated path relative
+// to the context is excluded. A pattern matching a directory excludes its
+// content as well.
+func (d dockerignore) excludes(name string) bool {
+	if name == "Dockerfile" || name == ".dockerignore" {
+		return false
+	}
+	excluded := false
+	for _, p := range d {
+		neg := strings.HasPrefix(p, "!")
+		if matchParents(strings.TrimPrefix(p, "!"), name) {
+			excluded = !neg
+		}
+	}
+	return excluded
+}
+
+func (d dockerignore) hasExceptions() bool {
+	for _, p := range d {
+		if strings.HasPrefix(p, "!") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchParents matches the pattern against the name and its parents.
+func matchParents(pattern, name string) bool {
+	for {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+		i := strings.LastIndex(name, "/")
+		if i < 0 {
+			return false
+		}
+		name = name[:i]
+	}
+}
+
+// gzipStream compresses r while it is read. BestSpeed keeps up with fast
+// connections while still shrinking typical contexts considerably.
+func gzipStream(r io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		zw, _ := gzip.NewWriterLevel(pw, gzip.BestSpeed)
+		_, err := io.Copy(zw, r)
+		if err == nil {
+			err = zw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// progressReader reports the number of bytes read so far.
+type progressReader struct {
+	r        io.Reader
+	n        int64
+	progress func(int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.n += int64(n)
+		p.progress(p.n)
+	}
+	return n, err
+}
+
+func (p *progressReader) Close() error {
+	if c, ok := p.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package docker
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates the files with their content below dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// tarNames returns the sorted names of the regular files in the archive.
+func tarNames(t *testing.T, r io.Reader) []string {
+	var names []string
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			names = append(names, hdr.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func Test_BuildContext(t *testing.T) {
+	dir, err := ioutil.TempDir("", "context")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"Dockerfile":        "FROM alpine\nCOPY . /sim\n",
+		".dockerignore":     "# build output\nbuild\n*.log\n!keep.log\nDockerfile\n",
+		"sim.go":            "package main",
+		"build/sim":         "binary",
+		"debug.log":         "log",
+		"keep.log":          "log",
+		"testdata/plc.json": "{}",
+	})
+
+	r := BuildContext(dir)
+	defer r.Close()
+	want := []string{".dockerignore", "Dockerfile", "keep.log", "sim.go", "testdata/plc.json"}
+	if got := tarNames(t, r); !reflect.DeepEqual(got, want) {
+		t.Errorf("want files %v, got %v", want, got)
+	}
+}
+
+func Test_BuildImage_Compress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "context")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"Dockerfile": "FROM alpine\n",
+		"data.csv":   strings.Repeat("0,1,2,3\n", 64<<10),
+	})
+
+	var received int64
+	var names []string
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cr := &progressReader{r: r.Body, progress: func(n int64) { received = n }}
+		zr, err := gzip.NewReader(cr)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		names = tarNames(t, zr)
+		io.Copy(ioutil.Discard, cr)
+		w.Write([]byte(`{"aux": {"ID": "sha256:abc"}}`))
+	}))
+	defer daemon.Close()
+	c, err := NewClientHost("tcp://" + strings.TrimPrefix(daemon.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sent int64
+	bc := BuildContext(dir)
+	defer bc.Close()
+	if _, err := c.BuildImage(context.Background(), bc, BuildOptions{
+		Compress:       true,
+		UploadProgress: func(n int64) { sent = n },
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Dockerfile", "data.csv"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("want files %v, got %v", want, names)
+	}
+	if sent != received || sent == 0 || sent > 64<<10 {
+		t.Errorf("unexpected upload progress: sent %d, received %d", sent, received)
+	}
+}
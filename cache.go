@@ -0,0 +1,267 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheOptions configures the response cache of a client.
+type CacheOptions struct {
+	// TTL limits how long a response is cached. Defaults to 5s.
+	TTL time.Duration
+}
+
+const (
+	defaultCacheTTL   = 5 * time.Second
+	cacheRetryBackoff = time.Second
+)
+
+// EnableCache caches the results of inspecting and listing containers and
+// networks, so hot paths like readiness polling and port lookups don't hit
+// the daemon every time. Cached results are invalidated by the events of
+// the daemon: any change of a container drops its inspect result and all
+// container lists, any change of a network drops the network results and
+// the inspect results of the containers it concerns. Requests of the client
+// changing containers or networks invalidate the results they concern right
+// away, without waiting for the event. The cache is only used
+// while the client is subscribed to the events, it is bypassed while the
+// subscription is reestablished. The cache stays enabled until the context
+// is done. EnableCache has to be called before the client is used.
+func (c *Client) EnableCache(ctx context.Context, opts CacheOptions) {
+	if opts.TTL <= 0 {
+		opts.TTL = defaultCacheTTL
+	}
+	cache := &responseCache{ttl: opts.TTL, now: time.Now}
+	c.cache = cache
+
+	go c.subscribe(ctx, EventFilter{Types: []string{"container", "network"}},
+		cache.activate, cache.deactivate, cache.invalidate)
+}
+
+// subscribe passes the events to fn until the context is done. connected is
+// called whenever the subscription was established, disconnected whenever
+// it was lost, before it is retried.
+func (c *Client) subscribe(ctx context.Context, filter EventFilter, connected, disconnected func(), fn func(Event)) {
+	backoff := c.backoffOr(ConstantBackoff(cacheRetryBackoff))
+	// n counts the failed attempts since the last connection
+	n := 0
+	for {
+		c.events(ctx, filter, func() {
+			n = 0
+			connected()
+		}, fn)
+		disconnected()
+		if err := sleepBackoff(ctx, backoff, n); err != nil {
+			return
+		}
+		n++
+	}
+}
+
+// responseCache caches response bodies by path and query. A nil cache
+// caches nothing.
+type responseCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu   sync.Mutex
+	live bool
+	// gen is increased by every invalidation, responses requested before
+	// are not cached anymore.
+	gen     uint64
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	body    []byte
+	expires time.Time
+	// id and name of the object of inspect results, which may have been
+	// requested by either
+	id, name string
+}
+
+// cacheable reports whether responses of GET requests for the path are
+// cached.
+func (rc *responseCache) cacheable(path string) bool {
+	if rc == nil {
+		return false
+	}
+	ss := strings.Split(path, "/")
+	switch {
+	case path == "containers/json", path == "networks":
+		return true
+	case len(ss) == 3 && ss[0] == "containers" && ss[2] == "json":
+		return true
+	case len(ss) == 2 && ss[0] == "networks" && ss[1] != "create" && ss[1] != "prune":
+		return true
+	}
+	return false
+}
+
+// get returns the cached body of the key and the generation to put a
+// fetched body with.
+func (rc *responseCache) get(key string) ([]byte, uint64, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	e, ok := rc.entries[key]
+	if !rc.live || !ok || rc.now().After(e.expires) {
+		return nil, rc.gen, false
+	}
+	return e.body, rc.gen, true
+}
+
+func (rc *responseCache) put(key string, body []byte, gen uint64) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if !rc.live || gen != rc.gen {
+		return
+	}
+	e := cacheEntry{body: body, expires: rc.now().Add(rc.ttl)}
+	var obj struct {
+		ID   string `json:"Id"`
+		Name string
+	}
+	// lists don't decode into an object
+	if json.Unmarshal(body, &obj) == nil {
+		e.id, e.name = obj.ID, strings.TrimPrefix(obj.Name, "/")
+	}
+	rc.entries[key] = e
+}
+
+func (rc *responseCache) activate() {
+	rc.mu.Lock()
+	rc.live = true
+	rc.entries = make(map[string]cacheEntry)
+	rc.gen++
+	rc.mu.Unlock()
+}
+
+func (rc *responseCache) deactivate() {
+	rc.mu.Lock()
+	rc.live = false
+	rc.entries = nil
+	rc.gen++
+	rc.mu.Unlock()
+}
+
+// invalidate drops the entries the event may have changed.
+func (rc *responseCache) invalidate(ev Event) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.gen++
+
+	var lists, prefix string
+	refs := []string{ev.ID, ev.Attributes["name"]}
+	switch ev.Type {
+	case "container":
+		lists, prefix = "containers/json", "containers/"
+	case "network":
+		lists, prefix = "networks", "networks/"
+		// connecting and disconnecting changes the container as well
+		if id := ev.Attributes["container"]; id != "" {
+			rc.dropObject("containers/", id)
+		}
+	default:
+		return
+	}
+	for key := range rc.entries {
+		if key == lists || strings.HasPrefix(key, lists+"?") {
+			delete(rc.entries, key)
+		}
+	}
+	for _, ref := range refs {
+		if ref != "" {
+			rc.dropObject(prefix, ref)
+		}
+	}
+}
+
+// invalidatePath drops the entries a request other than GET for the path
+// may have changed, e.g. "containers/c1/stop".
+func (rc *responseCache) invalidatePath(path string) {
+	if rc == nil {
+		return
+	}
+	ss := strings.Split(path, "/")
+	var lists, prefix string
+	switch ss[0] {
+	case "containers":
+		lists, prefix = "containers/json", "containers/"
+	case "networks":
+		lists, prefix = "networks", "networks/"
+	default:
+		return
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.gen++
+	for key := range rc.entries {
+		if key == lists || strings.HasPrefix(key, lists+"?") {
+			delete(rc.entries, key)
+		}
+	}
+	switch {
+	case len(ss) < 2 || ss[1] == "create":
+	case ss[1] == "prune":
+		rc.dropObject(prefix, "")
+	default:
+		rc.dropObject(prefix, ss[1])
+		// the container connected or disconnected is in the body only
+		if prefix == "networks/" && len(ss) == 3 {
+			rc.dropObject("containers/", "")
+		}
+	}
+}
+
+// dropObject drops the inspect results of the object, which may have been
+// requested by a prefix of its ID or by its name. An empty ref drops the
+// results of all objects. The caller has to hold the lock.
+func (rc *responseCache) dropObject(prefix, ref string) {
+	name := strings.TrimPrefix(ref, "/")
+	for key, e := range rc.entries {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		ss := strings.Split(strings.SplitN(key, "?", 2)[0], "/")
+		if len(ss) < 2 || ss[1] == "" {
+			continue
+		}
+		if ref == "" || ss[1] == ref || strings.HasPrefix(ref, ss[1]) ||
+			e.id != "" && strings.HasPrefix(e.id, ref) || e.name != "" && e.name == name {
+			delete(rc.entries, key)
+		}
+	}
+}
+
+// cachedJSON is doJSON for cacheable GET requests.
+func (c *Client) cachedJSON(ctx context.Context, path string, query url.Values, out interface{}, want ...int) error {
+	key := path + "?" + query.Encode()
+	body, gen, ok := c.cache.get(key)
+	if !ok {
+		r, err := c.do(ctx, http.MethodGet, path, query, nil)
+		if err != nil {
+			return err
+		}
+		defer drainClose(r.Body)
+		if err := checkResponse(r, want...); err != nil {
+			return err
+		}
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			return err
+		}
+		if c.strict != nil {
+			if err := c.strict.check(path, body, out); err != nil {
+				return err
+			}
+		}
+		c.cache.put(key, body, gen)
+	}
+	return json.Unmarshal(body, out)
+}
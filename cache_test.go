@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Cache(t *testing.T) {
+	var inspects int32
+	events := make(chan string)
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/events":
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			for {
+				select {
+				case ev := <-events:
+					w.Write([]byte(ev))
+					w.(http.Flusher).Flush()
+				case <-r.Context().Done():
+					return
+				}
+			}
+		case "/containers/c1/json":
+			atomic.AddInt32(&inspects, 1)
+			w.Write([]byte(`{"Id": "c1abc", "Name": "/plc", "State": {"Running": true}}`))
+		case "/containers/plc/stop":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer daemon.Close()
+
+	c, err := NewClientHost("tcp://" + strings.TrimPrefix(daemon.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.EnableCache(ctx, CacheOptions{TTL: time.Minute})
+
+	waitFor := func(cond func() bool) {
+		deadline := time.Now().Add(2 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatal("condition not reached")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	live := func() bool {
+		c.cache.mu.Lock()
+		defer c.cache.mu.Unlock()
+		return c.cache.live
+	}
+	inspect := func(want int32) {
+		t.Helper()
+		if _, err := c.inspectContainer(context.Background(), "c1"); err != nil {
+			t.Fatal(err)
+		}
+		if n := atomic.LoadInt32(&inspects); n != want {
+			t.Fatalf("want %d requests, got %d", want, n)
+		}
+	}
+	waitFor(live)
+
+	inspect(1)
+	inspect(1)
+
+	// c1 is a prefix of the ID in the event
+	events <- `{"Type": "container", "Action": "die", "Actor": {"ID": "c1abc"}}`
+	waitFor(func() bool {
+		c.cache.mu.Lock()
+		defer c.cache.mu.Unlock()
+		return len(c.cache.entries) == 0
+	})
+	inspect(2)
+	inspect(2)
+
+	// stopping by name invalidates the result requested by ID right away
+	if err := c.doJSON(context.Background(), http.MethodPost, "containers/plc/stop", nil, nil, nil, http.StatusNoContent); err != nil {
+		t.Fatal(err)
+	}
+	inspect(3)
+	inspect(3)
+
+	cancel()
+	waitFor(func() bool { return !live() })
+	inspect(4)
+	inspect(5)
+}
@@ -0,0 +1,97 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Capabilities reports the features of the daemon the simulator depends on.
+type Capabilities struct {
+	// IPv6 is true if the default bridge network has IPv6 enabled, which
+	// requires "ipv6": true in the daemon configuration.
+	IPv6 bool
+	// StorageDriver e.g. overlay2.
+	StorageDriver string
+	Seccomp       bool
+	CgroupV2      bool
+	// BuildKit is true if BuildKit is the default builder.
+	BuildKit bool
+}
+
+// Requirements lists the features needed by a caller. Check verifies them.
+type Requirements struct {
+	IPv6     bool
+	Seccomp  bool
+	CgroupV2 bool
+	BuildKit bool
+	// StorageDrivers lists the accepted storage drivers. Any driver is
+	// accepted if it is empty.
+	StorageDrivers []string
+}
+
+// Capabilities probes the daemon for the features the simulator depends on.
+// Nothing is created on the daemon.
+func (c *Client) Capabilities(ctx context.Context) (*Capabilities, error) {
+	info, err := c.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ping, err := c.Ping(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bridge, err := c.inspectNetwork(ctx, "bridge")
+	if err != nil && !isNotFound(err) {
+		return nil, fmt.Errorf("inspect network bridge: %w", err)
+	}
+
+	caps := &Capabilities{
+		IPv6:          bridge != nil && bridge.EnableIPv6,
+		StorageDriver: info.Driver,
+		CgroupV2:      info.CgroupVersion == "2",
+		BuildKit:      ping.BuilderVersion == "2",
+	}
+	// e.g. "name=seccomp,profile=default"
+	for _, opt := range info.SecurityOptions {
+		if strings.HasPrefix(opt, "name=seccomp") {
+			caps.Seccomp = true
+		}
+	}
+	return caps, nil
+}
+
+// Check returns an error describing every missing feature and how to enable
+// it, or nil if all requirements are met.
+func (c *Capabilities) Check(req Requirements) error {
+	var missing []string
+	if req.IPv6 && !c.IPv6 {
+		missing = append(missing, `IPv6 is disabled: set "ipv6": true and "fixed-cidr-v6" in /etc/docker/daemon.json and restart dockerd`)
+	}
+	if req.Seccomp && !c.Seccomp {
+		missing = append(missing, "seccomp is not available: use a kernel and dockerd built with seccomp support")
+	}
+	if req.CgroupV2 && !c.CgroupV2 {
+		missing = append(missing, "cgroup v2 is not used: boot the host with systemd.unified_cgroup_hierarchy=1")
+	}
+	if req.BuildKit && !c.BuildKit {
+		missing = append(missing, `BuildKit is not the default builder: set "features": {"buildkit": true} in /etc/docker/daemon.json`)
+	}
+	if len(req.StorageDrivers) > 0 && !contains(req.StorageDrivers, c.StorageDriver) {
+		missing = append(missing, fmt.Sprintf("storage driver %s is not supported, use one of %s",
+			c.StorageDriver, strings.Join(req.StorageDrivers, ", ")))
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("daemon lacks required features: %s", strings.Join(missing, "; "))
+	}
+	return nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
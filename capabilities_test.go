@@ -0,0 +1,34 @@
+package docker
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_Capabilities(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /info":            {Body: `{"Driver": "overlay2", "CgroupVersion": "2", "SecurityOptions": ["name=apparmor", "name=seccomp,profile=default"]}`},
+		"GET /_ping":           {Body: "OK", Header: map[string]string{"Builder-Version": "1"}},
+		"GET /networks/bridge": {Body: `{"Id": "b1", "Name": "bridge", "EnableIPv6": false}`},
+	})
+	defer srv.route(nil)
+
+	caps, err := client.Capabilities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Capabilities{StorageDriver: "overlay2", Seccomp: true, CgroupV2: true}
+	if !reflect.DeepEqual(caps, want) {
+		t.Errorf("want %+v, got %+v", want, caps)
+	}
+
+	if err := caps.Check(Requirements{Seccomp: true, CgroupV2: true, StorageDrivers: []string{"overlay2"}}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	err = caps.Check(Requirements{IPv6: true, BuildKit: true})
+	if err == nil || !strings.Contains(err.Error(), "IPv6 is disabled") || !strings.Contains(err.Error(), "BuildKit") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
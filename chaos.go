@@ -0,0 +1,181 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ChaosAction is a fault injected into a container by Chaos.
+type ChaosAction string
+
+// The actions of Chaos.
+const (
+	// ChaosPause freezes the processes of the container for a while, like a
+	// device which stops responding.
+	ChaosPause ChaosAction = "pause"
+	// ChaosKill sends a signal to the container, SIGKILL by default. Its
+	// restart policy decides whether it comes back.
+	ChaosKill ChaosAction = "kill"
+	// ChaosRestart restarts the container, like a device which reboots.
+	ChaosRestart ChaosAction = "restart"
+)
+
+// ChaosOptions configure Chaos.
+type ChaosOptions struct {
+	// Actions are chosen from at random. Defaults to all actions.
+	Actions []ChaosAction
+	// Interval is the time between two injections. Defaults to a minute.
+	Interval time.Duration
+	// PauseDuration is how long a container stays paused. Defaults to 10s.
+	PauseDuration time.Duration
+	// Signal is sent by ChaosKill. Defaults to SIGKILL.
+	Signal string
+	// Seed seeds the random choices. The same seed chooses the same
+	// actions and containers again, as long as the same containers run.
+	// Zero chooses a seed from the current time, see Chaos.Seed.
+	Seed int64
+	// Audit is called with every injection in addition to the log of
+	// Chaos, e.g. to write it to a test report.
+	Audit func(ChaosEvent)
+}
+
+// ChaosEvent records an injection of Chaos.
+type ChaosEvent struct {
+	Time      time.Time   `json:"time"`
+	Action    ChaosAction `json:"action"`
+	Container string      `json:"container"`
+	ID        string      `json:"id"`
+	// Error is set if the injection failed.
+	Error string `json:"error,omitempty"`
+}
+
+func (e ChaosEvent) String() string {
+	s := fmt.Sprintf("%s %s %s", e.Time.Format(time.RFC3339), e.Action, e.Container)
+	if e.Error != "" {
+		s += ": " + e.Error
+	}
+	return s
+}
+
+// Chaos injects faults into random running containers matching a selector
+// to test the resilience of a simulated system. Every injection is recorded
+// in an audit log.
+type Chaos struct {
+	c        *Client
+	selector Selector
+	opts     ChaosOptions
+
+	mu   sync.Mutex
+	rand *rand.Rand
+	log  []ChaosEvent
+}
+
+// NewChaos returns a chaos injector for the containers matching the selector.
+func NewChaos(c *Client, selector Selector, opts ChaosOptions) *Chaos {
+	if len(opts.Actions) == 0 {
+		opts.Actions = []ChaosAction{ChaosPause, ChaosKill, ChaosRestart}
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	if opts.PauseDuration <= 0 {
+		opts.PauseDuration = 10 * time.Second
+	}
+	if opts.Signal == "" {
+		opts.Signal = "SIGKILL"
+	}
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+	return &Chaos{
+		c:        c,
+		selector: selector,
+		opts:     opts,
+		rand:     rand.New(rand.NewSource(opts.Seed)),
+	}
+}
+
+// Seed returns the seed of the random choices, which allows to repeat a run.
+func (ch *Chaos) Seed() int64 {
+	return ch.opts.Seed
+}
+
+// Run injects a fault every interval until the context is done and returns
+// its error. Failed injections are recorded in the log and do not stop Run.
+func (ch *Chaos) Run(ctx context.Context) error {
+	t := time.NewTicker(ch.opts.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+		ch.Inject(ctx)
+	}
+}
+
+// Inject injects a fault into a random running container and returns the
+// recorded event. A paused container is unpaused before Inject returns, even
+// if the context is done meanwhile. An error is returned if no container
+// matches or the injection failed.
+func (ch *Chaos) Inject(ctx context.Context) (ChaosEvent, error) {
+	containers, err := ch.c.listContainers(ctx, false, ch.selector.filters())
+	if err != nil {
+		return ChaosEvent{}, fmt.Errorf("chaos: list containers: %w", err)
+	}
+	if len(containers) == 0 {
+		return ChaosEvent{}, fmt.Errorf("chaos: no running container matches")
+	}
+	// the daemon lists in no stable order, which would defeat the seed
+	sort.Slice(containers, func(i, j int) bool { return containers[i].Name() < containers[j].Name() })
+
+	ch.mu.Lock()
+	target := containers[ch.rand.Intn(len(containers))]
+	action := ch.opts.Actions[ch.rand.Intn(len(ch.opts.Actions))]
+	ch.mu.Unlock()
+
+	ev := ChaosEvent{Time: time.Now(), Action: action, Container: target.Name(), ID: target.ID}
+	err = ch.inject(ctx, action, target.ID)
+	if err != nil {
+		ev.Error = err.Error()
+		err = fmt.Errorf("chaos: %s %s: %w", action, target.Name(), err)
+	}
+	ch.mu.Lock()
+	ch.log = append(ch.log, ev)
+	ch.mu.Unlock()
+	if ch.opts.Audit != nil {
+		ch.opts.Audit(ev)
+	}
+	return ev, err
+}
+
+func (ch *Chaos) inject(ctx context.Context, action ChaosAction, id string) error {
+	switch action {
+	case ChaosPause:
+		if err := ch.c.pauseContainer(ctx, id); err != nil {
+			return err
+		}
+		select {
+		case <-time.After(ch.opts.PauseDuration):
+		case <-ctx.Done():
+		}
+		return ch.c.unpauseContainer(context.Background(), id)
+	case ChaosKill:
+		return ch.c.killContainer(ctx, id, ch.opts.Signal)
+	case ChaosRestart:
+		return ch.c.restartContainer(ctx, id)
+	}
+	return fmt.Errorf("unknown action %q", action)
+}
+
+// Log returns the audit log of all injections so far.
+func (ch *Chaos) Log() []ChaosEvent {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return append([]ChaosEvent(nil), ch.log...)
+}
@@ -0,0 +1,78 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Chaos(t *testing.T) {
+	routes := map[string]mockResponse{
+		"GET /containers/json": {Body: `[
+			{"Id": "c2", "Names": ["/meter"]},
+			{"Id": "c1", "Names": ["/plc"]},
+			{"Id": "c3", "Names": ["/inverter"]}
+		]`},
+	}
+	for _, id := range []string{"c1", "c2", "c3"} {
+		for _, action := range []string{"pause", "unpause", "kill", "restart"} {
+			routes["POST /containers/"+id+"/"+action] = mockResponse{StatusCode: http.StatusNoContent}
+		}
+	}
+	srv.route(routes)
+	defer srv.route(nil)
+
+	run := func() []ChaosEvent {
+		var audited []ChaosEvent
+		ch := NewChaos(client, RunSelector("sim"), ChaosOptions{
+			PauseDuration: time.Millisecond,
+			Seed:          42,
+			Audit:         func(ev ChaosEvent) { audited = append(audited, ev) },
+		})
+		for i := 0; i < 6; i++ {
+			if _, err := ch.Inject(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !reflect.DeepEqual(audited, ch.Log()) {
+			t.Errorf("audited %v, logged %v", audited, ch.Log())
+		}
+		return ch.Log()
+	}
+
+	first, second := run(), run()
+	for i := range first {
+		if first[i].Action != second[i].Action || first[i].Container != second[i].Container {
+			t.Errorf("injection %d differs with the same seed: %s, %s", i, first[i], second[i])
+		}
+	}
+
+	pauses, unpauses := 0, 0
+	for _, r := range srv.Requests() {
+		switch {
+		case strings.HasSuffix(r, "/pause"):
+			pauses++
+		case strings.HasSuffix(r, "/unpause"):
+			unpauses++
+		}
+	}
+	if pauses != unpauses {
+		t.Errorf("%d pauses, but %d unpauses", pauses, unpauses)
+	}
+}
+
+func Test_ChaosNoContainer(t *testing.T) {
+	srv.route(map[string]mockResponse{"GET /containers/json": {Body: `[]`}})
+	defer srv.route(nil)
+
+	ch := NewChaos(client, RunSelector("sim"), ChaosOptions{Seed: 1})
+	if _, err := ch.Inject(context.Background()); err == nil {
+		t.Error("expected error")
+	}
+	if len(ch.Log()) != 0 {
+		t.Errorf("got log %v", ch.Log())
+	}
+}
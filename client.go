@@ -2,21 +2,20 @@ package docker
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
-)
 
-func statusCode(statusCode, want int) error {
-	if statusCode != want {
-		return fmt.Errorf("invalid response code want=%d, got=%d",
-			want, statusCode)
-	}
-	return nil
-}
+	"github.com/grid-x/docker/types"
+)
 
 // Client offers the possibility to communicate with dockerd.
 // A local http connection is established via unix socket. This allows to
@@ -24,57 +23,252 @@ func statusCode(statusCode, want int) error {
 // Note: this is not a complete docker client implementation.
 // Only the requirements for the simulator are covered. And it tries not to
 // include docker as an external dependency in the project.
+//
+// A Client is safe for concurrent use by multiple goroutines. The methods
+// configuring it, e.g. SetTimeouts or EnableCache, are not: they have to be
+// called before the client is used, see Inflight to debug requests which
+// do not finish.
 type Client struct {
 	http *http.Client
+	// base is the URL all request paths are relative to.
+	base string
+	// transport is the transport of http, which may be wrapped.
+	transport *http.Transport
+
+	// pulls deduplicates concurrent pulls of the same image.
+	pulls flightGroup
+	// auth provides the credentials for pulls, it may be nil.
+	auth     AuthProvider
+	pullOpts PullOptions
+	// proxyEnv is injected into created containers, it may be nil.
+	proxyEnv *ProxyEnv
+	// cache caches inspect and list results, it may be nil.
+	cache *responseCache
+	// names caches the IDs of names, it may be nil.
+	names *nameCache
+	// strict validates responses, it may be nil.
+	strict *strictMode
+	// breaker, restarts and conflicts wrap the transport if they are
+	// enabled.
+	breaker   *breaker
+	restarts  *restartWaiter
+	conflicts *conflictRetrier
+	// inflight wraps the transport and all other round trippers.
+	inflight *inflightTracker
+	// backoff is the policy of waits between attempts, it may be nil.
+	backoff Backoff
+	// timeouts are the timeouts of the classes of requests.
+	timeouts Timeouts
+	// warnf logs the warnings of create requests, it may be nil.
+	warnf func(format string, args ...interface{})
 }
 
+// baseAddr is the base URL of the requests sent over a unix socket.
 const baseAddr = "http://localhost/"
 
 // NewClient returns a new docker client. The arguments are the path to the
 // docker sock which is necessary to control dockerd.
 // e.g.: c := NewClient(&logger, "/var/run/docker.sock")
 func NewClient(sock string) *Client {
-	return &Client{
-		http: &http.Client{
-			Transport: &http.Transport{
-				Dial: func(proto, addr string) (conn net.Conn, err error) {
-					return net.Dial("unix", sock)
-				},
-			},
-			Timeout: time.Second * 5,
-		},
+	return newClient("unix", sock, nil)
+}
+
+// NewClientHost returns a client for the daemon at the given host in the
+// format of DOCKER_HOST: either a socket (unix:///var/run/docker.sock) or a
+// TCP address (tcp://lab-1:2375). A plain path is treated as socket.
+// Connections to TCP hosts honor HTTP_PROXY, HTTPS_PROXY and NO_PROXY, see
+// SetProxy.
+func NewClientHost(host string) (*Client, error) {
+	network, addr, err := parseHost(host)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(network, addr, nil), nil
+}
+
+// NewClientTLS returns a client for the daemon at the TCP host, e.g.
+// tcp://lab-1:2376, which is connected to by TLS with the given config.
+// HTTP/2 is used if the daemon supports it.
+func NewClientTLS(host string, config *tls.Config) (*Client, error) {
+	network, addr, err := parseHost(host)
+	if err != nil {
+		return nil, err
 	}
+	if network != "tcp" {
+		return nil, fmt.Errorf("invalid docker host %q: TLS requires tcp", host)
+	}
+	return newClient(network, addr, config), nil
+}
+
+// Settings of the connection pool. Simulations send many requests in
+// parallel, the default of two idle connections per host would make the
+// transport open and close connections constantly.
+const (
+	maxIdleConns    = 100
+	idleConnTimeout = 90 * time.Second
+	dialTimeout     = 5 * time.Second
+	keepAlive       = 30 * time.Second
+)
+
+func newClient(network, addr string, config *tls.Config) *Client {
+	dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: keepAlive}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		MaxIdleConns:        maxIdleConns,
+		MaxIdleConnsPerHost: maxIdleConns,
+		IdleConnTimeout:     idleConnTimeout,
+	}
+	base := baseAddr
+	switch {
+	case network == "unix":
+		// every request goes to the socket regardless of the address. Failed
+		// dials are diagnosed until the socket was connected to once, the
+		// setup of the host is fine then and the diagnosis is not cheap.
+		var connected int32
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				if atomic.LoadInt32(&connected) == 0 {
+					return nil, diagnoseSocket(addr, err)
+				}
+				return nil, err
+			}
+			atomic.StoreInt32(&connected, 1)
+			return conn, nil
+		}
+	case config != nil:
+		base = "https://" + addr + "/"
+		transport.Proxy = http.ProxyFromEnvironment
+		// the transport adds the protocols for ALPN to the config
+		transport.TLSClientConfig = config.Clone()
+		transport.TLSHandshakeTimeout = dialTimeout
+		// custom dialers disable HTTP/2 unless it is forced. If the daemon
+		// negotiates h2, concurrent requests are multiplexed over a single
+		// connection, otherwise HTTP/1.1 is used.
+		transport.ForceAttemptHTTP2 = true
+	default:
+		base = "http://" + addr + "/"
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+	c := &Client{
+		base:      base,
+		transport: transport,
+		http:      &http.Client{},
+		inflight:  newInflightTracker(),
+		timeouts:  DefaultTimeouts,
+	}
+	c.chainTransport()
+	return c
+}
+
+// SetProxy sets the proxy the client connects to a TCP host through,
+// instead of the one set by the environment. nil disables the proxy.
+// Clients of a socket do not support proxies. SetProxy has to be called
+// before the client is used.
+func (c *Client) SetProxy(proxy *url.URL) error {
+	if c.base == baseAddr {
+		return fmt.Errorf("set proxy: not supported for clients of a socket")
+	}
+	t := c.transport
+	if proxy == nil {
+		t.Proxy = nil
+		return nil
+	}
+	t.Proxy = http.ProxyURL(proxy)
+	return nil
+}
+
+// parseHost splits a DOCKER_HOST value into network and address.
+func parseHost(host string) (string, string, error) {
+	if strings.HasPrefix(host, "/") {
+		return "unix", host, nil
+	}
+	ss := strings.SplitN(host, "://", 2)
+	if len(ss) != 2 || ss[1] == "" {
+		return "", "", fmt.Errorf("invalid docker host %q", host)
+	}
+	switch ss[0] {
+	case "unix":
+		return "unix", ss[1], nil
+	case "tcp", "http":
+		addr := strings.TrimSuffix(ss[1], "/")
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, "2375")
+		}
+		return "tcp", addr, nil
+	default:
+		return "", "", fmt.Errorf("invalid docker host %q: unsupported protocol %s", host, ss[0])
+	}
+}
+
+// PingResult holds the information the daemon sends in the headers of the
+// ping response.
+type PingResult struct {
+	APIVersion     string
+	OSType         string
+	Experimental   bool
+	BuilderVersion string
+	// SwarmNodeState is one of inactive, pending, active, error and locked.
+	// It is empty for daemons older than API version 1.40.
+	SwarmNodeState string
+	// SwarmManager is true if the node is a swarm manager.
+	SwarmManager bool
 }
 
-// Ping pings the server and returns true if the daemon responds with
-// http.StatusOK and false if an error occures.
-// docs.: https://docs.docker.com/engine/api/v1.36/#operation/SystemPing
-func (c *Client) Ping() bool {
-	endpoint := fmt.Sprintf("%s/_ping", baseAddr)
-	r, err := c.http.Get(endpoint)
+// Ping pings the server and returns what the daemon reports about itself.
+// An error is returned if the daemon does not respond with http.StatusOK.
+// docs.: https://docs.docker.com/engine/api/v1.41/#operation/SystemPing
+func (c *Client) Ping(ctx context.Context) (*PingResult, error) {
+	return c.ping(ctx, http.MethodGet)
+}
+
+// PingHead pings the server like Ping by a HEAD request.
+func (c *Client) PingHead(ctx context.Context) (*PingResult, error) {
+	return c.ping(ctx, http.MethodHead)
+}
+
+func (c *Client) ping(ctx context.Context, method string) (*PingResult, error) {
+	r, err := c.send(ctx, method, "_ping", nil, nil, "")
 	if err != nil {
-		return false
+		return nil, fmt.Errorf("ping: %w", err)
+	}
+	defer drainClose(r.Body)
+	if err := checkResponse(r, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("ping: %w", err)
 	}
-	return statusCode(r.StatusCode, http.StatusOK) == nil
+
+	res := &PingResult{
+		APIVersion:     r.Header.Get("Api-Version"),
+		OSType:         r.Header.Get("Ostype"),
+		Experimental:   r.Header.Get("Docker-Experimental") == "true",
+		BuilderVersion: r.Header.Get("Builder-Version"),
+	}
+	// e.g. "active/manager"
+	if swarm := r.Header.Get("Swarm"); swarm != "" {
+		ss := strings.SplitN(swarm, "/", 2)
+		res.SwarmNodeState = ss[0]
+		res.SwarmManager = len(ss) == 2 && ss[1] == "manager"
+	}
+	return res, nil
 }
 
 // ContainerIDByName returns the containerID for the given name. If this fails,
 // an error is returned.
 func (c *Client) ContainerIDByName(name string) (string, error) {
-	endpoint := fmt.Sprintf("%scontainers/json", baseAddr)
-	r, err := c.http.Get(endpoint)
+	id, gen, ok := c.names.get("container", name)
+	if ok {
+		return id, nil
+	}
+	endpoint := fmt.Sprintf("%scontainers/json", c.base)
+	r, err := c.httpClient(TimeoutFast).Get(endpoint)
 	if err != nil {
 		return "", err
 	}
+	defer drainClose(r.Body)
 
-	containers := []struct {
-		ID     string   `json:"ID"`
-		Status string   `json:"Status"`
-		Image  string   `json:"Image"`
-		Names  []string `json:"Names"`
-	}{}
+	var containers []types.ContainerSummary
 
-	if err = statusCode(r.StatusCode, http.StatusOK); err != nil {
+	if err = checkResponse(r, http.StatusOK); err != nil {
 		return "", err
 	}
 
@@ -82,13 +276,24 @@ func (c *Client) ContainerIDByName(name string) (string, error) {
 		return "", err
 	}
 
+	// an exact match wins, otherwise the first name containing the given
+	// one is used. Only exact matches are cached, a container with exactly
+	// the name may be created later.
+	partial := ""
 	for _, container := range containers {
 		for _, cn := range container.Names {
-			if ok := strings.Contains(cn, name); ok {
+			if strings.TrimPrefix(cn, "/") == name {
+				c.names.put("container", name, container.ID, gen)
 				return container.ID, nil
 			}
+			if partial == "" && strings.Contains(cn, name) {
+				partial = container.ID
+			}
 		}
 	}
+	if partial != "" {
+		return partial, nil
+	}
 
 	return "", fmt.Errorf("can not extract containerID for %s", name)
 }
@@ -103,146 +308,267 @@ func (c *Client) ContainerIDByName(name string) (string, error) {
 // ExposedPorts shall be so specified: ["<port>/<tcp|udp>", "<port>/<tcp|udp>"]
 // Mounts e.g.: ["/var/run/docker.sock:/var/run/docker.sock"]
 // All options can also be left empty. Then the defaults of the image are used.
+// CreateContainerWithOptions supports all other options.
 func (c *Client) CreateContainer(name, image string, cmd, exposedPorts, mounts []string) (string, error) {
-	endpoint := fmt.Sprintf("%scontainers/create?name=%s", baseAddr, name)
-
-	type Mount struct {
-		Target      string `json:"Target"`
-		Source      string `json:"Source"`
-		ReadOnly    bool   `json:"ReadOnly"`
-		Type        string `json:"Type"`
-		Consistency string `json:"Consistency"`
-	}
-
-	min := struct {
-		Name         string              `json:"Name"`
-		ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
-		Image        string              `json:"Image"`
-		Cmd          []string            `json:"Cmd,omitempty"`
-		HostConfig   struct {
-			Mounts       []Mount `json:"Mounts,omitempty"`
-			PortBindings map[string]struct {
-				HostIP   string `json:"HostIp"`
-				HostPort string `json:"HostPort"`
-			} `json:"PortBindings,omitempty"`
-		} `json:"HostConfig"`
-	}{
-		Name:         name,
-		Image:        image,
-		Cmd:          cmd,
-		ExposedPorts: make(map[string]struct{}),
-	}
-	min.HostConfig.Mounts = make([]Mount, len(mounts))
-
-	for _, port := range exposedPorts {
-		min.ExposedPorts[port] = struct{}{}
+	res, err := c.CreateContainerWithWarnings(name, image, cmd, exposedPorts, mounts)
+	if err != nil {
+		return "", err
 	}
+	return res.ID, nil
+}
 
-	for i, m := range mounts {
-		if ss := strings.Split(m, ":"); len(ss) == 2 {
-			min.HostConfig.Mounts[i] = Mount{
-				Source:      ss[0],
-				Target:      ss[1],
-				Type:        "bind",
-				Consistency: "default",
-			}
+// CreateContainerWithWarnings creates a container like CreateContainer and
+// returns its ID together with the warnings of the daemon.
+func (c *Client) CreateContainerWithWarnings(name, image string, cmd, exposedPorts, mounts []string) (*CreateResult, error) {
+	opts := CreateContainerOptions{
+		Name:   name,
+		Config: types.ContainerConfig{Image: image, Cmd: cmd},
+	}
+	if len(exposedPorts) > 0 {
+		opts.Config.ExposedPorts = make(map[string]struct{}, len(exposedPorts))
+		for _, port := range exposedPorts {
+			opts.Config.ExposedPorts[port] = struct{}{}
 		}
 	}
+	for _, m := range mounts {
+		ss := strings.Split(m, ":")
+		if len(ss) != 2 {
+			return nil, fmt.Errorf("invalid mount %q, want <source>:<target>", m)
+		}
+		opts.HostConfig.Mounts = append(opts.HostConfig.Mounts, types.Mount{
+			Source:      ss[0],
+			Target:      ss[1],
+			Type:        "bind",
+			Consistency: "default",
+		})
+	}
+	return c.CreateContainerWithOptions(context.Background(), opts)
+}
 
-	b, err := json.Marshal(&min)
-	if err != nil {
-		return "", err
-	}
+// CreateContainerOptions configure a container created by
+// CreateContainerWithOptions. They map onto the create request of the
+// engine API, so new options do not change the signature.
+type CreateContainerOptions struct {
+	// Name is optional, the daemon chooses one if it is empty.
+	Name string
+	// Config holds the configuration independent of the host. Image is
+	// required, all other fields default to the ones of the image.
+	Config types.ContainerConfig
+	// HostConfig holds the host specific configuration, e.g. mounts and
+	// port bindings.
+	HostConfig types.HostConfig
+	// NetworkingConfig attaches the container to a network on creation.
+	NetworkingConfig *types.NetworkingConfig
+}
 
-	r, err := c.http.Post(endpoint, "application/json", bytes.NewReader(b))
+// CreateContainerWithOptions creates a container and returns its ID together
+// with the warnings of the daemon.
+func (c *Client) CreateContainerWithOptions(ctx context.Context, opts CreateContainerOptions) (*CreateResult, error) {
+	if opts.Config.Image == "" {
+		return nil, fmt.Errorf("create container %s: image must not be empty", opts.Name)
+	}
+	cfg := opts.Config
+	body := &containerCreate{
+		Hostname:         cfg.Hostname,
+		User:             cfg.User,
+		WorkingDir:       cfg.WorkingDir,
+		Tty:              cfg.Tty,
+		StopSignal:       cfg.StopSignal,
+		Image:            cfg.Image,
+		Cmd:              cfg.Cmd,
+		Entrypoint:       cfg.Entrypoint,
+		Env:              cfg.Env,
+		Labels:           cfg.Labels,
+		ExposedPorts:     cfg.ExposedPorts,
+		HostConfig:       opts.HostConfig,
+		NetworkingConfig: opts.NetworkingConfig,
+	}
+	res, err := c.createContainer(ctx, opts.Name, body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	return &CreateResult{ID: res.ID, Warnings: res.Warnings}, nil
+}
 
-	if err := statusCode(r.StatusCode, http.StatusCreated); err != nil {
-		return "", err
-	}
+// DeleteOption changes the behaviour of DeleteContainer.
+type DeleteOption func(q url.Values)
 
-	res := struct {
-		ID       string        `json:"Id"`
-		Warnings []interface{} `json:"Warnings"`
-	}{}
+// DeleteForce kills a running container before it is removed, so it does
+// not have to be stopped first.
+func DeleteForce() DeleteOption {
+	return func(q url.Values) {
+		q.Set("force", "1")
+	}
+}
 
-	return res.ID, json.NewDecoder(r.Body).Decode(&res)
+// DeleteVolumes removes the anonymous volumes of the container with it.
+// Named volumes are kept.
+func DeleteVolumes() DeleteOption {
+	return func(q url.Values) {
+		q.Set("v", "1")
+	}
 }
 
 // DeleteContainer remove a container by the given ContainerID. If it fails,
 // an error is returend.
-func (c *Client) DeleteContainer(id string) error {
-	endpoint := fmt.Sprintf("%scontainers/%s", baseAddr, id)
+func (c *Client) DeleteContainer(id string, opts ...DeleteOption) error {
+	q := url.Values{}
+	for _, opt := range opts {
+		opt(q)
+	}
+	endpoint, err := c.endpoint("containers/"+pathName(id), q)
+	if err != nil {
+		return err
+	}
 	r, err := http.NewRequest("DELETE", endpoint, nil)
 	if err != nil {
 		return err
 	}
 
-	resp, err := c.http.Do(r)
+	resp, err := c.httpClient(TimeoutMedium).Do(r)
 	if err != nil {
 		return err
 	}
-	return statusCode(resp.StatusCode, http.StatusNoContent)
+	defer drainClose(resp.Body)
+	return checkResponse(resp, http.StatusNoContent)
+}
+
+// DeleteContainerIfExists removes the container like DeleteContainer. A
+// container which does not exist is not an error, so teardowns can be
+// repeated.
+func (c *Client) DeleteContainerIfExists(id string, opts ...DeleteOption) error {
+	if err := c.DeleteContainer(id, opts...); err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
 }
 
 // StartContainer by given containerID. If it fails, an error is returend.
 func (c *Client) StartContainer(id string) error {
-	endpoint := fmt.Sprintf("%scontainers/%s/start", baseAddr, id)
-	r, err := c.http.Post(endpoint, "application/json", nil)
+	endpoint, err := c.endpoint("containers/"+pathName(id)+"/start", nil)
 	if err != nil {
 		return err
 	}
-	return statusCode(r.StatusCode, http.StatusNoContent)
+	r, err := c.httpClient(TimeoutMedium).Post(endpoint, "application/json", nil)
+	if err != nil {
+		return err
+	}
+	defer drainClose(r.Body)
+	return checkResponse(r, http.StatusNoContent)
+}
+
+// StopOption changes the behaviour of StopContainer.
+type StopOption func(o *stopOptions)
+
+type stopOptions struct {
+	timeout *time.Duration
+}
+
+// StopTimeout sets the grace period between the stop signal and killing
+// the container, instead of the default of the daemon. It is rounded up to
+// seconds, zero kills the container immediately.
+func StopTimeout(d time.Duration) StopOption {
+	return func(o *stopOptions) {
+		o.timeout = &d
+	}
 }
 
 // StopContainer by given containerID. If it fails, an error is returend.
-func (c *Client) StopContainer(id string) error {
-	endpoint := fmt.Sprintf("%scontainers/%s/stop", baseAddr, id)
-	r, err := c.http.Post(endpoint, "application/json", nil)
+// With StopTimeout the request waits for the grace period, the medium
+// timeout of the client is added to it.
+func (c *Client) StopContainer(id string, opts ...StopOption) error {
+	var o stopOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.timeout == nil {
+		endpoint, err := c.endpoint("containers/"+pathName(id)+"/stop", nil)
+		if err != nil {
+			return err
+		}
+		r, err := c.httpClient(TimeoutMedium).Post(endpoint, "application/json", nil)
+		if err != nil {
+			return err
+		}
+		defer drainClose(r.Body)
+		return checkResponse(r, http.StatusNoContent)
+	}
+
+	r, err := c.stopGrace(context.Background(), id, *o.timeout)
 	if err != nil {
 		return err
 	}
-	return statusCode(r.StatusCode, http.StatusNoContent)
+	defer drainClose(r.Body)
+	return checkResponse(r, http.StatusNoContent)
+}
+
+// stopGrace sends the stop request with the grace period, rounded up to
+// seconds. The medium timeout of the client is extended by the grace period.
+// The caller has to close the body of the returned response.
+func (c *Client) stopGrace(ctx context.Context, id string, grace time.Duration) (*http.Response, error) {
+	secs := int((grace + time.Second - 1) / time.Second)
+	if secs < 0 {
+		secs = 0
+	}
+	timeout := c.timeout(TimeoutMedium)
+	if timeout > 0 {
+		timeout += time.Duration(secs) * time.Second
+	}
+	q := url.Values{"t": {strconv.Itoa(secs)}}
+	return c.sendTimeout(ctx, timeout, http.MethodPost, "containers/"+pathName(id)+"/stop", q, nil, nil)
 }
 
-// NetworkIDByName returns the networkID for the given Network name.
-// if this fails, an error is returned.
+// NetworkIDByName returns the networkID of the network with exactly the
+// given name. If this fails, an error is returned.
 func (c *Client) NetworkIDByName(name string) (string, error) {
-	endpoint := fmt.Sprintf("%snetworks", baseAddr)
-	r, err := c.http.Get(endpoint)
+	id, gen, ok := c.names.get("network", name)
+	if ok {
+		return id, nil
+	}
+	endpoint := fmt.Sprintf("%snetworks", c.base)
+	r, err := c.httpClient(TimeoutFast).Get(endpoint)
 	if err != nil {
 		return "", err
 	}
+	defer drainClose(r.Body)
 
-	if err = statusCode(r.StatusCode, http.StatusOK); err != nil {
+	if err = checkResponse(r, http.StatusOK); err != nil {
 		return "", err
 	}
 
-	networks := []struct {
-		Driver string `json:"Driver"`
-		ID     string `json:"ID"`
-		Name   string `json:"Name"`
-	}{}
+	var networks []types.NetworkSummary
 
 	if err := json.NewDecoder(r.Body).Decode(&networks); err != nil {
 		return "", err
 	}
 
+	// names of networks are not unique prefixes of each other, e.g.
+	// simulation_subnet_1 and simulation_subnet_10, only the exact name
+	// matches
 	for _, n := range networks {
-		if ok := strings.Contains(n.Name, name); ok {
+		if n.Name == name {
+			c.names.put("network", name, n.ID, gen)
 			return n.ID, nil
 		}
 	}
-	return "", fmt.Errorf("can not extract containerID for %s", name)
+	return "", fmt.Errorf("can not extract networkID for %s", name)
 }
 
 // CreateNetwork creates a default network with the given name.
 // This network uses the bridge driver and is attachable.
 // After success the NetworkID is returned. If it fails, an error is returned.
 func (c *Client) CreateNetwork(name string) (string, error) {
-	endpoint := fmt.Sprintf("%snetworks/create", baseAddr)
+	res, err := c.CreateNetworkWithWarnings(name)
+	if err != nil {
+		return "", err
+	}
+	return res.ID, nil
+}
+
+// CreateNetworkWithWarnings creates a network like CreateNetwork and returns
+// its ID together with the warnings of the daemon.
+func (c *Client) CreateNetworkWithWarnings(name string) (*CreateResult, error) {
+	endpoint := fmt.Sprintf("%snetworks/create", c.base)
 
 	min := struct {
 		Name       string `json:"Name"`
@@ -256,44 +582,64 @@ func (c *Client) CreateNetwork(name string) (string, error) {
 
 	b, err := json.Marshal(&min)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	r, err := c.http.Post(endpoint, "application/json", bytes.NewReader(b))
+	r, err := c.httpClient(TimeoutMedium).Post(endpoint, "application/json", bytes.NewReader(b))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	defer drainClose(r.Body)
 
-	if err = statusCode(r.StatusCode, http.StatusCreated); err != nil {
-		return "", err
+	if err = checkResponse(r, http.StatusCreated); err != nil {
+		return nil, err
 	}
 
-	res := struct {
-		ID       string        `json:"Id"`
-		Warnings []interface{} `json:"Warnings"`
-	}{}
-
-	return res.ID, json.NewDecoder(r.Body).Decode(&res)
+	var res types.CreateResponse
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	if res.Warning != "" {
+		res.Warnings = append(res.Warnings, res.Warning)
+	}
+	c.logWarnings("network "+name, res.Warnings)
+	return &CreateResult{ID: res.ID, Warnings: res.Warnings}, nil
 }
 
 // DeleteNetwork by the given NetworkID. If it fails an error is returned.
 func (c *Client) DeleteNetwork(id string) error {
-	endpoint := fmt.Sprintf("%snetworks/%s", baseAddr, id)
+	endpoint, err := c.endpoint("networks/"+pathName(id), nil)
+	if err != nil {
+		return err
+	}
 	r, err := http.NewRequest("DELETE", endpoint, nil)
 	if err != nil {
 		return err
 	}
-	resp, err := c.http.Do(r)
+	resp, err := c.httpClient(TimeoutMedium).Do(r)
 	if err != nil {
 		return err
 	}
-	return statusCode(resp.StatusCode, http.StatusNoContent)
+	defer drainClose(resp.Body)
+	return checkResponse(resp, http.StatusNoContent)
+}
+
+// DeleteNetworkIfExists removes the network like DeleteNetwork. A network
+// which does not exist is not an error.
+func (c *Client) DeleteNetworkIfExists(id string) error {
+	if err := c.DeleteNetwork(id); err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
 }
 
 // ConnectNetwork connects a container to a network. for doin this container
 // and network are identified by their ID. If it fails an error is returned.
 func (c *Client) ConnectNetwork(nwid string, cid string, aliases []string) error {
-	endpoint := fmt.Sprintf("%snetworks/%s/connect", baseAddr, nwid)
+	endpoint, err := c.endpoint("networks/"+pathName(nwid)+"/connect", nil)
+	if err != nil {
+		return err
+	}
 
 	type endpointConfig struct {
 		Aliases []string `json:"Aliases"`
@@ -313,17 +659,21 @@ func (c *Client) ConnectNetwork(nwid string, cid string, aliases []string) error
 	if err != nil {
 		return err
 	}
-	r, err := c.http.Post(endpoint, "application/json", bytes.NewReader(b))
+	r, err := c.httpClient(TimeoutMedium).Post(endpoint, "application/json", bytes.NewReader(b))
 	if err != nil {
 		return err
 	}
-	return statusCode(r.StatusCode, http.StatusOK)
+	defer drainClose(r.Body)
+	return checkResponse(r, http.StatusOK)
 }
 
 // DisconnectNetwork removes a container from a network. container and network
 // are identified by theier ID. If it fails, an error is returned.
 func (c *Client) DisconnectNetwork(nwid string, cid string) error {
-	endpoint := fmt.Sprintf("%snetworks/%s/disconnect", baseAddr, nwid)
+	endpoint, err := c.endpoint("networks/"+pathName(nwid)+"/disconnect", nil)
+	if err != nil {
+		return err
+	}
 
 	min := struct {
 		Container string `json:"Container"`
@@ -334,29 +684,20 @@ func (c *Client) DisconnectNetwork(nwid string, cid string) error {
 	if err != nil {
 		return err
 	}
-	r, err := c.http.Post(endpoint, "application/json", bytes.NewReader(b))
+	r, err := c.httpClient(TimeoutMedium).Post(endpoint, "application/json", bytes.NewReader(b))
 	if err != nil {
 		return err
 	}
-	return statusCode(r.StatusCode, http.StatusOK)
+	defer drainClose(r.Body)
+	return checkResponse(r, http.StatusOK)
 }
 
-// Labels returns a map of all labels belonging to the given containerID
+// Labels returns a map of all labels belonging to the given containerID.
+// The other parts of the configuration are returned by InspectConfig.
 func (c *Client) Labels(containerID string) (map[string]string, error) {
-	r, err := c.http.Get(fmt.Sprintf("%scontainers/%s/json", baseAddr, containerID))
+	config, err := c.InspectConfig(context.Background(), containerID)
 	if err != nil {
 		return nil, err
 	}
-
-	if err = statusCode(r.StatusCode, http.StatusOK); err != nil {
-		return nil, err
-	}
-
-	inspect := struct {
-		Config struct {
-			Labels map[string]string `json:"Labels"`
-		} `json:"Config"`
-	}{}
-
-	return inspect.Config.Labels, json.NewDecoder(r.Body).Decode(&inspect)
+	return config.Labels, nil
 }
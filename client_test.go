@@ -1,13 +1,25 @@
 package docker
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"testing"
 	"time"
+
+	"github.com/grid-x/docker/types"
 )
 
 const (
@@ -19,6 +31,86 @@ type daemonMock struct {
 	StatusCode int
 	Response   []byte
 	sock       net.Listener
+
+	// Routes maps "<method> <path>[?<query>]" to a response. If Routes is
+	// set, StatusCode and Response are ignored and unknown routes return 404.
+	Routes map[string]mockResponse
+
+	mu       sync.Mutex
+	requests []string
+	bodies   []string
+}
+
+type mockResponse struct {
+	StatusCode int
+	Body       string
+	// Delay is waited before the response is written.
+	Delay time.Duration
+	// Header is added to the response.
+	Header map[string]string
+	// Next replaces the route after it was served once.
+	Next *mockResponse
+}
+
+// route switches the mock to the given routes and resets the recorded
+// requests. route(nil) switches back to the static response.
+func (d *daemonMock) route(routes map[string]mockResponse) {
+	d.mu.Lock()
+	d.Routes = routes
+	d.requests = nil
+	d.bodies = nil
+	d.mu.Unlock()
+}
+
+// Requests returns the "<method> <path>" of all requests received since the
+// last call of route.
+func (d *daemonMock) Requests() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.requests...)
+}
+
+// Bodies returns the bodies of the requests in the order of Requests.
+func (d *daemonMock) Bodies() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.bodies...)
+}
+
+func (d *daemonMock) serveRoute(w http.ResponseWriter, r *http.Request) bool {
+	d.mu.Lock()
+	if d.Routes == nil {
+		d.mu.Unlock()
+		return false
+	}
+	key := r.Method + " " + path.Clean(r.URL.Path)
+	d.requests = append(d.requests, key)
+	b, _ := ioutil.ReadAll(r.Body)
+	d.bodies = append(d.bodies, string(b))
+	// routes with query take precedence
+	matched := key + "?" + r.URL.RawQuery
+	res, ok := d.Routes[matched]
+	if !ok {
+		matched = key
+		res, ok = d.Routes[key]
+	}
+	if ok && res.Next != nil {
+		d.Routes[matched] = *res.Next
+	}
+	d.mu.Unlock()
+	if !ok {
+		res = mockResponse{StatusCode: http.StatusNotFound, Body: `{"message": "no route ` + key + `"}`}
+	}
+	time.Sleep(res.Delay)
+	w.Header().Add("Content-Type", "application/json")
+	for k, v := range res.Header {
+		w.Header().Set(k, v)
+	}
+	if res.StatusCode != 0 {
+		w.WriteHeader(res.StatusCode)
+	}
+	w.Write([]byte(res.Body))
+	return true
 }
 
 func (d *daemonMock) Listen() error {
@@ -29,7 +121,10 @@ func (d *daemonMock) Listen() error {
 	}
 	go func() {
 		http.Serve(d.sock, http.HandlerFunc(
-			func(w http.ResponseWriter, _ *http.Request) {
+			func(w http.ResponseWriter, r *http.Request) {
+				if d.serveRoute(w, r) {
+					return
+				}
 				if d.StatusCode != 0 && d.StatusCode != http.StatusOK {
 					w.WriteHeader(d.StatusCode)
 				}
@@ -127,6 +222,25 @@ func Test_ContainerIDByName(t *testing.T) {
 	}
 }
 
+func Test_ContainerIDByNameCache(t *testing.T) {
+	c := NewClient(sockPath)
+	c.names = &nameCache{}
+	c.names.activate()
+
+	srv.Response = []byte(`[{"Id": "c1", "Names": ["/plc-1"]}, {"Id": "c2", "Names": ["/plc"]}]`)
+	for _, want := range []struct{ name, id string }{{"plc", "c2"}, {"plc-", "c1"}} {
+		if id, err := c.ContainerIDByName(want.name); err != nil || id != want.id {
+			t.Errorf("%s: got %s, %v, want %s", want.name, id, err, want.id)
+		}
+	}
+	if id, _, ok := c.names.get("container", "plc"); !ok || id != "c2" {
+		t.Errorf("exact match not cached, got %s, %v", id, ok)
+	}
+	if _, _, ok := c.names.get("container", "plc-"); ok {
+		t.Error("partial match was cached")
+	}
+}
+
 func Test_NetworkIDByName(t *testing.T) {
 
 	tt := []struct {
@@ -142,6 +256,12 @@ func Test_NetworkIDByName(t *testing.T) {
 			responseFile: "networks.json",
 			expect:       "422bb11698f5f30491ec100674f1baf46ea360bef19fed498d6dc40b9b5c2ca7",
 		},
+		{
+			name:         "partial name",
+			networkName:  "simulation_subnet",
+			responseFile: "networks.json",
+			wantErr:      true,
+		},
 		{
 			name:         "not in list",
 			networkName:  "not_in_list",
@@ -166,6 +286,9 @@ func Test_NetworkIDByName(t *testing.T) {
 			if err != nil && !tc.wantErr {
 				t.Error(err)
 			}
+			if err == nil && tc.wantErr {
+				t.Errorf("expected error, got %s", id)
+			}
 			if id != tc.expect && !tc.wantErr {
 				t.Errorf("got: %s, want: %s", id, tc.expect)
 			}
@@ -220,6 +343,73 @@ func Test_CreateContainer(t *testing.T) {
 	}
 }
 
+func Test_DeleteContainer(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"DELETE /containers/c1":             {StatusCode: http.StatusNoContent},
+		"DELETE /containers/c2?force=1&v=1": {StatusCode: http.StatusNoContent},
+	})
+	defer srv.route(nil)
+
+	if err := client.DeleteContainer("c1"); err != nil {
+		t.Error(err)
+	}
+	if err := client.DeleteContainer("c2", DeleteForce(), DeleteVolumes()); err != nil {
+		t.Error(err)
+	}
+	if err := client.DeleteContainer("c2"); err == nil {
+		t.Error("expected error without options")
+	}
+}
+
+func Test_DeleteIfExists(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"DELETE /containers/c1": {StatusCode: http.StatusNotFound, Body: `{"message": "No such container: c1"}`},
+		"DELETE /containers/c2": {StatusCode: http.StatusConflict, Body: `{"message": "container is running"}`},
+		"DELETE /networks/n1":   {StatusCode: http.StatusNotFound, Body: `{"message": "network n1 not found"}`},
+		"DELETE /networks/n2":   {StatusCode: http.StatusNoContent},
+	})
+	defer srv.route(nil)
+
+	if err := client.DeleteContainer("c1"); !isNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+	if err := client.DeleteContainerIfExists("c1"); err != nil {
+		t.Error(err)
+	}
+	if err := client.DeleteContainerIfExists("c2"); err == nil {
+		t.Error("expected conflict")
+	}
+	for _, id := range []string{"n1", "n2"} {
+		if err := client.DeleteNetworkIfExists(id); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func Test_StopContainer(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"POST /containers/c1/stop":      {StatusCode: http.StatusNoContent},
+		"POST /containers/c2/stop?t=2":  {StatusCode: http.StatusNoContent, Delay: 100 * time.Millisecond},
+		"POST /containers/c3/stop?t=0":  {StatusCode: http.StatusNoContent},
+		"POST /containers/c4/stop?t=60": {StatusCode: http.StatusNotModified},
+	})
+	defer srv.route(nil)
+
+	if err := client.StopContainer("c1"); err != nil {
+		t.Error(err)
+	}
+	// the grace period is rounded up
+	if err := client.StopContainer("c2", StopTimeout(1500*time.Millisecond)); err != nil {
+		t.Error(err)
+	}
+	if err := client.StopContainer("c3", StopTimeout(0)); err != nil {
+		t.Error(err)
+	}
+	if err := client.StopContainer("c4", StopTimeout(time.Minute)); err == nil {
+		t.Error("expected error for stopped container")
+	}
+}
+
 func Test_CreateNetwork(t *testing.T) {
 
 	tt := []struct {
@@ -315,3 +505,143 @@ func Test_Labels(t *testing.T) {
 		})
 	}
 }
+
+func Test_ConnectionReuse(t *testing.T) {
+	var conns int32
+	daemon := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"message": "boom"}` + strings.Repeat(" ", 32<<10)))
+	}))
+	daemon.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	daemon.Start()
+	defer daemon.Close()
+
+	c, err := NewClientHost("tcp://" + strings.TrimPrefix(daemon.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		// neither the error response nor the old API read the body
+		if _, err := c.Info(context.Background()); err == nil {
+			t.Fatal("expected error")
+		}
+		if err := c.StartContainer("c1"); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	if conns != 1 {
+		t.Errorf("expected a single connection, got %d", conns)
+	}
+}
+
+func Test_HTTP2(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		conns int
+		proto string
+	)
+	daemon := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		proto = r.Proto
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	}))
+	daemon.TLS = &tls.Config{NextProtos: []string{"h2"}}
+	daemon.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	daemon.StartTLS()
+	defer daemon.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(daemon.Certificate())
+	c, err := NewClientTLS("tcp://"+strings.TrimPrefix(daemon.URL, "https://"), &tls.Config{RootCAs: pool})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first request establishes the connection
+	if _, err := c.Info(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Info(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if proto != "HTTP/2.0" || conns != 1 {
+		t.Errorf("expected HTTP/2 over a single connection, got %s over %d", proto, conns)
+	}
+}
+
+func Test_CreateContainerWithOptions(t *testing.T) {
+	var body map[string]interface{}
+	var query string
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id": "c1", "Warnings": []}`))
+	}))
+	defer daemon.Close()
+	c, err := NewClientHost("tcp://" + strings.TrimPrefix(daemon.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.CreateContainerWithOptions(context.Background(), CreateContainerOptions{
+		Name: "plc",
+		Config: types.ContainerConfig{
+			Image:      "sim/plc",
+			User:       "1000",
+			WorkingDir: "/srv",
+			Env:        []string{"MODE=sim"},
+		},
+		HostConfig: types.HostConfig{
+			CapAdd:       []string{"NET_ADMIN"},
+			PortBindings: map[string][]types.PortBinding{"502/tcp": {{HostPort: "1502"}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ID != "c1" || query != "name=plc" {
+		t.Errorf("got id %s and query %s", res.ID, query)
+	}
+	expect := map[string]interface{}{
+		"Image":      "sim/plc",
+		"User":       "1000",
+		"WorkingDir": "/srv",
+		"Env":        []interface{}{"MODE=sim"},
+		"HostConfig": map[string]interface{}{
+			"CapAdd":       []interface{}{"NET_ADMIN"},
+			"PortBindings": map[string]interface{}{"502/tcp": []interface{}{map[string]interface{}{"HostIp": "", "HostPort": "1502"}}},
+		},
+	}
+	if !reflect.DeepEqual(body, expect) {
+		t.Errorf("got body: %v, want: %v", body, expect)
+	}
+
+	if _, err := c.CreateContainerWithOptions(context.Background(), CreateContainerOptions{Name: "plc"}); err == nil {
+		t.Error("expected error for missing image")
+	}
+}
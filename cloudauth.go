@@ -0,0 +1,509 @@
+package docker
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The providers in this file mint short-lived registry credentials from the
+// ambient credentials of the cloud the process runs in. They talk to the
+// cloud APIs directly to avoid depending on the cloud SDKs.
+
+// CloudAuth selects the provider by the registry of the image: ECR for
+// *.dkr.ecr.<region>.amazonaws.com, GCR for gcr.io and *-docker.pkg.dev and
+// ACR for *.azurecr.io. Providers which are nil and other registries fall
+// back to Fallback, which may be nil for anonymous pulls.
+type CloudAuth struct {
+	ECR      *ECRAuth
+	GCR      *GCRAuth
+	ACR      *ACRAuth
+	Fallback AuthProvider
+}
+
+// NewCloudAuth returns a CloudAuth with all cloud providers.
+func NewCloudAuth(fallback AuthProvider) *CloudAuth {
+	return &CloudAuth{ECR: &ECRAuth{}, GCR: &GCRAuth{}, ACR: &ACRAuth{}, Fallback: fallback}
+}
+
+// Auth implements AuthProvider.
+func (c *CloudAuth) Auth(ctx context.Context, image string) (*RegistryAuth, error) {
+	if p := c.provider(image); p != nil {
+		return p.Auth(ctx, image)
+	}
+	return nil, nil
+}
+
+// Invalidate implements AuthInvalidator.
+func (c *CloudAuth) Invalidate(image string) {
+	if inv, ok := c.provider(image).(AuthInvalidator); ok {
+		inv.Invalidate(image)
+	}
+}
+
+func (c *CloudAuth) provider(image string) AuthProvider {
+	host := parseReference(image).Registry
+	switch {
+	case c.ECR != nil && ecrRegion(host) != "":
+		return c.ECR
+	case c.GCR != nil && (host == "gcr.io" || strings.HasSuffix(host, ".gcr.io") || strings.HasSuffix(host, "-docker.pkg.dev")):
+		return c.GCR
+	case c.ACR != nil && strings.HasSuffix(host, ".azurecr.io"):
+		return c.ACR
+	}
+	return c.Fallback
+}
+
+// credentialCache caches credentials per registry until shortly before they
+// expire.
+type credentialCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedCredential
+}
+
+type cachedCredential struct {
+	auth    *RegistryAuth
+	expires time.Time
+}
+
+func (c *credentialCache) get(key string) *RegistryAuth {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || !time.Now().Add(tokenExpiryMargin).Before(e.expires) {
+		return nil
+	}
+	return e.auth
+}
+
+func (c *credentialCache) put(key string, auth *RegistryAuth, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]cachedCredential)
+	}
+	c.entries[key] = cachedCredential{auth: auth, expires: expires}
+}
+
+func (c *credentialCache) remove(key string) {
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.mu.Unlock()
+}
+
+// cloudRequest sends the request and decodes the JSON response into out.
+func cloudRequest(ctx context.Context, client *http.Client, req *http.Request, out interface{}) error {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	r, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if err := checkResponse(r, http.StatusOK); err != nil {
+		return err
+	}
+	if s, ok := out.(*string); ok {
+		b, err := ioutil.ReadAll(r.Body)
+		*s = string(b)
+		return err
+	}
+	return json.NewDecoder(r.Body).Decode(out)
+}
+
+// ECRAuth provides credentials for Amazon ECR. The AWS credentials are read
+// from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN. If
+// they are not set, the credentials of the task are requested from the
+// container credentials endpoint on ECS, otherwise the credentials of the
+// instance role from the instance metadata service (IMDSv2) on EC2.
+type ECRAuth struct {
+	HTTP *http.Client
+
+	cache credentialCache
+	// endpoint overrides https://api.ecr.<region>.amazonaws.com/, ecsURL
+	// the container credentials endpoint and imdsURL the instance metadata
+	// service.
+	endpoint string
+	ecsURL   string
+	imdsURL  string
+	now      func() time.Time
+}
+
+const (
+	ecsCredentialsURL = "http://169.254.170.2"
+	ec2IMDSURL        = "http://169.254.169.254"
+	// imdsTimeout limits the requests to the instance metadata service,
+	// which is not reachable outside of EC2.
+	imdsTimeout = 2 * time.Second
+)
+
+// awsCredentials are the credentials requests to AWS are signed with.
+type awsCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	Token           string `json:"Token"`
+}
+
+// Auth implements AuthProvider.
+func (e *ECRAuth) Auth(ctx context.Context, image string) (*RegistryAuth, error) {
+	host := parseReference(image).Registry
+	if auth := e.cache.get(host); auth != nil {
+		return auth, nil
+	}
+	region := ecrRegion(host)
+	if region == "" {
+		return nil, fmt.Errorf("ecr: %s is not an ECR registry", host)
+	}
+	creds, err := e.credentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ecr: %w", err)
+	}
+
+	endpoint := e.endpoint
+	if endpoint == "" {
+		endpoint = "https://api.ecr." + region + ".amazonaws.com/"
+	}
+	body := []byte("{}")
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken")
+	if creds.Token != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.Token)
+	}
+	now := time.Now
+	if e.now != nil {
+		now = e.now
+	}
+	signV4(req, body, creds.AccessKeyID, creds.SecretAccessKey, region, "ecr", now().UTC())
+
+	var res struct {
+		AuthorizationData []struct {
+			AuthorizationToken string  `json:"authorizationToken"`
+			ExpiresAt          float64 `json:"expiresAt"`
+		} `json:"authorizationData"`
+	}
+	if err := cloudRequest(ctx, e.HTTP, req, &res); err != nil {
+		return nil, fmt.Errorf("ecr: get authorization token: %w", err)
+	}
+	if len(res.AuthorizationData) == 0 {
+		return nil, fmt.Errorf("ecr: no authorization data returned")
+	}
+	data := res.AuthorizationData[0]
+	// the token is base64 of "AWS:<password>"
+	b, err := base64.StdEncoding.DecodeString(data.AuthorizationToken)
+	if err != nil {
+		return nil, fmt.Errorf("ecr: decode token: %w", err)
+	}
+	ss := strings.SplitN(string(b), ":", 2)
+	if len(ss) != 2 {
+		return nil, fmt.Errorf("ecr: invalid token")
+	}
+	auth := &RegistryAuth{Username: ss[0], Password: ss[1], ServerAddress: host}
+	e.cache.put(host, auth, time.Unix(int64(data.ExpiresAt), 0))
+	return auth, nil
+}
+
+// Invalidate implements AuthInvalidator.
+func (e *ECRAuth) Invalidate(image string) {
+	e.cache.remove(parseReference(image).Registry)
+}
+
+// credentials returns the AWS credentials of the environment, the ECS task
+// or the EC2 instance role, in this order.
+func (e *ECRAuth) credentials(ctx context.Context) (awsCredentials, error) {
+	creds := awsCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		Token:           os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID != "" && creds.SecretAccessKey != "" {
+		return creds, nil
+	}
+
+	// docs.: https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task-iam-roles.html
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+		endpoint = e.ecsURL
+		if endpoint == "" {
+			endpoint = ecsCredentialsURL
+		}
+		endpoint += uri
+	}
+	if endpoint != "" {
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+		if err != nil {
+			return creds, err
+		}
+		if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		if err := cloudRequest(ctx, e.HTTP, req, &creds); err != nil {
+			return creds, fmt.Errorf("get container credentials: %w", err)
+		}
+		return creds, nil
+	}
+
+	if err := e.instanceCredentials(ctx, &creds); err != nil {
+		return creds, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are not set "+
+			"and the instance role is not available: %w", err)
+	}
+	return creds, nil
+}
+
+// instanceCredentials requests the credentials of the role of the EC2
+// instance from the instance metadata service by IMDSv2.
+// docs.: https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/iam-roles-for-amazon-ec2.html
+func (e *ECRAuth) instanceCredentials(ctx context.Context, creds *awsCredentials) error {
+	ctx, cancel := context.WithTimeout(ctx, imdsTimeout)
+	defer cancel()
+	base := e.imdsURL
+	if base == "" {
+		base = ec2IMDSURL
+	}
+
+	req, err := http.NewRequest(http.MethodPut, base+"/latest/api/token", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	var token string
+	if err := cloudRequest(ctx, e.HTTP, req, &token); err != nil {
+		return fmt.Errorf("get metadata token: %w", err)
+	}
+
+	get := func(path string, out interface{}) error {
+		req, err := http.NewRequest(http.MethodGet, base+"/latest/meta-data/iam/security-credentials/"+path, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("X-aws-ec2-metadata-token", token)
+		return cloudRequest(ctx, e.HTTP, req, out)
+	}
+	var roles string
+	if err := get("", &roles); err != nil {
+		return fmt.Errorf("get instance role: %w", err)
+	}
+	role := strings.TrimSpace(strings.SplitN(roles, "\n", 2)[0])
+	if role == "" {
+		return fmt.Errorf("no instance role")
+	}
+	if err := get(url.PathEscape(role), creds); err != nil {
+		return fmt.Errorf("get credentials of role %s: %w", role, err)
+	}
+	return nil
+}
+
+// ecrRegion returns the region of an ECR registry host like
+// 123456789012.dkr.ecr.eu-central-1.amazonaws.com or an empty string.
+func ecrRegion(host string) string {
+	ss := strings.Split(host, ".")
+	if len(ss) < 6 || ss[1] != "dkr" || ss[2] != "ecr" || !strings.HasPrefix(strings.Join(ss[4:], "."), "amazonaws.com") {
+		return ""
+	}
+	return ss[3]
+}
+
+// signV4 signs the request by AWS signature version 4.
+// docs.: https://docs.aws.amazon.com/general/latest/gr/sigv4_signing.html
+func signV4(req *http.Request, body []byte, keyID, secret, region, service string, t time.Time) {
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	signed := []string{"host", "x-amz-date"}
+	for _, h := range []string{"content-type", "x-amz-target", "x-amz-security-token"} {
+		if req.Header.Get(h) != "" {
+			signed = append(signed, h)
+		}
+	}
+	// the headers have to be sorted
+	sort.Strings(signed)
+	var canonicalHeaders strings.Builder
+	for _, h := range signed {
+		v := req.Header.Get(h)
+		if h == "host" {
+			v = req.URL.Host
+		}
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(v) + "\n")
+	}
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonical := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signed, ";"),
+		sha256Hex(body),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonical))
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		keyID, scope, strings.Join(signed, ";"), signature))
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	io.WriteString(h, data)
+	return h.Sum(nil)
+}
+
+// GCRAuth provides credentials for Google Container Registry and Artifact
+// Registry. The access token is taken from GOOGLE_OAUTH_ACCESS_TOKEN or
+// minted by the metadata server of the instance.
+type GCRAuth struct {
+	HTTP *http.Client
+
+	cache credentialCache
+	// metadataURL overrides the token endpoint of the metadata server.
+	metadataURL string
+}
+
+const gcrMetadataURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// Auth implements AuthProvider.
+func (g *GCRAuth) Auth(ctx context.Context, image string) (*RegistryAuth, error) {
+	host := parseReference(image).Registry
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return &RegistryAuth{Username: "oauth2accesstoken", Password: token, ServerAddress: host}, nil
+	}
+	if auth := g.cache.get(host); auth != nil {
+		return auth, nil
+	}
+
+	endpoint := g.metadataURL
+	if endpoint == "" {
+		endpoint = gcrMetadataURL
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	var res struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := cloudRequest(ctx, g.HTTP, req, &res); err != nil {
+		return nil, fmt.Errorf("gcr: get access token: %w", err)
+	}
+	auth := &RegistryAuth{Username: "oauth2accesstoken", Password: res.AccessToken, ServerAddress: host}
+	g.cache.put(host, auth, time.Now().Add(time.Duration(res.ExpiresIn)*time.Second))
+	return auth, nil
+}
+
+// Invalidate implements AuthInvalidator.
+func (g *GCRAuth) Invalidate(image string) {
+	g.cache.remove(parseReference(image).Registry)
+}
+
+// ACRAuth provides credentials for Azure Container Registry. The managed
+// identity of the instance is exchanged for a refresh token of the
+// registry.
+type ACRAuth struct {
+	HTTP *http.Client
+	// ClientID selects a user assigned managed identity.
+	ClientID string
+
+	cache credentialCache
+	// imdsURL overrides the token endpoint of the instance metadata service,
+	// exchangeURL the exchange endpoint of the registry.
+	imdsURL     string
+	exchangeURL string
+}
+
+const (
+	acrIMDSURL = "http://169.254.169.254/metadata/identity/oauth2/token"
+	// acrUsername is the user name for registry refresh tokens.
+	acrUsername = "00000000-0000-0000-0000-000000000000"
+	// acrTokenLifetime is the lifetime of refresh tokens issued by ACR.
+	acrTokenLifetime = 3 * time.Hour
+)
+
+// Auth implements AuthProvider.
+func (a *ACRAuth) Auth(ctx context.Context, image string) (*RegistryAuth, error) {
+	host := parseReference(image).Registry
+	if auth := a.cache.get(host); auth != nil {
+		return auth, nil
+	}
+
+	endpoint := a.imdsURL
+	if endpoint == "" {
+		endpoint = acrIMDSURL
+	}
+	q := url.Values{"api-version": {"2018-02-01"}, "resource": {"https://management.azure.com/"}}
+	if a.ClientID != "" {
+		q.Set("client_id", a.ClientID)
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata", "true")
+	var aad struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := cloudRequest(ctx, a.HTTP, req, &aad); err != nil {
+		return nil, fmt.Errorf("acr: get managed identity token: %w", err)
+	}
+
+	exchange := a.exchangeURL
+	if exchange == "" {
+		exchange = "https://" + host + "/oauth2/exchange"
+	}
+	form := url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {host},
+		"access_token": {aad.AccessToken},
+	}
+	req, err = http.NewRequest(http.MethodPost, exchange, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var res struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := cloudRequest(ctx, a.HTTP, req, &res); err != nil {
+		return nil, fmt.Errorf("acr: exchange token: %w", err)
+	}
+	auth := &RegistryAuth{Username: acrUsername, Password: res.RefreshToken, ServerAddress: host}
+	a.cache.put(host, auth, time.Now().Add(acrTokenLifetime))
+	return auth, nil
+}
+
+// Invalidate implements AuthInvalidator.
+func (a *ACRAuth) Invalidate(image string) {
+	a.cache.remove(parseReference(image).Registry)
+}
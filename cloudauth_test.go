@@ -0,0 +1,173 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_signV4(t *testing.T) {
+	// example of the AWS documentation
+	req, _ := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	signV4(req, nil, "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "us-east-1", "iam",
+		time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date, " +
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func Test_ECRAuth(t *testing.T) {
+	for k, v := range map[string]string{"AWS_ACCESS_KEY_ID": "AKID", "AWS_SECRET_ACCESS_KEY": "secret"} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+	calls := 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") ||
+			!strings.Contains(r.Header.Get("Authorization"), "/eu-central-1/ecr/aws4_request") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		token := base64.StdEncoding.EncodeToString([]byte("AWS:pw"))
+		fmt.Fprintf(w, `{"authorizationData": [{"authorizationToken": %q, "expiresAt": %d}]}`,
+			token, time.Now().Add(time.Hour).Unix())
+	}))
+	defer api.Close()
+
+	ca := NewCloudAuth(nil)
+	ca.ECR.endpoint = api.URL
+	image := "123456789012.dkr.ecr.eu-central-1.amazonaws.com/sim/plc:1"
+	for i := 0; i < 2; i++ {
+		auth, err := ca.Auth(context.Background(), image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if auth.Username != "AWS" || auth.Password != "pw" {
+			t.Errorf("unexpected credentials %+v", auth)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected cached credentials, got %d calls", calls)
+	}
+}
+
+func Test_ECRAuthRoleCredentials(t *testing.T) {
+	env := []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN",
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI"}
+	for _, k := range env {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Unsetenv(k)
+	}
+	creds := `{"AccessKeyId": "ASIA", "SecretAccessKey": "secret", "Token": "session", "Expiration": "2021-06-01T18:00:00Z"}`
+	aws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		imdsToken := r.Header.Get("X-aws-ec2-metadata-token") == "imds"
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			fmt.Fprint(w, "imds")
+		case r.URL.Path == "/latest/meta-data/iam/security-credentials/" && imdsToken:
+			fmt.Fprint(w, "sim-role\n")
+		case r.URL.Path == "/latest/meta-data/iam/security-credentials/sim-role" && imdsToken,
+			r.URL.Path == "/v2/credentials/task":
+			fmt.Fprint(w, creds)
+		case r.URL.Path == "/ecr" && r.Header.Get("X-Amz-Security-Token") == "session" &&
+			strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=ASIA/"):
+			token := base64.StdEncoding.EncodeToString([]byte("AWS:pw"))
+			fmt.Fprintf(w, `{"authorizationData": [{"authorizationToken": %q, "expiresAt": %d}]}`,
+				token, time.Now().Add(time.Hour).Unix())
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer aws.Close()
+
+	tt := []struct {
+		name string
+		env  map[string]string
+	}{
+		{name: "instance role"},
+		{name: "task role", env: map[string]string{"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI": "/v2/credentials/task"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				os.Setenv(k, v)
+				defer os.Unsetenv(k)
+			}
+			ecr := &ECRAuth{endpoint: aws.URL + "/ecr", ecsURL: aws.URL, imdsURL: aws.URL}
+			auth, err := ecr.Auth(context.Background(), "123456789012.dkr.ecr.eu-central-1.amazonaws.com/sim/plc:1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if auth.Username != "AWS" || auth.Password != "pw" {
+				t.Errorf("unexpected credentials %+v", auth)
+			}
+		})
+	}
+}
+
+func Test_GCRAuth(t *testing.T) {
+	md := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `{"access_token": "ya29", "expires_in": 3599}`)
+	}))
+	defer md.Close()
+
+	ca := NewCloudAuth(nil)
+	ca.GCR.metadataURL = md.URL
+	auth, err := ca.Auth(context.Background(), "europe-docker.pkg.dev/proj/repo/app:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.Username != "oauth2accesstoken" || auth.Password != "ya29" {
+		t.Errorf("unexpected credentials %+v", auth)
+	}
+}
+
+func Test_ACRAuth(t *testing.T) {
+	cloud := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/imds":
+			fmt.Fprint(w, `{"access_token": "aad"}`)
+		case "/exchange":
+			r.ParseForm()
+			if r.PostForm.Get("access_token") != "aad" || r.PostForm.Get("service") != "sim.azurecr.io" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"refresh_token": "acr"}`)
+		}
+	}))
+	defer cloud.Close()
+
+	ca := NewCloudAuth(nil)
+	ca.ACR.imdsURL = cloud.URL + "/imds"
+	ca.ACR.exchangeURL = cloud.URL + "/exchange"
+	auth, err := ca.Auth(context.Background(), "sim.azurecr.io/plc:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.Username != acrUsername || auth.Password != "acr" {
+		t.Errorf("unexpected credentials %+v", auth)
+	}
+}
+
+func Test_CloudAuthFallback(t *testing.T) {
+	if auth, err := NewCloudAuth(nil).Auth(context.Background(), "postgres:12"); auth != nil || err != nil {
+		t.Errorf("expected anonymous pull, got %+v, %v", auth, err)
+	}
+}
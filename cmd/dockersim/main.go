@@ -0,0 +1,349 @@
+// Command dockersim manages simulation environments from the shell. It uses
+// the same code paths as the library:
+//
+//	dockersim apply [-dry-run] [-parallel N] [-rollback] [-var KEY=VALUE]... SPEC
+//	dockersim teardown [-dry-run] (-run RUN | -namespace NS)
+//	dockersim ls (-run RUN | -namespace NS)
+//	dockersim logs [-follow] CONTAINER
+//	dockersim report [-json] (-run RUN | -namespace NS)
+//
+// The daemon is taken from -host or DOCKER_HOST and defaults to the local
+// socket.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/grid-x/docker"
+)
+
+const defaultHost = "unix:///var/run/docker.sock"
+
+// errUsage is returned for invalid arguments, the usage is printed already.
+var errUsage = errors.New("invalid usage")
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, c *docker.Client, args []string, stdout, stderr io.Writer) error
+}
+
+var commands map[string]command
+
+func init() {
+	// the commands print their usage, so the map is set up after them
+	commands = map[string]command{
+		"apply":    {"apply [-dry-run] [-parallel N] [-rollback] [-var KEY=VALUE]... SPEC: reconcile the daemon with the spec file", apply},
+		"teardown": {"teardown [-dry-run] (-run RUN | -namespace NS): remove all resources", teardown},
+		"ls":       {"ls (-run RUN | -namespace NS): list the containers, networks and volumes", list},
+		"logs":     {"logs [-follow] CONTAINER: print the output of the container", logs},
+		"report":   {"report [-json] (-run RUN | -namespace NS): print the resource usage", report},
+	}
+}
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		cancel()
+	}()
+
+	err := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	cancel()
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "dockersim: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("dockersim", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	host := fs.String("host", os.Getenv("DOCKER_HOST"), "address of the daemon (default "+defaultHost+")")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: dockersim [-host HOST] COMMAND [ARGS]\n\ncommands:\n")
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(stderr, "  %s\n", commands[name].usage)
+		}
+		fmt.Fprintf(stderr, "\nflags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fs.Usage()
+		return errUsage
+	}
+	if *host == "" {
+		*host = defaultHost
+	}
+	c, err := docker.NewClientHost(*host)
+	if err != nil {
+		return err
+	}
+	return cmd.run(ctx, c, fs.Args()[1:], stdout, stderr)
+}
+
+// newFlagSet returns the flag set of the command.
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("dockersim "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: dockersim %s\n", commands[name].usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// owner holds the flags selecting the resources of a run or a namespace.
+type owner struct {
+	run, namespace string
+}
+
+func (o *owner) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.run, "run", "", "select the resources of the run")
+	fs.StringVar(&o.namespace, "namespace", "", "select the resources of the namespace")
+}
+
+// selector returns the selector of the resources, exactly one of run and
+// namespace must be set.
+func (o *owner) selector(c *docker.Client) (docker.Selector, error) {
+	switch {
+	case o.run != "" && o.namespace != "":
+		return nil, fmt.Errorf("-run and -namespace are exclusive")
+	case o.run != "":
+		return docker.RunSelector(o.run), nil
+	case o.namespace != "":
+		oc, err := c.Owned(docker.Namespace{Name: o.namespace})
+		if err != nil {
+			return nil, err
+		}
+		return oc.Selector(), nil
+	}
+	return nil, fmt.Errorf("either -run or -namespace is required")
+}
+
+func apply(ctx context.Context, c *docker.Client, args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("apply", stderr)
+	dryRun := fs.Bool("dry-run", false, "only print the planned operations")
+	parallel := fs.Int("parallel", 1, "set up at most `N` containers at the same time")
+	rollback := fs.Bool("rollback", false, "remove the created resources if applying fails")
+	vars := templateVars{}
+	fs.Var(vars, "var", "execute the spec as template with the variable `KEY=VALUE`, can be repeated")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	var spec *docker.Spec
+	var err error
+	if len(vars) > 0 {
+		spec, err = docker.LoadSpecTemplateFile(fs.Arg(0), vars)
+	} else {
+		spec, err = docker.LoadSpecFile(fs.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+	opts := []docker.ApplyOption{docker.Parallel(*parallel)}
+	if *dryRun {
+		opts = append(opts, docker.DryRun())
+	}
+	if *rollback {
+		opts = append(opts, docker.Rollback(docker.RollbackRemove))
+	}
+	ops, err := c.Apply(ctx, spec, opts...)
+	printOperations(stdout, ops)
+	return err
+}
+
+// templateVars collects the -var flags.
+type templateVars map[string]interface{}
+
+func (v templateVars) String() string {
+	return ""
+}
+
+func (v templateVars) Set(s string) error {
+	ss := strings.SplitN(s, "=", 2)
+	if len(ss) != 2 || ss[0] == "" {
+		return fmt.Errorf("want KEY=VALUE")
+	}
+	v[ss[0]] = ss[1]
+	return nil
+}
+
+func teardown(ctx context.Context, c *docker.Client, args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("teardown", stderr)
+	dryRun := fs.Bool("dry-run", false, "only print the planned operations")
+	var o owner
+	o.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errUsage
+	}
+	var opts []docker.ApplyOption
+	if *dryRun {
+		opts = append(opts, docker.DryRun())
+	}
+
+	var ops []docker.Operation
+	var err error
+	switch {
+	case o.run != "" && o.namespace != "":
+		return fmt.Errorf("-run and -namespace are exclusive")
+	case o.run != "":
+		ops, err = c.TeardownRun(ctx, o.run, opts...)
+	case o.namespace != "":
+		oc, oerr := c.Owned(docker.Namespace{Name: o.namespace})
+		if oerr != nil {
+			return oerr
+		}
+		ops, err = oc.RemoveAll(ctx, opts...)
+	default:
+		return fmt.Errorf("either -run or -namespace is required")
+	}
+	printOperations(stdout, ops)
+	return err
+}
+
+func printOperations(w io.Writer, ops []docker.Operation) {
+	for _, op := range ops {
+		fmt.Fprintln(w, op)
+	}
+}
+
+func list(ctx context.Context, c *docker.Client, args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("ls", stderr)
+	var o owner
+	o.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	selector, err := o.selector(c)
+	if err != nil {
+		return err
+	}
+
+	containers, err := c.ListContainers(ctx, selector, true)
+	if err != nil {
+		return err
+	}
+	networks, err := c.ListNetworks(ctx, selector)
+	if err != nil {
+		return err
+	}
+	volumes, err := c.ListVolumes(ctx, selector)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESOURCE\tNAME\tDETAILS")
+	for _, cs := range containers {
+		fmt.Fprintf(tw, "container\t%s\t%s (%s)\n", cs.Name(), cs.Status, cs.Image)
+	}
+	for _, n := range networks {
+		fmt.Fprintf(tw, "network\t%s\t%s\n", n.Name, n.Driver)
+	}
+	for _, v := range volumes {
+		fmt.Fprintf(tw, "volume\t%s\t%s\n", v.Name, v.Driver)
+	}
+	return tw.Flush()
+}
+
+func logs(ctx context.Context, c *docker.Client, args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("logs", stderr)
+	follow := fs.Bool("follow", false, "follow the output until the container exits")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	if *follow {
+		return c.FollowLogs(ctx, fs.Arg(0), stdout, stderr)
+	}
+	return c.Logs(ctx, fs.Arg(0), stdout, stderr)
+}
+
+func report(ctx context.Context, c *docker.Client, args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("report", stderr)
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	var o owner
+	o.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	selector, err := o.selector(c)
+	if err != nil {
+		return err
+	}
+	r, err := c.UsageReport(ctx, selector)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tIMAGE\tSTATE\tRESTARTS\tPEAK MEMORY\tCPU\tNET RX/TX")
+	for _, u := range r.Containers {
+		state := "running"
+		if !u.Running {
+			state = fmt.Sprintf("exited (%d)", u.ExitCode)
+		}
+		if u.OOMKilled {
+			state += ", oom killed"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s/%s\n", u.Name, u.Image, state, u.RestartCount,
+			formatBytes(u.PeakMemory), time.Duration(u.CPUSeconds*float64(time.Second)).Round(time.Millisecond),
+			formatBytes(u.NetworkRx), formatBytes(u.NetworkTx))
+	}
+	return tw.Flush()
+}
+
+// formatBytes formats a size in binary units, e.g. 12.5MiB.
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// daemon starts a fake daemon which answers the requests of the commands.
+func daemon() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.Query().Get("filters"), "com.grid-x.docker.run=r1") {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Write([]byte(`[{"Id": "c1", "Names": ["/plc"], "Image": "sim/plc", "State": "running", "Status": "Up 2 minutes"}]`))
+	})
+	mux.HandleFunc("/networks", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"Id": "n1", "Name": "field", "Driver": "bridge"}]`))
+	})
+	mux.HandleFunc("/volumes", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Volumes": [{"Name": "plc-data", "Driver": "local"}]}`))
+	})
+	mux.HandleFunc("/containers/plc/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id": "c1", "Name": "/plc", "Config": {"Image": "sim/plc"}}`))
+	})
+	mux.HandleFunc("/containers/plc/logs", func(w http.ResponseWriter, r *http.Request) {
+		msg := "started\n"
+		header := make([]byte, 8)
+		header[0] = 1
+		binary.BigEndian.PutUint32(header[4:], uint32(len(msg)))
+		w.Write(append(header, msg...))
+	})
+	return httptest.NewServer(mux)
+}
+
+func Test_Run(t *testing.T) {
+	srv := daemon()
+	defer srv.Close()
+	host := "tcp://" + srv.Listener.Addr().String()
+	tt := []struct {
+		name    string
+		args    []string
+		expect  []string
+		wantErr error
+	}{
+		{
+			name:   "ls",
+			args:   []string{"ls", "-run", "r1"},
+			expect: []string{"container  plc       Up 2 minutes (sim/plc)", "network    field", "volume     plc-data"},
+		},
+		{
+			name:   "teardown dry run",
+			args:   []string{"teardown", "-dry-run", "-run", "r1"},
+			expect: []string{"remove container plc", "remove network field", "remove volume plc-data"},
+		},
+		{
+			name:   "apply template dry run",
+			args:   []string{"apply", "-dry-run", "-var", "Run=1", "-var", "Devices=2", "../../testfiles/spec.tmpl.yaml"},
+			expect: []string{"create network field-1", "create container meter-1-1", "start container meter-1-2"},
+		},
+		{
+			name:   "logs",
+			args:   []string{"logs", "plc"},
+			expect: []string{"started"},
+		},
+		{
+			name:    "unknown command",
+			args:    []string{"deploy"},
+			wantErr: errUsage,
+		},
+		{
+			name:    "missing owner",
+			args:    []string{"ls"},
+			wantErr: errors.New("either -run or -namespace is required"),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			err := run(context.Background(), append([]string{"-host", host}, tc.args...), &stdout, &stderr)
+			if tc.wantErr == nil && err != nil {
+				t.Fatal(err)
+			}
+			if tc.wantErr != nil && (err == nil || err.Error() != tc.wantErr.Error()) {
+				t.Fatalf("got error: %v, want: %v", err, tc.wantErr)
+			}
+			for _, e := range tc.expect {
+				if !strings.Contains(stdout.String(), e) {
+					t.Errorf("expected %q in output:\n%s", e, stdout.String())
+				}
+			}
+		})
+	}
+}
+
+func Test_FormatBytes(t *testing.T) {
+	for n, want := range map[uint64]string{
+		512:     "512B",
+		1536:    "1.5KiB",
+		5 << 20: "5.0MiB",
+		3 << 30: "3.0GiB",
+	} {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %s, want: %s", n, got, want)
+		}
+	}
+}
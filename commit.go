@@ -0,0 +1,82 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// commitInstructions are the Dockerfile instructions the daemon accepts as
+// changes of a commit.
+var commitInstructions = map[string]bool{
+	"CMD": true, "ENTRYPOINT": true, "ENV": true, "EXPOSE": true, "LABEL": true,
+	"ONBUILD": true, "STOPSIGNAL": true, "USER": true, "VOLUME": true, "WORKDIR": true,
+}
+
+// CommitOptions configure the image created by CommitContainer.
+// Changes are Dockerfile instructions applied to the configuration of the
+// image, e.g.: ["ENV MODE=replay", "EXPOSE 502/tcp", `CMD ["/sim", "--replay"]`]
+// Only CMD, ENTRYPOINT, ENV, EXPOSE, LABEL, ONBUILD, STOPSIGNAL, USER, VOLUME
+// and WORKDIR are supported.
+type CommitOptions struct {
+	// Reference is the repository and optional tag of the new image, e.g.:
+	// "sim/plc:snapshot". The image is untagged if it is empty.
+	Reference string
+	Comment   string
+	Author    string
+	Changes   []string
+	// NoPause commits without pausing the container. The image may then
+	// contain files which are written inconsistently.
+	NoPause bool
+}
+
+// CommitContainer creates an image from the filesystem and configuration of
+// the container and returns its ID.
+// docs.: https://docs.docker.com/engine/api/v1.41/#operation/ImageCommit
+func (c *Client) CommitContainer(ctx context.Context, id string, opts CommitOptions) (string, error) {
+	q := url.Values{"container": {id}}
+	if opts.Reference != "" {
+		repo, tag := repoTag(opts.Reference)
+		q.Set("repo", repo)
+		if tag != "" {
+			q.Set("tag", tag)
+		}
+	}
+	if opts.Comment != "" {
+		q.Set("comment", opts.Comment)
+	}
+	if opts.Author != "" {
+		q.Set("author", opts.Author)
+	}
+	if opts.NoPause {
+		q.Set("pause", "0")
+	}
+	for _, change := range opts.Changes {
+		fields := strings.Fields(change)
+		if len(fields) == 0 || !commitInstructions[strings.ToUpper(fields[0])] {
+			return "", fmt.Errorf("commit container %s: unsupported change %q", id, change)
+		}
+		q.Add("changes", change)
+	}
+
+	var res createResponse
+	if err := c.doJSON(ctx, http.MethodPost, "commit", q, nil, &res, http.StatusCreated); err != nil {
+		return "", fmt.Errorf("commit container %s: %w", id, err)
+	}
+	return res.ID, nil
+}
+
+// repoTag splits the reference into repository and tag. The tag is empty if
+// the reference has none. A digest is dropped, the image it is applied to
+// gets a digest of its own.
+func repoTag(ref string) (string, string) {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		ref = ref[:i]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, ""
+}
@@ -0,0 +1,37 @@
+package docker
+
+import (
+	"context"
+	"net/url"
+	"testing"
+)
+
+func Test_CommitContainer(t *testing.T) {
+	q := url.Values{
+		"container": {"c1"},
+		"repo":      {"registry:5000/sim/plc"},
+		"tag":       {"snapshot"},
+		"comment":   {"after replay"},
+		"changes":   {"ENV MODE=replay", "EXPOSE 502/tcp"},
+	}
+	srv.route(map[string]mockResponse{
+		"POST /commit?" + q.Encode(): {StatusCode: 201, Body: `{"Id": "sha256:abc"}`},
+	})
+	defer srv.route(nil)
+
+	id, err := client.CommitContainer(context.Background(), "c1", CommitOptions{
+		Reference: "registry:5000/sim/plc:snapshot",
+		Comment:   "after replay",
+		Changes:   []string{"ENV MODE=replay", "EXPOSE 502/tcp"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "sha256:abc" {
+		t.Errorf("unexpected image ID %s", id)
+	}
+
+	if _, err := client.CommitContainer(context.Background(), "c1", CommitOptions{Changes: []string{"RUN rm -rf /"}}); err == nil {
+		t.Error("expected error for unsupported change")
+	}
+}
@@ -0,0 +1,536 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Compose is the subset of the docker-compose file format which is supported
+// by this package: services with image, command, environment, ports, volumes,
+// networks, depends_on and healthcheck plus the top level networks and volumes. Unknown
+// fields are reported as error instead of being silently ignored.
+// docs.: https://docs.docker.com/compose/compose-file/
+type Compose struct {
+	Version  string                     `json:"version,omitempty"`
+	Services map[string]*ComposeService `json:"services"`
+	Networks map[string]*ComposeNetwork `json:"networks,omitempty"`
+	Volumes  map[string]*ComposeVolume  `json:"volumes,omitempty"`
+
+	// dir is the directory of the compose file. Relative bind mounts are
+	// resolved against it.
+	dir string
+}
+
+// ComposeService is a service of a compose file.
+type ComposeService struct {
+	Image         string              `json:"image"`
+	ContainerName string              `json:"container_name,omitempty"`
+	Command       composeCommand      `json:"command,omitempty"`
+	Entrypoint    composeCommand      `json:"entrypoint,omitempty"`
+	Environment   composeEnvironment  `json:"environment,omitempty"`
+	Labels        composeMapping      `json:"labels,omitempty"`
+	Ports         []string            `json:"ports,omitempty"`
+	Expose        []string            `json:"expose,omitempty"`
+	Volumes       []string            `json:"volumes,omitempty"`
+	Networks      composeNetworks     `json:"networks,omitempty"`
+	DependsOn     composeDepends      `json:"depends_on,omitempty"`
+	Privileged    bool                `json:"privileged,omitempty"`
+	Healthcheck   *ComposeHealthcheck `json:"healthcheck,omitempty"`
+	// Deploy is only used by StackDeploy.
+	Deploy *ComposeDeploy `json:"deploy,omitempty"`
+}
+
+// ComposeHealthcheck is the healthcheck of a service.
+type ComposeHealthcheck struct {
+	Test        composeHealthTest `json:"test,omitempty"`
+	Interval    string            `json:"interval,omitempty"`
+	Timeout     string            `json:"timeout,omitempty"`
+	StartPeriod string            `json:"start_period,omitempty"`
+	Retries     int               `json:"retries,omitempty"`
+	Disable     bool              `json:"disable,omitempty"`
+}
+
+// composeHealthTest accepts the test of a healthcheck as list like
+// ["CMD", "curl", "-f", "http://localhost"] or as string, which is run by the
+// shell of the container.
+type composeHealthTest []string
+
+func (t *composeHealthTest) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*t = []string{"CMD-SHELL", s}
+		return nil
+	}
+	var l []string
+	if err := json.Unmarshal(b, &l); err != nil {
+		return fmt.Errorf("test must be a string or a list of strings")
+	}
+	*t = l
+	return nil
+}
+
+// ComposeDeploy holds the swarm settings of a service.
+type ComposeDeploy struct {
+	Replicas  *int           `json:"replicas,omitempty"`
+	Labels    composeMapping `json:"labels,omitempty"`
+	Placement *struct {
+		Constraints []string `json:"constraints,omitempty"`
+	} `json:"placement,omitempty"`
+}
+
+// ComposeNetwork is a top level network of a compose file.
+type ComposeNetwork struct {
+	Name     string         `json:"name,omitempty"`
+	Driver   string         `json:"driver,omitempty"`
+	External bool           `json:"external,omitempty"`
+	Internal bool           `json:"internal,omitempty"`
+	Labels   composeMapping `json:"labels,omitempty"`
+	IPAM     *ComposeIPAM   `json:"ipam,omitempty"`
+}
+
+// ComposeIPAM holds the address configuration of a compose network. Only the
+// first config entry is used.
+type ComposeIPAM struct {
+	Config []struct {
+		Subnet  string `json:"subnet,omitempty"`
+		Gateway string `json:"gateway,omitempty"`
+	} `json:"config,omitempty"`
+}
+
+// ComposeVolume is a top level volume of a compose file.
+type ComposeVolume struct {
+	Name     string         `json:"name,omitempty"`
+	Driver   string         `json:"driver,omitempty"`
+	External bool           `json:"external,omitempty"`
+	Labels   composeMapping `json:"labels,omitempty"`
+}
+
+// composeCommand accepts a command as string or as list.
+type composeCommand []string
+
+func (c *composeCommand) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*c = splitCommand(s)
+		return nil
+	}
+	var l []string
+	if err := json.Unmarshal(b, &l); err != nil {
+		return fmt.Errorf("command must be a string or a list of strings")
+	}
+	*c = l
+	return nil
+}
+
+// splitCommand splits a command line at white spaces. Single and double
+// quotes group words.
+func splitCommand(s string) []string {
+	var (
+		res   []string
+		cur   strings.Builder
+		quote rune
+		word  bool
+	)
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			cur.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, word = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if word {
+				res = append(res, cur.String())
+				cur.Reset()
+				word = false
+			}
+		default:
+			cur.WriteRune(r)
+			word = true
+		}
+	}
+	if word {
+		res = append(res, cur.String())
+	}
+	return res
+}
+
+// composeMapping accepts a mapping or a list of "key=value" strings. Keys
+// without value are empty.
+type composeMapping map[string]string
+
+func (m *composeMapping) UnmarshalJSON(b []byte) error {
+	return unmarshalMapping(b, (*map[string]string)(m), func(string) (string, bool) { return "", true })
+}
+
+// composeEnvironment is a composeMapping whose keys without value take the
+// value of the environment variable, keys of unset variables are omitted.
+type composeEnvironment map[string]string
+
+func (m *composeEnvironment) UnmarshalJSON(b []byte) error {
+	return unmarshalMapping(b, (*map[string]string)(m), os.LookupEnv)
+}
+
+// unmarshalMapping decodes a mapping or a list of "key=value" strings into
+// m. The values of keys without value are looked up by bare.
+func unmarshalMapping(b []byte, m *map[string]string, bare func(string) (string, bool)) error {
+	raw := make(map[string]*string)
+	var l []string
+	if err := json.Unmarshal(b, &l); err == nil {
+		for _, kv := range l {
+			ss := strings.SplitN(kv, "=", 2)
+			if len(ss) == 1 {
+				raw[ss[0]] = nil
+			} else {
+				raw[ss[0]] = &ss[1]
+			}
+		}
+	} else if err := json.Unmarshal(b, &raw); err != nil {
+		return fmt.Errorf("expected a mapping or a list of key=value")
+	}
+	*m = make(map[string]string, len(raw))
+	for k, v := range raw {
+		if v != nil {
+			(*m)[k] = *v
+		} else if s, ok := bare(k); ok {
+			(*m)[k] = s
+		}
+	}
+	return nil
+}
+
+// composeServiceNetwork is the attachment of a service to a network.
+type composeServiceNetwork struct {
+	Aliases     []string `json:"aliases,omitempty"`
+	IPv4Address string   `json:"ipv4_address,omitempty"`
+}
+
+// composeNetworks accepts a list of network names or a mapping of network
+// names to attachment options.
+type composeNetworks map[string]*composeServiceNetwork
+
+func (n *composeNetworks) UnmarshalJSON(b []byte) error {
+	var l []string
+	if err := json.Unmarshal(b, &l); err == nil {
+		*n = make(composeNetworks, len(l))
+		for _, name := range l {
+			(*n)[name] = nil
+		}
+		return nil
+	}
+	var m map[string]*composeServiceNetwork
+	if err := json.Unmarshal(b, &m); err != nil {
+		return fmt.Errorf("networks must be a list or a mapping: %v", err)
+	}
+	*n = m
+	return nil
+}
+
+// composeDepends accepts a list of service names or a mapping of service names
+// to conditions.
+type composeDepends map[string]string
+
+func (d *composeDepends) UnmarshalJSON(b []byte) error {
+	var l []string
+	if err := json.Unmarshal(b, &l); err == nil {
+		*d = make(composeDepends, len(l))
+		for _, name := range l {
+			(*d)[name] = "service_started"
+		}
+		return nil
+	}
+	var m map[string]struct {
+		Condition string `json:"condition"`
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return fmt.Errorf("depends_on must be a list or a mapping: %v", err)
+	}
+	*d = make(composeDepends, len(m))
+	for name, v := range m {
+		(*d)[name] = v.Condition
+	}
+	return nil
+}
+
+// LoadComposeFile reads a compose file. Relative bind mounts are resolved
+// against the directory of the file.
+func LoadComposeFile(path string) (*Compose, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := LoadCompose(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if c.dir, err = filepath.Abs(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadCompose parses a compose file in YAML or JSON format. Environment
+// variables in values are interpolated like docker-compose does.
+func LoadCompose(data []byte) (*Compose, error) {
+	parse := parseYAML
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		parse = parseJSON
+	}
+	n, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := interpolateEnv(n); err != nil {
+		return nil, err
+	}
+	var c Compose
+	if err := decodeNode(n, &c); err != nil {
+		return nil, err
+	}
+	if len(c.Services) == 0 {
+		return nil, fmt.Errorf("compose: no services defined")
+	}
+	return &c, nil
+}
+
+// Spec converts the compose file into a spec for the given project. Like
+// docker-compose, resources are prefixed with the project name, services
+// without networks are attached to the network <project>_default and every
+// service is reachable by its name on its networks. The containers are
+// ordered by their dependencies.
+func (c *Compose) Spec(project string) (*Spec, error) {
+	spec := &Spec{Run: project}
+	prefix := project + "_"
+
+	networkName := func(name string) (string, error) {
+		if name == "default" && c.Networks["default"] == nil {
+			return prefix + "default", nil
+		}
+		nw, ok := c.Networks[name]
+		if !ok {
+			return "", fmt.Errorf("undefined network %s", name)
+		}
+		switch {
+		case nw != nil && nw.Name != "":
+			return nw.Name, nil
+		case nw != nil && nw.External:
+			return name, nil
+		}
+		return prefix + name, nil
+	}
+
+	for _, name := range sortedKeys(c.Networks) {
+		nw := c.Networks[name]
+		if nw != nil && nw.External {
+			continue
+		}
+		full, _ := networkName(name)
+		ns := NetworkSpec{Name: full}
+		if nw != nil {
+			ns.Driver = nw.Driver
+			ns.Internal = nw.Internal
+			ns.Labels = nw.Labels
+			if nw.IPAM != nil && len(nw.IPAM.Config) > 0 {
+				ns.Subnet = nw.IPAM.Config[0].Subnet
+				ns.Gateway = nw.IPAM.Config[0].Gateway
+			}
+		}
+		spec.Networks = append(spec.Networks, ns)
+	}
+
+	volumeName := func(name string) string {
+		if v := c.Volumes[name]; v != nil && v.Name != "" {
+			return v.Name
+		}
+		if v := c.Volumes[name]; v != nil && v.External {
+			return name
+		}
+		return prefix + name
+	}
+	for _, name := range sortedKeys(c.Volumes) {
+		v := c.Volumes[name]
+		if v != nil && v.External {
+			continue
+		}
+		vs := VolumeSpec{Name: volumeName(name)}
+		if v != nil {
+			vs.Driver = v.Driver
+			vs.Labels = v.Labels
+		}
+		spec.Volumes = append(spec.Volumes, vs)
+	}
+
+	order, err := c.serviceOrder()
+	if err != nil {
+		return nil, err
+	}
+	containerNames := make(map[string]string, len(order))
+	for _, name := range order {
+		containerNames[name] = c.Services[name].ContainerName
+		if containerNames[name] == "" {
+			containerNames[name] = prefix + name
+		}
+	}
+
+	usesDefault := false
+	for _, name := range order {
+		svc := c.Services[name]
+		cs := ContainerSpec{
+			Name:         containerNames[name],
+			Image:        svc.Image,
+			Cmd:          svc.Command,
+			Entrypoint:   svc.Entrypoint,
+			Env:          map[string]string(svc.Environment),
+			Labels:       svc.Labels,
+			ExposedPorts: svc.Expose,
+			Ports:        svc.Ports,
+			Privileged:   svc.Privileged,
+		}
+		if hc := svc.Healthcheck; hc != nil {
+			cs.Healthcheck = &Healthcheck{Test: hc.Test, Interval: hc.Interval, Timeout: hc.Timeout,
+				StartPeriod: hc.StartPeriod, Retries: hc.Retries}
+			if hc.Disable {
+				cs.Healthcheck = &Healthcheck{Test: []string{"NONE"}}
+			}
+		}
+		for _, dep := range sortedKeys(svc.DependsOn) {
+			if svc.DependsOn[dep] == "service_healthy" {
+				cs.DependsOnHealthy = append(cs.DependsOnHealthy, containerNames[dep])
+				continue
+			}
+			cs.DependsOn = append(cs.DependsOn, containerNames[dep])
+		}
+
+		for _, v := range svc.Volumes {
+			m, err := c.mount(v, volumeName)
+			if err != nil {
+				return nil, fmt.Errorf("service %s: %v", name, err)
+			}
+			cs.Mounts = append(cs.Mounts, m)
+		}
+
+		networks := svc.Networks
+		if len(networks) == 0 {
+			networks = composeNetworks{"default": nil}
+		}
+		for _, nwName := range sortedKeys(networks) {
+			full, err := networkName(nwName)
+			if err != nil {
+				return nil, fmt.Errorf("service %s: %v", name, err)
+			}
+			if full == prefix+"default" && c.Networks["default"] == nil {
+				usesDefault = true
+			}
+			a := NetworkAttachment{Network: full, Aliases: []string{name}}
+			if opts := networks[nwName]; opts != nil {
+				a.Aliases = append(a.Aliases, opts.Aliases...)
+				a.IPv4Address = opts.IPv4Address
+			}
+			cs.Networks = append(cs.Networks, a)
+		}
+		spec.Containers = append(spec.Containers, cs)
+	}
+	if usesDefault {
+		spec.Networks = append(spec.Networks, NetworkSpec{Name: prefix + "default"})
+	}
+
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// mount converts the short volume syntax of compose into a spec mount.
+func (c *Compose) mount(v string, volumeName func(string) string) (string, error) {
+	ss := strings.Split(v, ":")
+	if len(ss) < 2 {
+		return "", fmt.Errorf("anonymous volume %q is not supported", v)
+	}
+	src := ss[0]
+	switch {
+	case filepath.IsAbs(src):
+	case strings.HasPrefix(src, "."):
+		if c.dir == "" {
+			return "", fmt.Errorf("relative bind mount %q needs the compose file location", v)
+		}
+		src = filepath.Join(c.dir, src)
+	default:
+		if _, ok := c.Volumes[src]; !ok {
+			return "", fmt.Errorf("undefined volume %s", src)
+		}
+		src = volumeName(src)
+	}
+	ss[0] = src
+	return strings.Join(ss, ":"), nil
+}
+
+// serviceOrder returns the service names ordered such that every service
+// follows its dependencies.
+func (c *Compose) serviceOrder() ([]string, error) {
+	var (
+		order []string
+		state = make(map[string]int) // 1: visiting, 2: done
+		visit func(name string, path []string) error
+	)
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("compose: dependency cycle %s", strings.Join(append(path, name), " -> "))
+		case 2:
+			return nil
+		}
+		svc, ok := c.Services[name]
+		if !ok {
+			return fmt.Errorf("compose: service %s depends on undefined service %s", path[len(path)-1], name)
+		}
+		state[name] = 1
+		for _, dep := range sortedKeys(svc.DependsOn) {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range sortedKeys(c.Services) {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// ComposeUp creates or updates the project described by the compose file.
+func (c *Client) ComposeUp(ctx context.Context, project string, compose *Compose, opts ...ApplyOption) ([]Operation, error) {
+	spec, err := compose.Spec(project)
+	if err != nil {
+		return nil, err
+	}
+	return c.Apply(ctx, spec, opts...)
+}
+
+// ComposeDown removes the containers and networks of the project. Volumes are
+// kept, like docker-compose down does without -v.
+func (c *Client) ComposeDown(ctx context.Context, project string, opts ...ApplyOption) ([]Operation, error) {
+	return c.Apply(ctx, &Spec{Run: project}, opts...)
+}
+
+// sortedKeys returns the keys of a map with string keys in sorted order.
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
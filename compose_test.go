@@ -0,0 +1,156 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ComposeSpec(t *testing.T) {
+	c, err := LoadComposeFile(testfileLocation + "docker-compose.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := c.Spec("sim")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, _ := filepath.Abs(testfileLocation)
+	expect := &Spec{
+		Run: "sim",
+		Networks: []NetworkSpec{
+			{Name: "sim_back", Internal: true},
+			{Name: "sim_front"},
+			{Name: "sim_default"},
+		},
+		Volumes: []VolumeSpec{{Name: "sim_db-data"}},
+		Containers: []ContainerSpec{
+			{
+				Name:  "sim_db",
+				Image: "postgres:9.4",
+				Env:   map[string]string{"POSTGRES_PASSWORD": "secret"},
+				Mounts: []string{
+					"sim_db-data:/var/lib/postgresql/data",
+					filepath.Join(dir, "init") + ":/docker-entrypoint-initdb.d:ro",
+				},
+				Networks: []NetworkAttachment{{Network: "sim_back", Aliases: []string{"db"}}},
+				Healthcheck: &Healthcheck{
+					Test:     []string{"CMD-SHELL", "pg_isready -U postgres"},
+					Interval: "5s",
+					Retries:  10,
+				},
+			},
+			{
+				Name:             "sim_probe",
+				Image:            "busybox",
+				Cmd:              []string{"sh", "-c", "sleep 3600"},
+				Networks:         []NetworkAttachment{{Network: "sim_default", Aliases: []string{"probe"}}},
+				DependsOnHealthy: []string{"sim_db"},
+			},
+			{
+				Name:  "sim_web",
+				Image: "nginxdemos/hello:plain-text",
+				Ports: []string{"8080:80"},
+				Networks: []NetworkAttachment{
+					{Network: "sim_back", Aliases: []string{"web"}},
+					{Network: "sim_front", Aliases: []string{"web", "www"}},
+				},
+				DependsOn: []string{"sim_db"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(spec, expect) {
+		t.Errorf("got: %+v, want: %+v", spec, expect)
+	}
+
+	body, err := spec.Containers[0].createBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hc := &healthConfig{Test: []string{"CMD-SHELL", "pg_isready -U postgres"}, Interval: int64(5 * time.Second), Retries: 10}
+	if !reflect.DeepEqual(body.Healthcheck, hc) {
+		t.Errorf("got healthcheck: %+v, want: %+v", body.Healthcheck, hc)
+	}
+}
+
+func Test_ComposeEnvironment(t *testing.T) {
+	for k, v := range map[string]string{"SIM_FROM_HOST": "host", "SIM_UNSET": ""} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+	os.Unsetenv("SIM_UNSET")
+
+	tt := []struct {
+		name    string
+		compose string
+	}{
+		{name: "list", compose: "services:\n  a:\n    image: x\n    environment: [SIM_FROM_HOST, SIM_UNSET, MODE=sim]\n"},
+		{name: "mapping", compose: "services:\n  a:\n    image: x\n    environment:\n      SIM_FROM_HOST:\n      SIM_UNSET:\n      MODE: sim\n"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := LoadCompose([]byte(tc.compose))
+			if err != nil {
+				t.Fatal(err)
+			}
+			spec, err := c.Spec("sim")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// variables without value are taken from the host, unset ones
+			// are omitted
+			want := map[string]string{"SIM_FROM_HOST": "host", "MODE": "sim"}
+			if got := spec.Containers[0].Env; !reflect.DeepEqual(got, want) {
+				t.Errorf("got: %v, want: %v", got, want)
+			}
+		})
+	}
+}
+
+func Test_ComposeErrors(t *testing.T) {
+	tt := []struct {
+		name    string
+		compose string
+		expect  string
+	}{
+		{
+			name:    "cycle",
+			compose: "services:\n  a:\n    image: x\n    depends_on: [b]\n  b:\n    image: x\n    depends_on: [a]\n",
+			expect:  "dependency cycle a -> b -> a",
+		},
+		{
+			name:    "unknown dependency",
+			compose: "services:\n  a:\n    image: x\n    depends_on: [b]\n",
+			expect:  "depends on undefined service b",
+		},
+		{
+			name:    "unsupported field",
+			compose: "services:\n  a:\n    image: x\n    build: .\n",
+			expect:  "line 4: services.a.build: unknown field",
+		},
+		{
+			name:    "undefined volume",
+			compose: "services:\n  a:\n    image: x\n    volumes: [data:/data]\n",
+			expect:  "undefined volume data",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := LoadCompose([]byte(tc.compose))
+			if err == nil {
+				_, err = c.Spec("sim")
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tc.expect) {
+				t.Errorf("got: %s, want: %s", err, tc.expect)
+			}
+		})
+	}
+}
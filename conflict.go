@@ -0,0 +1,150 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// ConflictRetryOptions configures the retry of conflicts, see
+// SetConflictRetry.
+type ConflictRetryOptions struct {
+	// Retries is the number of retries of a conflicting request. Defaults
+	// to 5.
+	Retries int
+	// Backoff is the wait time before the first retry, it is doubled for
+	// every further retry. Defaults to the policy of the client, see
+	// SetBackoff, or 200ms.
+	Backoff time.Duration
+	// Messages are the parts of error messages which mark a conflict as
+	// transient. Defaults to DefaultTransientConflicts.
+	Messages []string
+}
+
+// DefaultTransientConflicts are the messages of conflicts which resolve
+// themselves while a teardown finishes: a removal which is in progress, a
+// name still used by a container being removed and a network whose
+// containers are being removed.
+var DefaultTransientConflicts = []string{
+	"already in progress",
+	"is already in use by container",
+	"has active endpoints",
+}
+
+const (
+	defaultConflictRetries = 5
+	defaultConflictBackoff = 200 * time.Millisecond
+)
+
+// SetConflictRetry enables the retry of requests the daemon answers with a
+// transient conflict (409), nil disables it. These are routine when
+// containers are recreated quickly after their removal. Conflicts which
+// are not transient, e.g. a name of a running container, are retried as
+// well if their message matches, they fail after the retries. The retries
+// count against the timeout of the request, the conflict is returned once
+// the next wait would exceed it. SetConflictRetry has to be called before
+// the client is used.
+func (c *Client) SetConflictRetry(opts *ConflictRetryOptions) {
+	if opts == nil {
+		c.conflicts = nil
+		c.chainTransport()
+		return
+	}
+	r := &conflictRetrier{retries: opts.Retries, messages: opts.Messages}
+	if r.retries <= 0 {
+		r.retries = defaultConflictRetries
+	}
+	r.backoff = c.backoffOr(ExponentialBackoff(defaultConflictBackoff, 0, 0))
+	if opts.Backoff > 0 {
+		r.backoff = ExponentialBackoff(opts.Backoff, 0, 0)
+	}
+	if len(r.messages) == 0 {
+		r.messages = DefaultTransientConflicts
+	}
+	c.conflicts = r
+	c.chainTransport()
+}
+
+// chainTransport wraps the transport of the client by the enabled round
+// trippers: conflicts and requests which failed by a restart of the daemon
+// are retried outside of the breaker, so every retry is seen by it. The
+// in-flight tracker sees a request with its retries once.
+func (c *Client) chainTransport() {
+	var rt http.RoundTripper = c.transport
+	if c.breaker != nil {
+		c.breaker.next = rt
+		rt = c.breaker
+	}
+	if c.restarts != nil {
+		c.restarts.next = rt
+		rt = c.restarts
+	}
+	if c.conflicts != nil {
+		c.conflicts.next = rt
+		rt = c.conflicts
+	}
+	c.inflight.next = rt
+	c.http.Transport = c.inflight
+}
+
+// conflictRetrier is a RoundTripper which retries transient conflicts.
+type conflictRetrier struct {
+	next     http.RoundTripper
+	retries  int
+	backoff  Backoff
+	messages []string
+}
+
+func (r *conflictRetrier) RoundTrip(req *http.Request) (*http.Response, error) {
+	for i := 0; ; i++ {
+		res, err := r.next.RoundTrip(req)
+		if err != nil || res.StatusCode != http.StatusConflict || i == r.retries {
+			return res, err
+		}
+		// requests with a body can only be retried if it can be recreated
+		if req.Body != nil && req.GetBody == nil {
+			return res, nil
+		}
+		body, err := ioutil.ReadAll(io.LimitReader(res.Body, 64<<10))
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		res.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if !r.transient(body) {
+			return res, nil
+		}
+		// the retries have to fit into the timeout of the request
+		wait := r.backoff.Delay(i)
+		if deadline, ok := req.Context().Deadline(); ok && time.Until(deadline) < wait {
+			return res, nil
+		}
+
+		if err := sleepBackoff(req.Context(), ConstantBackoff(wait), 0); err != nil {
+			return nil, err
+		}
+		if req.GetBody != nil {
+			b, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = b
+		}
+	}
+}
+
+// transient reports whether the error body of a conflict matches one of
+// the messages.
+func (r *conflictRetrier) transient(body []byte) bool {
+	var msg struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		msg.Message = string(body)
+	}
+	return containsAny(msg.Message, r.messages)
+}
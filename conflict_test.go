@@ -0,0 +1,72 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_ConflictRetry(t *testing.T) {
+	removing := `{"message": "removal of container c1 is already in progress"}`
+	srv.route(map[string]mockResponse{
+		"DELETE /containers/c1": {StatusCode: http.StatusConflict, Body: removing,
+			Next: &mockResponse{StatusCode: http.StatusConflict, Body: removing,
+				Next: &mockResponse{StatusCode: http.StatusNoContent}}},
+		"POST /containers/create": {StatusCode: http.StatusConflict,
+			Body: `{"message": "Conflict. The container name \"/plc\" is already in use by container \"c1\"."}`,
+			Next: &mockResponse{StatusCode: http.StatusCreated, Body: `{"Id": "c2"}`}},
+		"POST /containers/c3/kill": {StatusCode: http.StatusConflict, Body: `{"message": "container c3 is not running"}`},
+	})
+	defer srv.route(nil)
+
+	c := NewClient(sockPath)
+	c.SetBreaker(&BreakerOptions{})
+	c.SetConflictRetry(&ConflictRetryOptions{Backoff: time.Millisecond})
+
+	if err := c.DeleteContainer("c1"); err != nil {
+		t.Error(err)
+	}
+	res, err := c.createContainer(context.Background(), "plc", &containerCreate{Image: "sim/plc"})
+	if err != nil || res.ID != "c2" {
+		t.Errorf("expected c2, got %+v, %v", res, err)
+	}
+	// other conflicts are not retried
+	if err := c.doJSON(context.Background(), http.MethodPost, "containers/c3/kill", nil, nil, nil, http.StatusNoContent); err == nil {
+		t.Error("expected conflict")
+	}
+	want := []string{"DELETE /containers/c1", "DELETE /containers/c1", "DELETE /containers/c1",
+		"POST /containers/create", "POST /containers/create", "POST /containers/c3/kill"}
+	if got := srv.Requests(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected requests %v, got %v", want, got)
+	}
+
+	// the retries are limited
+	srv.route(map[string]mockResponse{
+		"DELETE /containers/c1": {StatusCode: http.StatusConflict, Body: removing},
+	})
+	c.SetConflictRetry(&ConflictRetryOptions{Retries: 2, Backoff: time.Millisecond})
+	if err := c.DeleteContainer("c1"); err == nil {
+		t.Error("expected conflict")
+	}
+	if n := len(srv.Requests()); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+
+	// the retries end before the timeout of the request
+	srv.route(map[string]mockResponse{
+		"DELETE /containers/c1": {StatusCode: http.StatusConflict, Body: removing},
+	})
+	c.SetTimeouts(Timeouts{Fast: time.Second, Medium: 100 * time.Millisecond, Long: time.Second})
+	c.SetConflictRetry(&ConflictRetryOptions{Backoff: 40 * time.Millisecond})
+	err = c.removeContainer(context.Background(), "c1", false)
+	var e *APIError
+	if !errors.As(err, &e) || e.StatusCode != http.StatusConflict {
+		t.Errorf("expected conflict, got %v", err)
+	}
+	if n := len(srv.Requests()); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
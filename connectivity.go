@@ -0,0 +1,168 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ErrUnreachable is returned by TestConnectivity if the address can not be
+// connected to.
+var ErrUnreachable = errors.New("unreachable")
+
+// ErrNotResolved is returned by ResolveAlias if the name can not be
+// resolved.
+var ErrNotResolved = errors.New("not resolved")
+
+// ErrNoProbe is returned if the container lacks the tools to run a check,
+// e.g. images without shell.
+var ErrNoProbe = errors.New("no probe available in container")
+
+// connectProbe tries to open a TCP connection to the host $1 and port $2
+// with the tools commonly found in images: bash with /dev/tcp or nc. It
+// exits with 127 if neither is available.
+const connectProbe = `t=""
+command -v timeout >/dev/null 2>&1 && t="timeout 5"
+if command -v bash >/dev/null 2>&1; then
+	exec $t bash -c 'exec 3<>"/dev/tcp/$0/$1"' "$1" "$2"
+fi
+if command -v nc >/dev/null 2>&1; then
+	exec $t nc -z -w 5 "$1" "$2"
+fi
+echo "neither bash nor nc found" >&2
+exit 127`
+
+// resolveProbe resolves the name $1 by getent or nslookup, whatever the
+// image has. The first line of the output names the tool.
+const resolveProbe = `if command -v getent >/dev/null 2>&1; then
+	echo getent
+	exec getent hosts "$1"
+fi
+if command -v nslookup >/dev/null 2>&1; then
+	echo nslookup
+	exec nslookup "$1"
+fi
+echo "neither getent nor nslookup found" >&2
+exit 127`
+
+// TestConnectivity verifies that the container can open a TCP connection to
+// the address, e.g. "db:5432", to check the wiring of a topology. The check
+// runs in the container, so names are resolved by its DNS. An unreachable
+// address results in ErrUnreachable, a container without shell, bash and
+// nc in ErrNoProbe.
+func (c *Client) TestConnectivity(ctx context.Context, fromID, toAddr string) error {
+	host, port, err := net.SplitHostPort(toAddr)
+	if err != nil {
+		return fmt.Errorf("test connectivity: %w", err)
+	}
+	res, err := c.Exec(ctx, fromID, []string{"sh", "-c", connectProbe, "probe", host, port})
+	if err != nil {
+		return fmt.Errorf("test connectivity from %s to %s: %w", fromID, toAddr, err)
+	}
+	return probeResult(res, fromID, toAddr, ErrUnreachable)
+}
+
+// probeResult converts the exit code of a probe into an error. 126 and 127
+// are the codes of shells for commands which can not be executed or found.
+func probeResult(res *CaptureResult, from, to string, failed error) error {
+	out := strings.TrimSpace(string(res.Stderr))
+	switch res.ExitCode {
+	case 0:
+		return nil
+	case 126, 127:
+		return fmt.Errorf("%s: %w: %s", from, ErrNoProbe, out)
+	}
+	if out == "" {
+		return fmt.Errorf("%s to %s: %w", from, to, failed)
+	}
+	return fmt.Errorf("%s to %s: %w: %s", from, to, failed, out)
+}
+
+// ResolveAlias resolves the name, e.g. a network alias, in the container
+// and returns its addresses. Names which can not be resolved result in
+// ErrNotResolved, a container without shell, getent and nslookup in
+// ErrNoProbe.
+func (c *Client) ResolveAlias(ctx context.Context, id, alias string) ([]string, error) {
+	res, err := c.Exec(ctx, id, []string{"sh", "-c", resolveProbe, "probe", alias})
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s in %s: %w", alias, id, err)
+	}
+	if err := probeResult(res, id, alias, ErrNotResolved); err != nil {
+		return nil, fmt.Errorf("resolve: %w", err)
+	}
+	addrs := parseResolved(string(res.Stdout))
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("resolve: %s to %s: %w", id, alias, ErrNotResolved)
+	}
+	return addrs, nil
+}
+
+// VerifyAlias checks that the alias resolves in the container fromID to an
+// address of the container toID, i.e. that the alias was configured on a
+// network both are attached to.
+func (c *Client) VerifyAlias(ctx context.Context, fromID, alias, toID string) error {
+	addrs, err := c.ResolveAlias(ctx, fromID, alias)
+	if err != nil {
+		return err
+	}
+	cj, err := c.inspectContainer(ctx, toID)
+	if err != nil {
+		return fmt.Errorf("inspect container %s: %w", toID, err)
+	}
+	for _, ep := range cj.NetworkSettings.Networks {
+		for _, a := range addrs {
+			if a == ep.IPAddress || a == ep.GlobalIPv6Address {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("alias %s resolves to %s in %s, not to %s",
+		alias, strings.Join(addrs, ", "), fromID, strings.TrimPrefix(cj.Name, "/"))
+}
+
+// parseResolved extracts the addresses from the output of resolveProbe.
+// getent prints "<address> <names>" per address. nslookup prints the server
+// first, the addresses follow the line with the name, either as
+// "Address: <address>" or as "Address 1: <address> <name>".
+func parseResolved(out string) []string {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	var addrs []string
+	add := func(a string) {
+		if net.ParseIP(a) == nil {
+			return
+		}
+		for _, b := range addrs {
+			if a == b {
+				return
+			}
+		}
+		addrs = append(addrs, a)
+	}
+	switch lines[0] {
+	case "getent":
+		for _, l := range lines[1:] {
+			if fs := strings.Fields(l); len(fs) > 0 {
+				add(fs[0])
+			}
+		}
+	case "nslookup":
+		named := false
+		for _, l := range lines[1:] {
+			l = strings.TrimSpace(l)
+			if strings.HasPrefix(l, "Name:") {
+				named = true
+			}
+			if !named || !strings.HasPrefix(l, "Address") {
+				continue
+			}
+			if i := strings.Index(l, ":"); i >= 0 {
+				if fs := strings.Fields(l[i+1:]); len(fs) > 0 {
+					add(fs[0])
+				}
+			}
+		}
+	}
+	return addrs
+}
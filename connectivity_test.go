@@ -0,0 +1,76 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func Test_TestConnectivity(t *testing.T) {
+	for _, tc := range []struct {
+		exitCode int
+		stderr   string
+		expect   error
+	}{
+		{0, "", nil},
+		{1, "Connection refused", ErrUnreachable},
+		{124, "", ErrUnreachable},
+		{127, "neither bash nor nc found", ErrNoProbe},
+	} {
+		srv.route(map[string]mockResponse{
+			"POST /containers/plc/exec": {StatusCode: http.StatusCreated, Body: `{"Id": "e1"}`},
+			"POST /exec/e1/start":       {Body: frame(streamStderr, tc.stderr)},
+			"GET /exec/e1/json":         {Body: fmt.Sprintf(`{"ID": "e1", "ExitCode": %d}`, tc.exitCode)},
+		})
+		err := client.TestConnectivity(context.Background(), "plc", "meter:502")
+		if tc.expect == nil && err != nil || !errors.Is(err, tc.expect) {
+			t.Errorf("exit code %d: expected %v, got %v", tc.exitCode, tc.expect, err)
+		}
+	}
+	srv.route(nil)
+
+	if err := client.TestConnectivity(context.Background(), "plc", "meter"); err == nil {
+		t.Error("expected error for address without port")
+	}
+}
+
+func Test_VerifyAlias(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"POST /containers/plc/exec": {StatusCode: http.StatusCreated, Body: `{"Id": "e1"}`},
+		"POST /exec/e1/start":       {Body: frame(streamStdout, "getent\n172.18.0.3      meter\n")},
+		"GET /exec/e1/json":         {Body: `{"ID": "e1", "ExitCode": 0}`},
+		"GET /containers/meter/json": {Body: `{"Id": "c2", "Name": "/meter", "State": {"Status": "running"},
+			"NetworkSettings": {"Networks": {"sim": {"IPAddress": "172.18.0.3"}}}}`},
+		"GET /containers/gateway/json": {Body: `{"Id": "c3", "Name": "/gateway", "State": {"Status": "running"},
+			"NetworkSettings": {"Networks": {"sim": {"IPAddress": "172.18.0.4"}}}}`},
+	})
+	defer srv.route(nil)
+
+	if err := client.VerifyAlias(context.Background(), "plc", "meter", "meter"); err != nil {
+		t.Error(err)
+	}
+	if err := client.VerifyAlias(context.Background(), "plc", "meter", "gateway"); err == nil {
+		t.Error("expected error for alias of other container")
+	}
+}
+
+func Test_parseResolved(t *testing.T) {
+	for _, tc := range []struct {
+		out    string
+		expect []string
+	}{
+		{"getent\n172.18.0.3      meter\nfd00::3         meter\n", []string{"172.18.0.3", "fd00::3"}},
+		{"nslookup\nServer:\t\t127.0.0.11\nAddress:\t127.0.0.11:53\n\nNon-authoritative answer:\n" +
+			"Name:\tmeter\nAddress: 172.18.0.3\n", []string{"172.18.0.3"}},
+		{"nslookup\nServer:    127.0.0.11\nAddress 1: 127.0.0.11\n\nName:      meter\n" +
+			"Address 1: 172.18.0.3 meter.sim\n", []string{"172.18.0.3"}},
+		{"getent\n", nil},
+	} {
+		if got := parseResolved(tc.out); !reflect.DeepEqual(got, tc.expect) {
+			t.Errorf("%q: expected %v, got %v", tc.out, tc.expect, got)
+		}
+	}
+}
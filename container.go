@@ -0,0 +1,165 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Container is a handle of a container managed by the client. It is
+// returned by Create, Run and Adopt and saves passing the ID to every call.
+type Container struct {
+	ID string
+	// Name is the name of the container without the leading slash. It is
+	// empty if the daemon chose the name.
+	Name string
+	c    *Client
+}
+
+// Create creates the container and connects it to its networks, without
+// starting it. If connecting fails, the container is removed again.
+func (c *Client) Create(ctx context.Context, spec ContainerSpec) (*Container, error) {
+	body, err := spec.createBody()
+	if err != nil {
+		return nil, fmt.Errorf("create container %s: %w", spec.Name, err)
+	}
+	res, err := c.createContainer(ctx, spec.Name, body)
+	if err != nil {
+		return nil, fmt.Errorf("create container %s: %w", spec.Name, err)
+	}
+	for i, a := range spec.Networks {
+		if i == 0 {
+			continue
+		}
+		if err := c.connectNetwork(ctx, a.Network, res.ID, a.endpointConfig()); err != nil {
+			// the context may be done already, the cleanup must not depend on it
+			c.removeContainer(context.Background(), res.ID, true)
+			return nil, fmt.Errorf("connect container %s to %s: %w", spec.Name, a.Network, err)
+		}
+	}
+	return &Container{ID: res.ID, Name: spec.Name, c: c}, nil
+}
+
+// Start starts the container. A running container is not treated as error.
+func (ct *Container) Start(ctx context.Context) error {
+	if err := ct.c.startContainer(ctx, ct.ID); err != nil {
+		return fmt.Errorf("start container %s: %w", ct, err)
+	}
+	return nil
+}
+
+// Stop stops the container, see StopTimeout for the grace period. A stopped
+// container is not treated as error.
+func (ct *Container) Stop(ctx context.Context, opts ...StopOption) error {
+	var o stopOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.timeout == nil {
+		if err := ct.c.stopContainer(ctx, ct.ID); err != nil {
+			return fmt.Errorf("stop container %s: %w", ct, err)
+		}
+		return nil
+	}
+	r, err := ct.c.stopGrace(ctx, ct.ID, *o.timeout)
+	if err == nil {
+		defer drainClose(r.Body)
+		err = checkResponse(r, http.StatusNoContent, http.StatusNotModified)
+	}
+	if err != nil {
+		return fmt.Errorf("stop container %s: %w", ct, err)
+	}
+	return nil
+}
+
+// Logs writes the output of the container so far, like Client.Logs.
+func (ct *Container) Logs(ctx context.Context, stdout, stderr io.Writer) error {
+	return ct.c.Logs(ctx, ct.ID, stdout, stderr)
+}
+
+// Exec runs the command in the container, like Client.Exec.
+func (ct *Container) Exec(ctx context.Context, cmd []string) (*CaptureResult, error) {
+	return ct.c.Exec(ctx, ct.ID, cmd)
+}
+
+// IP returns the addresses of the container on the network, like
+// Client.ContainerIP.
+func (ct *Container) IP(ctx context.Context, network string) (*ContainerAddress, error) {
+	return ct.c.ContainerIP(ctx, ct.ID, network)
+}
+
+// Remove removes the container, a running one is killed first. A container
+// which is gone already is not treated as error.
+func (ct *Container) Remove(ctx context.Context) error {
+	if err := ct.c.removeContainer(ctx, ct.ID, true); err != nil && !isNotFound(err) {
+		return fmt.Errorf("remove container %s: %w", ct, err)
+	}
+	return nil
+}
+
+// String returns the name of the container or its ID, if it has no name.
+func (ct *Container) String() string {
+	if ct.Name != "" {
+		return ct.Name
+	}
+	return ct.ID
+}
+
+// AdoptSpec describes what Adopt expects of a container. Empty fields are
+// not checked.
+type AdoptSpec struct {
+	// Image is the reference the container was created from, e.g.
+	// postgres:12. It also matches if the container was created from
+	// another reference of the same local image.
+	Image string
+	// Labels must be carried by the container. An empty value matches
+	// every value of the label.
+	Labels Selector
+}
+
+// Adopt validates a container created by other tools, e.g. compose or the
+// CLI, against the spec and returns a handle of it, which brings it under
+// the lifecycle management of the client. All mismatches are reported in
+// the returned error.
+func (c *Client) Adopt(ctx context.Context, nameOrID string, spec AdoptSpec) (*Container, error) {
+	cj, err := c.inspectContainer(ctx, nameOrID)
+	if err != nil {
+		return nil, fmt.Errorf("adopt %s: %w", nameOrID, err)
+	}
+
+	var mismatches []string
+	if spec.Image != "" && !c.sameImage(ctx, cj, spec.Image) {
+		mismatches = append(mismatches, fmt.Sprintf("image is %s, want %s", cj.Config.Image, spec.Image))
+	}
+	keys := make([]string, 0, len(spec.Labels))
+	for k := range spec.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		got, ok := cj.Config.Labels[k]
+		switch want := spec.Labels[k]; {
+		case !ok:
+			mismatches = append(mismatches, fmt.Sprintf("label %s is missing", k))
+		case want != "" && got != want:
+			mismatches = append(mismatches, fmt.Sprintf("label %s is %q, want %q", k, got, want))
+		}
+	}
+	if len(mismatches) > 0 {
+		return nil, fmt.Errorf("adopt %s: %s", nameOrID, strings.Join(mismatches, "; "))
+	}
+	return &Container{ID: cj.ID, Name: strings.TrimPrefix(cj.Name, "/"), c: c}, nil
+}
+
+// sameImage reports whether the container was created from the image
+// reference, either by name or by the ID the reference resolves to.
+func (c *Client) sameImage(ctx context.Context, cj *containerJSON, ref string) bool {
+	if parseReference(cj.Config.Image).String() == parseReference(ref).String() {
+		return true
+	}
+	img, err := c.inspectImage(ctx, ref)
+	return err == nil && img.ID == cj.Image
+}
@@ -0,0 +1,120 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_Adopt(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /containers/db/json": {Body: `{"Id": "c1", "Name": "/sim_db_1", "Image": "sha256:abc",
+			"Config": {"Image": "postgres:12", "Labels": {"com.docker.compose.project": "sim", "role": "db"}}}`},
+		"GET /images/docker.io/library/postgres:12.4/json": {Body: `{"Id": "sha256:abc"}`},
+		"GET /images/postgres:13/json":                     {Body: `{"Id": "sha256:def"}`},
+	})
+	defer srv.route(nil)
+
+	tt := []struct {
+		name    string
+		spec    AdoptSpec
+		wantErr string
+	}{
+		{
+			name: "matching",
+			spec: AdoptSpec{Image: "docker.io/library/postgres:12", Labels: Selector{"com.docker.compose.project": "sim", "role": ""}},
+		},
+		{
+			name: "same image ID",
+			spec: AdoptSpec{Image: "docker.io/library/postgres:12.4"},
+		},
+		{
+			name:    "mismatches",
+			spec:    AdoptSpec{Image: "postgres:13", Labels: Selector{"role": "cache", "owner": ""}},
+			wantErr: `adopt db: image is postgres:12, want postgres:13; label owner is missing; label role is "db", want "cache"`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ct, err := client.Adopt(context.Background(), "db", tc.spec)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("got error: %v, want: %s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := (&Container{ID: "c1", Name: "sim_db_1", c: client}); !reflect.DeepEqual(ct, want) {
+				t.Errorf("got: %+v, want: %+v", ct, want)
+			}
+		})
+	}
+
+	srv.route(map[string]mockResponse{})
+	if _, err := client.Adopt(context.Background(), "db", AdoptSpec{}); !isNotFound(err) {
+		t.Errorf("got error: %v, want not found", err)
+	}
+}
+
+func Test_Container(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"POST /containers/create?name=plc": {StatusCode: http.StatusCreated, Body: `{"Id": "c1"}`},
+		"POST /networks/field/connect":     {},
+		"POST /containers/c1/start":        {StatusCode: http.StatusNoContent},
+		"GET /containers/c1/json":          {Body: `{"Id": "c1", "Name": "/plc", "NetworkSettings": {"Networks": {"field": {"IPAddress": "10.0.0.2"}}}}`},
+		"GET /containers/c1/logs":          {Body: frame(1, "ready\n")},
+		"POST /containers/c1/stop?t=2":     {StatusCode: http.StatusNotModified},
+		"DELETE /containers/c1?force=1":    {StatusCode: http.StatusNotFound, Body: `{"message": "No such container: c1"}`},
+	})
+	defer srv.route(nil)
+
+	ctx := context.Background()
+	ct, err := client.Create(ctx, ContainerSpec{Name: "plc", Image: "sim/plc",
+		Networks: []NetworkAttachment{{Network: "bridge"}, {Network: "field"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ct.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	addr, err := ct.IP(ctx, "field")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr.IPv4 != "10.0.0.2" {
+		t.Errorf("got address %s, want: 10.0.0.2", addr.IPv4)
+	}
+	var stdout bytes.Buffer
+	if err := ct.Logs(ctx, &stdout, nil); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "ready\n" {
+		t.Errorf("got logs %q", stdout.String())
+	}
+	if err := ct.Stop(ctx, StopTimeout(2*time.Second)); err != nil {
+		t.Error(err)
+	}
+	if err := ct.Remove(ctx); err != nil {
+		t.Error(err)
+	}
+
+	expect := []string{
+		"POST /containers/create",
+		"POST /networks/field/connect",
+		"POST /containers/c1/start",
+		"GET /containers/c1/json",
+		"GET /containers/c1/json",
+		"GET /containers/c1/logs",
+		"POST /containers/c1/stop",
+		"DELETE /containers/c1",
+	}
+	if got := srv.Requests(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("got: %v, want: %v", got, expect)
+	}
+}
@@ -0,0 +1,346 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Media types of the manifests CopyImage copies.
+const (
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex       = "application/vnd.oci.image.index.v1+json"
+)
+
+var manifestAccept = strings.Join([]string{
+	mediaTypeOCIIndex, mediaTypeDockerList, mediaTypeOCIManifest, mediaTypeDockerManifest,
+}, ", ")
+
+// descriptor references the content of a manifest.
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// manifest holds the references of an image manifest or an index of
+// manifests for multiple platforms.
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Config    *descriptor  `json:"config"`
+	Layers    []descriptor `json:"layers"`
+	Manifests []descriptor `json:"manifests"`
+}
+
+// CopyImage copies the image src to dst, e.g. from a public registry into
+// the registry of a lab. The registries are talked to directly: blobs which
+// already exist in the destination are skipped, blobs of the same registry
+// are mounted instead of copied and all other content is streamed from
+// registry to registry without touching the disk. Images for multiple
+// platforms are copied completely. If a registry can not be reached from
+// here, the image is copied by the daemon instead: it is pulled, tagged and
+// pushed. auth provides the credentials of both registries, it may be nil.
+// Registries on loopback addresses are talked to by plain HTTP, like the
+// daemon does.
+func (c *Client) CopyImage(ctx context.Context, src, dst string, auth AuthProvider) error {
+	rc := &registryClient{http: &http.Client{Transport: http.DefaultTransport}, auth: auth}
+	err := rc.copyImage(ctx, parseReference(src), parseReference(dst))
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		err = c.copyImageByDaemon(ctx, src, dst, auth)
+	}
+	if err != nil {
+		return fmt.Errorf("copy image %s to %s: %w", src, dst, err)
+	}
+	return nil
+}
+
+// copyImageByDaemon pulls src, tags it as dst and pushes dst.
+func (c *Client) copyImageByDaemon(ctx context.Context, src, dst string, auth AuthProvider) error {
+	if err := c.PullImage(ctx, src); err != nil {
+		return err
+	}
+	repo, tag := repoTag(dst)
+	if tag == "" {
+		tag = "latest"
+	}
+	if err := c.tagImage(ctx, src, repo, tag); err != nil {
+		return err
+	}
+	header := http.Header{}
+	if auth != nil {
+		a, err := authFor(ctx, auth, dst, "pull,push")
+		if err != nil {
+			return err
+		}
+		if a != nil {
+			header.Set("X-Registry-Auth", a.header())
+		}
+	}
+	// the daemon requires the header even for anonymous pushes
+	if header.Get("X-Registry-Auth") == "" {
+		header.Set("X-Registry-Auth", (&RegistryAuth{}).header())
+	}
+	r, err := c.streamHeader(ctx, http.MethodPost, "images/"+pathRef(repo)+"/push", url.Values{"tag": {tag}}, nil, header)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if err := checkResponse(r, http.StatusOK); err != nil {
+		return err
+	}
+	return readProgress(r.Body)
+}
+
+// registryClient talks to registries by the registry API.
+// docs.: https://github.com/opencontainers/distribution-spec/blob/main/spec.md
+type registryClient struct {
+	http *http.Client
+	auth AuthProvider
+
+	mu sync.Mutex
+	// authorization headers by registry host and repository
+	authorization map[string]string
+}
+
+func (rc *registryClient) copyImage(ctx context.Context, src, dst reference) error {
+	body, mediaType, err := rc.getManifest(ctx, src, src.tagOrDigest())
+	if err != nil {
+		return err
+	}
+	if err := rc.copyContent(ctx, src, dst, body, mediaType); err != nil {
+		return err
+	}
+	return rc.putManifest(ctx, dst, dst.tagOrDigest(), body, mediaType)
+}
+
+// copyContent copies everything the manifest references.
+func (rc *registryClient) copyContent(ctx context.Context, src, dst reference, body []byte, mediaType string) error {
+	var m manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return err
+	}
+	switch mediaType {
+	case mediaTypeDockerList, mediaTypeOCIIndex:
+		for _, d := range m.Manifests {
+			child, childType, err := rc.getManifest(ctx, src, d.Digest)
+			if err != nil {
+				return err
+			}
+			if err := rc.copyContent(ctx, src, dst, child, childType); err != nil {
+				return err
+			}
+			if err := rc.putManifest(ctx, dst, d.Digest, child, childType); err != nil {
+				return err
+			}
+		}
+		return nil
+	case mediaTypeDockerManifest, mediaTypeOCIManifest:
+		blobs := m.Layers
+		if m.Config != nil {
+			blobs = append([]descriptor{*m.Config}, blobs...)
+		}
+		for _, b := range blobs {
+			if err := rc.copyBlob(ctx, src, dst, b); err != nil {
+				return fmt.Errorf("copy blob %s: %w", b.Digest, err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported manifest type %q", mediaType)
+}
+
+func (rc *registryClient) getManifest(ctx context.Context, ref reference, tagOrDigest string) ([]byte, string, error) {
+	req, err := http.NewRequest(http.MethodGet, ref.url("manifests/"+tagOrDigest), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Accept", manifestAccept)
+	r, err := rc.do(ctx, ref, "pull", req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer r.Body.Close()
+	if err := checkResponse(r, http.StatusOK); err != nil {
+		return nil, "", err
+	}
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	mediaType := r.Header.Get("Content-Type")
+	if i := strings.Index(mediaType, ";"); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	return b, mediaType, nil
+}
+
+func (rc *registryClient) putManifest(ctx context.Context, ref reference, tagOrDigest string, body []byte, mediaType string) error {
+	req, err := http.NewRequest(http.MethodPut, ref.url("manifests/"+tagOrDigest), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mediaType)
+	r, err := rc.do(ctx, ref, "pull,push", req)
+	if err != nil {
+		return err
+	}
+	defer drainClose(r.Body)
+	return checkResponse(r, http.StatusCreated)
+}
+
+// copyBlob copies the blob unless the destination has it already. Blobs of
+// the same registry are mounted from the source repository.
+func (rc *registryClient) copyBlob(ctx context.Context, src, dst reference, d descriptor) error {
+	req, err := http.NewRequest(http.MethodHead, dst.url("blobs/"+d.Digest), nil)
+	if err != nil {
+		return err
+	}
+	r, err := rc.do(ctx, dst, "pull,push", req)
+	if err != nil {
+		return err
+	}
+	r.Body.Close()
+	if r.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	q := url.Values{}
+	if src.apiHost() == dst.apiHost() {
+		q.Set("mount", d.Digest)
+		q.Set("from", src.Repository)
+	}
+	req, err = http.NewRequest(http.MethodPost, dst.url("blobs/uploads/")+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	r, err = rc.do(ctx, dst, "pull,push", req)
+	if err != nil {
+		return err
+	}
+	drainClose(r.Body)
+	if r.StatusCode == http.StatusCreated {
+		// mounted
+		return nil
+	}
+	if err := checkResponse(r, http.StatusAccepted); err != nil {
+		return err
+	}
+	location, err := r.Request.URL.Parse(r.Header.Get("Location"))
+	if err != nil {
+		return err
+	}
+
+	req, err = http.NewRequest(http.MethodGet, src.url("blobs/"+d.Digest), nil)
+	if err != nil {
+		return err
+	}
+	blob, err := rc.do(ctx, src, "pull", req)
+	if err != nil {
+		return err
+	}
+	defer blob.Body.Close()
+	if err := checkResponse(blob, http.StatusOK); err != nil {
+		return err
+	}
+
+	q = location.Query()
+	q.Set("digest", d.Digest)
+	location.RawQuery = q.Encode()
+	req, err = http.NewRequest(http.MethodPut, location.String(), blob.Body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = blob.ContentLength
+	req.Header.Set("Content-Type", "application/octet-stream")
+	r, err = rc.do(ctx, dst, "pull,push", req)
+	if err != nil {
+		return err
+	}
+	defer drainClose(r.Body)
+	return checkResponse(r, http.StatusCreated)
+}
+
+// do sends the request with the authorization for the repository. If the
+// registry asks for authorization, it is obtained for the actions, e.g.
+// "pull,push", and the request is sent again if its body can be replayed.
+func (rc *registryClient) do(ctx context.Context, ref reference, actions string, req *http.Request) (*http.Response, error) {
+	key := ref.apiHost() + "/" + ref.Repository
+	rc.mu.Lock()
+	authorization := rc.authorization[key]
+	rc.mu.Unlock()
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	r, err := rc.http.Do(req.WithContext(ctx))
+	if err != nil || r.StatusCode != http.StatusUnauthorized {
+		return r, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return r, nil
+	}
+	drainClose(r.Body)
+
+	authorization, err = rc.authorize(ctx, ref, actions, r.Header.Get("WWW-Authenticate"))
+	if err != nil {
+		return nil, err
+	}
+	rc.mu.Lock()
+	if rc.authorization == nil {
+		rc.authorization = make(map[string]string)
+	}
+	rc.authorization[key] = authorization
+	rc.mu.Unlock()
+
+	retry := req.Clone(ctx)
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	retry.Header.Set("Authorization", authorization)
+	return rc.http.Do(retry)
+}
+
+// authorize answers the challenge of the registry with the credentials of
+// the auth provider, for pushes with those for pushing.
+func (rc *registryClient) authorize(ctx context.Context, ref reference, actions, challenge string) (string, error) {
+	var a RegistryAuth
+	if rc.auth != nil {
+		p, err := authFor(ctx, rc.auth, ref.String(), actions)
+		if err != nil {
+			return "", err
+		}
+		if p != nil {
+			a = *p
+		}
+	}
+	if a.RegistryToken != "" {
+		return "Bearer " + a.RegistryToken, nil
+	}
+	scheme, params := parseChallenge(challenge)
+	switch {
+	case strings.EqualFold(scheme, "basic") && a.Username != "":
+		req := &http.Request{Header: http.Header{}}
+		req.SetBasicAuth(a.Username, a.Password)
+		return req.Header.Get("Authorization"), nil
+	case strings.EqualFold(scheme, "bearer") && params["realm"] != "":
+		tok, err := requestToken(ctx, rc.http, params,
+			[]string{"repository:" + ref.Repository + ":" + actions}, a.Username, a.Password)
+		if err != nil {
+			return "", fmt.Errorf("registry token for %s: %w", ref, err)
+		}
+		return "Bearer " + tok.token, nil
+	}
+	return "", fmt.Errorf("registry %s: unauthorized", ref.Registry)
+}
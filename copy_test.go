@@ -0,0 +1,166 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRegistry implements the parts of the registry API used by CopyImage.
+// If user is set, requests need basic authentication.
+type fakeRegistry struct {
+	user, password string
+
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	uploads   int
+	mounts    int
+}
+
+func newFakeRegistry() *fakeRegistry {
+	return &fakeRegistry{blobs: map[string][]byte{}, manifests: map[string][]byte{}}
+}
+
+// addImage stores an image with a config and a layer in the repository.
+func (f *fakeRegistry) addImage(repo, tag, layer string) string {
+	config := []byte(`{"architecture": "amd64", "os": "linux"}`)
+	f.blobs[repo+"@"+digestOf(config)] = config
+	f.blobs[repo+"@"+digestOf([]byte(layer))] = []byte(layer)
+	m := fmt.Sprintf(`{"schemaVersion": 2, "mediaType": %q,
+		"config": {"mediaType": "application/vnd.oci.image.config.v1+json", "digest": %q, "size": %d},
+		"layers": [{"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": %q, "size": %d}]}`,
+		mediaTypeOCIManifest, digestOf(config), len(config), digestOf([]byte(layer)), len(layer))
+	f.manifests[repo+":"+tag] = []byte(m)
+	f.manifests[repo+"@"+digestOf([]byte(m))] = []byte(m)
+	return digestOf([]byte(m))
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if user, pass, _ := r.BasicAuth(); f.user != "" && (user != f.user || pass != f.password) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p := strings.TrimPrefix(r.URL.Path, "/v2/")
+	switch {
+	case strings.Contains(p, "/manifests/"):
+		i := strings.Index(p, "/manifests/")
+		repo, ref := p[:i], p[i+len("/manifests/"):]
+		sep := ":"
+		if strings.HasPrefix(ref, "sha256:") {
+			sep = "@"
+		}
+		if r.Method == http.MethodPut {
+			b, _ := ioutil.ReadAll(r.Body)
+			f.manifests[repo+sep+ref] = b
+			f.manifests[repo+"@"+digestOf(b)] = b
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		b, ok := f.manifests[repo+sep+ref]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", mediaTypeOCIManifest)
+		w.Header().Set("Docker-Content-Digest", digestOf(b))
+		w.Write(b)
+	case strings.Contains(p, "/blobs/uploads/"):
+		i := strings.Index(p, "/blobs/uploads/")
+		repo := p[:i]
+		if r.Method == http.MethodPost {
+			if d := r.URL.Query().Get("mount"); d != "" {
+				if b, ok := f.blobs[r.URL.Query().Get("from")+"@"+d]; ok {
+					f.blobs[repo+"@"+d] = b
+					f.mounts++
+					w.WriteHeader(http.StatusCreated)
+					return
+				}
+			}
+			w.Header().Set("Location", "/v2/"+repo+"/blobs/uploads/1?state=x")
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		if d := r.URL.Query().Get("digest"); d != digestOf(b) || r.URL.Query().Get("state") != "x" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.blobs[repo+"@"+digestOf(b)] = b
+		f.uploads++
+		w.WriteHeader(http.StatusCreated)
+	case strings.Contains(p, "/blobs/"):
+		i := strings.Index(p, "/blobs/")
+		b, ok := f.blobs[p[:i]+"@"+p[i+len("/blobs/"):]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(b)))
+		w.Write(b)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// staticAuth provides the same credentials for every image.
+type staticAuth RegistryAuth
+
+func (a staticAuth) Auth(context.Context, string) (*RegistryAuth, error) {
+	ra := RegistryAuth(a)
+	return &ra, nil
+}
+
+func Test_CopyImage(t *testing.T) {
+	public := newFakeRegistry()
+	digest := public.addImage("sim/plc", "1.0", "layer-1")
+	publicSrv := httptest.NewServer(public)
+	defer publicSrv.Close()
+
+	lab := newFakeRegistry()
+	lab.user, lab.password = "ci", "secret"
+	labSrv := httptest.NewServer(lab)
+	defer labSrv.Close()
+
+	publicHost := strings.TrimPrefix(publicSrv.URL, "http://")
+	labHost := strings.TrimPrefix(labSrv.URL, "http://")
+	auth := staticAuth{Username: "ci", Password: "secret"}
+	ctx := context.Background()
+
+	if err := client.CopyImage(ctx, publicHost+"/sim/plc:1.0", labHost+"/lab/plc:1.0", auth); err != nil {
+		t.Fatal(err)
+	}
+	if lab.uploads != 2 || lab.mounts != 0 {
+		t.Errorf("expected 2 uploads, got %d uploads and %d mounts", lab.uploads, lab.mounts)
+	}
+	if _, ok := lab.manifests["lab/plc@"+digest]; !ok {
+		t.Error("manifest was not copied unchanged")
+	}
+
+	// blobs which exist are skipped, blobs within the registry are mounted
+	if err := client.CopyImage(ctx, labHost+"/lab/plc:1.0", labHost+"/archive/plc:1.0", auth); err != nil {
+		t.Fatal(err)
+	}
+	if lab.uploads != 2 || lab.mounts != 2 {
+		t.Errorf("expected 2 mounts, got %d uploads and %d mounts", lab.uploads, lab.mounts)
+	}
+	if err := client.CopyImage(ctx, labHost+"/lab/plc:1.0", labHost+"/archive/plc:1.0", auth); err != nil {
+		t.Fatal(err)
+	}
+	if lab.uploads != 2 || lab.mounts != 2 {
+		t.Errorf("expected no transfer, got %d uploads and %d mounts", lab.uploads, lab.mounts)
+	}
+
+	if err := client.CopyImage(ctx, labHost+"/lab/plc:1.0", labHost+"/archive/plc:1.0", nil); err == nil {
+		t.Error("expected error without credentials")
+	}
+}
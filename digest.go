@@ -0,0 +1,134 @@
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ComputeDigest returns the digest of the content in the form the registry
+// uses, e.g. "sha256:...".
+func ComputeDigest(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func digestOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// RegistryDigest returns the digest of the manifest the reference points to
+// in its registry. For images of multiple platforms it is the digest of the
+// index, as recorded by the daemon when pulling by tag. auth may be nil.
+func (c *Client) RegistryDigest(ctx context.Context, ref string, auth AuthProvider) (string, error) {
+	rc := &registryClient{http: &http.Client{Transport: http.DefaultTransport}, auth: auth}
+	d, err := rc.manifestDigest(ctx, parseReference(ref))
+	if err != nil {
+		return "", fmt.Errorf("registry digest of %s: %w", ref, err)
+	}
+	return d, nil
+}
+
+// LocalDigests returns the registry digests of the local image, one per
+// repository it was pulled from or pushed to. Images which were only built
+// locally have none.
+func (c *Client) LocalDigests(ctx context.Context, image string) ([]string, error) {
+	img, err := c.inspectImage(ctx, image)
+	if err != nil {
+		return nil, fmt.Errorf("inspect image %s: %w", image, err)
+	}
+	digests := make([]string, 0, len(img.RepoDigests))
+	for _, rd := range img.RepoDigests {
+		if i := strings.Index(rd, "@"); i >= 0 {
+			digests = append(digests, rd[i+1:])
+		}
+	}
+	return digests, nil
+}
+
+// ImageLayers returns the digests of the uncompressed layers of the local
+// image, from the base layer up. Images sharing a prefix of layers share
+// these layers on disk.
+func (c *Client) ImageLayers(ctx context.Context, image string) ([]string, error) {
+	img, err := c.inspectImage(ctx, image)
+	if err != nil {
+		return nil, fmt.Errorf("inspect image %s: %w", image, err)
+	}
+	return img.RootFS.Layers, nil
+}
+
+// SameImage reports whether both references point to the same content in
+// their registries, e.g. whether a tag was copied to another registry.
+func (c *Client) SameImage(ctx context.Context, a, b string, auth AuthProvider) (bool, error) {
+	da, err := c.RegistryDigest(ctx, a, auth)
+	if err != nil {
+		return false, err
+	}
+	db, err := c.RegistryDigest(ctx, b, auth)
+	if err != nil {
+		return false, err
+	}
+	return da == db, nil
+}
+
+// ContainerImageStale reports whether the image reference the container was
+// created from points to different content in the registry than the image
+// the container runs, e.g. because a new version was pushed to the tag.
+// Containers of images which were never pulled are always stale.
+func (c *Client) ContainerImageStale(ctx context.Context, id string, auth AuthProvider) (bool, error) {
+	cj, err := c.inspectContainer(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("inspect container %s: %w", id, err)
+	}
+	local, err := c.LocalDigests(ctx, cj.Image)
+	if err != nil {
+		return false, err
+	}
+	remote, err := c.RegistryDigest(ctx, cj.Config.Image, auth)
+	if err != nil {
+		return false, err
+	}
+	for _, d := range local {
+		if d == remote {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// manifestDigest asks the registry for the digest of the manifest. If the
+// registry does not send it, the manifest is fetched and hashed.
+func (rc *registryClient) manifestDigest(ctx context.Context, ref reference) (string, error) {
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+	req, err := http.NewRequest(http.MethodHead, ref.url("manifests/"+ref.Tag), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", manifestAccept)
+	r, err := rc.do(ctx, ref, "pull", req)
+	if err != nil {
+		return "", err
+	}
+	drainClose(r.Body)
+	if err := checkResponse(r, http.StatusOK); err != nil {
+		return "", err
+	}
+	if d := r.Header.Get("Docker-Content-Digest"); d != "" {
+		return d, nil
+	}
+	b, _, err := rc.getManifest(ctx, ref, ref.Tag)
+	if err != nil {
+		return "", err
+	}
+	return digestOf(b), nil
+}
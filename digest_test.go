@@ -0,0 +1,51 @@
+package docker
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_ComputeDigest(t *testing.T) {
+	d, err := ComputeDigest(strings.NewReader("layer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := digestOf([]byte("layer")); d != want || !strings.HasPrefix(d, "sha256:") {
+		t.Errorf("want digest %s, got %s", want, d)
+	}
+}
+
+func Test_ContainerImageStale(t *testing.T) {
+	reg := newFakeRegistry()
+	digest := reg.addImage("sim/plc", "1.0", "layer-1")
+	regSrv := httptest.NewServer(reg)
+	defer regSrv.Close()
+	image := strings.TrimPrefix(regSrv.URL, "http://") + "/sim/plc:1.0"
+	ctx := context.Background()
+
+	if d, err := client.RegistryDigest(ctx, image, nil); err != nil || d != digest {
+		t.Fatalf("want digest %s, got %s, %v", digest, d, err)
+	}
+
+	srv.route(map[string]mockResponse{
+		"GET /containers/c1/json": {Body: `{"Id": "c1", "Name": "/plc", "Image": "sha256:img",
+			"Config": {"Image": "` + image + `"}}`},
+		"GET /images/sha256:img/json": {Body: `{"Id": "sha256:img",
+			"RepoDigests": ["` + strings.Split(image, ":1.0")[0] + "@" + digest + `"]}`},
+	})
+	defer srv.route(nil)
+
+	if stale, err := client.ContainerImageStale(ctx, "c1", nil); err != nil || stale {
+		t.Errorf("expected container to be up to date, got %v, %v", stale, err)
+	}
+	// a new version is pushed to the tag
+	reg.addImage("sim/plc", "1.0", "layer-2")
+	if stale, err := client.ContainerImageStale(ctx, "c1", nil); err != nil || !stale {
+		t.Errorf("expected container to be stale, got %v, %v", stale, err)
+	}
+	if same, err := client.SameImage(ctx, image, strings.Replace(image, ":1.0", "@"+digest, 1), nil); err != nil || same {
+		t.Errorf("expected different content, got %v, %v", same, err)
+	}
+}
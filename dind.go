@@ -0,0 +1,213 @@
+package docker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// DefaultDindImage is the image of the daemon started by RunDind.
+	DefaultDindImage = "docker:20.10-dind"
+	// LabelDind marks containers started by RunDind.
+	LabelDind = "com.grid-x.docker.dind"
+
+	defaultDindTimeout = time.Minute
+)
+
+// DindOptions configures RunDind. By default the inner daemon listens
+// without TLS on a port published on the loopback interface of the host.
+type DindOptions struct {
+	// Image defaults to DefaultDindImage.
+	Image string
+	// Name of the container, generated by the daemon if empty.
+	Name   string
+	Labels map[string]string
+	// TLS lets the inner daemon generate certificates and require them.
+	// The client certificates are written to a temporary directory on the
+	// host, which is removed by Close.
+	TLS bool
+	// SocketDir exposes the socket of the inner daemon as
+	// SocketDir/docker.sock on the host instead of publishing a port. The
+	// directory has to exist.
+	SocketDir string
+	// StartupTimeout limits the wait for the inner daemon. Defaults to one
+	// minute.
+	StartupTimeout time.Duration
+}
+
+// Dind is a Docker-in-Docker daemon started by RunDind.
+type Dind struct {
+	// ID of the dind container.
+	ID string
+	// Client is connected to the inner daemon.
+	Client *Client
+	// Host is the address of the inner daemon in the DOCKER_HOST format.
+	Host string
+
+	parent  *Client
+	certDir string
+}
+
+// RunDind starts a privileged Docker-in-Docker container, waits until its
+// daemon answers and returns a client for it. The image is pulled if it is
+// not available. Like the reaper, published ports are bound to the
+// loopback interface, so the outer daemon has to run on the local host
+// unless SocketDir is used on a shared filesystem.
+func (c *Client) RunDind(ctx context.Context, opts DindOptions) (*Dind, error) {
+	if opts.Image == "" {
+		opts.Image = DefaultDindImage
+	}
+	if opts.StartupTimeout <= 0 {
+		opts.StartupTimeout = defaultDindTimeout
+	}
+	spec := ContainerSpec{
+		Name:       opts.Name,
+		Image:      opts.Image,
+		Env:        map[string]string{"DOCKER_TLS_CERTDIR": ""},
+		Labels:     copyLabels(opts.Labels),
+		Privileged: true,
+	}
+	spec.Labels[LabelDind] = "true"
+
+	d := &Dind{parent: c}
+	port := "2375/tcp"
+	switch {
+	case opts.SocketDir != "":
+		spec.Mounts = []string{opts.SocketDir + ":/var/run"}
+	case opts.TLS:
+		dir, err := ioutil.TempDir("", "dind-certs")
+		if err != nil {
+			return nil, fmt.Errorf("dind: %w", err)
+		}
+		d.certDir = dir
+		port = "2376/tcp"
+		spec.Env["DOCKER_TLS_CERTDIR"] = "/certs"
+		spec.Mounts = []string{dir + ":/certs/client"}
+		spec.Ports = []string{"127.0.0.1::" + port}
+	default:
+		spec.Ports = []string{"127.0.0.1::" + port}
+	}
+
+	body, err := spec.createBody()
+	if err != nil {
+		d.removeCerts()
+		return nil, fmt.Errorf("dind: %w", err)
+	}
+	res, err := c.createContainer(ctx, spec.Name, body)
+	if isNotFound(err) {
+		if err := c.PullImage(ctx, opts.Image); err != nil {
+			d.removeCerts()
+			return nil, fmt.Errorf("dind: %w", err)
+		}
+		res, err = c.createContainer(ctx, spec.Name, body)
+	}
+	if err != nil {
+		d.removeCerts()
+		return nil, fmt.Errorf("dind: create container: %w", err)
+	}
+	d.ID = res.ID
+
+	err = func() error {
+		if err := c.startContainer(ctx, res.ID); err != nil {
+			return fmt.Errorf("start container: %w", err)
+		}
+		if opts.SocketDir != "" {
+			d.Host = "unix://" + filepath.Join(opts.SocketDir, "docker.sock")
+		} else {
+			hostPort, err := c.HostPort(ctx, res.ID, port)
+			if err != nil {
+				return err
+			}
+			d.Host = "tcp://" + net.JoinHostPort("127.0.0.1", hostPort)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, opts.StartupTimeout)
+		defer cancel()
+		return d.waitReady(ctx)
+	}()
+	if err != nil {
+		// the context may be done already, the cleanup must not depend on it
+		d.Close(context.Background())
+		return nil, fmt.Errorf("dind: %w", err)
+	}
+	return d, nil
+}
+
+// waitReady polls until the inner daemon answers. With TLS the client can
+// only be created once the daemon wrote the certificates.
+func (d *Dind) waitReady(ctx context.Context) error {
+	backoff := d.parent.backoffOr(ConstantBackoff(defaultPollInterval))
+	var err error
+	for n := 0; ; n++ {
+		if d.Client == nil {
+			d.Client, err = d.client()
+		}
+		if d.Client != nil {
+			if _, err = d.Client.Ping(ctx); err == nil {
+				return nil
+			}
+		}
+		if serr := sleepBackoff(ctx, backoff, n); serr != nil {
+			return fmt.Errorf("wait for daemon: %v: %w", err, serr)
+		}
+	}
+}
+
+func (d *Dind) client() (*Client, error) {
+	if d.certDir == "" {
+		return NewClientHost(d.Host)
+	}
+	config, err := loadClientTLS(d.certDir)
+	if err != nil {
+		return nil, err
+	}
+	return NewClientTLS(d.Host, config)
+}
+
+// loadClientTLS loads ca.pem, cert.pem and key.pem of the directory.
+func loadClientTLS(dir string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))
+	if err != nil {
+		return nil, err
+	}
+	ca, err := ioutil.ReadFile(filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates in %s", filepath.Join(dir, "ca.pem"))
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		// the certificate of the daemon is issued for its container
+		ServerName: "docker",
+	}, nil
+}
+
+// Close removes the dind container together with its data and the client
+// certificates.
+func (d *Dind) Close(ctx context.Context) error {
+	defer d.removeCerts()
+	q := url.Values{"force": {"1"}, "v": {"1"}}
+	if err := d.parent.doJSON(ctx, http.MethodDelete, "containers/"+pathName(d.ID), q, nil, nil, http.StatusNoContent); err != nil && !isNotFound(err) {
+		return fmt.Errorf("remove dind %s: %w", d.ID, err)
+	}
+	return nil
+}
+
+func (d *Dind) removeCerts() {
+	if d.certDir != "" {
+		os.RemoveAll(d.certDir)
+	}
+}
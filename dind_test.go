@@ -0,0 +1,62 @@
+package docker
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_RunDind(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"POST /containers/create":   {StatusCode: http.StatusCreated, Body: `{"Id": "d1"}`},
+		"POST /containers/d1/start": {StatusCode: http.StatusNoContent},
+		"DELETE /containers/d1?" + url.Values{"force": {"1"}, "v": {"1"}}.Encode(): {StatusCode: http.StatusNoContent},
+	})
+	defer srv.route(nil)
+
+	dir, err := ioutil.TempDir("", "dind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the inner daemon does not answer yet
+	_, err = client.RunDind(context.Background(), DindOptions{SocketDir: dir, StartupTimeout: 100 * time.Millisecond})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	want := []string{"POST /containers/create", "POST /containers/d1/start", "DELETE /containers/d1"}
+	if reqs := srv.Requests(); !reflect.DeepEqual(reqs, want) {
+		t.Errorf("want requests %v, got %v", want, reqs)
+	}
+
+	l, err := net.Listen("unix", filepath.Join(dir, "docker.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+
+	d, err := client.RunDind(context.Background(), DindOptions{SocketDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.ID != "d1" || d.Host != "unix://"+filepath.Join(dir, "docker.sock") {
+		t.Errorf("unexpected dind %+v", d)
+	}
+	if _, err := d.Client.Ping(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if err := d.Close(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,107 @@
+package dockertest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/grid-x/docker"
+)
+
+// DefaultStartupTimeout limits the time GenericContainer waits for a
+// container to become ready.
+var DefaultStartupTimeout = time.Minute
+
+// Request describes a container started by GenericContainer.
+type Request struct {
+	Image string
+	Cmd   []string
+	Env   map[string]string
+	// ExposedPorts are published on random ports of the loopback interface,
+	// e.g. ["5432/tcp"]. Use MappedPort to look them up.
+	ExposedPorts []string
+	Labels       map[string]string
+	// WaitingFor decides when the container is ready. If it is nil the
+	// container is ready as soon as it was started.
+	WaitingFor WaitStrategy
+	// StartupTimeout defaults to DefaultStartupTimeout.
+	StartupTimeout time.Duration
+}
+
+// Container is a running container started by GenericContainer.
+type Container struct {
+	ID  string
+	c   *docker.Client
+	run string
+	// ports are the exposed ports of the request.
+	ports []string
+}
+
+// GenericContainer starts the container described by the request and waits
+// until it is ready. If the container does not become ready, it is removed.
+// The daemon has to run on the local host.
+// e.g.: ct, err := GenericContainer(ctx, c, Request{Image: "postgres:12",
+// ExposedPorts: []string{"5432/tcp"}, WaitingFor: ForListeningPort("5432/tcp")})
+func GenericContainer(ctx context.Context, c *docker.Client, req Request) (*Container, error) {
+	labels, run := ownRun(req.Labels)
+	spec := docker.ContainerSpec{
+		Image:  req.Image,
+		Cmd:    req.Cmd,
+		Env:    req.Env,
+		Labels: labels,
+	}
+	for _, p := range req.ExposedPorts {
+		spec.Ports = append(spec.Ports, "127.0.0.1::"+p)
+	}
+
+	res, err := c.Run(ctx, spec, docker.RunOptions{})
+	if err != nil {
+		return nil, err
+	}
+	ct := &Container{ID: res.ID, c: c, run: run, ports: req.ExposedPorts}
+	if req.WaitingFor == nil {
+		return ct, nil
+	}
+
+	timeout := req.StartupTimeout
+	if timeout == 0 {
+		timeout = DefaultStartupTimeout
+	}
+	wctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := req.WaitingFor.WaitUntilReady(wctx, ct); err != nil {
+		// the context may be done already, the cleanup must not depend on it
+		ct.Terminate(context.Background())
+		return nil, fmt.Errorf("wait for %s: %w", req.Image, err)
+	}
+	return ct, nil
+}
+
+// Host returns the host the published ports are reachable on.
+func (ct *Container) Host() string {
+	return "127.0.0.1"
+}
+
+// MappedPort returns the port on the host the given port of the container is
+// published on.
+func (ct *Container) MappedPort(ctx context.Context, port string) (string, error) {
+	return ct.c.HostPort(ctx, ct.ID, port)
+}
+
+// Logs returns the output the container has written so far. Stdout and
+// stderr are combined.
+func (ct *Container) Logs(ctx context.Context) (io.Reader, error) {
+	var out bytes.Buffer
+	if err := ct.c.Logs(ctx, ct.ID, &out, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Terminate removes the container.
+func (ct *Container) Terminate(ctx context.Context) error {
+	_, err := ct.c.TeardownRun(ctx, ct.run)
+	return err
+}
@@ -0,0 +1,66 @@
+package dockertest
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func Test_GenericContainer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	d := &fakeDaemon{port: port, logs: "database system is ready\n"}
+	c, stop := startDaemon(t, d)
+	defer stop()
+	ctx := context.Background()
+
+	ct, err := GenericContainer(ctx, c, Request{
+		Image:        "postgres:12",
+		ExposedPorts: []string{"80/tcp"},
+		WaitingFor:   ForListeningPort("80/tcp"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ct.MappedPort(ctx, "80"); err != nil || got != port {
+		t.Errorf("unexpected mapped port %s, %v", got, err)
+	}
+	r, err := ct.Logs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadAll(r); string(b) != d.logs {
+		t.Errorf("unexpected logs %q", b)
+	}
+	if err := ct.Terminate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !d.removed() {
+		t.Error("container was not removed")
+	}
+}
+
+func Test_GenericContainerNotReady(t *testing.T) {
+	d := &fakeDaemon{logs: "starting\n"}
+	c, stop := startDaemon(t, d)
+	defer stop()
+
+	_, err := GenericContainer(context.Background(), c, Request{
+		Image:          "postgres:12",
+		WaitingFor:     ForLog("ready"),
+		StartupTimeout: 200 * time.Millisecond,
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !d.removed() {
+		t.Error("container was not removed")
+	}
+}
@@ -0,0 +1,82 @@
+// Package dockertest runs containers for integration tests against a real
+// daemon. The containers are removed automatically when the test ends and
+// their logs are printed if the test failed.
+package dockertest
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/grid-x/docker"
+)
+
+// Socket is the docker socket used by StartContainer.
+var Socket = "/var/run/docker.sock"
+
+// CleanupTimeout limits the time to collect the logs and remove the
+// containers of a test.
+var CleanupTimeout = time.Minute
+
+// T is the subset of *testing.T used by the helpers.
+type T interface {
+	Helper()
+	Logf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+	Failed() bool
+	Cleanup(func())
+}
+
+// StartContainer starts a container on the daemon listening on Socket. See
+// StartContainerWith.
+func StartContainer(t T, spec docker.ContainerSpec) string {
+	t.Helper()
+	return StartContainerWith(t, docker.NewClient(Socket), spec)
+}
+
+// StartContainerWith starts a container and returns its ID. The test fails
+// immediately if the container can not be started. A cleanup handler is
+// registered which prints the logs of the container if the test failed and
+// removes the container.
+// e.g.: id := dockertest.StartContainerWith(t, c, docker.ContainerSpec{Image: "postgres:12"})
+func StartContainerWith(t T, c *docker.Client, spec docker.ContainerSpec) string {
+	t.Helper()
+
+	var run string
+	spec.Labels, run = ownRun(spec.Labels)
+
+	res, err := c.Run(context.Background(), spec, docker.RunOptions{})
+	if err != nil {
+		t.Fatalf("start container %s: %v", spec.Image, err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), CleanupTimeout)
+		defer cancel()
+
+		if t.Failed() {
+			var out bytes.Buffer
+			if err := c.Logs(ctx, res.ID, &out, &out); err != nil {
+				t.Logf("read logs of container %s: %v", spec.Image, err)
+			} else {
+				t.Logf("logs of container %s (%s):\n%s", spec.Image, res.ID, out.String())
+			}
+		}
+		if _, err := c.TeardownRun(ctx, run); err != nil {
+			t.Logf("remove container %s: %v", spec.Image, err)
+		}
+	})
+	return res.ID
+}
+
+// ownRun returns a copy of the labels with a new run. Every container gets
+// its own run, so removing the run removes exactly this container.
+func ownRun(labels map[string]string) (map[string]string, string) {
+	run := docker.NewNameGenerator("dockertest", "").Next()
+	res := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		res[k] = v
+	}
+	res[docker.LabelRun] = run
+	return res, run
+}
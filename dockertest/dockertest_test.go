@@ -0,0 +1,139 @@
+package dockertest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/grid-x/docker"
+)
+
+// fakeT records the calls of the helpers.
+type fakeT struct {
+	failed   bool
+	logs     []string
+	cleanups []func()
+}
+
+func (f *fakeT) Helper() {}
+func (f *fakeT) Logf(format string, args ...interface{}) {
+	f.logs = append(f.logs, fmt.Sprintf(format, args...))
+}
+func (f *fakeT) Fatalf(format string, args ...interface{}) {
+	panic(fmt.Sprintf(format, args...))
+}
+func (f *fakeT) Failed() bool      { return f.failed }
+func (f *fakeT) Cleanup(fn func()) { f.cleanups = append(f.cleanups, fn) }
+
+// fakeDaemon answers the requests of the helpers for a single container c1.
+// Its port 80/tcp is published on port.
+type fakeDaemon struct {
+	port string
+	logs string
+	// execExit is the exit code of commands run by exec.
+	execExit int
+
+	mu       sync.Mutex
+	requests []string
+}
+
+// startDaemon serves a fakeDaemon on a unix socket and returns a client for
+// it. The returned function stops the daemon.
+func startDaemon(t *testing.T, d *fakeDaemon) (*docker.Client, func()) {
+	dir, err := ioutil.TempDir("", "dockertest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sock := filepath.Join(dir, "docker.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go http.Serve(l, d)
+	return docker.NewClient(sock), func() {
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func (d *fakeDaemon) removed() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, r := range d.requests {
+		if r == "DELETE /containers/c1" {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *fakeDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	d.requests = append(d.requests, r.Method+" "+r.URL.Path)
+	d.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/containers/create":
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"Id": "c1"}`)
+	case r.Method == http.MethodPost && r.URL.Path == "/containers/c1/start":
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == "/containers/c1/json":
+		fmt.Fprintf(w, `{"Id": "c1", "NetworkSettings": {"Ports": {"80/tcp": [{"HostIp": "127.0.0.1", "HostPort": %q}]}}}`, d.port)
+	case r.URL.Path == "/containers/c1/logs":
+		msg := d.logs
+		hdr := make([]byte, 8)
+		hdr[0] = 1
+		binary.BigEndian.PutUint32(hdr[4:], uint32(len(msg)))
+		w.Write(append(hdr, msg...))
+	case r.URL.Path == "/containers/c1/exec":
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"Id": "e1"}`)
+	case r.URL.Path == "/exec/e1/start":
+	case r.URL.Path == "/exec/e1/json":
+		fmt.Fprintf(w, `{"ID": "e1", "ExitCode": %d}`, d.execExit)
+	case r.URL.Path == "/containers/json":
+		fmt.Fprint(w, `[{"Id": "c1", "Names": ["/test"]}]`)
+	case r.Method == http.MethodDelete && r.URL.Path == "/containers/c1":
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == "/networks":
+		fmt.Fprint(w, `[]`)
+	case r.URL.Path == "/volumes":
+		fmt.Fprint(w, `{"Volumes": []}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "no route"}`)
+	}
+}
+
+func Test_StartContainerWith(t *testing.T) {
+	d := &fakeDaemon{logs: "ready\n"}
+	c, stop := startDaemon(t, d)
+	defer stop()
+
+	ft := &fakeT{}
+	id := StartContainerWith(ft, c, docker.ContainerSpec{Image: "busybox"})
+	if id != "c1" {
+		t.Errorf("unexpected ID %s", id)
+	}
+	if len(ft.cleanups) != 1 {
+		t.Fatalf("expected one cleanup, got %d", len(ft.cleanups))
+	}
+
+	ft.failed = true
+	ft.cleanups[0]()
+
+	if len(ft.logs) != 1 || !strings.Contains(ft.logs[0], "ready") {
+		t.Errorf("expected the logs of the container, got %q", ft.logs)
+	}
+	if !d.removed() {
+		t.Errorf("container was not removed: %v", d.requests)
+	}
+}
@@ -0,0 +1,241 @@
+package dockertest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PollInterval is the interval the wait strategies check the container.
+var PollInterval = 100 * time.Millisecond
+
+// WaitStrategy decides whether a container is ready. WaitUntilReady blocks
+// until the container is ready or the context is done.
+type WaitStrategy interface {
+	WaitUntilReady(ctx context.Context, ct *Container) error
+}
+
+// WaitFunc adapts a function to a WaitStrategy.
+type WaitFunc func(ctx context.Context, ct *Container) error
+
+// WaitUntilReady calls f.
+func (f WaitFunc) WaitUntilReady(ctx context.Context, ct *Container) error {
+	return f(ctx, ct)
+}
+
+// poll calls check every PollInterval until it reports ready, returns an
+// error or the context is done. The last reason for not being ready is part
+// of the error in the latter case.
+func poll(ctx context.Context, check func() (bool, error)) error {
+	t := time.NewTicker(PollInterval)
+	defer t.Stop()
+
+	var last error
+	for {
+		ready, err := check()
+		if ready {
+			return nil
+		}
+		// errors caused by the end of the context do not tell the reason
+		if err != nil && ctx.Err() == nil {
+			last = err
+		}
+		select {
+		case <-ctx.Done():
+			if last != nil {
+				return fmt.Errorf("%w: %v", ctx.Err(), last)
+			}
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// ForListeningPort waits until the port of the container accepts
+// connections. The published port is accepted by the docker-proxy even if
+// nothing listens in the container yet, the proxy closes such connections
+// right away. So the port is only ready if the connection stays open for a
+// PollInterval or the container sends data.
+func ForListeningPort(port string) WaitStrategy {
+	return WaitFunc(func(ctx context.Context, ct *Container) error {
+		hostPort, err := ct.MappedPort(ctx, port)
+		if err != nil {
+			return err
+		}
+		addr := net.JoinHostPort(ct.Host(), hostPort)
+		return poll(ctx, func() (bool, error) {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return false, err
+			}
+			defer conn.Close()
+			conn.SetReadDeadline(time.Now().Add(PollInterval))
+			_, err = conn.Read(make([]byte, 1))
+			var netErr net.Error
+			switch {
+			case err == nil, errors.As(err, &netErr) && netErr.Timeout():
+				return true, nil
+			case err == io.EOF:
+				return false, fmt.Errorf("port %s: connection closed", port)
+			}
+			return false, err
+		})
+	})
+}
+
+// ForLog waits until the output of the container contains the text.
+func ForLog(text string) WaitStrategy {
+	return WaitFunc(func(ctx context.Context, ct *Container) error {
+		return poll(ctx, func() (bool, error) {
+			r, err := ct.Logs(ctx)
+			if err != nil {
+				return false, err
+			}
+			b, err := ioutil.ReadAll(r)
+			if err != nil {
+				return false, err
+			}
+			return strings.Contains(string(b), text), nil
+		})
+	})
+}
+
+// HTTPStrategy waits until an HTTP endpoint of the container answers with
+// the expected status code.
+type HTTPStrategy struct {
+	path    string
+	status  int
+	timeout time.Duration
+	port    string
+}
+
+// WaitForHTTP waits until a GET request of the path answers with the status
+// code. The request is sent to the first exposed port of the container
+// unless another one is set by WithPort. A timeout of zero means the
+// strategy only ends with the context.
+// e.g.: WaitForHTTP("/health", http.StatusOK, 30*time.Second).WithPort("8080/tcp")
+func WaitForHTTP(path string, status int, timeout time.Duration) *HTTPStrategy {
+	return &HTTPStrategy{path: path, status: status, timeout: timeout}
+}
+
+// WithPort sets the port of the container the requests are sent to.
+func (s *HTTPStrategy) WithPort(port string) *HTTPStrategy {
+	s.port = port
+	return s
+}
+
+// WaitUntilReady implements WaitStrategy.
+func (s *HTTPStrategy) WaitUntilReady(ctx context.Context, ct *Container) error {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	port := s.port
+	if port == "" {
+		if len(ct.ports) == 0 {
+			return fmt.Errorf("wait for http: no port exposed")
+		}
+		port = ct.ports[0]
+	}
+	hostPort, err := ct.MappedPort(ctx, port)
+	if err != nil {
+		return err
+	}
+	endpoint := "http://" + net.JoinHostPort(ct.Host(), hostPort) + "/" + strings.TrimPrefix(s.path, "/")
+
+	client := &http.Client{Timeout: PollInterval * 10}
+	return poll(ctx, func() (bool, error) {
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+		if err != nil {
+			return false, err
+		}
+		r, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return false, err
+		}
+		io.Copy(ioutil.Discard, r.Body)
+		r.Body.Close()
+		if r.StatusCode != s.status {
+			return false, fmt.Errorf("GET %s: status %d", s.path, r.StatusCode)
+		}
+		return true, nil
+	})
+}
+
+// WaitForExec waits until the command run inside the container exits with
+// the expected exit code, e.g. WaitForExec([]string{"pg_isready"}, 0).
+func WaitForExec(cmd []string, exitCode int) WaitStrategy {
+	return WaitFunc(func(ctx context.Context, ct *Container) error {
+		return poll(ctx, func() (bool, error) {
+			res, err := ct.c.Exec(ctx, ct.ID, cmd)
+			if err != nil {
+				return false, err
+			}
+			if res.ExitCode != exitCode {
+				return false, fmt.Errorf("%s exited with code %d: %s",
+					strings.Join(cmd, " "), res.ExitCode, bytes.TrimSpace(res.Stderr))
+			}
+			return true, nil
+		})
+	})
+}
+
+// All waits until all strategies report the container ready. They are
+// checked one after the other.
+func All(strategies ...WaitStrategy) WaitStrategy {
+	return WaitFunc(func(ctx context.Context, ct *Container) error {
+		for _, s := range strategies {
+			if err := s.WaitUntilReady(ctx, ct); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Any waits until one of the strategies reports the container ready. They
+// are checked in parallel, the others are canceled once one succeeded. If
+// all fail, the error of the first one is returned.
+func Any(strategies ...WaitStrategy) WaitStrategy {
+	return WaitFunc(func(ctx context.Context, ct *Container) error {
+		if len(strategies) == 0 {
+			return nil
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		errs := make([]error, len(strategies))
+		done := make(chan int, len(strategies))
+		for i, s := range strategies {
+			go func(i int, s WaitStrategy) {
+				errs[i] = s.WaitUntilReady(ctx, ct)
+				done <- i
+			}(i, s)
+		}
+		for range strategies {
+			if i := <-done; errs[i] == nil {
+				return nil
+			}
+		}
+		return errs[0]
+	})
+}
+
+// WithTimeout limits the time the strategy may take in total.
+// e.g.: WithTimeout(time.Minute, All(ForListeningPort("5432/tcp"), ForLog("ready")))
+func WithTimeout(timeout time.Duration, s WaitStrategy) WaitStrategy {
+	return WaitFunc(func(ctx context.Context, ct *Container) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return s.WaitUntilReady(ctx, ct)
+	})
+}
@@ -0,0 +1,113 @@
+package dockertest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_WaitForHTTP(t *testing.T) {
+	var calls int32
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" || atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer web.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(web.URL, "http://"))
+
+	c, stop := startDaemon(t, &fakeDaemon{port: port})
+	defer stop()
+	ct := &Container{ID: "c1", c: c, ports: []string{"80/tcp"}}
+
+	if err := WaitForHTTP("/health", http.StatusOK, time.Second).WaitUntilReady(context.Background(), ct); err != nil {
+		t.Fatal(err)
+	}
+
+	err := WaitForHTTP("/other", http.StatusOK, 200*time.Millisecond).WaitUntilReady(context.Background(), ct)
+	if err == nil || !strings.Contains(err.Error(), "status 503") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func Test_ForListeningPort(t *testing.T) {
+	// like the docker-proxy while nothing listens in the container
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	var listening int32
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			if atomic.AddInt32(&listening, 1) < 3 {
+				conn.Close()
+				continue
+			}
+			defer conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	c, stop := startDaemon(t, &fakeDaemon{port: port})
+	defer stop()
+	ct := &Container{ID: "c1", c: c}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := ForListeningPort("80/tcp").WaitUntilReady(ctx, ct); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&listening); n != 3 {
+		t.Errorf("expected ready with the 3rd connection, got %d", n)
+	}
+}
+
+func Test_WaitForExec(t *testing.T) {
+	d := &fakeDaemon{}
+	c, stop := startDaemon(t, d)
+	defer stop()
+	ct := &Container{ID: "c1", c: c}
+
+	if err := WaitForExec([]string{"pg_isready"}, 0).WaitUntilReady(context.Background(), ct); err != nil {
+		t.Fatal(err)
+	}
+
+	d.execExit = 2
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err := WaitForExec([]string{"pg_isready"}, 0).WaitUntilReady(ctx, ct)
+	if err == nil || !strings.Contains(err.Error(), "exited with code 2") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func Test_CombinedStrategies(t *testing.T) {
+	ready := WaitFunc(func(context.Context, *Container) error { return nil })
+	never := WaitFunc(func(ctx context.Context, _ *Container) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	ctx := context.Background()
+
+	if err := WithTimeout(100*time.Millisecond, Any(never, ready)).WaitUntilReady(ctx, nil); err != nil {
+		t.Errorf("any: unexpected error %v", err)
+	}
+	if err := WithTimeout(100*time.Millisecond, All(ready, never)).WaitUntilReady(ctx, nil); err != context.DeadlineExceeded {
+		t.Errorf("all: unexpected error %v", err)
+	}
+	if err := WithTimeout(100*time.Millisecond, Any(never, never)).WaitUntilReady(ctx, nil); err != context.DeadlineExceeded {
+		t.Errorf("any: unexpected error %v", err)
+	}
+}
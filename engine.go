@@ -0,0 +1,248 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/grid-x/docker/types"
+)
+
+// The engine types are declared by the types package, the aliases keep the
+// names short within this package.
+type (
+	containerSummary = types.ContainerSummary
+	containerState   = types.ContainerState
+	containerConfig  = types.ContainerConfig
+	endpointSettings = types.EndpointSettings
+	portBinding      = types.PortBinding
+	mountPoint       = types.MountPoint
+	containerJSON    = types.ContainerJSON
+	mount            = types.Mount
+	hostConfig       = types.HostConfig
+	endpointConfig   = types.EndpointConfig
+	ipamConfig       = types.IPAMConfig
+	containerCreate  = types.ContainerCreate
+	healthConfig     = types.HealthConfig
+	networkSummary   = types.NetworkSummary
+	networkCreate    = types.NetworkCreate
+	volume           = types.Volume
+	imageInspect     = types.ImageInspect
+	createResponse   = types.CreateResponse
+)
+
+func (c *Client) listContainers(ctx context.Context, all bool, filters map[string][]string) ([]containerSummary, error) {
+	q := filterQuery(filters)
+	if all {
+		q.Set("all", "1")
+	}
+	var res []containerSummary
+	return res, c.doJSON(ctx, http.MethodGet, "containers/json", q, nil, &res, http.StatusOK)
+}
+
+func (c *Client) inspectContainer(ctx context.Context, id string) (*containerJSON, error) {
+	var res containerJSON
+	if err := c.doJSON(ctx, http.MethodGet, "containers/"+pathName(id)+"/json", nil, nil, &res, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) createContainer(ctx context.Context, name string, body *containerCreate) (*createResponse, error) {
+	q := url.Values{}
+	if name != "" {
+		if err := validContainerName(name); err != nil {
+			return nil, err
+		}
+		q.Set("name", name)
+	}
+	if len(body.HostConfig.Annotations) > 0 {
+		if err := c.checkAnnotations(ctx); err != nil {
+			return nil, err
+		}
+	}
+	body.Env = c.proxyEnv.inject(body.Env)
+	var res createResponse
+	if err := c.doJSON(ctx, http.MethodPost, "containers/create", q, body, &res, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = res.ID
+	}
+	c.logWarnings("container "+name, res.Warnings)
+	return &res, nil
+}
+
+func (c *Client) startContainer(ctx context.Context, id string) error {
+	return c.doJSON(ctx, http.MethodPost, "containers/"+pathName(id)+"/start", nil, nil, nil,
+		http.StatusNoContent, http.StatusNotModified)
+}
+
+func (c *Client) stopContainer(ctx context.Context, id string) error {
+	return c.doJSON(ctx, http.MethodPost, "containers/"+pathName(id)+"/stop", nil, nil, nil,
+		http.StatusNoContent, http.StatusNotModified)
+}
+
+func (c *Client) pauseContainer(ctx context.Context, id string) error {
+	return c.doJSON(ctx, http.MethodPost, "containers/"+pathName(id)+"/pause", nil, nil, nil, http.StatusNoContent)
+}
+
+func (c *Client) unpauseContainer(ctx context.Context, id string) error {
+	return c.doJSON(ctx, http.MethodPost, "containers/"+pathName(id)+"/unpause", nil, nil, nil, http.StatusNoContent)
+}
+
+func (c *Client) restartContainer(ctx context.Context, id string) error {
+	return c.doJSON(ctx, http.MethodPost, "containers/"+pathName(id)+"/restart", nil, nil, nil, http.StatusNoContent)
+}
+
+// killContainer sends the signal to the container. Containers which are not
+// running are not treated as error.
+func (c *Client) killContainer(ctx context.Context, id, signal string) error {
+	q := url.Values{}
+	if signal != "" {
+		q.Set("signal", signal)
+	}
+	return c.doJSON(ctx, http.MethodPost, "containers/"+pathName(id)+"/kill", q, nil, nil,
+		http.StatusNoContent, http.StatusConflict)
+}
+
+func (c *Client) removeContainer(ctx context.Context, id string, force bool) error {
+	q := url.Values{}
+	if force {
+		q.Set("force", "1")
+	}
+	return c.doJSON(ctx, http.MethodDelete, "containers/"+pathName(id), q, nil, nil, http.StatusNoContent)
+}
+
+func (c *Client) listNetworks(ctx context.Context, filters map[string][]string) ([]networkSummary, error) {
+	var res []networkSummary
+	return res, c.doJSON(ctx, http.MethodGet, "networks", filterQuery(filters), nil, &res, http.StatusOK)
+}
+
+func (c *Client) inspectNetwork(ctx context.Context, id string) (*networkSummary, error) {
+	var res networkSummary
+	if err := c.doJSON(ctx, http.MethodGet, "networks/"+pathName(id), nil, nil, &res, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) createNetwork(ctx context.Context, body *networkCreate) (*createResponse, error) {
+	var res createResponse
+	if err := c.doJSON(ctx, http.MethodPost, "networks/create", nil, body, &res, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	if res.Warning != "" {
+		res.Warnings = append(res.Warnings, res.Warning)
+	}
+	c.logWarnings("network "+body.Name, res.Warnings)
+	return &res, nil
+}
+
+func (c *Client) removeNetwork(ctx context.Context, id string) error {
+	return c.doJSON(ctx, http.MethodDelete, "networks/"+pathName(id), nil, nil, nil, http.StatusNoContent)
+}
+
+func (c *Client) connectNetwork(ctx context.Context, nwid, cid string, cfg *endpointConfig) error {
+	body := struct {
+		Container      string          `json:"Container"`
+		EndpointConfig *endpointConfig `json:"EndpointConfig,omitempty"`
+	}{
+		Container:      cid,
+		EndpointConfig: cfg,
+	}
+	return c.doJSON(ctx, http.MethodPost, "networks/"+pathName(nwid)+"/connect", nil, &body, nil, http.StatusOK)
+}
+
+func (c *Client) disconnectNetwork(ctx context.Context, nwid, cid string, force bool) error {
+	body := struct {
+		Container string `json:"Container"`
+		Force     bool   `json:"Force"`
+	}{
+		Container: cid,
+		Force:     force,
+	}
+	return c.doJSON(ctx, http.MethodPost, "networks/"+pathName(nwid)+"/disconnect", nil, &body, nil, http.StatusOK)
+}
+
+func (c *Client) listVolumes(ctx context.Context, filters map[string][]string) ([]volume, error) {
+	res := struct {
+		Volumes []volume `json:"Volumes"`
+	}{}
+	return res.Volumes, c.doJSON(ctx, http.MethodGet, "volumes", filterQuery(filters), nil, &res, http.StatusOK)
+}
+
+func (c *Client) createVolume(ctx context.Context, name, driver string, labels map[string]string) (*volume, error) {
+	body := struct {
+		Name   string            `json:"Name"`
+		Driver string            `json:"Driver,omitempty"`
+		Labels map[string]string `json:"Labels,omitempty"`
+	}{
+		Name:   name,
+		Driver: driver,
+		Labels: labels,
+	}
+	var res volume
+	if err := c.doJSON(ctx, http.MethodPost, "volumes/create", nil, &body, &res, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) inspectVolume(ctx context.Context, name string) (*volume, error) {
+	var res volume
+	if err := c.doJSON(ctx, http.MethodGet, "volumes/"+pathName(name), nil, nil, &res, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) removeVolume(ctx context.Context, name string, force bool) error {
+	q := url.Values{}
+	if force {
+		q.Set("force", "1")
+	}
+	return c.doJSON(ctx, http.MethodDelete, "volumes/"+pathName(name), q, nil, nil, http.StatusNoContent)
+}
+
+func (c *Client) inspectImage(ctx context.Context, name string) (*imageInspect, error) {
+	var res imageInspect
+	if err := c.doJSON(ctx, http.MethodGet, "images/"+pathRef(name)+"/json", nil, nil, &res, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// tagImage tags the image as repo:tag.
+func (c *Client) tagImage(ctx context.Context, name, repo, tag string) error {
+	q := url.Values{"repo": {repo}, "tag": {tag}}
+	return c.doJSON(ctx, http.MethodPost, "images/"+pathRef(name)+"/tag", q, nil, nil, http.StatusCreated)
+}
+
+// getArchive returns a tar archive of the given path in the container. The
+// caller has to close the returned reader.
+func (c *Client) getArchive(ctx context.Context, id, path string) (io.ReadCloser, error) {
+	r, err := c.streamHeader(ctx, http.MethodGet, "containers/"+pathName(id)+"/archive", url.Values{"path": {path}}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(r, http.StatusOK); err != nil {
+		drainClose(r.Body)
+		return nil, err
+	}
+	return r.Body, nil
+}
+
+// putArchive extracts the tar archive into the directory path of the
+// container.
+func (c *Client) putArchive(ctx context.Context, id, path string, archive io.Reader) error {
+	header := http.Header{"Content-Type": {"application/x-tar"}}
+	r, err := c.streamHeader(ctx, http.MethodPut, "containers/"+pathName(id)+"/archive", url.Values{"path": {path}},
+		archive, header)
+	if err != nil {
+		return err
+	}
+	defer drainClose(r.Body)
+	return checkResponse(r, http.StatusOK)
+}
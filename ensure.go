@@ -0,0 +1,176 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+)
+
+// EnsureContainer makes sure a container matching the spec exists and runs
+// and returns its ID. An existing container with the same name is reused if
+// its image, command and mounts match the spec, otherwise it is replaced.
+// Containers created by EnsureContainer or Apply are compared by their
+// LabelConfigHash label instead, which covers the complete spec. A matching
+// container created concurrently under the same name is adopted.
+func (c *Client) EnsureContainer(ctx context.Context, spec ContainerSpec) (string, error) {
+	if spec.Name == "" || spec.Image == "" {
+		return "", fmt.Errorf("ensure container: name and image must not be empty")
+	}
+	body, err := spec.createBody()
+	if err != nil {
+		return "", fmt.Errorf("ensure container %s: %w", spec.Name, err)
+	}
+
+	cur, err := c.inspectContainer(ctx, spec.Name)
+	switch {
+	case isNotFound(err):
+	case err != nil:
+		return "", fmt.Errorf("inspect container %s: %w", spec.Name, err)
+	case drifted(cur, spec):
+		if err := c.removeContainer(ctx, cur.ID, true); err != nil && !isNotFound(err) {
+			return "", fmt.Errorf("remove changed container %s: %w", spec.Name, err)
+		}
+	default:
+		return c.adoptContainer(ctx, cur, spec)
+	}
+
+	body.Labels[LabelConfigHash] = spec.hash()
+	res, err := c.createContainer(ctx, spec.Name, body)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict {
+		// created concurrently, e.g. by another EnsureContainer
+		cur, ierr := c.inspectContainer(ctx, spec.Name)
+		if ierr == nil && !drifted(cur, spec) {
+			return c.adoptContainer(ctx, cur, spec)
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("create container %s: %w", spec.Name, err)
+	}
+	for i, a := range spec.Networks {
+		if i == 0 {
+			continue
+		}
+		if err := c.connectNetwork(ctx, a.Network, res.ID, a.endpointConfig()); err != nil {
+			// the container has the hash of the spec, it would be reused
+			// without the network
+			if rerr := c.removeContainer(ctx, res.ID, true); rerr != nil && !isNotFound(rerr) {
+				return res.ID, fmt.Errorf("connect container %s to %s: %w (remove container: %v)",
+					spec.Name, a.Network, err, rerr)
+			}
+			return "", fmt.Errorf("connect container %s to %s: %w", spec.Name, a.Network, err)
+		}
+	}
+	if err := c.startContainer(ctx, res.ID); err != nil {
+		return res.ID, fmt.Errorf("start container %s: %w", spec.Name, err)
+	}
+	return res.ID, nil
+}
+
+// adoptContainer starts the existing container matching the spec, unless it
+// runs already, and returns its ID.
+func (c *Client) adoptContainer(ctx context.Context, cur *containerJSON, spec ContainerSpec) (string, error) {
+	if !cur.State.Running {
+		if err := c.startContainer(ctx, cur.ID); err != nil {
+			return "", fmt.Errorf("start container %s: %w", spec.Name, err)
+		}
+	}
+	return cur.ID, nil
+}
+
+// drifted reports whether the container does not match the spec anymore.
+func drifted(cur *containerJSON, spec ContainerSpec) bool {
+	if hash, ok := cur.Config.Labels[LabelConfigHash]; ok {
+		return hash != spec.hash()
+	}
+	if cur.Config.Image != spec.Image {
+		return true
+	}
+	if len(spec.Cmd) > 0 && !reflect.DeepEqual(cur.Config.Cmd, spec.Cmd) {
+		return true
+	}
+
+	var want []string
+	targets := make(map[string]bool)
+	for _, m := range spec.Mounts {
+		mnt, err := parseMount(m)
+		if err != nil {
+			return true
+		}
+		want = append(want, fmt.Sprintf("%s:%s:%s:%t", mnt.Type, mnt.Source, mnt.Target, mnt.ReadOnly))
+		targets[mnt.Target] = true
+	}
+	var got []string
+	for _, m := range cur.Mounts {
+		// anonymous volumes of the VOLUMEs of the image
+		if m.Type == "volume" && !targets[m.Destination] {
+			continue
+		}
+		src := m.Source
+		if m.Type == "volume" {
+			src = m.Name
+		}
+		got = append(got, fmt.Sprintf("%s:%s:%s:%t", m.Type, src, m.Destination, !m.RW))
+	}
+	sort.Strings(want)
+	sort.Strings(got)
+	return !reflect.DeepEqual(want, got)
+}
+
+// EnsureNetwork makes sure a network with the given name exists and returns
+// its ID. An existing network is validated against the options: if its
+// driver, subnet, gateway or internal flag differ an error is returned,
+// because the network may still be in use.
+func (c *Client) EnsureNetwork(ctx context.Context, name string, opts ...NetworkOption) (string, error) {
+	spec := NetworkSpec{Name: name}
+	for _, opt := range opts {
+		opt(&spec)
+	}
+	body := spec.createBody()
+
+	nws, err := c.listNetworks(ctx, map[string][]string{"name": {name}})
+	if err != nil {
+		return "", fmt.Errorf("list networks: %w", err)
+	}
+	for _, n := range nws {
+		if n.Name != name {
+			continue
+		}
+		if err := matchNetwork(&n, body); err != nil {
+			return "", fmt.Errorf("network %s exists with different configuration: %v", name, err)
+		}
+		return n.ID, nil
+	}
+
+	res, err := c.createNetwork(ctx, body)
+	if err != nil {
+		return "", fmt.Errorf("create network %s: %w", name, err)
+	}
+	return res.ID, nil
+}
+
+func matchNetwork(n *networkSummary, want *networkCreate) error {
+	if n.Driver != want.Driver {
+		return fmt.Errorf("driver %s, want %s", n.Driver, want.Driver)
+	}
+	if n.Internal != want.Internal {
+		return fmt.Errorf("internal %t, want %t", n.Internal, want.Internal)
+	}
+	if want.IPAM == nil {
+		return nil
+	}
+	cfg := want.IPAM.Config[0]
+	for _, have := range n.IPAM.Config {
+		if have.Subnet != cfg["Subnet"] {
+			continue
+		}
+		if gw, ok := cfg["Gateway"]; ok && have.Gateway != gw {
+			return fmt.Errorf("gateway %s, want %s", have.Gateway, gw)
+		}
+		return nil
+	}
+	return fmt.Errorf("subnet %s is not configured", cfg["Subnet"])
+}
@@ -0,0 +1,175 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func Test_EnsureContainer(t *testing.T) {
+	spec := ContainerSpec{Name: "plc", Image: "plc:latest", Mounts: []string{"data:/data"}}
+
+	tt := []struct {
+		name   string
+		routes map[string]mockResponse
+		expect []string
+		id     string
+	}{
+		{
+			name: "create",
+			routes: map[string]mockResponse{
+				"POST /containers/create":   {StatusCode: http.StatusCreated, Body: `{"Id": "c1"}`},
+				"POST /containers/c1/start": {StatusCode: http.StatusNoContent},
+			},
+			expect: []string{"GET /containers/plc/json", "POST /containers/create", "POST /containers/c1/start"},
+			id:     "c1",
+		},
+		{
+			name: "matching",
+			routes: map[string]mockResponse{
+				"GET /containers/plc/json": {Body: `{"Id": "c1", "State": {"Running": true},
+					"Config": {"Image": "plc:latest", "Cmd": ["run"]},
+					"Mounts": [{"Type": "volume", "Name": "data", "Destination": "/data", "RW": true},
+						{"Type": "volume", "Name": "4f2a9c", "Destination": "/var/log", "RW": true}]}`},
+			},
+			expect: []string{"GET /containers/plc/json"},
+			id:     "c1",
+		},
+		{
+			name: "drifted",
+			routes: map[string]mockResponse{
+				"GET /containers/plc/json":  {Body: `{"Id": "c1", "Config": {"Image": "plc:old"}}`},
+				"DELETE /containers/c1":     {StatusCode: http.StatusNoContent},
+				"POST /containers/create":   {StatusCode: http.StatusCreated, Body: `{"Id": "c2"}`},
+				"POST /containers/c2/start": {StatusCode: http.StatusNoContent},
+			},
+			expect: []string{
+				"GET /containers/plc/json",
+				"DELETE /containers/c1",
+				"POST /containers/create",
+				"POST /containers/c2/start",
+			},
+			id: "c2",
+		},
+		{
+			name: "created concurrently",
+			routes: map[string]mockResponse{
+				"GET /containers/plc/json": {StatusCode: http.StatusNotFound, Body: `{"message": "No such container: plc"}`,
+					Next: &mockResponse{Body: fmt.Sprintf(`{"Id": "c3", "State": {"Running": true},
+						"Config": {"Image": "plc:latest", "Labels": {%q: %q}}}`, LabelConfigHash, spec.hash())}},
+				"POST /containers/create": {StatusCode: http.StatusConflict,
+					Body: `{"message": "Conflict. The container name \"/plc\" is already in use by container \"c3\"."}`},
+			},
+			expect: []string{"GET /containers/plc/json", "POST /containers/create", "GET /containers/plc/json"},
+			id:     "c3",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			srv.route(tc.routes)
+			defer srv.route(nil)
+
+			id, err := client.EnsureContainer(context.Background(), spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != tc.id {
+				t.Errorf("got: %s, want: %s", id, tc.id)
+			}
+			if got := srv.Requests(); !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("got: %v, want: %v", got, tc.expect)
+			}
+		})
+	}
+}
+
+func Test_EnsureContainerConnectFailed(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"POST /containers/create":         {StatusCode: http.StatusCreated, Body: `{"Id": "c1"}`},
+		"POST /networks/backhaul/connect": {StatusCode: http.StatusNotFound, Body: `{"message": "network backhaul not found"}`},
+		"DELETE /containers/c1":           {StatusCode: http.StatusNoContent},
+	})
+	defer srv.route(nil)
+
+	spec := ContainerSpec{Name: "plc", Image: "plc:latest",
+		Networks: []NetworkAttachment{{Network: "field"}, {Network: "backhaul"}}}
+	id, err := client.EnsureContainer(context.Background(), spec)
+	if !isNotFound(err) || id != "" {
+		t.Fatalf("expected not found without ID, got %q, %v", id, err)
+	}
+	// the container is removed, it would be reused without the network
+	expect := []string{
+		"GET /containers/plc/json",
+		"POST /containers/create",
+		"POST /networks/backhaul/connect",
+		"DELETE /containers/c1",
+	}
+	if got := srv.Requests(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("got: %v, want: %v", got, expect)
+	}
+}
+
+func Test_EnsureNetwork(t *testing.T) {
+	tt := []struct {
+		name    string
+		routes  map[string]mockResponse
+		opts    []NetworkOption
+		expect  string
+		wantErr bool
+	}{
+		{
+			name: "create",
+			routes: map[string]mockResponse{
+				"GET /networks":         {Body: `[{"Id": "n0", "Name": "subnet-2"}]`},
+				"POST /networks/create": {StatusCode: http.StatusCreated, Body: `{"Id": "n1"}`},
+			},
+			expect: "n1",
+		},
+		{
+			name: "existing",
+			routes: map[string]mockResponse{
+				"GET /networks": {Body: `[{"Id": "n1", "Name": "subnet", "Driver": "bridge",
+					"IPAM": {"Config": [{"Subnet": "172.28.0.0/16"}]}}]`},
+			},
+			opts:   []NetworkOption{WithSubnet("172.28.0.0/16")},
+			expect: "n1",
+		},
+		{
+			name: "subnet mismatch",
+			routes: map[string]mockResponse{
+				"GET /networks": {Body: `[{"Id": "n1", "Name": "subnet", "Driver": "bridge",
+					"IPAM": {"Config": [{"Subnet": "172.29.0.0/16"}]}}]`},
+			},
+			opts:    []NetworkOption{WithSubnet("172.28.0.0/16")},
+			wantErr: true,
+		},
+		{
+			name: "driver mismatch",
+			routes: map[string]mockResponse{
+				"GET /networks": {Body: `[{"Id": "n1", "Name": "subnet", "Driver": "overlay"}]`},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			srv.route(tc.routes)
+			defer srv.route(nil)
+
+			id, err := client.EnsureNetwork(context.Background(), "subnet", tc.opts...)
+			if err != nil && !tc.wantErr {
+				t.Error(err)
+			}
+			if err == nil && tc.wantErr {
+				t.Error("expected error")
+			}
+			if id != tc.expect {
+				t.Errorf("got: %s, want: %s", id, tc.expect)
+			}
+		})
+	}
+}
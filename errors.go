@@ -0,0 +1,157 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// APIError is returned if the daemon answers with an unexpected status
+// code. Use errors.As to inspect it and IsRetryable to decide whether the
+// failed call is worth repeating.
+type APIError struct {
+	// StatusCode is the status code of the response, Want the one expected.
+	StatusCode int
+	Want       int
+	// Message is the error message sent by dockerd.
+	Message string
+	// Method and Path are the failed request, the path is relative to the
+	// API, e.g. "containers/sim-1/start".
+	Method string
+	Path   string
+	// Resource is the kind of the resource addressed by the path, e.g.
+	// "container", and ID its name or ID. ID is empty for requests on a
+	// collection like "containers/create".
+	Resource string
+	ID       string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("invalid response code want=%d, got=%d", e.Want, e.StatusCode)
+	}
+	return fmt.Sprintf("invalid response code want=%d, got=%d: %s",
+		e.Want, e.StatusCode, e.Message)
+}
+
+// transientServerErrors are parts of the messages of internal server
+// errors which are caused by the environment of the daemon, e.g. a
+// registry which is not reachable for the moment, and not by the request.
+var transientServerErrors = []string{
+	"connection refused",
+	"connection reset by peer",
+	"i/o timeout",
+	"TLS handshake timeout",
+	"toomanyrequests",
+	"device or resource busy",
+}
+
+// transientConflicts are the conflicts of DefaultTransientConflicts which
+// resolve themselves for sure. A name in use is left out, it is transient
+// only if the container holding it is being removed, which its message does
+// not tell.
+var transientConflicts = []string{
+	"already in progress",
+	"has active endpoints",
+}
+
+// Retryable reports whether repeating the request may succeed: timeouts,
+// rate limits, an unavailable daemon or registry, a removal in progress, a
+// network whose containers are being removed and internal errors caused by
+// the network. All other errors are permanent, e.g. a missing resource, an
+// invalid configuration or a name in use by another container.
+func (e *APIError) Retryable() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusConflict:
+		return containsAny(e.Message, transientConflicts)
+	case http.StatusInternalServerError:
+		return containsAny(e.Message, transientServerErrors)
+	}
+	return false
+}
+
+// IsRetryable classifies an error returned by the client: errors of the
+// daemon are classified by APIError.Retryable. Timeouts, refused and reset
+// connections, e.g. of a restarting daemon, an open circuit breaker and
+// rate limited pulls are retryable. Canceled contexts, invalid names, other
+// network errors like a missing socket, denied access or an invalid
+// certificate and all other errors are permanent.
+func IsRetryable(err error) bool {
+	var (
+		apiErr *APIError
+		netErr net.Error
+	)
+	switch {
+	case err == nil, errors.Is(err, context.Canceled), errors.Is(err, ErrInvalidName):
+		return false
+	case errors.As(err, &apiErr):
+		return apiErr.Retryable()
+	case errors.Is(err, ErrDaemonUnavailable), errors.Is(err, ErrRateLimited):
+		return true
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET):
+		return true
+	case errors.As(err, &netErr):
+		return netErr.Timeout()
+	}
+	return false
+}
+
+func containsAny(s string, parts []string) bool {
+	for _, p := range parts {
+		if strings.Contains(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectionActions are the endpoints of a resource kind, which are not
+// followed by a name or ID.
+var collectionActions = map[string]bool{
+	"create": true, "json": true, "prune": true, "load": true, "search": true, "privileges": true, "pull": true,
+}
+
+// refActions are the actions following the references of images and
+// plugins, which contain slashes themselves.
+var refActions = map[string]bool{
+	"json": true, "tag": true, "push": true, "history": true, "get": true,
+	"set": true, "enable": true, "disable": true, "upgrade": true,
+}
+
+// parseAPIPath returns the path of the request relative to the API and the
+// resource it addresses, e.g. "container" and "sim-1" for
+// /containers/sim-1/start.
+func parseAPIPath(u *url.URL) (path, resource, id string) {
+	segs := strings.Split(strings.TrimPrefix(u.EscapedPath(), "/"), "/")
+	for i, s := range segs {
+		if v, err := url.PathUnescape(s); err == nil {
+			segs[i] = v
+		}
+	}
+	path = strings.Join(segs, "/")
+	if len(segs) < 2 || len(segs) == 2 && collectionActions[segs[1]] {
+		return path, "", ""
+	}
+	kind := segs[0]
+	switch kind {
+	case "images", "plugins":
+		ref := segs[1:]
+		if len(ref) > 1 && refActions[ref[len(ref)-1]] {
+			ref = ref[:len(ref)-1]
+		}
+		return path, strings.TrimSuffix(kind, "s"), strings.Join(ref, "/")
+	case "containers", "networks", "volumes", "services", "tasks", "nodes", "secrets", "configs":
+		return path, strings.TrimSuffix(kind, "s"), segs[1]
+	case "exec":
+		return path, kind, segs[1]
+	}
+	return path, "", ""
+}
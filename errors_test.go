@@ -0,0 +1,109 @@
+package docker
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func Test_APIError(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"POST /containers/sim-1/start":            {StatusCode: http.StatusConflict, Body: `{"message": "removal of container sim-1 is already in progress"}`},
+		"POST /containers/sim-2/start":            {StatusCode: http.StatusInternalServerError, Body: `{"message": "invalid mount config"}`},
+		"GET /images/registry:5000/sim/ap:1/json": {StatusCode: http.StatusNotFound, Body: `{"message": "No such image"}`},
+		"DELETE /networks/n1":                     {StatusCode: http.StatusServiceUnavailable},
+		"POST /containers/create":                 {StatusCode: http.StatusBadRequest, Body: `{"message": "invalid reference format"}`},
+	})
+	defer srv.route(nil)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		want APIError
+	}{
+		{
+			name: "transient conflict",
+			call: func() error { return client.startContainer(ctx, "/sim-1") },
+			want: APIError{StatusCode: 409, Want: 204, Message: "removal of container sim-1 is already in progress",
+				Method: "POST", Path: "containers/sim-1/start", Resource: "container", ID: "sim-1"},
+		},
+		{
+			name: "server error",
+			call: func() error { return client.StartContainer("sim-2") },
+			want: APIError{StatusCode: 500, Want: 204, Message: "invalid mount config",
+				Method: "POST", Path: "containers/sim-2/start", Resource: "container", ID: "sim-2"},
+		},
+		{
+			name: "image",
+			call: func() error { _, err := client.inspectImage(ctx, "registry:5000/sim/ap:1"); return err },
+			want: APIError{StatusCode: 404, Want: 200, Message: "No such image",
+				Method: "GET", Path: "images/registry:5000/sim/ap:1/json", Resource: "image", ID: "registry:5000/sim/ap:1"},
+		},
+		{
+			name: "unavailable",
+			call: func() error { return client.DeleteNetwork("n1") },
+			want: APIError{StatusCode: 503, Want: 204, Method: "DELETE", Path: "networks/n1", Resource: "network", ID: "n1"},
+		},
+		{
+			name: "collection",
+			call: func() error { _, err := client.createContainer(ctx, "", &containerCreate{Image: "Sim"}); return err },
+			want: APIError{StatusCode: 400, Want: 201, Message: "invalid reference format",
+				Method: "POST", Path: "containers/create"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var e *APIError
+			if err := tc.call(); !errors.As(fmt.Errorf("wrapped: %w", err), &e) {
+				t.Fatalf("got %v, want *APIError", err)
+			}
+			if *e != tc.want {
+				t.Errorf("got %+v, want %+v", *e, tc.want)
+			}
+		})
+	}
+}
+
+func Test_IsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"transient conflict", &APIError{StatusCode: 409, Message: "network n1 has active endpoints"}, true},
+		{"conflict", &APIError{StatusCode: 409, Message: "container is running"}, false},
+		{"name conflict", &APIError{StatusCode: 409, Message: `Conflict. The container name "/plc" is already in use by container "c1".`}, false},
+		{"not found", &APIError{StatusCode: 404}, false},
+		{"bad request", &APIError{StatusCode: 400}, false},
+		{"rate limit", &APIError{StatusCode: 429}, true},
+		{"unavailable", &APIError{StatusCode: 503}, true},
+		{"registry down", &APIError{StatusCode: 500, Message: "Get https://registry/v2/: dial tcp: i/o timeout"}, true},
+		{"server error", &APIError{StatusCode: 500, Message: "invalid mount config"}, false},
+		{"wrapped", fmt.Errorf("start sim-1: %w", &APIError{StatusCode: 504}), true},
+		{"connection", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, true},
+		{"timeout", &url.Error{Op: "Get", Err: context.DeadlineExceeded}, true},
+		{"missing socket", &SocketError{Err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ENOENT)}}, false},
+		{"denied", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.EACCES)}, false},
+		{"certificate", &url.Error{Op: "Get", Err: x509.UnknownAuthorityError{}}, false},
+		{"breaker", fmt.Errorf("start: %w", ErrDaemonUnavailable), true},
+		{"canceled", fmt.Errorf("start: %w", context.Canceled), false},
+		{"invalid name", fmt.Errorf("start: %w", ErrInvalidName), false},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsRetryable(tc.err); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
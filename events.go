@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Event is an event of the daemon, e.g. a container which was started.
+// docs.: https://docs.docker.com/engine/api/v1.41/#operation/SystemEvents
+type Event struct {
+	// Type is the type of the object, e.g. container, network or volume.
+	Type string
+	// Action is what happened, e.g. create, start, die or destroy.
+	Action string
+	// ID of the object.
+	ID string
+	// Attributes are the labels of the object and further details, e.g.
+	// the name of a container.
+	Attributes map[string]string
+	Time       time.Time
+}
+
+// EventFilter restricts the events passed by Events. Empty fields do not
+// restrict.
+type EventFilter struct {
+	Types   []string
+	Actions []string
+	// Selector matches the labels of the objects.
+	Selector Selector
+}
+
+func (f EventFilter) filters() map[string][]string {
+	filters := f.Selector.filters()
+	if filters == nil {
+		filters = make(map[string][]string)
+	}
+	if len(f.Types) > 0 {
+		filters["type"] = f.Types
+	}
+	if len(f.Actions) > 0 {
+		filters["event"] = f.Actions
+	}
+	return filters
+}
+
+type eventMessage struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+	TimeNano int64 `json:"timeNano"`
+}
+
+// Events calls fn for every event matching the filter until the context is
+// done or the stream fails. It returns the error of the context in the
+// first case. No timeout of the client applies.
+func (c *Client) Events(ctx context.Context, filter EventFilter, fn func(Event)) error {
+	return c.events(ctx, filter, nil, fn)
+}
+
+// events is Events which calls connected once the daemon accepted the
+// subscription, so no later event is missed.
+func (c *Client) events(ctx context.Context, filter EventFilter, connected func(), fn func(Event)) error {
+	r, err := c.stream(ctx, "events", filterQuery(filter.filters()))
+	if err != nil {
+		return fmt.Errorf("events: %w", err)
+	}
+	defer r.Body.Close()
+	if err := checkResponse(r, http.StatusOK); err != nil {
+		return fmt.Errorf("events: %w", err)
+	}
+	if connected != nil {
+		connected()
+	}
+
+	dec := json.NewDecoder(r.Body)
+	for {
+		var msg eventMessage
+		if err := dec.Decode(&msg); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("events: %w", err)
+		}
+		fn(Event{
+			Type:       msg.Type,
+			Action:     msg.Action,
+			ID:         msg.Actor.ID,
+			Attributes: msg.Actor.Attributes,
+			Time:       time.Unix(0, msg.TimeNano),
+		})
+	}
+}
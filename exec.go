@@ -0,0 +1,64 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type execCreate struct {
+	Cmd          []string `json:"Cmd"`
+	AttachStdout bool     `json:"AttachStdout"`
+	AttachStderr bool     `json:"AttachStderr"`
+}
+
+type execInspect struct {
+	ID       string `json:"ID"`
+	Running  bool   `json:"Running"`
+	ExitCode int    `json:"ExitCode"`
+}
+
+// Exec runs the command in the running container and returns its exit code
+// and output. A non zero exit code is not treated as error.
+// docs.: https://docs.docker.com/engine/api/v1.36/#operation/ContainerExec
+func (c *Client) Exec(ctx context.Context, id string, cmd []string) (*CaptureResult, error) {
+	if len(cmd) == 0 {
+		return nil, fmt.Errorf("exec: command must not be empty")
+	}
+	var created createResponse
+	body := &execCreate{Cmd: cmd, AttachStdout: true, AttachStderr: true}
+	if err := c.doJSON(ctx, http.MethodPost, "containers/"+pathName(id)+"/exec", nil, body, &created, http.StatusCreated); err != nil {
+		return nil, fmt.Errorf("exec in %s: %w", id, err)
+	}
+
+	// the output is streamed multiplexed until the command exits
+	header := http.Header{"Content-Type": {"application/json"}}
+	r, err := c.streamHeader(ctx, http.MethodPost, "exec/"+pathName(created.ID)+"/start", nil,
+		strings.NewReader(`{"Detach": false}`), header)
+	if err != nil {
+		return nil, fmt.Errorf("exec in %s: %w", id, err)
+	}
+	defer drainClose(r.Body)
+	if err := checkResponse(r, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("exec in %s: %w", id, err)
+	}
+	var stdout, stderr bytes.Buffer
+	if err := demuxStream(r.Body, &stdout, &stderr); err != nil {
+		return nil, fmt.Errorf("exec in %s: %w", id, err)
+	}
+
+	var state execInspect
+	if err := c.doJSON(ctx, http.MethodGet, "exec/"+pathName(created.ID)+"/json", nil, nil, &state, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("exec in %s: %w", id, err)
+	}
+	if state.Running {
+		return nil, fmt.Errorf("exec in %s: command still running after its output ended", id)
+	}
+	return &CaptureResult{
+		ExitCode: state.ExitCode,
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+	}, nil
+}
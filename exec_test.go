@@ -0,0 +1,27 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func Test_Exec(t *testing.T) {
+	out := string([]byte{1, 0, 0, 0, 0, 0, 0, 15}) + "accepting conns"
+	srv.route(map[string]mockResponse{
+		"POST /containers/db/exec": {StatusCode: http.StatusCreated, Body: `{"Id": "e1"}`},
+		"POST /exec/e1/start":      {Body: out},
+		"GET /exec/e1/json":        {Body: `{"ID": "e1", "Running": false, "ExitCode": 0}`},
+	})
+	defer srv.route(nil)
+
+	res, err := client.Exec(context.Background(), "db", []string{"pg_isready"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := &CaptureResult{Stdout: []byte("accepting conns")}
+	if !reflect.DeepEqual(res, expect) {
+		t.Errorf("got: %+v, want: %+v", res, expect)
+	}
+}
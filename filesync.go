@@ -0,0 +1,267 @@
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Packet types of the file sync protocol of BuildKit.
+// docs.: https://github.com/tonistiigi/fsutil/blob/master/types/wire.proto
+const (
+	packetStat = 0
+	packetReq  = 1
+	packetData = 2
+	packetFin  = 3
+	packetErr  = 4
+)
+
+// syncedFile is a file offered to BuildKit.
+type syncedFile struct {
+	path string
+	name string
+	info os.FileInfo
+}
+
+// addFileSync serves the directories to BuildKit by name. BuildKit compares
+// the offered files with its copy of the directory from earlier builds with
+// the same shared key and only requests the files which changed. The
+// .dockerignore file excludes files of the directory named context.
+func (s *buildSession) addFileSync(dirs map[string]string) {
+	s.methods["/moby.filesync.v1.FileSync/DiffCopy"] = func(w http.ResponseWriter, r *http.Request) {
+		name := r.Header.Get("dir-name")
+		dir, ok := dirs[name]
+		if !ok {
+			writeGRPCStatus(w, grpcNotFound, "no directory "+name)
+			return
+		}
+		var ignore dockerignore
+		if name == "context" {
+			var err error
+			if ignore, err = readDockerignore(dir); err != nil {
+				writeGRPCStatus(w, grpcInternal, err.Error())
+				return
+			}
+		}
+		ignore = append(ignore, r.Header["Exclude-Patterns"]...)
+		files, err := walkSynced(dir, ignore, r.Header["Include-Patterns"])
+		if err != nil {
+			writeGRPCStatus(w, grpcInternal, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.WriteHeader(http.StatusOK)
+		stream := &grpcStream{w: w}
+		if err := sendFiles(r.Body, stream, files); err != nil {
+			writeGRPCStatus(w, grpcInternal, err.Error())
+			return
+		}
+		writeGRPCStatus(w, grpcOK, "")
+	}
+}
+
+// walkSynced lists the files of dir in the order of filepath.Walk which
+// BuildKit expects. If includes are given, only matching files and their
+// parents are listed.
+func walkSynced(dir string, ignore dockerignore, includes []string) ([]syncedFile, error) {
+	var files []syncedFile
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if ignore.excludes(rel) || !included(rel, info.IsDir(), includes) {
+			if info.IsDir() && !ignore.hasExceptions() && len(includes) == 0 {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		files = append(files, syncedFile{path: p, name: rel, info: info})
+		return nil
+	})
+	return files, err
+}
+
+// included reports whether the file matches one of the patterns. A directory
+// is included as well if a pattern may match files within it.
+func included(name string, dir bool, includes []string) bool {
+	if len(includes) == 0 {
+		return true
+	}
+	for _, p := range includes {
+		if matchParents(p, name) || dir && strings.HasPrefix(p, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// sendFiles offers the files by stat packets and sends the content of the
+// requested ones until BuildKit finishes the transfer.
+func sendFiles(in io.Reader, out *grpcStream, files []syncedFile) error {
+	errc := make(chan error, 1)
+	go func() {
+		for _, f := range files {
+			if err := out.send(encodePacket(packetStat, statMessage(f), 0, nil)); err != nil {
+				errc <- err
+				return
+			}
+		}
+		// an empty stat ends the listing
+		errc <- out.send(encodePacket(packetStat, nil, 0, nil))
+	}()
+
+	err := receiveRequests(in, out, files)
+	if werr := <-errc; err == nil {
+		err = werr
+	}
+	if err != nil {
+		return err
+	}
+	return out.send(encodePacket(packetFin, nil, 0, nil))
+}
+
+// receiveRequests answers the requests for files until BuildKit sends fin.
+func receiveRequests(in io.Reader, out *grpcStream, files []syncedFile) error {
+	for {
+		msg, err := readGRPCMessage(in)
+		if err != nil {
+			return fmt.Errorf("file sync: %w", err)
+		}
+		typ, id, data, err := decodePacket(msg)
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case packetReq:
+			if int(id) >= len(files) {
+				return fmt.Errorf("file sync: invalid file %d requested", id)
+			}
+			if err := sendFileData(out, id, files[id].path); err != nil {
+				return err
+			}
+		case packetFin:
+			return nil
+		case packetErr:
+			return fmt.Errorf("file sync: %s", data)
+		}
+	}
+}
+
+// sendFileData sends the content of the file in data packets, followed by
+// an empty one.
+func sendFileData(out *grpcStream, id uint32, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			if err := out.send(encodePacket(packetData, nil, id, buf[:n])); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+	return out.send(encodePacket(packetData, nil, id, nil))
+}
+
+// statMessage encodes the Stat message of the file. The owner is reset,
+// like the docker CLI does for build contexts.
+func statMessage(f syncedFile) []byte {
+	var b []byte
+	b = protoBytes(b, 1, []byte(f.name))
+	b = protoVarint(b, 2, uint64(uint32(f.info.Mode())))
+	if f.info.Mode().IsRegular() {
+		b = protoVarint(b, 5, uint64(f.info.Size()))
+	}
+	b = protoVarint(b, 6, uint64(f.info.ModTime().UnixNano()))
+	if f.info.Mode()&os.ModeSymlink != 0 {
+		if link, err := os.Readlink(f.path); err == nil {
+			b = protoBytes(b, 7, []byte(link))
+		}
+	}
+	return b
+}
+
+// encodePacket encodes Packet{Type = 1, Stat = 2, ID = 3, Data = 4}.
+func encodePacket(typ int, stat []byte, id uint32, data []byte) []byte {
+	var b []byte
+	if typ != 0 {
+		b = protoVarint(b, 1, uint64(typ))
+	}
+	if stat != nil {
+		b = protoBytes(b, 2, stat)
+	}
+	if id != 0 {
+		b = protoVarint(b, 3, uint64(id))
+	}
+	if len(data) > 0 {
+		b = protoBytes(b, 4, data)
+	}
+	return b
+}
+
+func decodePacket(msg []byte) (typ int, id uint32, data []byte, err error) {
+	fields, err := parseProto(msg)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	for _, f := range fields {
+		switch f.Num {
+		case 1:
+			typ = int(f.Varint)
+		case 3:
+			id = uint32(f.Varint)
+		case 4:
+			data = f.Bytes
+		}
+	}
+	return typ, id, data, nil
+}
+
+// grpcStream writes the messages of a streaming response.
+type grpcStream struct {
+	mu sync.Mutex
+	w  http.ResponseWriter
+}
+
+func (s *grpcStream) send(msg []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(grpcFrame(msg)); err != nil {
+		return err
+	}
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// contextSharedKey identifies the directory across builds, so BuildKit
+// reuses its copy of the earlier transferred context.
+func contextSharedKey(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	sum := sha256.Sum256([]byte(dir))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,91 @@
+//go:build go1.24
+// +build go1.24
+
+package docker
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func Test_BuildContextDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "context")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"Dockerfile":    "FROM alpine\nCOPY . /sim\n",
+		".dockerignore": "*.log\n",
+		"sim/main.go":   "package main",
+		"debug.log":     "log",
+	})
+
+	var names []string
+	var content string
+	buildWithSession(t, BuildOptions{ContextDir: dir}, func(rt http.RoundTripper) {
+		pr, pw := io.Pipe()
+		req, _ := http.NewRequest(http.MethodPost, "http://session/moby.filesync.v1.FileSync/DiffCopy", pr)
+		req.Header.Set("Content-Type", "application/grpc")
+		req.Header.Set("dir-name", "context")
+		r, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer r.Body.Close()
+
+		// the listing ends with an empty stat
+		for {
+			msg, err := readGRPCMessage(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			fields, _ := parseProto(msg)
+			if len(fields) == 0 {
+				break
+			}
+			name, _ := protoString(fields[0].Bytes, 1)
+			names = append(names, name)
+		}
+
+		// request sim/main.go only, as if everything else was unchanged
+		pw.Write(grpcFrame(encodePacket(packetReq, nil, 3, nil)))
+		for {
+			msg, err := readGRPCMessage(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			typ, id, data, _ := decodePacket(msg)
+			if typ != packetData || id != 3 {
+				t.Errorf("unexpected packet %d for %d", typ, id)
+			}
+			if len(data) == 0 {
+				break
+			}
+			content += string(data)
+		}
+
+		pw.Write(grpcFrame(encodePacket(packetFin, nil, 0, nil)))
+		msg, err := readGRPCMessage(r.Body)
+		if typ, _, _, _ := decodePacket(msg); err != nil || typ != packetFin {
+			t.Errorf("expected fin, got %d, %v", typ, err)
+		}
+		pw.Close()
+		ioutil.ReadAll(r.Body)
+	})
+
+	want := []string{".dockerignore", "Dockerfile", "sim", "sim/main.go"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("want files %v, got %v", want, names)
+	}
+	if content != "package main" {
+		t.Errorf("unexpected content %q", content)
+	}
+}
@@ -0,0 +1,150 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// hijackedConn is a connection the daemon upgraded to a raw stream, e.g. to
+// attach to a container. Reads return the data buffered while reading the
+// response first.
+type hijackedConn struct {
+	net.Conn
+	r    *bufio.Reader
+	once sync.Once
+	// done stops closing the connection with the context.
+	done chan struct{}
+}
+
+func (c *hijackedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// CloseWrite closes the sending side of the connection, e.g. to signal the
+// end of stdin, while the output can still be read.
+func (c *hijackedConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return fmt.Errorf("closing the write side of %T is not supported", c.Conn)
+}
+
+func (c *hijackedConn) Close() error {
+	c.once.Do(func() { close(c.done) })
+	return c.Conn.Close()
+}
+
+// hijack sends a request which the daemon answers by upgrading the
+// connection to the protocol, e.g. "tcp" to attach to the streams of a
+// container. Upgrades require HTTP/1.1, so the request is sent on a
+// connection of its own, dialed like the ones of the transport but without
+// proxy. The connection is closed when the context is done.
+// docs.: https://docs.docker.com/engine/api/v1.41/#operation/ContainerAttach
+func (c *Client) hijack(ctx context.Context, method, path string, query url.Values, body io.Reader, header http.Header, protocol string) (*hijackedConn, error) {
+	endpoint, err := c.endpoint(path, query)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", protocol)
+
+	// the upgrade bypasses the transport, it is in flight until the
+	// connection is handed over
+	_, done := c.inflight.start(method, req.URL)
+	defer done()
+	conn, err := c.dialDaemon(ctx, req.URL)
+	if err != nil {
+		return nil, err
+	}
+	// the context limits the request until the connection is handed over
+	stop, watched := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(watched)
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+	r := bufio.NewReader(conn)
+	err = req.Write(conn)
+	var resp *http.Response
+	if err == nil {
+		resp, err = http.ReadResponse(r, req)
+	}
+	close(stop)
+	<-watched
+	if err == nil {
+		// old daemons answer with 200 and upgrade anyway
+		err = checkResponse(resp, http.StatusSwitchingProtocols, http.StatusOK)
+	}
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	hc := &hijackedConn{Conn: conn, r: r, done: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			hc.Close()
+		case <-hc.done:
+		}
+	}()
+	return hc, nil
+}
+
+// dialDaemon opens a connection to the daemon, with TLS if the client uses
+// it. Only HTTP/1.1 is negotiated.
+func (c *Client) dialDaemon(ctx context.Context, u *url.URL) (net.Conn, error) {
+	addr := u.Host
+	if u.Port() == "" && u.Scheme == "https" {
+		addr = net.JoinHostPort(u.Hostname(), "443")
+	} else if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "80")
+	}
+	conn, err := c.transport.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if c.transport.TLSClientConfig == nil {
+		return conn, nil
+	}
+
+	config := c.transport.TLSClientConfig.Clone()
+	config.NextProtos = []string{"http/1.1"}
+	if config.ServerName == "" {
+		config.ServerName = u.Hostname()
+	}
+	tc := tls.Client(conn, config)
+	deadline := time.Now().Add(dialTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	tc.SetDeadline(deadline)
+	if err := tc.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tc.SetDeadline(time.Time{})
+	return tc, nil
+}
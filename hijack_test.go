@@ -0,0 +1,88 @@
+package docker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// upgradeHandler upgrades /attach to a stream which echoes the input in
+// upper case once the client closed its side.
+func upgradeHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/containers/c1/attach" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Upgrade") != "tcp" || r.ProtoMajor != 1 {
+			t.Errorf("unexpected upgrade %q over %s", r.Header.Get("Upgrade"), r.Proto)
+		}
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 101 UPGRADED\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+		buf.Flush()
+		in, _ := ioutil.ReadAll(buf)
+		conn.Write([]byte(strings.ToUpper(string(in))))
+	})
+}
+
+func Test_hijack(t *testing.T) {
+	plain := httptest.NewServer(upgradeHandler(t))
+	defer plain.Close()
+	secure := httptest.NewUnstartedServer(upgradeHandler(t))
+	secure.TLS = &tls.Config{NextProtos: []string{"h2"}}
+	secure.StartTLS()
+	defer secure.Close()
+
+	c1, err := NewClientHost("tcp://" + strings.TrimPrefix(plain.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(secure.Certificate())
+	c2, err := NewClientTLS("tcp://"+strings.TrimPrefix(secure.URL, "https://"), &tls.Config{RootCAs: pool})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []*Client{c1, c2} {
+		conn, err := c.hijack(context.Background(), http.MethodPost, "containers/c1/attach", nil, nil, nil, "tcp")
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Write([]byte("ping"))
+		if err := conn.CloseWrite(); err != nil {
+			t.Fatal(err)
+		}
+		out, err := ioutil.ReadAll(conn)
+		if err != nil || string(out) != "PING" {
+			t.Errorf("%s: expected PING, got %q, %v", c.base, out, err)
+		}
+		conn.Close()
+
+		if _, err := c.hijack(context.Background(), http.MethodPost, "containers/c2/attach", nil, nil, nil, "tcp"); !isNotFound(err) {
+			t.Errorf("%s: expected not found, got %v", c.base, err)
+		}
+	}
+
+	// the connection is closed with the context
+	ctx, cancel := context.WithCancel(context.Background())
+	conn, err := c1.hijack(ctx, http.MethodPost, "containers/c1/attach", nil, nil, nil, "tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := ioutil.ReadAll(conn); err == nil {
+		t.Error("expected error reading the closed connection")
+	}
+}
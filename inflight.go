@@ -0,0 +1,102 @@
+package docker
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// InflightRequest is a request of the client which has not finished yet.
+type InflightRequest struct {
+	Method string
+	// Path is relative to the API, e.g. "containers/sim-1/wait".
+	Path  string
+	Start time.Time
+	// Responded is true if the daemon sent the response headers and the
+	// body is being read, e.g. of logs or a pull. Otherwise the client
+	// waits for the daemon.
+	Responded bool
+}
+
+// Inflight returns the requests of the client which have been sent and not
+// finished, the oldest first. A request finishes when its response body is
+// closed. Requests which hang around long after their start point at a
+// stuck operation or a body which is never closed.
+func (c *Client) Inflight() []InflightRequest {
+	return c.inflight.snapshot()
+}
+
+// inflightTracker is the outermost RoundTripper of the client, which records
+// the requests from their start until their response body is closed.
+type inflightTracker struct {
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	n        uint64
+	requests map[uint64]*InflightRequest
+}
+
+func newInflightTracker() *inflightTracker {
+	return &inflightTracker{requests: make(map[uint64]*InflightRequest)}
+}
+
+func (t *inflightTracker) RoundTrip(req *http.Request) (*http.Response, error) {
+	id, done := t.start(req.Method, req.URL)
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	t.mu.Lock()
+	if r, ok := t.requests[id]; ok {
+		r.Responded = true
+	}
+	t.mu.Unlock()
+	res.Body = &trackedBody{ReadCloser: res.Body, done: done}
+	return res, nil
+}
+
+// start records a request and returns its ID and the function to call when
+// it is finished, which may be called more than once.
+func (t *inflightTracker) start(method string, u *url.URL) (uint64, func()) {
+	path, _, _ := parseAPIPath(u)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.n++
+	id := t.n
+	t.requests[id] = &InflightRequest{Method: method, Path: path, Start: time.Now()}
+	var once sync.Once
+	return id, func() {
+		once.Do(func() {
+			t.mu.Lock()
+			delete(t.requests, id)
+			t.mu.Unlock()
+		})
+	}
+}
+
+func (t *inflightTracker) snapshot() []InflightRequest {
+	t.mu.Lock()
+	res := make([]InflightRequest, 0, len(t.requests))
+	for _, r := range t.requests {
+		res = append(res, *r)
+	}
+	t.mu.Unlock()
+	sort.Slice(res, func(i, j int) bool { return res[i].Start.Before(res[j].Start) })
+	return res
+}
+
+// trackedBody finishes the request when it is closed.
+type trackedBody struct {
+	io.ReadCloser
+	done func()
+}
+
+func (b *trackedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done()
+	return err
+}
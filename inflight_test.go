@@ -0,0 +1,85 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_Inflight(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /containers/slow/json": {StatusCode: http.StatusOK, Body: `{"Id": "slow"}`, Delay: 200 * time.Millisecond},
+		"GET /containers/c1/logs":   {StatusCode: http.StatusOK, Body: "log"},
+	})
+	defer srv.route(nil)
+	ctx := context.Background()
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := client.inspectContainer(ctx, "slow")
+		errc <- err
+	}()
+	var got []InflightRequest
+	for i := 0; i < 100 && len(got) == 0; i++ {
+		time.Sleep(5 * time.Millisecond)
+		got = client.Inflight()
+	}
+	if len(got) != 1 || got[0].Method != http.MethodGet || got[0].Path != "containers/slow/json" || got[0].Responded {
+		t.Errorf("got %+v, want the waiting inspect request", got)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if got := client.Inflight(); len(got) != 0 {
+		t.Errorf("got %+v after the request finished", got)
+	}
+
+	r, err := client.stream(ctx, "containers/c1/logs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := client.Inflight(); len(got) != 1 || !got[0].Responded {
+		t.Errorf("got %+v, want the responded logs request", got)
+	}
+	r.Body.Close()
+	r.Body.Close()
+	if got := client.Inflight(); len(got) != 0 {
+		t.Errorf("got %+v after the body was closed", got)
+	}
+}
+
+func Test_ClientConcurrentUse(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /containers/c1/json":   {StatusCode: http.StatusOK, Body: `{"Id": "c1"}`, Delay: time.Millisecond},
+		"GET /containers/json":      {StatusCode: http.StatusOK, Body: `[]`},
+		"POST /containers/c1/start": {StatusCode: http.StatusNoContent},
+	})
+	defer srv.route(nil)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if _, err := client.inspectContainer(ctx, "c1"); err != nil {
+					t.Error(err)
+				}
+				if _, err := client.listContainers(ctx, true, nil); err != nil {
+					t.Error(err)
+				}
+				if err := client.StartContainer("c1"); err != nil {
+					t.Error(err)
+				}
+				client.Inflight()
+			}
+		}()
+	}
+	wg.Wait()
+	if got := client.Inflight(); len(got) != 0 {
+		t.Errorf("got %+v after all requests finished", got)
+	}
+}
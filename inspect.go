@@ -0,0 +1,156 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grid-x/docker/types"
+)
+
+// ListContainers lists the containers matching the selector. With all,
+// stopped containers are included.
+func (c *Client) ListContainers(ctx context.Context, selector Selector, all bool) ([]types.ContainerSummary, error) {
+	res, err := c.listContainers(ctx, all, selector.filters())
+	if err != nil {
+		return nil, fmt.Errorf("list containers: %w", err)
+	}
+	return res, nil
+}
+
+// InspectContainer returns the details of the container with the given ID or
+// name.
+func (c *Client) InspectContainer(ctx context.Context, id string) (*types.ContainerJSON, error) {
+	res, err := c.inspectContainer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("inspect container %s: %w", id, err)
+	}
+	return res, nil
+}
+
+// InspectConfig returns the configuration the container with the given ID
+// or name was created with: image, command, entrypoint, environment,
+// exposed ports and labels. Values the container inherited from its image
+// are included.
+func (c *Client) InspectConfig(ctx context.Context, id string) (*types.ContainerConfig, error) {
+	res, err := c.inspectContainer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("inspect container %s: %w", id, err)
+	}
+	return &res.Config, nil
+}
+
+// Env returns the environment of the container with the given ID or name,
+// including the variables set by its image. Later entries of duplicate
+// variables win, like in the container.
+func (c *Client) Env(ctx context.Context, id string) (map[string]string, error) {
+	config, err := c.InspectConfig(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return parseEnv(config.Env), nil
+}
+
+// Mounts returns the mounts of the container with the given ID or name:
+// bind mounts, volumes including the anonymous ones of its image, and
+// tmpfs mounts.
+func (c *Client) Mounts(ctx context.Context, id string) ([]types.MountPoint, error) {
+	res, err := c.inspectContainer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("inspect container %s: %w", id, err)
+	}
+	return res.Mounts, nil
+}
+
+// ContainerAddress is the address of a container on a network. Either IP is
+// empty if the network does not assign one, e.g. IPv6 without enable_ipv6.
+type ContainerAddress struct {
+	IPv4 string
+	IPv6 string
+}
+
+// ContainerIP returns the address of the container with the given ID or
+// name on the network, which is given by name or ID.
+func (c *Client) ContainerIP(ctx context.Context, id, network string) (*ContainerAddress, error) {
+	cj, err := c.inspectContainer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("inspect container %s: %w", id, err)
+	}
+	ep, ok := cj.NetworkSettings.Networks[network]
+	if !ok {
+		for _, e := range cj.NetworkSettings.Networks {
+			if e != nil && e.NetworkID != "" && e.NetworkID == network {
+				ep, ok = e, true
+				break
+			}
+		}
+	}
+	if !ok || ep == nil {
+		return nil, fmt.Errorf("container %s is not attached to network %s", id, network)
+	}
+	return &ContainerAddress{IPv4: ep.IPAddress, IPv6: ep.GlobalIPv6Address}, nil
+}
+
+// ContainerTiming is the restart count of a container and the times of its
+// last start and exit.
+type ContainerTiming struct {
+	// RestartCount counts the restarts by the restart policy of the
+	// daemon, restarts requested by clients are not counted.
+	RestartCount int
+	// StartedAt is the time of the last start, restarts included. It is
+	// zero if the container was never started.
+	StartedAt time.Time
+	// FinishedAt is the time of the last exit, it is kept while a restarted
+	// container runs again. It is zero if the container never exited.
+	FinishedAt time.Time
+}
+
+// ContainerTiming returns the restart count and the times of the last start
+// and exit of the container with the given ID or name, e.g. to detect
+// devices which restart repeatedly.
+func (c *Client) ContainerTiming(ctx context.Context, id string) (*ContainerTiming, error) {
+	cj, err := c.inspectContainer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("inspect container %s: %w", id, err)
+	}
+	return &ContainerTiming{
+		RestartCount: cj.RestartCount,
+		StartedAt:    parseStateTime(cj.State.StartedAt),
+		FinishedAt:   parseStateTime(cj.State.FinishedAt),
+	}, nil
+}
+
+// parseStateTime parses a time of the state of a container. The daemon sends
+// 0001-01-01T00:00:00Z for unset times, which is the zero time.
+func parseStateTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
+
+// ListNetworks lists the networks matching the selector.
+func (c *Client) ListNetworks(ctx context.Context, selector Selector) ([]types.NetworkSummary, error) {
+	res, err := c.listNetworks(ctx, selector.filters())
+	if err != nil {
+		return nil, fmt.Errorf("list networks: %w", err)
+	}
+	return res, nil
+}
+
+// InspectNetwork returns the details of the network with the given ID or
+// name, including the attached containers.
+func (c *Client) InspectNetwork(ctx context.Context, id string) (*types.NetworkSummary, error) {
+	res, err := c.inspectNetwork(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("inspect network %s: %w", id, err)
+	}
+	return res, nil
+}
+
+// ListVolumes lists the volumes matching the selector.
+func (c *Client) ListVolumes(ctx context.Context, selector Selector) ([]types.Volume, error) {
+	res, err := c.listVolumes(ctx, selector.filters())
+	if err != nil {
+		return nil, fmt.Errorf("list volumes: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,172 @@
+package docker
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grid-x/docker/types"
+)
+
+func Test_InspectContainer(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /containers/json?" + filterQuery(RunSelector("r1").filters()).Encode(): {Body: `[{"Id": "c1", "Names": ["/plc"]}]`},
+		"GET /containers/plc/json": {Body: `{"Id": "c1", "Name": "/plc", "State": {"Status": "running", "Running": true,
+			"Health": {"Status": "healthy"}}, "NetworkSettings": {"Networks": {"field": {"IPAddress": "172.28.0.2"}}}}`},
+	})
+	defer srv.route(nil)
+	ctx := context.Background()
+
+	list, err := client.ListContainers(ctx, RunSelector("r1"), false)
+	if err != nil || len(list) != 1 || list[0].Name() != "plc" {
+		t.Errorf("unexpected list %+v, %v", list, err)
+	}
+	cj, err := client.InspectContainer(ctx, "plc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cj.State.Health.Status != "healthy" || cj.NetworkSettings.Networks["field"].IPAddress != "172.28.0.2" {
+		t.Errorf("unexpected container %+v", cj)
+	}
+	if _, err := client.InspectContainer(ctx, "db"); !isNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func Test_InspectConfig(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /containers/plc/json": {Body: `{"Id": "c1", "Name": "/plc", "State": {"Status": "running"},
+			"Config": {"Image": "sim/plc:1.2", "Cmd": ["--port", "502"], "Entrypoint": ["/plc"],
+			"Env": ["PATH=/usr/bin", "MODE=sim"], "ExposedPorts": {"502/tcp": {}},
+			"Labels": {"com.grid-x.run": "r1"}}}`},
+	})
+	defer srv.route(nil)
+
+	config, err := client.InspectConfig(context.Background(), "plc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &types.ContainerConfig{
+		Image:        "sim/plc:1.2",
+		Cmd:          []string{"--port", "502"},
+		Entrypoint:   []string{"/plc"},
+		Env:          []string{"PATH=/usr/bin", "MODE=sim"},
+		ExposedPorts: map[string]struct{}{"502/tcp": {}},
+		Labels:       map[string]string{"com.grid-x.run": "r1"},
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("got: %+v, want: %+v", config, want)
+	}
+	if _, err := client.InspectConfig(context.Background(), "db"); !isNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func Test_Env(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /containers/plc/json": {Body: `{"Id": "c1", "Name": "/plc", "State": {"Status": "running"},
+			"Config": {"Env": ["PATH=/usr/bin", "MODBUS_ADDR=slave=1", "DEBUG", "EMPTY="]}}`},
+	})
+	defer srv.route(nil)
+
+	env, err := client.Env(context.Background(), "plc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"PATH": "/usr/bin", "MODBUS_ADDR": "slave=1", "DEBUG": "", "EMPTY": ""}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("got: %v, want: %v", env, want)
+	}
+	if _, err := client.Env(context.Background(), "db"); !isNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func Test_Mounts(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /containers/plc/json": {Body: `{"Id": "c1", "Name": "/plc", "State": {"Status": "running"}, "Mounts": [
+			{"Type": "bind", "Source": "/srv/plc", "Destination": "/etc/plc", "Mode": "ro", "RW": false, "Propagation": "rprivate"},
+			{"Type": "volume", "Name": "plc-data", "Driver": "local", "Source": "/var/lib/docker/volumes/plc-data/_data",
+				"Destination": "/data", "Mode": "z", "RW": true}]}`},
+	})
+	defer srv.route(nil)
+
+	mounts, err := client.Mounts(context.Background(), "plc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []types.MountPoint{
+		{Type: "bind", Source: "/srv/plc", Destination: "/etc/plc", Mode: "ro", Propagation: "rprivate"},
+		{Type: "volume", Name: "plc-data", Driver: "local", Source: "/var/lib/docker/volumes/plc-data/_data",
+			Destination: "/data", Mode: "z", RW: true},
+	}
+	if !reflect.DeepEqual(mounts, want) {
+		t.Errorf("got: %+v, want: %+v", mounts, want)
+	}
+	if _, err := client.Mounts(context.Background(), "db"); !isNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func Test_ContainerIP(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /containers/plc/json": {Body: `{"Id": "c1", "Name": "/plc", "State": {"Status": "running"},
+			"NetworkSettings": {"Networks": {
+				"field": {"NetworkID": "n1", "IPAddress": "172.28.0.2", "GlobalIPv6Address": "fd00:28::2"},
+				"backend": {"NetworkID": "n2", "IPAddress": "172.29.0.5"}}}}`},
+	})
+	defer srv.route(nil)
+	ctx := context.Background()
+
+	tt := []struct {
+		network string
+		expect  *ContainerAddress
+		wantErr bool
+	}{
+		{network: "field", expect: &ContainerAddress{IPv4: "172.28.0.2", IPv6: "fd00:28::2"}},
+		{network: "n2", expect: &ContainerAddress{IPv4: "172.29.0.5"}},
+		{network: "office", wantErr: true},
+	}
+	for _, tc := range tt {
+		addr, err := client.ContainerIP(ctx, "plc", tc.network)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: unexpected error %v", tc.network, err)
+		}
+		if !reflect.DeepEqual(addr, tc.expect) {
+			t.Errorf("%s: got: %+v, want: %+v", tc.network, addr, tc.expect)
+		}
+	}
+}
+
+func Test_ContainerTiming(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /containers/plc/json": {Body: `{"Id": "c1", "Name": "/plc", "RestartCount": 3, "State": {"Status": "running",
+			"StartedAt": "2021-03-04T10:15:02.123456789Z", "FinishedAt": "2021-03-04T10:15:01.5Z"}}`},
+		"GET /containers/db/json": {Body: `{"Id": "c2", "Name": "/db", "State": {"Status": "created",
+			"StartedAt": "0001-01-01T00:00:00Z", "FinishedAt": "0001-01-01T00:00:00Z"}}`},
+	})
+	defer srv.route(nil)
+	ctx := context.Background()
+
+	timing, err := client.ContainerTiming(ctx, "plc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ContainerTiming{
+		RestartCount: 3,
+		StartedAt:    time.Date(2021, 3, 4, 10, 15, 2, 123456789, time.UTC),
+		FinishedAt:   time.Date(2021, 3, 4, 10, 15, 1, 500000000, time.UTC),
+	}
+	if !reflect.DeepEqual(timing, want) {
+		t.Errorf("got: %+v, want: %+v", timing, want)
+	}
+
+	timing, err = client.ContainerTiming(ctx, "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timing.RestartCount != 0 || !timing.StartedAt.IsZero() || !timing.FinishedAt.IsZero() {
+		t.Errorf("expected zero timing, got %+v", timing)
+	}
+}
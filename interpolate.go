@@ -0,0 +1,164 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// interpolateNode replaces the variables in all scalar values of the node
+// with the syntax of compose files:
+//
+//	$VAR, ${VAR}     the value of VAR, empty if it is unset
+//	${VAR:-default}  default if VAR is unset or empty
+//	${VAR-default}   default if VAR is unset
+//	${VAR:?message}  an error if VAR is unset or empty
+//	${VAR?message}   an error if VAR is unset
+//	${VAR:+other}    other if VAR is set and not empty
+//	${VAR+other}     other if VAR is set
+//	$$               a literal $
+//
+// Defaults may contain variables themselves. Mapping keys are not
+// interpolated.
+func interpolateNode(n *yamlNode, path string, lookup func(string) (string, bool)) error {
+	switch n.kind {
+	case yamlScalar:
+		v, err := interpolate(n.value, lookup)
+		if err != nil {
+			return &schemaError{line: n.line, path: path, msg: err.Error()}
+		}
+		n.value = v
+	case yamlSeq:
+		for i, item := range n.values {
+			if err := interpolateNode(item, fmt.Sprintf("%s[%d]", path, i), lookup); err != nil {
+				return err
+			}
+		}
+	case yamlMap:
+		for i, k := range n.keys {
+			if err := interpolateNode(n.values[i], joinPath(path, k), lookup); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// interpolateEnv interpolates the node with the environment of the process.
+func interpolateEnv(n *yamlNode) error {
+	return interpolateNode(n, "", os.LookupEnv)
+}
+
+func interpolate(s string, lookup func(string) (string, bool)) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' {
+			b.WriteByte(s[i])
+			continue
+		}
+		rest := s[i+1:]
+		switch {
+		case strings.HasPrefix(rest, "$"):
+			b.WriteByte('$')
+			i++
+		case strings.HasPrefix(rest, "{"):
+			end := closingBrace(rest)
+			if end < 0 {
+				return "", fmt.Errorf("invalid interpolation %q: missing }", s[i:])
+			}
+			v, err := expand(rest[1:end], lookup)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+			i += end + 1
+		default:
+			name := varName(rest)
+			if name == "" {
+				return "", fmt.Errorf("invalid interpolation %q, use $$ for a literal $", s)
+			}
+			v, _ := lookup(name)
+			b.WriteString(v)
+			i += len(name)
+		}
+	}
+	return b.String(), nil
+}
+
+// expand evaluates the expression between the braces of ${...}.
+func expand(expr string, lookup func(string) (string, bool)) (string, error) {
+	name := varName(expr)
+	if name == "" {
+		return "", fmt.Errorf("invalid interpolation ${%s}: missing variable name", expr)
+	}
+	op := expr[len(name):]
+	v, set := lookup(name)
+	if op == "" {
+		return v, nil
+	}
+
+	colon := strings.HasPrefix(op, ":")
+	if colon {
+		op = op[1:]
+	}
+	if op == "" {
+		return "", fmt.Errorf("invalid interpolation ${%s}", expr)
+	}
+	// with a colon an empty variable counts as unset
+	present := set && (!colon || v != "")
+	arg := op[1:]
+	switch op[0] {
+	case '-':
+		if present {
+			return v, nil
+		}
+		return interpolate(arg, lookup)
+	case '?':
+		if present {
+			return v, nil
+		}
+		if arg == "" {
+			arg = "not set"
+		}
+		return "", fmt.Errorf("variable %s: %s", name, arg)
+	case '+':
+		if !present {
+			return "", nil
+		}
+		return interpolate(arg, lookup)
+	}
+	return "", fmt.Errorf("invalid interpolation ${%s}", expr)
+}
+
+// closingBrace returns the index of the brace closing the one at s[0],
+// nested ${...} are skipped. It returns -1 if there is none.
+func closingBrace(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// varName returns the variable name at the start of s.
+func varName(s string) string {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9' {
+			continue
+		}
+		return s[:i]
+	}
+	return s
+}
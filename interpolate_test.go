@@ -0,0 +1,73 @@
+package docker
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_Interpolate(t *testing.T) {
+	env := map[string]string{"TAG": "1.2", "EMPTY": "", "HOST": "registry.local"}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	tt := []struct {
+		in      string
+		expect  string
+		wantErr string
+	}{
+		{in: "plain", expect: "plain"},
+		{in: "sim/plc:$TAG", expect: "sim/plc:1.2"},
+		{in: "sim/plc:${TAG}-arm", expect: "sim/plc:1.2-arm"},
+		{in: "${MISSING}", expect: ""},
+		{in: "${MISSING:-latest}", expect: "latest"},
+		{in: "${EMPTY:-latest}", expect: "latest"},
+		{in: "${EMPTY-latest}", expect: ""},
+		{in: "${MISSING:-${HOST:-docker.io}}/sim", expect: "registry.local/sim"},
+		{in: "${TAG:+tagged}${MISSING+set}", expect: "tagged"},
+		{in: "echo $$HOME", expect: "echo $HOME"},
+		{in: "${MISSING:?run id required}", wantErr: "variable MISSING: run id required"},
+		{in: "${EMPTY?}", expect: ""},
+		{in: "${TAG", wantErr: "missing }"},
+		{in: "cost: 5$", wantErr: "use $$ for a literal $"},
+		{in: "${:-x}", wantErr: "missing variable name"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := interpolate(tc.in, lookup)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("got error: %v, want: %s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.expect {
+				t.Errorf("got: %q, want: %q", got, tc.expect)
+			}
+		})
+	}
+}
+
+func Test_LoadSpecInterpolation(t *testing.T) {
+	os.Setenv("DOCKER_TEST_REGISTRY", "registry.local:5000")
+	defer os.Unsetenv("DOCKER_TEST_REGISTRY")
+
+	spec, err := LoadSpecYAML([]byte("run: ${DOCKER_TEST_RUN:-ci}\ncontainers:\n  - name: plc\n    image: ${DOCKER_TEST_REGISTRY}/sim/plc:${DOCKER_TEST_TAG:-latest}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Run != "ci" || spec.Containers[0].Image != "registry.local:5000/sim/plc:latest" {
+		t.Errorf("got run %s and image %s", spec.Run, spec.Containers[0].Image)
+	}
+
+	_, err = LoadSpecYAML([]byte("run: ci\ncontainers:\n  - name: plc\n    image: ${DOCKER_TEST_IMAGE:?set the image}\n"))
+	want := "line 4: containers[0].image: variable DOCKER_TEST_IMAGE: set the image"
+	if err == nil || err.Error() != want {
+		t.Errorf("got error: %v, want: %s", err, want)
+	}
+}
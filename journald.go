@@ -0,0 +1,175 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// journalctl is the command querying the journal.
+var journalctl = "journalctl"
+
+// timestampFormat is the format of the timestamps docker prefixes log lines
+// with.
+const timestampFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// journalOptions select the entries read by journalLogs.
+type journalOptions struct {
+	follow bool
+	// since is a unix time with fraction like the since parameter of the
+	// logs endpoint.
+	since      string
+	timestamps bool
+}
+
+// isReadingUnsupported reports whether the logs endpoint failed because the
+// log driver of the container can not be read back, e.g. for journald with
+// the daemon's log cache disabled.
+func isReadingUnsupported(err error) bool {
+	var e *APIError
+	return errors.As(err, &e) && (e.StatusCode == http.StatusNotImplemented ||
+		strings.Contains(e.Message, "does not support reading"))
+}
+
+// usesJournald reports whether the container logs to journald and the
+// journal is the one of the local host, which is the case for daemons
+// reached by socket or loopback address only.
+func (c *Client) usesJournald(cj *containerJSON) bool {
+	if cj.HostConfig.LogConfig == nil || cj.HostConfig.LogConfig.Type != "journald" {
+		return false
+	}
+	ip := net.ParseIP(c.daemonHost())
+	return ip != nil && ip.IsLoopback()
+}
+
+// journalEntry is an entry written by the journald log driver.
+// docs.: https://docs.docker.com/config/containers/logging/journald/
+type journalEntry struct {
+	Message  json.RawMessage `json:"MESSAGE"`
+	Priority string          `json:"PRIORITY"`
+	// Realtime is the time of the entry in microseconds since the epoch.
+	Realtime string `json:"__REALTIME_TIMESTAMP"`
+	Partial  string `json:"CONTAINER_PARTIAL_MESSAGE"`
+}
+
+// message returns the message of the entry. The journal encodes messages
+// which are not valid UTF-8 as array of bytes.
+func (e *journalEntry) message() ([]byte, error) {
+	var s string
+	if err := json.Unmarshal(e.Message, &s); err == nil {
+		return []byte(s), nil
+	}
+	var bs []byte
+	var ints []int
+	if err := json.Unmarshal(e.Message, &ints); err != nil {
+		return nil, fmt.Errorf("invalid journal message %s", e.Message)
+	}
+	for _, i := range ints {
+		bs = append(bs, byte(i))
+	}
+	return bs, nil
+}
+
+// followJournal follows the journal entries of the container until it exited
+// or the context is done.
+func (c *Client) followJournal(ctx context.Context, id string, opts journalOptions, stdout, stderr io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		// journalctl keeps following after the container stopped
+		c.waitContainer(ctx, id, WaitExited, c.backoffOr(ConstantBackoff(time.Second)))
+		cancel()
+	}()
+	opts.follow = true
+	err := journalLogs(ctx, id, opts, stdout, stderr)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// journalLogs queries the journal of the local host for the output of the
+// container, for daemons whose log driver can not be read through the API.
+// The lines are written to stdout and stderr like by the logs endpoint.
+// This only works if the daemon runs on the same host and the user may read
+// the journal.
+func journalLogs(ctx context.Context, id string, opts journalOptions, stdout, stderr io.Writer) error {
+	args := []string{"--output=json", "--no-pager", "CONTAINER_ID_FULL=" + id}
+	if opts.follow {
+		args = append(args, "--follow")
+	}
+	if opts.since != "" {
+		args = append(args, "--since=@"+opts.since)
+	}
+	cmd := exec.CommandContext(ctx, journalctl, args...)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var errBuf strings.Builder
+	cmd.Stderr = &errBuf
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("query journal: %w", err)
+	}
+
+	err = copyJournal(out, opts.timestamps, stdout, stderr)
+	if err != nil {
+		cmd.Process.Kill()
+	}
+	if werr := cmd.Wait(); err == nil && werr != nil && ctx.Err() == nil {
+		err = fmt.Errorf("query journal: %w: %s", werr, strings.TrimSpace(errBuf.String()))
+	}
+	return err
+}
+
+// copyJournal writes the messages of the JSON journal entries to stdout
+// and stderr. The driver logs stdout with priority info and stderr with
+// priority err.
+func copyJournal(r io.Reader, timestamps bool, stdout, stderr io.Writer) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 4<<20)
+	// continued is set if the last message of the stream was partial
+	continued := make(map[io.Writer]bool)
+	for sc.Scan() {
+		var e journalEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return fmt.Errorf("invalid journal entry: %w", err)
+		}
+		msg, err := e.message()
+		if err != nil {
+			return err
+		}
+		w := stdout
+		if e.Priority == "3" {
+			w = stderr
+		}
+		if w == nil {
+			continue
+		}
+
+		var line []byte
+		if timestamps && !continued[w] {
+			usec, _ := strconv.ParseInt(e.Realtime, 10, 64)
+			line = append(line, time.Unix(0, usec*int64(time.Microsecond)).UTC().Format(timestampFormat)...)
+			line = append(line, ' ')
+		}
+		line = append(line, msg...)
+		continued[w] = e.Partial == "true"
+		if !continued[w] {
+			line = append(line, '\n')
+		}
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
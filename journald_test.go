@@ -0,0 +1,93 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grid-x/docker/types"
+)
+
+func Test_Logs_journald(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	script := filepath.Join(dir, "journalctl")
+	err = ioutil.WriteFile(script, []byte(`#!/bin/sh
+echo "$@" > `+filepath.Join(dir, "args")+`
+echo '{"MESSAGE": "started", "PRIORITY": "6", "__REALTIME_TIMESTAMP": "1622548800000001"}'
+echo '{"MESSAGE": "warn", "PRIORITY": "3", "__REALTIME_TIMESTAMP": "1622548801000000"}'
+`), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(cmd string) { journalctl = cmd }(journalctl)
+	journalctl = script
+
+	srv.route(map[string]mockResponse{
+		"GET /containers/c1/logs": {StatusCode: 501,
+			Body: `{"message": "configured logging driver does not support reading"}`},
+		"GET /containers/c1/json": {Body: `{"Id": "c1", "HostConfig": {"LogConfig": {"Type": "journald"}}}`},
+	})
+	defer srv.route(nil)
+
+	var stdout, stderr bytes.Buffer
+	if err := client.Logs(context.Background(), "c1", &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "started\n" || stderr.String() != "warn\n" {
+		t.Errorf("unexpected output %q, %q", stdout.String(), stderr.String())
+	}
+	args, _ := ioutil.ReadFile(filepath.Join(dir, "args"))
+	if want := "--output=json --no-pager CONTAINER_ID_FULL=c1\n"; string(args) != want {
+		t.Errorf("want args %q, got %q", want, args)
+	}
+}
+
+func Test_usesJournald(t *testing.T) {
+	cj := &containerJSON{}
+	cj.HostConfig.LogConfig = &types.LogConfig{Type: "journald"}
+	tt := []struct {
+		host   string
+		expect bool
+	}{
+		{host: "unix:///var/run/docker.sock", expect: true},
+		{host: "tcp://127.0.0.1:2375", expect: true},
+		{host: "tcp://[::1]:2375", expect: true},
+		// the journal of remote daemons is not the local one
+		{host: "tcp://192.0.2.1:2375", expect: false},
+	}
+	for _, tc := range tt {
+		c, err := NewClientHost(tc.host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.usesJournald(cj); got != tc.expect {
+			t.Errorf("%s: got %t, want %t", tc.host, got, tc.expect)
+		}
+	}
+}
+
+func Test_copyJournal(t *testing.T) {
+	entries := strings.Join([]string{
+		`{"MESSAGE": "part ", "PRIORITY": "6", "__REALTIME_TIMESTAMP": "1622548800000001", "CONTAINER_PARTIAL_MESSAGE": "true"}`,
+		`{"MESSAGE": "error", "PRIORITY": "3", "__REALTIME_TIMESTAMP": "1622548800500000"}`,
+		`{"MESSAGE": [98, 105, 110, 255], "PRIORITY": "6", "__REALTIME_TIMESTAMP": "1622548801000000"}`,
+	}, "\n")
+	var stdout, stderr bytes.Buffer
+	if err := copyJournal(strings.NewReader(entries), true, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if want := "2021-06-01T12:00:00.000001000Z part bin\xff\n"; stdout.String() != want {
+		t.Errorf("want stdout %q, got %q", want, stdout.String())
+	}
+	if want := "2021-06-01T12:00:00.500000000Z error\n"; stderr.String() != want {
+		t.Errorf("want stderr %q, got %q", want, stderr.String())
+	}
+}
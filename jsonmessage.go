@@ -0,0 +1,93 @@
+package docker
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONMessage is a message of the JSON streams the daemon sends while it
+// pulls, pushes, builds or loads images.
+// docs.: https://docs.docker.com/engine/api/v1.41/#operation/ImageCreate
+type JSONMessage struct {
+	// Stream is output of a build step.
+	Stream string `json:"stream,omitempty"`
+	// Status describes the progress, e.g. "Downloading", of the layer or
+	// image with the ID.
+	Status string `json:"status,omitempty"`
+	ID     string `json:"id,omitempty"`
+	// Progress is the progress in bytes, ProgressMessage its rendering as
+	// progress bar.
+	Progress        *JSONProgress `json:"progressDetail,omitempty"`
+	ProgressMessage string        `json:"progress,omitempty"`
+	// Error is set by the message which ends a failed operation.
+	Error *JSONError `json:"errorDetail,omitempty"`
+	// ErrorMessage is the message of Error, older daemons only send this.
+	ErrorMessage string `json:"error,omitempty"`
+	// Aux carries results, e.g. the ID of a built image as
+	// {"ID": "sha256:..."} or the digest of a pushed one.
+	Aux json.RawMessage `json:"aux,omitempty"`
+}
+
+// JSONProgress is the progress of a transfer in bytes. Total is zero if the
+// size is not known.
+type JSONProgress struct {
+	Current int64 `json:"current,omitempty"`
+	Total   int64 `json:"total,omitempty"`
+}
+
+// JSONError is the failure reported within a JSON message stream, after the
+// daemon already sent the status code.
+type JSONError struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *JSONError) Error() string {
+	return e.Message
+}
+
+// JSONMessageDecoder decodes a JSON message stream.
+type JSONMessageDecoder struct {
+	dec *json.Decoder
+}
+
+// NewJSONMessageDecoder returns a decoder of the stream.
+func NewJSONMessageDecoder(r io.Reader) *JSONMessageDecoder {
+	return &JSONMessageDecoder{dec: json.NewDecoder(r)}
+}
+
+// Next returns the next message of the stream and io.EOF at its end. A
+// message reporting a failure is returned together with its *JSONError.
+func (d *JSONMessageDecoder) Next() (*JSONMessage, error) {
+	var msg JSONMessage
+	if err := d.dec.Decode(&msg); err != nil {
+		return nil, err
+	}
+	if msg.Error == nil && msg.ErrorMessage != "" {
+		msg.Error = &JSONError{Message: msg.ErrorMessage}
+	}
+	if msg.Error != nil {
+		return &msg, msg.Error
+	}
+	return &msg, nil
+}
+
+// ReadJSONMessages passes the messages of the stream to fn until the stream
+// ends. It returns the first error of the stream, the decoder or fn. fn may
+// be nil to only wait for the end of the operation.
+func ReadJSONMessages(r io.Reader, fn func(*JSONMessage) error) error {
+	dec := NewJSONMessageDecoder(r)
+	for {
+		msg, err := dec.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if fn != nil {
+			if err := fn(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
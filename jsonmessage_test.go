@@ -0,0 +1,59 @@
+package docker
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_JSONMessageDecoder(t *testing.T) {
+	stream := `{"status": "Pulling from library/alpine", "id": "3.14"}
+{"status": "Downloading", "id": "a0d0", "progressDetail": {"current": 512, "total": 2048}, "progress": "[==>  ]"}
+{"errorDetail": {"code": 1, "message": "unexpected EOF"}, "error": "unexpected EOF"}
+{"error": "no space left on device"}
+`
+	dec := NewJSONMessageDecoder(strings.NewReader(stream))
+	msg, err := dec.Next()
+	if err != nil || msg.Status != "Pulling from library/alpine" || msg.ID != "3.14" {
+		t.Errorf("unexpected message %+v, %v", msg, err)
+	}
+	msg, err = dec.Next()
+	if err != nil || !reflect.DeepEqual(msg.Progress, &JSONProgress{Current: 512, Total: 2048}) {
+		t.Errorf("unexpected message %+v, %v", msg, err)
+	}
+
+	var jerr *JSONError
+	msg, err = dec.Next()
+	if !errors.As(err, &jerr) || jerr.Code != 1 || err.Error() != "unexpected EOF" || msg.Error != jerr {
+		t.Errorf("unexpected error %+v, %v", msg, err)
+	}
+	// older daemons only send the message
+	if _, err = dec.Next(); !errors.As(err, &jerr) || jerr.Message != "no space left on device" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := dec.Next(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+func Test_ReadJSONMessages(t *testing.T) {
+	var statuses []string
+	err := ReadJSONMessages(strings.NewReader(`{"status": "a"}{"status": "b"}{"error": "failed"}{"status": "c"}`),
+		func(msg *JSONMessage) error {
+			statuses = append(statuses, msg.Status)
+			return nil
+		})
+	if err == nil || err.Error() != "failed" {
+		t.Errorf("expected failure, got %v", err)
+	}
+	if !reflect.DeepEqual(statuses, []string{"a", "b"}) {
+		t.Errorf("unexpected statuses %v", statuses)
+	}
+
+	stop := errors.New("stop")
+	if err := ReadJSONMessages(strings.NewReader(`{"status": "a"}`), func(*JSONMessage) error { return stop }); err != stop {
+		t.Errorf("expected error of fn, got %v", err)
+	}
+}
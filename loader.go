@@ -0,0 +1,334 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LoadSpecFile reads the spec from the given file. Files ending on .json are
+// parsed as JSON, all others as YAML.
+//
+// Like in compose files, all spec loaders interpolate environment variables
+// in values, e.g. image: ${REGISTRY:-docker.io}/sim/plc:${TAG:-latest}.
+// Use $$ for a literal $.
+//
+// Relative bind mounts, e.g. "./data:/data", are resolved against the
+// directory of the file. The other loaders reject them.
+func LoadSpecFile(path string) (*Spec, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return loadSpec(path, b)
+}
+
+// loadSpec parses the content of the spec file at path.
+func loadSpec(path string, b []byte) (*Spec, error) {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	parse := parseYAML
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		parse = parseJSON
+	}
+	n, err := parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	spec, err := decodeSpec(n, dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// LoadSpecYAML parses and validates a spec in YAML format. Errors contain the
+// line and the path of the offending field, e.g.:
+// "line 12: containers[0].ports: expected a sequence".
+func LoadSpecYAML(data []byte) (*Spec, error) {
+	n, err := parseYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSpec(n, "")
+}
+
+// LoadSpecJSON parses and validates a spec in JSON format. Syntax errors
+// contain line and column, schema errors the path of the offending field.
+func LoadSpecJSON(data []byte) (*Spec, error) {
+	n, err := parseJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSpec(n, "")
+}
+
+// decodeSpec decodes and validates the spec. Relative bind mounts are
+// resolved against dir, unless it is empty.
+func decodeSpec(n *yamlNode, dir string) (*Spec, error) {
+	if err := interpolateEnv(n); err != nil {
+		return nil, err
+	}
+	var spec Spec
+	if err := decodeNode(n, &spec); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		spec.resolveMounts(dir)
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// decodeNode checks the node against the schema defined by the type of out
+// and its json tags and decodes it into out. Unknown fields and values of the
+// wrong type are reported with their position.
+func decodeNode(n *yamlNode, out interface{}) error {
+	v, err := convertNode(n, reflect.TypeOf(out).Elem(), "")
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+type schemaError struct {
+	line int
+	path string
+	msg  string
+}
+
+func (e *schemaError) Error() string {
+	path := e.path
+	if path == "" {
+		path = "document"
+	}
+	if e.line > 0 {
+		return fmt.Sprintf("line %d: %s: %s", e.line, path, e.msg)
+	}
+	return fmt.Sprintf("%s: %s", path, e.msg)
+}
+
+var jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// convertNode converts the node into a value which encodes to JSON matching
+// type t.
+func convertNode(n *yamlNode, t reflect.Type, path string) (interface{}, error) {
+	fail := func(format string, args ...interface{}) (interface{}, error) {
+		return nil, &schemaError{line: n.line, path: path, msg: fmt.Sprintf(format, args...)}
+	}
+
+	if n.kind == yamlNull {
+		return nil, nil
+	}
+	if reflect.PtrTo(t).Implements(jsonUnmarshaler) || t.Kind() == reflect.Interface {
+		// the type handles the decoding itself
+		return genericNode(n), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return convertNode(n, t.Elem(), path)
+	case reflect.String:
+		if n.kind != yamlScalar {
+			return fail("expected a string")
+		}
+		return n.value, nil
+	case reflect.Bool:
+		if n.kind == yamlScalar {
+			switch strings.ToLower(n.value) {
+			case "true", "yes", "on":
+				return true, nil
+			case "false", "no", "off":
+				return false, nil
+			}
+		}
+		return fail("expected a boolean")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n.kind == yamlScalar {
+			if i, err := strconv.ParseInt(n.value, 0, 64); err == nil {
+				return i, nil
+			}
+		}
+		return fail("expected an integer")
+	case reflect.Float32, reflect.Float64:
+		if n.kind == yamlScalar {
+			if f, err := strconv.ParseFloat(n.value, 64); err == nil {
+				return f, nil
+			}
+		}
+		return fail("expected a number")
+	case reflect.Slice:
+		if n.kind != yamlSeq {
+			return fail("expected a sequence")
+		}
+		res := make([]interface{}, len(n.values))
+		for i, item := range n.values {
+			v, err := convertNode(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			res[i] = v
+		}
+		return res, nil
+	case reflect.Map:
+		if n.kind != yamlMap {
+			return fail("expected a mapping")
+		}
+		res := make(map[string]interface{}, len(n.keys))
+		for i, k := range n.keys {
+			v, err := convertNode(n.values[i], t.Elem(), joinPath(path, k))
+			if err != nil {
+				return nil, err
+			}
+			res[k] = v
+		}
+		return res, nil
+	case reflect.Struct:
+		if n.kind != yamlMap {
+			return fail("expected a mapping")
+		}
+		fields := jsonFields(t)
+		res := make(map[string]interface{}, len(n.keys))
+		for i, k := range n.keys {
+			f, ok := fields[k]
+			if !ok {
+				p := joinPath(path, k)
+				return nil, &schemaError{line: n.values[i].line, path: p,
+					msg: fmt.Sprintf("unknown field, expected one of: %s", fieldNames(fields))}
+			}
+			v, err := convertNode(n.values[i], f.Type, joinPath(path, k))
+			if err != nil {
+				return nil, err
+			}
+			res[k] = v
+		}
+		return res, nil
+	}
+	return fail("unsupported type %s", t)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// jsonFields returns the fields of the struct by their json name.
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	res := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		res[name] = f
+	}
+	return res
+}
+
+func fieldNames(fields map[string]reflect.StructField) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// genericNode converts the node without a schema. Scalars stay strings.
+func genericNode(n *yamlNode) interface{} {
+	switch n.kind {
+	case yamlScalar:
+		return n.value
+	case yamlSeq:
+		res := make([]interface{}, len(n.values))
+		for i, item := range n.values {
+			res[i] = genericNode(item)
+		}
+		return res
+	case yamlMap:
+		res := make(map[string]interface{}, len(n.keys))
+		for i, k := range n.keys {
+			res[k] = genericNode(n.values[i])
+		}
+		return res
+	}
+	return nil
+}
+
+// parseJSON parses a JSON document into nodes, so it can be checked with the
+// same schema validation as YAML documents.
+func parseJSON(data []byte) (*yamlNode, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		if se, ok := err.(*json.SyntaxError); ok {
+			line, col := position(data, se.Offset-1)
+			return nil, fmt.Errorf("line %d, column %d: %v", line, col, err)
+		}
+		return nil, err
+	}
+	return jsonNode(v), nil
+}
+
+func jsonNode(v interface{}) *yamlNode {
+	switch v := v.(type) {
+	case []interface{}:
+		n := &yamlNode{kind: yamlSeq}
+		for _, item := range v {
+			n.values = append(n.values, jsonNode(item))
+		}
+		return n
+	case map[string]interface{}:
+		n := &yamlNode{kind: yamlMap}
+		for k := range v {
+			n.keys = append(n.keys, k)
+		}
+		sort.Strings(n.keys)
+		for _, k := range n.keys {
+			n.values = append(n.values, jsonNode(v[k]))
+		}
+		return n
+	case nil:
+		return &yamlNode{kind: yamlNull}
+	}
+	return &yamlNode{kind: yamlScalar, value: fmt.Sprint(v), quoted: true}
+}
+
+// position converts a byte offset into a 1-based line and column.
+func position(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
@@ -0,0 +1,201 @@
+package docker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_LoadSpecFile(t *testing.T) {
+	tt := []struct {
+		name   string
+		file   string
+		expect *Spec
+	}{
+		{
+			name: "yaml",
+			file: "spec.yaml",
+			expect: &Spec{
+				Run: "sim-1",
+				Networks: []NetworkSpec{
+					{Name: "simulation_subnet_1", Subnet: "172.28.0.0/16"},
+				},
+				Volumes: []VolumeSpec{{Name: "plc-data"}},
+				Containers: []ContainerSpec{
+					{
+						Name:       "plc-1",
+						Image:      "nginxdemos/hello:plain-text",
+						Cmd:        []string{"sleep", "3600"},
+						Env:        map[string]string{"MODE": "simulation", "GREETING": "hello # world"},
+						Ports:      []string{"8080:80"},
+						Mounts:     []string{"plc-data:/data"},
+						Privileged: true,
+						Networks: []NetworkAttachment{
+							{Network: "simulation_subnet_1", Aliases: []string{"plc"}},
+						},
+					},
+					{
+						Name:       "meter-1",
+						Image:      "busybox",
+						Entrypoint: []string{"/bin/sh", "-c", "echo started\nsleep 3600\n"},
+					},
+				},
+			},
+		},
+		{
+			name: "json",
+			file: "spec.json",
+			expect: &Spec{
+				Run:        "sim-1",
+				Containers: []ContainerSpec{{Name: "plc-1", Image: "busybox", Privileged: true}},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := LoadSpecFile(testfileLocation + tc.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("got: %+v, want: %+v", got, tc.expect)
+			}
+		})
+	}
+}
+
+func Test_LoadSpecFileRelativeMount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spec.yaml")
+	spec := "run: a\ncontainers:\n  - name: a\n    image: b\n    mounts: [./data:/data:ro, ../init:/init]\n"
+	if err := ioutil.WriteFile(path, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadSpecFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "data") + ":/data:ro", filepath.Join(filepath.Dir(dir), "init") + ":/init"}
+	if !reflect.DeepEqual(got.Containers[0].Mounts, want) {
+		t.Errorf("got: %v, want: %v", got.Containers[0].Mounts, want)
+	}
+}
+
+func Test_LoadSpecErrors(t *testing.T) {
+	tt := []struct {
+		name   string
+		yaml   string
+		json   string
+		expect string
+	}{
+		{
+			name:   "unknown field",
+			yaml:   "run: a\ncontainers:\n  - name: a\n    imgae: b\n",
+			expect: "line 4: containers[0].imgae: unknown field",
+		},
+		{
+			name:   "wrong type",
+			yaml:   "run: a\ncontainers:\n  - name: a\n    image: b\n    cmd: sleep\n",
+			expect: "line 5: containers[0].cmd: expected a sequence",
+		},
+		{
+			name:   "bad indentation",
+			yaml:   "run: a\n  containers: []\n",
+			expect: "line 2: unexpected indentation",
+		},
+		{
+			name:   "json syntax",
+			json:   "{\n  \"run\": \"a\",\n}",
+			expect: "line 3, column 1",
+		},
+		{
+			name:   "json unknown field",
+			json:   `{"run": "a", "containers": [{"name": "a", "image": "b", "foo": 1}]}`,
+			expect: "containers[0].foo: unknown field",
+		},
+		{
+			name:   "invalid spec",
+			yaml:   "containers: []",
+			expect: "run must not be empty",
+		},
+		{
+			name:   "relative bind mount",
+			yaml:   "run: a\ncontainers:\n  - name: a\n    image: b\n    mounts: [./data:/data]\n",
+			expect: `relative bind mount "./data:/data" needs the spec file location`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			if tc.json != "" {
+				_, err = LoadSpecJSON([]byte(tc.json))
+			} else {
+				_, err = LoadSpecYAML([]byte(tc.yaml))
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tc.expect) {
+				t.Errorf("got: %s, want: %s", err, tc.expect)
+			}
+		})
+	}
+}
+
+func Test_stripComment(t *testing.T) {
+	tt := []struct {
+		line   string
+		expect string
+	}{
+		{line: "a: it's here # note", expect: "a: it's here "},
+		{line: "a: 'quoted # no note' # note", expect: "a: 'quoted # no note' "},
+		{line: "a: 'it''s # no note'", expect: "a: 'it''s # no note'"},
+		{line: `a: "say \"# no note\"" # note`, expect: `a: "say \"# no note\"" `},
+		{line: "- 'x # no note'", expect: "- 'x # no note'"},
+		{line: "a: [b, 'c # no note'] # note", expect: "a: [b, 'c # no note'] "},
+		{line: "'k # no note': v # note", expect: "'k # no note': v "},
+		{line: "a: b-'c # note", expect: "a: b-'c "},
+	}
+	for _, tc := range tt {
+		if got := stripComment(tc.line); got != tc.expect {
+			t.Errorf("%s: got %q, want %q", tc.line, got, tc.expect)
+		}
+	}
+}
+
+func Test_ParseYAMLEmptyScalars(t *testing.T) {
+	tt := []struct {
+		yaml   string
+		expect []yamlKind
+	}{
+		{yaml: "a: {b: }", expect: []yamlKind{yamlNull}},
+		{yaml: "a: [x, ,y]", expect: []yamlKind{yamlScalar, yamlNull, yamlScalar}},
+		{yaml: "a: [,]", expect: []yamlKind{yamlNull}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.yaml, func(t *testing.T) {
+			n, err := parseYAML([]byte(tc.yaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []yamlKind
+			for _, v := range n.get("a").values {
+				got = append(got, v.kind)
+			}
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("got: %v, want: %v", got, tc.expect)
+			}
+		})
+	}
+}
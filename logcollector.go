@@ -0,0 +1,348 @@
+package docker
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLogMaxSize is the size at which the log files of a LogCollector are
+// rotated by default.
+const DefaultLogMaxSize = 100 << 20
+
+// LogCollectorOptions configure a LogCollector.
+type LogCollectorOptions struct {
+	// Dir is the directory the log files are written to. The log of a
+	// container is written to <name>.log, rotated files are named
+	// <name>.<time of rotation>.log.
+	Dir string
+	// MaxSize is the size in bytes at which a log file is rotated. Defaults
+	// to DefaultLogMaxSize.
+	MaxSize int64
+	// MaxFiles is the number of rotated files kept per container, the oldest
+	// ones are removed. Zero keeps all files.
+	MaxFiles int
+	// Compress gzips rotated files.
+	Compress bool
+}
+
+// LogCollector archives the logs of containers to files. Every line is
+// prefixed by its timestamp and the stream, e.g.
+// "2021-06-01T12:00:00.000000000Z stdout message".
+type LogCollector struct {
+	c        *Client
+	selector Selector
+	opts     LogCollectorOptions
+
+	mu sync.Mutex
+	// following are the containers whose logs are followed.
+	following map[string]bool
+	// last is the timestamp of the last line of a container, a restarted
+	// container is followed from there.
+	last  map[string]string
+	files map[string]*rotatingFile
+	wg    sync.WaitGroup
+}
+
+// NewLogCollector returns a collector of the logs of the containers
+// matching the selector.
+func NewLogCollector(c *Client, selector Selector, opts LogCollectorOptions) *LogCollector {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultLogMaxSize
+	}
+	return &LogCollector{
+		c:         c,
+		selector:  selector,
+		opts:      opts,
+		following: make(map[string]bool),
+		last:      make(map[string]string),
+		files:     make(map[string]*rotatingFile),
+	}
+}
+
+// Run follows the logs of all matching containers, including the ones
+// started later, until the context is done. The log files are closed before
+// it returns the error of the context. Restarted containers continue their
+// log file.
+func (l *LogCollector) Run(ctx context.Context) error {
+	if err := os.MkdirAll(l.opts.Dir, 0755); err != nil {
+		return fmt.Errorf("collect logs: %w", err)
+	}
+	filter := EventFilter{Types: []string{"container"}, Actions: []string{"start"}, Selector: l.selector}
+	connected := func() {
+		// containers started while the subscription was down
+		containers, err := l.c.listContainers(ctx, false, l.selector.filters())
+		if err != nil {
+			return
+		}
+		for _, cs := range containers {
+			l.follow(ctx, cs.ID, cs.Name())
+		}
+	}
+	l.c.subscribe(ctx, filter, connected, func() {}, func(ev Event) {
+		l.follow(ctx, ev.ID, ev.Attributes["name"])
+	})
+
+	l.wg.Wait()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, f := range l.files {
+		f.Close()
+	}
+	return ctx.Err()
+}
+
+// follow starts to follow the logs of the container unless they are
+// followed already.
+func (l *LogCollector) follow(ctx context.Context, id, name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.following[id] || ctx.Err() != nil {
+		return
+	}
+	f, ok := l.files[id]
+	if !ok {
+		if name == "" {
+			name = id
+		}
+		f = &rotatingFile{dir: l.opts.Dir, name: name, opts: l.opts}
+		l.files[id] = f
+	}
+	l.following[id] = true
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		l.copyLogs(ctx, id, f)
+		l.mu.Lock()
+		delete(l.following, id)
+		l.mu.Unlock()
+	}()
+}
+
+// copyLogs writes the logs of the container to the file until the
+// container stopped.
+func (l *LogCollector) copyLogs(ctx context.Context, id string, f *rotatingFile) error {
+	q := url.Values{"stdout": {"1"}, "stderr": {"1"}, "follow": {"1"}, "timestamps": {"1"}}
+	l.mu.Lock()
+	if since := l.last[id]; since != "" {
+		q.Set("since", since)
+	}
+	l.mu.Unlock()
+
+	cj, err := l.c.inspectContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+	r, err := l.c.stream(ctx, "containers/"+pathName(id)+"/logs", q)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	err = checkResponse(r, http.StatusOK)
+	if isReadingUnsupported(err) && l.c.usesJournald(cj) {
+		stdout, stderr := l.lineWriters(id, f)
+		return l.copyJournal(ctx, id, q.Get("since"), stdout, stderr)
+	}
+	if err != nil {
+		return err
+	}
+	stdout, stderr := l.lineWriters(id, f)
+	return copyLogs(r.Body, cj.Config.Tty, stdout, stderr)
+}
+
+// copyJournal follows the journal entries of the container until it
+// stopped.
+func (l *LogCollector) copyJournal(ctx context.Context, id, since string, stdout, stderr io.Writer) error {
+	return l.c.followJournal(ctx, id, journalOptions{since: since, timestamps: true}, stdout, stderr)
+}
+
+// lineWriters return the writers of the stdout and stderr of the container
+// which prefix the lines by the stream and track the last timestamp.
+func (l *LogCollector) lineWriters(id string, f *rotatingFile) (stdout, stderr io.Writer) {
+	line := func(stream string) func([]byte) []byte {
+		return func(line []byte) []byte {
+			// "<RFC3339Nano timestamp> <message>"
+			i := bytes.IndexByte(line, ' ')
+			if i < 0 {
+				return line
+			}
+			if t, err := time.Parse(time.RFC3339Nano, string(line[:i])); err == nil {
+				l.mu.Lock()
+				l.last[id] = sinceAfter(t)
+				l.mu.Unlock()
+			}
+			res := make([]byte, 0, len(line)+len(stream)+2)
+			res = append(res, line[:i+1]...)
+			res = append(res, stream...)
+			res = append(res, line[i:]...)
+			return res
+		}
+	}
+	return &lineWriter{w: f, fn: line("stdout")}, &lineWriter{w: f, fn: line("stderr")}
+}
+
+// sinceAfter formats the time right after t for the since parameter.
+func sinceAfter(t time.Time) string {
+	t = t.Add(time.Nanosecond)
+	return strconv.FormatInt(t.Unix(), 10) + "." + fmt.Sprintf("%09d", t.Nanosecond())
+}
+
+// rotatedTimeFormat is the time in the names of rotated files, it sorts
+// chronologically.
+const rotatedTimeFormat = "20060102T150405.000000000"
+
+// rotatingFile is a log file which is rotated once it reaches the maximum
+// size. Writes are expected to be whole lines, so lines are never split
+// across files.
+type rotatingFile struct {
+	dir  string
+	name string
+	opts LogCollectorOptions
+	now  func() time.Time
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+func (r *rotatingFile) Write(b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f != nil && r.size > 0 && r.size+int64(len(b)) > r.opts.MaxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	if r.f == nil {
+		f, err := os.OpenFile(r.path(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return 0, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return 0, err
+		}
+		r.f, r.size = f, info.Size()
+	}
+	n, err := r.f.Write(b)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
+
+func (r *rotatingFile) path() string {
+	return filepath.Join(r.dir, r.name+".log")
+}
+
+// rotate moves the current file aside, compresses it if configured and
+// removes the oldest rotated files. The caller has to hold the lock.
+func (r *rotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	r.f = nil
+	now := time.Now
+	if r.now != nil {
+		now = r.now
+	}
+	rotated := filepath.Join(r.dir, r.name+"."+now().UTC().Format(rotatedTimeFormat)+".log")
+	if err := os.Rename(r.path(), rotated); err != nil {
+		return err
+	}
+	if r.opts.Compress {
+		if err := gzipFile(rotated); err != nil {
+			return err
+		}
+	}
+	if r.opts.MaxFiles <= 0 {
+		return nil
+	}
+	old, err := r.rotatedFiles()
+	if err != nil {
+		return err
+	}
+	sort.Strings(old)
+	for len(old) > r.opts.MaxFiles {
+		os.Remove(filepath.Join(r.dir, old[0]))
+		old = old[1:]
+	}
+	return nil
+}
+
+// rotatedFiles lists the names of the rotated files of this log. Files of
+// other logs in the directory are left out, even if their name starts with
+// the name of this one, e.g. plc.1.log next to plc.log.
+func (r *rotatingFile) rotatedFiles() ([]string, error) {
+	fis, err := ioutil.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, fi := range fis {
+		ts := strings.TrimPrefix(fi.Name(), r.name+".")
+		if ts == fi.Name() {
+			continue
+		}
+		if strings.HasSuffix(ts, ".log.gz") {
+			ts = strings.TrimSuffix(ts, ".log.gz")
+		} else if strings.HasSuffix(ts, ".log") {
+			ts = strings.TrimSuffix(ts, ".log")
+		} else {
+			continue
+		}
+		if _, err := time.Parse(rotatedTimeFormat, ts); err != nil || len(ts) != len(rotatedTimeFormat) {
+			continue
+		}
+		names = append(names, fi.Name())
+	}
+	return names, nil
+}
+
+// gzipFile replaces the file by its compressed version with suffix .gz.
+func gzipFile(p string) error {
+	in, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(p + ".gz")
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(p)
+}
@@ -0,0 +1,110 @@
+package docker
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_LogCollector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	srv.route(map[string]mockResponse{
+		"GET /containers/json": {Body: `[{"Id": "c1", "Names": ["/plc"]}]`},
+		"GET /events": {Body: `{"Type": "container", "Action": "start",
+			"Actor": {"ID": "c2", "Attributes": {"name": "meter"}}}`},
+		"GET /containers/c1/json": {Body: `{"Id": "c1", "Name": "/plc"}`},
+		"GET /containers/c2/json": {Body: `{"Id": "c2", "Name": "/meter"}`},
+		"GET /containers/c1/logs": {Body: frame(streamStdout, "2021-06-01T12:00:00.000000001Z started\n") +
+			frame(streamStderr, "2021-06-01T12:00:01.5Z warn")},
+		"GET /containers/c2/logs": {Body: frame(streamStdout, "2021-06-01T12:00:02Z reading\n")},
+	})
+	defer srv.route(nil)
+
+	lc := NewLogCollector(client, RunSelector("r1"), LogCollectorOptions{Dir: dir})
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := lc.Run(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	// the incomplete last line is not written
+	want := map[string]string{
+		"plc.log":   "2021-06-01T12:00:00.000000001Z stdout started\n",
+		"meter.log": "2021-06-01T12:00:02Z stdout reading\n",
+	}
+	for name, content := range want {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != content {
+			t.Errorf("%s: want %q, got %q", name, content, b)
+		}
+	}
+	if since := lc.last["c1"]; since != "1622548800.000000002" {
+		t.Errorf("unexpected since %s", since)
+	}
+}
+
+func Test_rotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the rotated files of another container are kept
+	other := []string{"plc.1.20210601T110000.000000000.log", "plc.1.log", "plc.20210601T110000.000000000.log.old"}
+	for _, name := range other {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	f := &rotatingFile{dir: dir, name: "plc", opts: LogCollectorOptions{MaxSize: 10, MaxFiles: 2, Compress: true},
+		now: func() time.Time {
+			now = now.Add(time.Second)
+			return now
+		}}
+	for _, line := range []string{"line 1\n", "line 2\n", "line 3\n", "line 4\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	sort.Strings(files)
+	for i := range files {
+		files[i] = filepath.Base(files[i])
+	}
+	want := append(other, "plc.20210601T120002.000000000.log.gz", "plc.20210601T120003.000000000.log.gz", "plc.log")
+	sort.Strings(want)
+	if strings.Join(files, ",") != strings.Join(want, ",") {
+		t.Fatalf("want files %v, got %v", want, files)
+	}
+	gz, err := os.Open(filepath.Join(dir, "plc.20210601T120003.000000000.log.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gz.Close()
+	zr, err := gzip.NewReader(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadAll(zr); string(b) != "line 3\n" {
+		t.Errorf("unexpected rotated content %q", b)
+	}
+}
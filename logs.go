@@ -0,0 +1,139 @@
+package docker
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// Stream identifiers of the multiplexed attach and logs streams.
+const (
+	streamStdin  = 0
+	streamStdout = 1
+	streamStderr = 2
+)
+
+// containerLogs returns the logs of the container. The caller has to close
+// the returned reader. For containers without TTY the stream is multiplexed
+// and has to be split by demuxStream.
+func (c *Client) containerLogs(ctx context.Context, id string, query url.Values) (io.ReadCloser, error) {
+	r, err := c.streamHeader(ctx, http.MethodGet, "containers/"+pathName(id)+"/logs", query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(r, http.StatusOK); err != nil {
+		drainClose(r.Body)
+		return nil, err
+	}
+	return r.Body, nil
+}
+
+// Logs copies the output the container has written so far to stdout and
+// stderr. Either writer may be nil to discard the stream. The output of
+// containers with TTY is not split, it is copied to stdout. Containers
+// logging to journald whose logs can not be read through the API are read
+// from the journal of the local host, if the daemon runs there.
+func (c *Client) Logs(ctx context.Context, id string, stdout, stderr io.Writer) error {
+	cj, err := c.inspectContainer(ctx, id)
+	if err != nil {
+		return fmt.Errorf("read logs of %s: %w", id, err)
+	}
+	logs, err := c.containerLogs(ctx, id, url.Values{"stdout": {"1"}, "stderr": {"1"}})
+	if isReadingUnsupported(err) && c.usesJournald(cj) {
+		err = journalLogs(ctx, id, journalOptions{}, stdout, stderr)
+		if err != nil {
+			return fmt.Errorf("read logs of %s: %w", id, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read logs of %s: %w", id, err)
+	}
+	defer logs.Close()
+	if err := copyLogs(logs, cj.Config.Tty, stdout, stderr); err != nil {
+		return fmt.Errorf("read logs of %s: %w", id, err)
+	}
+	return nil
+}
+
+// FollowLogs copies the output of the container to stdout and stderr like
+// Logs and keeps following new output until the container exits or the
+// context is done.
+func (c *Client) FollowLogs(ctx context.Context, id string, stdout, stderr io.Writer) error {
+	cj, err := c.inspectContainer(ctx, id)
+	if err != nil {
+		return fmt.Errorf("follow logs of %s: %w", id, err)
+	}
+	q := url.Values{"stdout": {"1"}, "stderr": {"1"}, "follow": {"1"}}
+	r, err := c.stream(ctx, "containers/"+pathName(id)+"/logs", q)
+	if err == nil {
+		if err = checkResponse(r, http.StatusOK); err != nil {
+			drainClose(r.Body)
+		}
+	}
+	if isReadingUnsupported(err) && c.usesJournald(cj) {
+		err = c.followJournal(ctx, id, journalOptions{}, stdout, stderr)
+		if err != nil {
+			return fmt.Errorf("follow logs of %s: %w", id, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("follow logs of %s: %w", id, err)
+	}
+	defer r.Body.Close()
+	if err := copyLogs(r.Body, cj.Config.Tty, stdout, stderr); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("follow logs of %s: %w", id, err)
+	}
+	return nil
+}
+
+// copyLogs copies the logs stream of a container to stdout and stderr. The
+// stream of containers with TTY is not multiplexed, it is copied to stdout.
+func copyLogs(r io.Reader, tty bool, stdout, stderr io.Writer) error {
+	if !tty {
+		return demuxStream(r, stdout, stderr)
+	}
+	if stdout == nil {
+		stdout = ioutil.Discard
+	}
+	_, err := io.Copy(stdout, r)
+	return err
+}
+
+// demuxStream splits a multiplexed stream into stdout and stderr. Every frame
+// starts with an 8 byte header: the stream type, three zero bytes and the
+// length of the payload as big endian uint32.
+// docs.: https://docs.docker.com/engine/api/v1.36/#operation/ContainerAttach
+func demuxStream(r io.Reader, stdout, stderr io.Writer) error {
+	var hdr [8]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		size := int64(binary.BigEndian.Uint32(hdr[4:]))
+
+		var w io.Writer
+		switch hdr[0] {
+		case streamStdin, streamStdout:
+			w = stdout
+		case streamStderr:
+			w = stderr
+		default:
+			return fmt.Errorf("invalid stream type %d", hdr[0])
+		}
+		if w == nil {
+			w = ioutil.Discard
+		}
+		if _, err := io.CopyN(w, r, size); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func Test_FollowLogs(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /containers/c1/json":                            {Body: `{"Id": "c1", "Config": {"Tty": false}}`},
+		"GET /containers/c1/logs?follow=1&stderr=1&stdout=1": {Body: frame(streamStdout, "started\n") + frame(streamStderr, "warn\n")},
+		"GET /containers/c3/json":                            {Body: `{"Id": "c3", "Config": {"Tty": true}}`},
+		"GET /containers/c3/logs?follow=1&stderr=1&stdout=1": {Body: "\x1b[1mprompt\r\n"},
+	})
+	defer srv.route(nil)
+
+	var stdout, stderr bytes.Buffer
+	if err := client.FollowLogs(context.Background(), "c1", &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "started\n" || stderr.String() != "warn\n" {
+		t.Errorf("unexpected output %q, %q", stdout.String(), stderr.String())
+	}
+	if err := client.FollowLogs(context.Background(), "c2", &stdout, &stderr); !isNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+	// the output of containers with TTY is not multiplexed
+	stdout.Reset()
+	stderr.Reset()
+	if err := client.FollowLogs(context.Background(), "c3", &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "\x1b[1mprompt\r\n" || stderr.Len() != 0 {
+		t.Errorf("unexpected output %q, %q", stdout.String(), stderr.String())
+	}
+	want := []string{"GET /containers/c1/json", "GET /containers/c1/logs", "GET /containers/c2/json",
+		"GET /containers/c3/json", "GET /containers/c3/logs"}
+	if got := srv.Requests(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,186 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Manager holds clients for several daemons by name, so one simulator can
+// span multiple lab machines. It tracks the health of every host: calls
+// through Do and Check update it, errors reported by the daemon itself do
+// not mark a host unhealthy, only failing connections do.
+// The zero value is not usable, use NewManager.
+type Manager struct {
+	mu    sync.Mutex
+	hosts map[string]*managedHost
+}
+
+type managedHost struct {
+	client *Client
+	status HostStatus
+}
+
+// HostStatus is the health of a host managed by a Manager.
+type HostStatus struct {
+	Name string
+	// Host is the address the client was created for, it is empty for
+	// hosts added by AddClient.
+	Host string
+	// Healthy is true until a connection to the daemon failed and becomes
+	// true again with the next successful call.
+	Healthy bool
+	// Failures counts the consecutive connection failures.
+	Failures int
+	// LastError is the error of the last failed connection.
+	LastError error
+	// LastSeen is the time the daemon last answered.
+	LastSeen time.Time
+}
+
+// NewManager returns an empty Manager.
+func NewManager() *Manager {
+	return &Manager{hosts: make(map[string]*managedHost)}
+}
+
+// Add adds a client for the daemon at host, see NewClientHost for the
+// supported formats. Names must be unique.
+func (m *Manager) Add(name, host string) error {
+	c, err := NewClientHost(host)
+	if err != nil {
+		return err
+	}
+	return m.add(name, host, c)
+}
+
+// AddClient adds an existing client under the name.
+func (m *Manager) AddClient(name string, c *Client) error {
+	return m.add(name, "", c)
+}
+
+func (m *Manager) add(name, host string, c *Client) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.hosts[name]; ok {
+		return fmt.Errorf("host %s: %w", name, ErrNameTaken)
+	}
+	m.hosts[name] = &managedHost{
+		client: c,
+		status: HostStatus{Name: name, Host: host, Healthy: true},
+	}
+	return nil
+}
+
+// Remove removes the host. Unknown names are ignored.
+func (m *Manager) Remove(name string) {
+	m.mu.Lock()
+	delete(m.hosts, name)
+	m.mu.Unlock()
+}
+
+// Client returns the client of the host.
+func (m *Manager) Client(name string) (*Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.hosts[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown host %s", name)
+	}
+	return h.client, nil
+}
+
+// Do runs fn with the client of the host and updates the health of the host
+// by the returned error. Errors are prefixed with the name of the host.
+//
+//	err := m.Do(ctx, "lab-2", func(ctx context.Context, c *docker.Client) error {
+//	    _, err := c.Run(ctx, spec, docker.RunOptions{})
+//	    return err
+//	})
+func (m *Manager) Do(ctx context.Context, name string, fn func(context.Context, *Client) error) error {
+	c, err := m.Client(name)
+	if err != nil {
+		return err
+	}
+	err = fn(ctx, c)
+	m.record(ctx, name, err)
+	if err != nil {
+		return fmt.Errorf("host %s: %w", name, err)
+	}
+	return nil
+}
+
+// Check pings all hosts in parallel and updates their health. If hosts are
+// unreachable, a BatchError keyed by host name is returned.
+func (m *Manager) Check(ctx context.Context) error {
+	names := m.Names()
+	return forEach(ctx, names, len(names), func(ctx context.Context, name string) error {
+		return m.Do(ctx, name, func(ctx context.Context, c *Client) error {
+			_, err := c.Ping(ctx)
+			return err
+		})
+	})
+}
+
+// Names returns the names of all hosts in sorted order.
+func (m *Manager) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return sortedKeys(m.hosts)
+}
+
+// Healthy returns the names of the healthy hosts in sorted order.
+func (m *Manager) Healthy() []string {
+	var names []string
+	for _, s := range m.Status() {
+		if s.Healthy {
+			names = append(names, s.Name)
+		}
+	}
+	return names
+}
+
+// Status returns the health of all hosts sorted by name.
+func (m *Manager) Status() []HostStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := make([]HostStatus, 0, len(m.hosts))
+	for _, h := range m.hosts {
+		res = append(res, h.status)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// record updates the health of the host by the result of a call. Errors
+// which are neither caused by the connection nor by the daemon, e.g. those
+// of the caller's context, leave the health as it is.
+func (m *Manager) record(ctx context.Context, name string, err error) {
+	var (
+		apiErr *APIError
+		urlErr *url.Error
+	)
+	// a connection canceled by the caller does not tell anything
+	failed := errors.As(err, &urlErr) && ctx.Err() == nil
+	if err != nil && !failed && !errors.As(err, &apiErr) {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.hosts[name]
+	if !ok {
+		return
+	}
+	if failed {
+		h.status.Healthy = false
+		h.status.Failures++
+		h.status.LastError = err
+		return
+	}
+	h.status.Healthy = true
+	h.status.Failures = 0
+	h.status.LastSeen = time.Now()
+}
@@ -0,0 +1,76 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func Test_parseHost(t *testing.T) {
+	tests := []struct {
+		host, network, addr string
+		err                 bool
+	}{
+		{host: "/var/run/docker.sock", network: "unix", addr: "/var/run/docker.sock"},
+		{host: "unix:///var/run/docker.sock", network: "unix", addr: "/var/run/docker.sock"},
+		{host: "tcp://lab-1:2376", network: "tcp", addr: "lab-1:2376"},
+		{host: "tcp://10.0.0.1", network: "tcp", addr: "10.0.0.1:2375"},
+		{host: "ssh://lab-1", err: true},
+		{host: "lab-1", err: true},
+	}
+	for _, tt := range tests {
+		network, addr, err := parseHost(tt.host)
+		if (err != nil) != tt.err || network != tt.network || addr != tt.addr {
+			t.Errorf("%s: unexpected result %s, %s, %v", tt.host, network, addr, err)
+		}
+	}
+}
+
+func Test_Manager(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /_ping": {Body: "OK"},
+		"GET /info":  {StatusCode: http.StatusInternalServerError, Body: `{"message": "boom"}`},
+	})
+	defer srv.route(nil)
+	ctx := context.Background()
+
+	m := NewManager()
+	if err := m.Add("lab-1", "unix://"+sockPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add("lab-2", "unix:///nonexistent/docker.sock"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddClient("lab-1", client); !errors.Is(err, ErrNameTaken) {
+		t.Errorf("expected ErrNameTaken, got %v", err)
+	}
+
+	err := m.Check(ctx)
+	be, ok := err.(BatchError)
+	if !ok || len(be) != 1 || be["lab-2"] == nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if got := m.Healthy(); len(got) != 1 || got[0] != "lab-1" {
+		t.Errorf("unexpected healthy hosts %v", got)
+	}
+	status := m.Status()
+	if status[1].Healthy || status[1].Failures != 1 || status[1].LastError == nil {
+		t.Errorf("unexpected status %+v", status[1])
+	}
+
+	// errors reported by the daemon do not affect the health
+	err = m.Do(ctx, "lab-1", func(ctx context.Context, c *Client) error {
+		_, err := c.Info(ctx)
+		return err
+	})
+	if err == nil {
+		t.Error("expected error")
+	}
+	if got := m.Healthy(); len(got) != 1 {
+		t.Errorf("unexpected healthy hosts %v", got)
+	}
+	if err := m.Do(ctx, "lab-3", nil); err == nil {
+		t.Error("expected error for unknown host")
+	}
+}
@@ -0,0 +1,86 @@
+package docker
+
+import (
+	"context"
+	"sync"
+)
+
+// EnableNameCache caches the IDs looked up by ContainerIDByName,
+// NetworkIDByName and by the name lookups of Apply. An entry is dropped when
+// the daemon reports that its container was destroyed or renamed or its
+// network destroyed. The cache is only used while the client is subscribed
+// to the events and stays enabled until the context is done.
+// EnableNameCache has to be called before the client is used.
+func (c *Client) EnableNameCache(ctx context.Context) {
+	cache := &nameCache{}
+	c.names = cache
+	go c.subscribe(ctx, EventFilter{
+		Types:   []string{"container", "network"},
+		Actions: []string{"destroy", "rename"},
+	}, cache.activate, cache.deactivate, cache.invalidate)
+}
+
+// nameCache maps names to IDs by the kind of the object. A nil cache caches
+// nothing.
+type nameCache struct {
+	mu   sync.Mutex
+	live bool
+	// gen is increased by every invalidation, lookups started before are
+	// not cached anymore.
+	gen uint64
+	ids map[nameKey]string
+}
+
+type nameKey struct {
+	kind, name string
+}
+
+// get returns the cached ID and the generation to put a looked up ID with.
+func (nc *nameCache) get(kind, name string) (string, uint64, bool) {
+	if nc == nil {
+		return "", 0, false
+	}
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	id, ok := nc.ids[nameKey{kind, name}]
+	return id, nc.gen, ok && nc.live
+}
+
+func (nc *nameCache) put(kind, name, id string, gen uint64) {
+	if nc == nil {
+		return
+	}
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	if nc.live && gen == nc.gen {
+		nc.ids[nameKey{kind, name}] = id
+	}
+}
+
+func (nc *nameCache) activate() {
+	nc.mu.Lock()
+	nc.live = true
+	nc.ids = make(map[nameKey]string)
+	nc.gen++
+	nc.mu.Unlock()
+}
+
+func (nc *nameCache) deactivate() {
+	nc.mu.Lock()
+	nc.live = false
+	nc.ids = nil
+	nc.gen++
+	nc.mu.Unlock()
+}
+
+// invalidate drops the names of the destroyed or renamed object.
+func (nc *nameCache) invalidate(ev Event) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	nc.gen++
+	for k, id := range nc.ids {
+		if k.kind == ev.Type && id == ev.ID {
+			delete(nc.ids, k)
+		}
+	}
+}
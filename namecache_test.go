@@ -0,0 +1,40 @@
+package docker
+
+import "testing"
+
+func Test_nameCache(t *testing.T) {
+	var none *nameCache
+	if _, _, ok := none.get("container", "plc"); ok {
+		t.Error("nil cache must not hit")
+	}
+	none.put("container", "plc", "c1", 0)
+
+	nc := &nameCache{}
+	_, gen, _ := nc.get("container", "plc")
+	nc.put("container", "plc", "c1", gen)
+	if _, _, ok := nc.get("container", "plc"); ok {
+		t.Error("inactive cache must not hit")
+	}
+
+	nc.activate()
+	_, gen, _ = nc.get("container", "plc")
+	nc.put("container", "plc", "c1", gen)
+	nc.put("network", "field", "c1", gen)
+	if id, _, ok := nc.get("container", "plc"); !ok || id != "c1" {
+		t.Errorf("unexpected lookup %s, %v", id, ok)
+	}
+
+	// lookups started before an invalidation are not cached
+	_, gen, _ = nc.get("container", "db")
+	nc.invalidate(Event{Type: "container", Action: "rename", ID: "c1"})
+	nc.put("container", "db", "c2", gen)
+	if _, _, ok := nc.get("container", "db"); ok {
+		t.Error("stale lookup was cached")
+	}
+	if _, _, ok := nc.get("container", "plc"); ok {
+		t.Error("renamed container still cached")
+	}
+	if _, _, ok := nc.get("network", "field"); !ok {
+		t.Error("network with the same ID was dropped")
+	}
+}
@@ -0,0 +1,100 @@
+package docker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrNameTaken is returned by FindOrAdoptByName if a resource with the name
+// exists but belongs to somebody else.
+var ErrNameTaken = errors.New("name is taken")
+
+// NameGenerator generates names of the form <prefix>-<run>-<n>-<suffix>.
+// The counter n makes names unique within the generator, the random suffix
+// makes them unique between generators with the same prefix and run, e.g.
+// of CI jobs running in parallel on one host.
+type NameGenerator struct {
+	prefix string
+	run    string
+
+	mu sync.Mutex
+	n  int
+}
+
+// NewNameGenerator returns a generator for names of the given run.
+// e.g.: g := NewNameGenerator("sim", run); name := g.Next()
+func NewNameGenerator(prefix, run string) *NameGenerator {
+	return &NameGenerator{prefix: prefix, run: run}
+}
+
+// Next returns a new name.
+func (g *NameGenerator) Next() string {
+	g.mu.Lock()
+	g.n++
+	n := g.n
+	g.mu.Unlock()
+
+	parts := make([]string, 0, 4)
+	for _, p := range []string{g.prefix, g.run} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	parts = append(parts, fmt.Sprint(n), randomSuffix())
+	return strings.Join(parts, "-")
+}
+
+func randomSuffix() string {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("read random: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// FindOrAdoptByName looks up the container or network with the given name.
+// If it exists and matches the owner selector, its ID is returned and found
+// is true, so the caller can adopt it instead of creating it again. If it
+// does not exist, found is false. A resource with the name which does not
+// match the selector belongs to somebody else and ErrNameTaken is returned.
+// The resource is either "container" or "network".
+func (c *Client) FindOrAdoptByName(ctx context.Context, resource, name string, owner Selector) (id string, found bool, err error) {
+	var (
+		actual string
+		labels map[string]string
+	)
+	switch resource {
+	case "container":
+		cj, err := c.inspectContainer(ctx, name)
+		if isNotFound(err) {
+			return "", false, nil
+		} else if err != nil {
+			return "", false, fmt.Errorf("inspect container %s: %w", name, err)
+		}
+		id, actual, labels = cj.ID, strings.TrimPrefix(cj.Name, "/"), cj.Config.Labels
+	case "network":
+		n, err := c.inspectNetwork(ctx, name)
+		if isNotFound(err) {
+			return "", false, nil
+		} else if err != nil {
+			return "", false, fmt.Errorf("inspect network %s: %w", name, err)
+		}
+		id, actual, labels = n.ID, n.Name, n.Labels
+	default:
+		return "", false, fmt.Errorf("unknown resource %q", resource)
+	}
+
+	// the daemon also resolves ID prefixes, those are not matches
+	if actual != name {
+		return "", false, nil
+	}
+	if !owner.Matches(labels) {
+		return "", false, fmt.Errorf("%s %s: %w", resource, name, ErrNameTaken)
+	}
+	return id, true, nil
+}
@@ -0,0 +1,43 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+)
+
+func Test_NameGenerator(t *testing.T) {
+	g := NewNameGenerator("sim", "r1")
+	a, b := g.Next(), g.Next()
+	if !regexp.MustCompile(`^sim-r1-1-[0-9a-f]{6}$`).MatchString(a) {
+		t.Errorf("unexpected name %s", a)
+	}
+	if !regexp.MustCompile(`^sim-r1-2-[0-9a-f]{6}$`).MatchString(b) {
+		t.Errorf("unexpected name %s", b)
+	}
+}
+
+func Test_FindOrAdoptByName(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /containers/mine/json":    {Body: `{"Id": "c1", "Name": "/mine", "Config": {"Labels": {"` + LabelRun + `": "r1"}}}`},
+		"GET /containers/foreign/json": {Body: `{"Id": "c2", "Name": "/foreign", "Config": {"Labels": {}}}`},
+		"GET /networks/abc":            {Body: `{"Id": "abcdef", "Name": "other"}`},
+	})
+	defer srv.route(nil)
+	ctx := context.Background()
+
+	id, found, err := client.FindOrAdoptByName(ctx, "container", "mine", RunSelector("r1"))
+	if err != nil || !found || id != "c1" {
+		t.Errorf("unexpected result %s, %v, %v", id, found, err)
+	}
+	if _, _, err := client.FindOrAdoptByName(ctx, "container", "foreign", RunSelector("r1")); !errors.Is(err, ErrNameTaken) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, found, err := client.FindOrAdoptByName(ctx, "container", "missing", RunSelector("r1")); err != nil || found {
+		t.Errorf("unexpected result %v, %v", found, err)
+	}
+	if _, found, err := client.FindOrAdoptByName(ctx, "network", "abc", RunSelector("r1")); err != nil || found {
+		t.Errorf("ID prefix must not match: %v, %v", found, err)
+	}
+}
@@ -0,0 +1,151 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Impairment degrades the network of a container like a flaky field network
+// does. It is applied with the netem queueing discipline of tc to the
+// outgoing traffic of an interface.
+type Impairment struct {
+	// Latency delays every packet, Jitter varies the delay randomly by up
+	// to its value.
+	Latency time.Duration
+	Jitter  time.Duration
+	// Loss is the percentage of dropped packets, e.g. 0.5.
+	Loss float64
+	// Bandwidth caps the rate in bits per second.
+	Bandwidth int64
+}
+
+// args returns the netem arguments of tc.
+func (i Impairment) args() ([]string, error) {
+	var args []string
+	switch {
+	case i.Latency < 0 || i.Jitter < 0:
+		return nil, fmt.Errorf("latency and jitter must not be negative")
+	case i.Jitter > 0 && i.Latency == 0:
+		return nil, fmt.Errorf("jitter requires a latency")
+	case i.Latency > 0:
+		args = append(args, "delay", fmt.Sprintf("%dus", i.Latency.Microseconds()))
+		if i.Jitter > 0 {
+			args = append(args, fmt.Sprintf("%dus", i.Jitter.Microseconds()))
+		}
+	}
+	switch {
+	case i.Loss < 0 || i.Loss > 100:
+		return nil, fmt.Errorf("loss %g is not a percentage", i.Loss)
+	case i.Loss > 0:
+		args = append(args, "loss", fmt.Sprintf("%g%%", i.Loss))
+	}
+	switch {
+	case i.Bandwidth < 0:
+		return nil, fmt.Errorf("bandwidth must not be negative")
+	case i.Bandwidth > 0:
+		args = append(args, "rate", fmt.Sprintf("%dbit", i.Bandwidth))
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no impairment set")
+	}
+	return args, nil
+}
+
+// ImpairOptions configure how an impairment is applied.
+type ImpairOptions struct {
+	// Interface is the interface of the container. Defaults to eth0.
+	Interface string
+	// Sidecar is the image of a helper container, e.g. nicolaka/netshoot,
+	// which runs tc in the network namespace of the container with the
+	// NET_ADMIN capability. If it is empty, tc is executed in the container
+	// itself, which then needs tc and the NET_ADMIN capability.
+	Sidecar string
+}
+
+// Impair applies the impairment to the container's interface and replaces
+// a previous one. It lasts until ClearImpairment is called or the container
+// stops.
+func (c *Client) Impair(ctx context.Context, id string, imp Impairment, opts ImpairOptions) error {
+	args, err := imp.args()
+	if err != nil {
+		return fmt.Errorf("impair %s: %v", id, err)
+	}
+	args = append([]string{"qdisc", "replace", "dev", opts.iface(), "root", "netem"}, args...)
+	if err := c.tc(ctx, id, args, opts); err != nil {
+		return fmt.Errorf("impair %s: %w", id, err)
+	}
+	return nil
+}
+
+// ClearImpairment removes the impairment from the container's interface.
+// An interface without impairment is not treated as error.
+func (c *Client) ClearImpairment(ctx context.Context, id string, opts ImpairOptions) error {
+	err := c.tc(ctx, id, []string{"qdisc", "del", "dev", opts.iface(), "root"}, opts)
+	// tc reports the default discipline, which can not be deleted, in
+	// various ways depending on its version
+	if err != nil && !strings.Contains(err.Error(), "handle of zero") &&
+		!strings.Contains(err.Error(), "No such file or directory") {
+		return fmt.Errorf("clear impairment of %s: %w", id, err)
+	}
+	return nil
+}
+
+func (o ImpairOptions) iface() string {
+	if o.Interface == "" {
+		return "eth0"
+	}
+	return o.Interface
+}
+
+// tc runs tc with the arguments in the network namespace of the container.
+func (c *Client) tc(ctx context.Context, id string, args []string, opts ImpairOptions) error {
+	var res *CaptureResult
+	var err error
+	if opts.Sidecar == "" {
+		res, err = c.Exec(ctx, id, append([]string{"tc"}, args...))
+	} else {
+		res, err = c.runSidecar(ctx, id, opts.Sidecar, args)
+	}
+	if err != nil {
+		return err
+	}
+	if res.ExitCode != 0 {
+		return fmt.Errorf("tc exited with code %d: %s", res.ExitCode, bytes.TrimSpace(res.Stderr))
+	}
+	return nil
+}
+
+// runSidecar runs tc in a container of the image which shares the network
+// namespace of the container. The sidecar is removed afterwards.
+func (c *Client) runSidecar(ctx context.Context, id, image string, args []string) (*CaptureResult, error) {
+	body := &containerCreate{
+		Image:      image,
+		Entrypoint: []string{"tc"},
+		Cmd:        args,
+		HostConfig: hostConfig{
+			NetworkMode: "container:" + id,
+			CapAdd:      []string{"NET_ADMIN"},
+		},
+	}
+	created, err := c.createContainer(ctx, "", body)
+	if err != nil {
+		return nil, fmt.Errorf("create sidecar: %w", err)
+	}
+	defer c.removeContainer(context.Background(), created.ID, true)
+
+	if err := c.startContainer(ctx, created.ID); err != nil {
+		return nil, fmt.Errorf("start sidecar: %w", err)
+	}
+	cj, err := c.waitContainer(ctx, created.ID, WaitExited, c.backoffOr(ConstantBackoff(defaultPollInterval)))
+	if err != nil {
+		return nil, fmt.Errorf("sidecar: %w", err)
+	}
+	var stdout, stderr bytes.Buffer
+	if err := c.Logs(ctx, created.ID, &stdout, &stderr); err != nil {
+		return nil, fmt.Errorf("sidecar: %w", err)
+	}
+	return &CaptureResult{ExitCode: cj.State.ExitCode, Stdout: stdout.Bytes(), Stderr: stderr.Bytes()}, nil
+}
@@ -0,0 +1,114 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ImpairmentArgs(t *testing.T) {
+	tt := []struct {
+		name    string
+		imp     Impairment
+		expect  []string
+		wantErr bool
+	}{
+		{
+			name:   "all",
+			imp:    Impairment{Latency: 100 * time.Millisecond, Jitter: 20 * time.Millisecond, Loss: 0.5, Bandwidth: 64000},
+			expect: []string{"delay", "100000us", "20000us", "loss", "0.5%", "rate", "64000bit"},
+		},
+		{
+			name:   "loss only",
+			imp:    Impairment{Loss: 10},
+			expect: []string{"loss", "10%"},
+		},
+		{
+			name:    "jitter without latency",
+			imp:     Impairment{Jitter: time.Millisecond},
+			wantErr: true,
+		},
+		{
+			name:    "loss above 100",
+			imp:     Impairment{Loss: 150},
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			args, err := tc.imp.args()
+			if err != nil && !tc.wantErr {
+				t.Fatal(err)
+			}
+			if err == nil && tc.wantErr {
+				t.Fatal("expected error")
+			}
+			if !reflect.DeepEqual(args, tc.expect) {
+				t.Errorf("got: %v, want: %v", args, tc.expect)
+			}
+		})
+	}
+}
+
+func Test_Impair(t *testing.T) {
+	stderr := "Error: Cannot delete qdisc with handle of zero.\n"
+	srv.route(map[string]mockResponse{
+		"POST /containers/plc/exec": {StatusCode: http.StatusCreated, Body: `{"Id": "e1"}`},
+		"POST /exec/e1/start":       {},
+		"GET /exec/e1/json":         {Body: `{"ID": "e1", "Running": false, "ExitCode": 0}`},
+	})
+	defer srv.route(nil)
+
+	imp := Impairment{Latency: 50 * time.Millisecond}
+	if err := client.Impair(context.Background(), "plc", imp, ImpairOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.route(map[string]mockResponse{
+		"POST /containers/plc/exec": {StatusCode: http.StatusCreated, Body: `{"Id": "e1"}`},
+		"POST /exec/e1/start":       {Body: string([]byte{2, 0, 0, 0, 0, 0, 0, byte(len(stderr))}) + stderr},
+		"GET /exec/e1/json":         {Body: `{"ID": "e1", "Running": false, "ExitCode": 2}`},
+	})
+	if err := client.ClearImpairment(context.Background(), "plc", ImpairOptions{}); err != nil {
+		t.Errorf("clearing an unimpaired interface failed: %v", err)
+	}
+	err := client.Impair(context.Background(), "plc", imp, ImpairOptions{})
+	if err == nil || !strings.Contains(err.Error(), "tc exited with code 2: Error: Cannot delete") {
+		t.Errorf("got error: %v", err)
+	}
+}
+
+func Test_ImpairSidecar(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"POST /containers/create":   {StatusCode: http.StatusCreated, Body: `{"Id": "s1"}`},
+		"POST /containers/s1/start": {StatusCode: http.StatusNoContent},
+		"GET /containers/s1/json":   {Body: `{"Id": "s1", "Name": "/s1", "State": {"Status": "exited", "ExitCode": 0}}`},
+		"GET /containers/s1/logs":   {},
+		"DELETE /containers/s1":     {StatusCode: http.StatusNoContent},
+	})
+	defer srv.route(nil)
+
+	opts := ImpairOptions{Sidecar: "nicolaka/netshoot", Interface: "eth1"}
+	if err := client.Impair(context.Background(), "plc", Impairment{Loss: 5}, opts); err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"POST /containers/create",
+		"POST /containers/s1/start",
+		"GET /containers/s1/json",
+		"GET /containers/s1/json",
+		"GET /containers/s1/logs",
+		"DELETE /containers/s1",
+	}
+	if got := srv.Requests(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("got: %v, want: %v", got, expect)
+	}
+}
@@ -0,0 +1,88 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grid-x/docker/types"
+)
+
+// Network is a handle of a network managed by the client.
+type Network struct {
+	ID   string
+	Name string
+	c    *Client
+}
+
+// CreateNetworkFromSpec creates the network and returns its handle.
+func (c *Client) CreateNetworkFromSpec(ctx context.Context, spec NetworkSpec) (*Network, error) {
+	if spec.Name == "" {
+		return nil, fmt.Errorf("create network: name must not be empty")
+	}
+	res, err := c.createNetwork(ctx, spec.createBody())
+	if err != nil {
+		return nil, fmt.Errorf("create network %s: %w", spec.Name, err)
+	}
+	return &Network{ID: res.ID, Name: spec.Name, c: c}, nil
+}
+
+// Network returns the handle of an existing network.
+func (c *Client) Network(ctx context.Context, nameOrID string) (*Network, error) {
+	n, err := c.inspectNetwork(ctx, nameOrID)
+	if err != nil {
+		return nil, fmt.Errorf("inspect network %s: %w", nameOrID, err)
+	}
+	return &Network{ID: n.ID, Name: n.Name, c: c}, nil
+}
+
+// ConnectOptions configure the attachment of a container to a network.
+type ConnectOptions struct {
+	// Aliases are additional names of the container on the network.
+	Aliases []string
+	// IPv4Address is a static address within the subnet of the network.
+	IPv4Address string
+}
+
+func (o ConnectOptions) endpointConfig() *endpointConfig {
+	return NetworkAttachment{Aliases: o.Aliases, IPv4Address: o.IPv4Address}.endpointConfig()
+}
+
+// Connect attaches the container to the network.
+func (n *Network) Connect(ctx context.Context, ct *Container, opts ConnectOptions) error {
+	if err := n.c.connectNetwork(ctx, n.ID, ct.ID, opts.endpointConfig()); err != nil {
+		return fmt.Errorf("connect container %s to %s: %w", ct, n.Name, err)
+	}
+	return nil
+}
+
+// ConnectAll attaches the containers to the network, see Client.ConnectAll.
+func (n *Network) ConnectAll(ctx context.Context, containers []ConnectSpec, concurrency int) error {
+	return n.c.ConnectAll(ctx, n.ID, containers, concurrency)
+}
+
+// Disconnect detaches the container from the network. A running container
+// is detached by force.
+func (n *Network) Disconnect(ctx context.Context, ct *Container) error {
+	if err := n.c.disconnectNetwork(ctx, n.ID, ct.ID, true); err != nil {
+		return fmt.Errorf("disconnect container %s from %s: %w", ct, n.Name, err)
+	}
+	return nil
+}
+
+// Inspect returns the current state of the network.
+func (n *Network) Inspect(ctx context.Context) (*types.NetworkSummary, error) {
+	res, err := n.c.inspectNetwork(ctx, n.ID)
+	if err != nil {
+		return nil, fmt.Errorf("inspect network %s: %w", n.Name, err)
+	}
+	return res, nil
+}
+
+// Remove removes the network. A network which is gone already is not
+// treated as error.
+func (n *Network) Remove(ctx context.Context) error {
+	if err := n.c.removeNetwork(ctx, n.ID); err != nil && !isNotFound(err) {
+		return fmt.Errorf("remove network %s: %w", n.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func Test_Network(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"POST /networks/create":        {StatusCode: http.StatusCreated, Body: `{"Id": "n1"}`},
+		"POST /networks/n1/connect":    {},
+		"POST /networks/n1/disconnect": {},
+		"GET /networks/n1":             {Body: `{"Id": "n1", "Name": "field", "Driver": "bridge"}`},
+		"GET /networks/field":          {Body: `{"Id": "n1", "Name": "field", "Driver": "bridge"}`},
+		"DELETE /networks/n1":          {StatusCode: http.StatusNoContent},
+	})
+	defer srv.route(nil)
+
+	ctx := context.Background()
+	nw, err := client.CreateNetworkFromSpec(ctx, NetworkSpec{Name: "field", Subnet: "10.0.0.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct := &Container{ID: "c1", Name: "plc", c: client}
+	if err := nw.Connect(ctx, ct, ConnectOptions{Aliases: []string{"plc"}, IPv4Address: "10.0.0.2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := nw.Disconnect(ctx, ct); err != nil {
+		t.Fatal(err)
+	}
+	n, err := nw.Inspect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Driver != "bridge" {
+		t.Errorf("got driver %s", n.Driver)
+	}
+	same, err := client.Network(ctx, "field")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(same, nw) {
+		t.Errorf("got: %+v, want: %+v", same, nw)
+	}
+	if err := nw.Remove(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []string{
+		"POST /networks/create",
+		"POST /networks/n1/connect",
+		"POST /networks/n1/disconnect",
+		"GET /networks/n1",
+		"GET /networks/field",
+		"DELETE /networks/n1",
+	}
+	if got := srv.Requests(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("got: %v, want: %v", got, expect)
+	}
+}
@@ -0,0 +1,257 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grid-x/docker/types"
+)
+
+// OCIVersion is the version of the OCI runtime spec written by
+// ExportOCISpec.
+const OCIVersion = "1.0.2"
+
+// OCISpec is the subset of the OCI runtime spec (config.json) which can be
+// derived from an inspected container.
+// docs.: https://github.com/opencontainers/runtime-spec/blob/main/config.md
+type OCISpec struct {
+	OCIVersion  string            `json:"ociVersion"`
+	Process     OCIProcess        `json:"process"`
+	Root        OCIRoot           `json:"root"`
+	Hostname    string            `json:"hostname,omitempty"`
+	Mounts      []OCIMount        `json:"mounts,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Linux       *OCILinux         `json:"linux,omitempty"`
+}
+
+// OCIProcess is the process started in the container.
+type OCIProcess struct {
+	Terminal     bool             `json:"terminal,omitempty"`
+	User         OCIUser          `json:"user"`
+	Args         []string         `json:"args"`
+	Env          []string         `json:"env,omitempty"`
+	Cwd          string           `json:"cwd"`
+	Capabilities *OCICapabilities `json:"capabilities,omitempty"`
+}
+
+// OCIUser is the user the process runs as.
+type OCIUser struct {
+	UID uint32 `json:"uid"`
+	GID uint32 `json:"gid"`
+}
+
+// OCICapabilities are the Linux capabilities of the process.
+type OCICapabilities struct {
+	Bounding  []string `json:"bounding,omitempty"`
+	Effective []string `json:"effective,omitempty"`
+	Permitted []string `json:"permitted,omitempty"`
+}
+
+// OCIRoot is the root filesystem of the container, relative to the bundle.
+type OCIRoot struct {
+	Path     string `json:"path"`
+	Readonly bool   `json:"readonly,omitempty"`
+}
+
+// OCIMount is a mount of the container.
+type OCIMount struct {
+	Destination string   `json:"destination"`
+	Type        string   `json:"type,omitempty"`
+	Source      string   `json:"source,omitempty"`
+	Options     []string `json:"options,omitempty"`
+}
+
+// OCILinux holds the Linux specific configuration.
+type OCILinux struct {
+	Namespaces    []OCINamespace `json:"namespaces"`
+	MaskedPaths   []string       `json:"maskedPaths,omitempty"`
+	ReadonlyPaths []string       `json:"readonlyPaths,omitempty"`
+}
+
+// OCINamespace is a namespace the container is created in.
+type OCINamespace struct {
+	Type string `json:"type"`
+}
+
+// defaultCapabilities are the capabilities docker grants by default.
+var defaultCapabilities = []string{
+	"CAP_AUDIT_WRITE", "CAP_CHOWN", "CAP_DAC_OVERRIDE", "CAP_FOWNER", "CAP_FSETID",
+	"CAP_KILL", "CAP_MKNOD", "CAP_NET_BIND_SERVICE", "CAP_NET_RAW", "CAP_SETFCAP",
+	"CAP_SETGID", "CAP_SETPCAP", "CAP_SETUID", "CAP_SYS_CHROOT",
+}
+
+// allCapabilities are the capabilities of privileged containers.
+var allCapabilities = []string{
+	"CAP_AUDIT_CONTROL", "CAP_AUDIT_READ", "CAP_AUDIT_WRITE", "CAP_BLOCK_SUSPEND", "CAP_BPF",
+	"CAP_CHECKPOINT_RESTORE", "CAP_CHOWN", "CAP_DAC_OVERRIDE", "CAP_DAC_READ_SEARCH", "CAP_FOWNER",
+	"CAP_FSETID", "CAP_IPC_LOCK", "CAP_IPC_OWNER", "CAP_KILL", "CAP_LEASE",
+	"CAP_LINUX_IMMUTABLE", "CAP_MAC_ADMIN", "CAP_MAC_OVERRIDE", "CAP_MKNOD", "CAP_NET_ADMIN",
+	"CAP_NET_BIND_SERVICE", "CAP_NET_BROADCAST", "CAP_NET_RAW", "CAP_PERFMON", "CAP_SETFCAP",
+	"CAP_SETGID", "CAP_SETPCAP", "CAP_SETUID", "CAP_SYSLOG", "CAP_SYS_ADMIN",
+	"CAP_SYS_BOOT", "CAP_SYS_CHROOT", "CAP_SYS_MODULE", "CAP_SYS_NICE", "CAP_SYS_PACCT",
+	"CAP_SYS_PTRACE", "CAP_SYS_RAWIO", "CAP_SYS_RESOURCE", "CAP_SYS_TIME", "CAP_SYS_TTY_CONFIG",
+	"CAP_WAKE_ALARM",
+}
+
+// defaultOCIMounts are the filesystems every container gets, as in the
+// spec generated by runc.
+var defaultOCIMounts = []OCIMount{
+	{Destination: "/proc", Type: "proc", Source: "proc"},
+	{Destination: "/dev", Type: "tmpfs", Source: "tmpfs", Options: []string{"nosuid", "strictatime", "mode=755", "size=65536k"}},
+	{Destination: "/dev/pts", Type: "devpts", Source: "devpts", Options: []string{"nosuid", "noexec", "newinstance", "ptmxmode=0666", "mode=0620", "gid=5"}},
+	{Destination: "/dev/shm", Type: "tmpfs", Source: "shm", Options: []string{"nosuid", "noexec", "nodev", "mode=1777", "size=65536k"}},
+	{Destination: "/dev/mqueue", Type: "mqueue", Source: "mqueue", Options: []string{"nosuid", "noexec", "nodev"}},
+	{Destination: "/sys", Type: "sysfs", Source: "sysfs", Options: []string{"nosuid", "noexec", "nodev", "ro"}},
+}
+
+// OCISpecFromInspect converts an inspected container into an OCI runtime
+// spec. The root filesystem is expected in the rootfs directory of the
+// bundle, e.g. extracted from docker export. The labels and annotations of
+// the container become annotations, annotations win over labels of the same
+// name. Users have to be numeric, names can not be resolved
+// without the filesystem of the image. Networking, resource limits and
+// security profiles are not converted.
+func OCISpecFromInspect(cj *types.ContainerJSON) (*OCISpec, error) {
+	user, err := parseOCIUser(cj.Config.User)
+	if err != nil {
+		return nil, err
+	}
+	spec := &OCISpec{
+		OCIVersion: OCIVersion,
+		Process: OCIProcess{
+			Terminal: cj.Config.Tty,
+			User:     user,
+			Args:     append(append([]string(nil), cj.Config.Entrypoint...), cj.Config.Cmd...),
+			Env:      cj.Config.Env,
+			Cwd:      cj.Config.WorkingDir,
+		},
+		Root:     OCIRoot{Path: "rootfs", Readonly: cj.HostConfig.ReadonlyRootfs},
+		Hostname: cj.Config.Hostname,
+		Mounts:   append([]OCIMount(nil), defaultOCIMounts...),
+		Linux: &OCILinux{
+			Namespaces: []OCINamespace{{"pid"}, {"network"}, {"ipc"}, {"uts"}, {"mount"}},
+		},
+	}
+	if spec.Process.Cwd == "" {
+		spec.Process.Cwd = "/"
+	}
+	if n := len(cj.Config.Labels) + len(cj.HostConfig.Annotations); n > 0 {
+		spec.Annotations = make(map[string]string, n)
+		for k, v := range cj.Config.Labels {
+			spec.Annotations[k] = v
+		}
+		for k, v := range cj.HostConfig.Annotations {
+			spec.Annotations[k] = v
+		}
+	}
+
+	caps := allCapabilities
+	if !cj.HostConfig.Privileged {
+		caps = adjustCapabilities(defaultCapabilities, cj.HostConfig.CapAdd, cj.HostConfig.CapDrop)
+		spec.Linux.MaskedPaths = []string{"/proc/acpi", "/proc/kcore", "/proc/keys", "/proc/latency_stats",
+			"/proc/timer_list", "/proc/timer_stats", "/proc/sched_debug", "/proc/scsi", "/sys/firmware"}
+		spec.Linux.ReadonlyPaths = []string{"/proc/asound", "/proc/bus", "/proc/fs", "/proc/irq",
+			"/proc/sys", "/proc/sysrq-trigger"}
+	}
+	spec.Process.Capabilities = &OCICapabilities{Bounding: caps, Effective: caps, Permitted: caps}
+
+	for _, m := range cj.Mounts {
+		om := OCIMount{Destination: m.Destination, Type: "bind", Source: m.Source}
+		switch m.Type {
+		case "bind", "volume":
+			om.Options = []string{"rbind"}
+		case "tmpfs":
+			om.Type, om.Source = "tmpfs", "tmpfs"
+			om.Options = []string{"nosuid", "nodev"}
+		default:
+			continue
+		}
+		if m.RW {
+			om.Options = append(om.Options, "rw")
+		} else {
+			om.Options = append(om.Options, "ro")
+		}
+		spec.Mounts = append(spec.Mounts, om)
+	}
+	return spec, nil
+}
+
+// ExportOCISpec writes the OCI runtime spec of the container as indented
+// JSON to w, ready to be used as config.json of a bundle.
+func (c *Client) ExportOCISpec(ctx context.Context, id string, w io.Writer) error {
+	cj, err := c.inspectContainer(ctx, id)
+	if err != nil {
+		return fmt.Errorf("inspect container %s: %w", id, err)
+	}
+	spec, err := OCISpecFromInspect(cj)
+	if err != nil {
+		return fmt.Errorf("export OCI spec of %s: %w", id, err)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(spec)
+}
+
+// parseOCIUser parses the numeric user of a container as uid[:gid].
+func parseOCIUser(user string) (OCIUser, error) {
+	if user == "" {
+		return OCIUser{}, nil
+	}
+	ss := strings.SplitN(user, ":", 2)
+	uid, err := strconv.ParseUint(ss[0], 10, 32)
+	if err != nil {
+		return OCIUser{}, fmt.Errorf("user %q can not be resolved without the image", user)
+	}
+	res := OCIUser{UID: uint32(uid)}
+	if len(ss) == 2 {
+		gid, err := strconv.ParseUint(ss[1], 10, 32)
+		if err != nil {
+			return OCIUser{}, fmt.Errorf("group %q can not be resolved without the image", ss[1])
+		}
+		res.GID = uint32(gid)
+	}
+	return res, nil
+}
+
+// adjustCapabilities adds and drops capabilities, given with or without the
+// CAP_ prefix. ALL drops every capability.
+func adjustCapabilities(caps, add, drop []string) []string {
+	set := make(map[string]bool)
+	for _, c := range caps {
+		set[c] = true
+	}
+	norm := func(c string) string {
+		c = strings.ToUpper(c)
+		if c != "ALL" && !strings.HasPrefix(c, "CAP_") {
+			c = "CAP_" + c
+		}
+		return c
+	}
+	for _, c := range drop {
+		if c = norm(c); c == "ALL" {
+			set = make(map[string]bool)
+			continue
+		}
+		delete(set, c)
+	}
+	for _, c := range add {
+		if c = norm(c); c == "ALL" {
+			for _, a := range allCapabilities {
+				set[a] = true
+			}
+			continue
+		}
+		set[c] = true
+	}
+	res := make([]string, 0, len(set))
+	for c := range set {
+		res = append(res, c)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,61 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func Test_ExportOCISpec(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /containers/c1/json": {Body: `{"Id": "c1", "Name": "/plc",
+			"Config": {"Hostname": "plc", "User": "1000:1000", "Entrypoint": ["/sim"], "Cmd": ["--slaves", "10"],
+				"Env": ["MODE=tcp"], "Labels": {"run": "r1"}},
+			"HostConfig": {"CapAdd": ["NET_ADMIN"], "CapDrop": ["ALL"]},
+			"Mounts": [{"Type": "volume", "Source": "/var/lib/docker/volumes/data/_data", "Destination": "/data", "RW": true},
+				{"Type": "npipe", "Source": "x", "Destination": "y"}]}`},
+	})
+	defer srv.route(nil)
+
+	var buf bytes.Buffer
+	if err := client.ExportOCISpec(context.Background(), "c1", &buf); err != nil {
+		t.Fatal(err)
+	}
+	var spec OCISpec
+	if err := json.Unmarshal(buf.Bytes(), &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	wantProcess := OCIProcess{
+		User: OCIUser{UID: 1000, GID: 1000},
+		Args: []string{"/sim", "--slaves", "10"},
+		Env:  []string{"MODE=tcp"},
+		Cwd:  "/",
+		Capabilities: &OCICapabilities{
+			Bounding:  []string{"CAP_NET_ADMIN"},
+			Effective: []string{"CAP_NET_ADMIN"},
+			Permitted: []string{"CAP_NET_ADMIN"},
+		},
+	}
+	if !reflect.DeepEqual(spec.Process, wantProcess) {
+		t.Errorf("want process %+v, got %+v", wantProcess, spec.Process)
+	}
+	wantMount := OCIMount{Destination: "/data", Type: "bind", Source: "/var/lib/docker/volumes/data/_data", Options: []string{"rbind", "rw"}}
+	if n := len(spec.Mounts); n != len(defaultOCIMounts)+1 || !reflect.DeepEqual(spec.Mounts[n-1], wantMount) {
+		t.Errorf("unexpected mounts %+v", spec.Mounts)
+	}
+	if spec.Hostname != "plc" || spec.Annotations["run"] != "r1" || spec.Root.Path != "rootfs" {
+		t.Errorf("unexpected spec %+v", spec)
+	}
+}
+
+func Test_parseOCIUser(t *testing.T) {
+	if u, err := parseOCIUser("1000"); err != nil || u != (OCIUser{UID: 1000}) {
+		t.Errorf("unexpected user %+v, %v", u, err)
+	}
+	if _, err := parseOCIUser("postgres"); err == nil {
+		t.Error("expected error for named user")
+	}
+}
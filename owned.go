@@ -0,0 +1,255 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Namespace separates the resources of several users of one daemon.
+type Namespace struct {
+	// Name identifies the namespace. It is the value of the ownership label.
+	Name string
+	// LabelPrefix is the prefix of the ownership label <LabelPrefix>.namespace.
+	// Defaults to com.grid-x.docker.
+	LabelPrefix string
+	// NamePrefix is prepended to the names of all resources. Defaults to
+	// <Name>_.
+	NamePrefix string
+}
+
+// OwnedClient restricts a Client to the resources of a namespace. Everything
+// it creates carries the ownership label and the name prefix of the
+// namespace, which are added automatically. Names passed to and returned by
+// an OwnedClient are without prefix. Resources of other namespaces can
+// neither be listed nor changed.
+type OwnedClient struct {
+	c          *Client
+	label      string
+	value      string
+	namePrefix string
+}
+
+// Owned returns a client restricted to the namespace.
+// e.g.: oc, err := c.Owned(Namespace{Name: "ci-1234"})
+func (c *Client) Owned(ns Namespace) (*OwnedClient, error) {
+	if ns.Name == "" {
+		return nil, fmt.Errorf("namespace: name must not be empty")
+	}
+	if ns.LabelPrefix == "" {
+		ns.LabelPrefix = "com.grid-x.docker"
+	}
+	if ns.NamePrefix == "" {
+		ns.NamePrefix = ns.Name + "_"
+	}
+	return &OwnedClient{
+		c:          c,
+		label:      ns.LabelPrefix + ".namespace",
+		value:      ns.Name,
+		namePrefix: ns.NamePrefix,
+	}, nil
+}
+
+// Selector selects all resources of the namespace.
+func (o *OwnedClient) Selector() Selector {
+	return Selector{o.label: o.value}
+}
+
+// CreateContainer creates the container and connects it to its networks. The
+// names of networks, named volumes and dependencies refer to resources of
+// the namespace; the predefined networks bridge, host and none are kept.
+func (o *OwnedClient) CreateContainer(ctx context.Context, spec ContainerSpec) (string, error) {
+	spec = o.containerSpec(spec)
+	body, err := spec.createBody()
+	if err != nil {
+		return "", fmt.Errorf("container %s: %w", spec.Name, err)
+	}
+	for i, m := range body.HostConfig.Mounts {
+		if m.Type == "volume" {
+			body.HostConfig.Mounts[i].Source = o.namePrefix + m.Source
+		}
+	}
+
+	res, err := o.c.createContainer(ctx, spec.Name, body)
+	if err != nil {
+		return "", fmt.Errorf("create container %s: %w", spec.Name, err)
+	}
+	for i, att := range spec.Networks {
+		if i == 0 {
+			continue
+		}
+		if err := o.c.connectNetwork(ctx, att.Network, res.ID, att.endpointConfig()); err != nil {
+			return res.ID, fmt.Errorf("connect container %s to %s: %w", spec.Name, att.Network, err)
+		}
+	}
+	return res.ID, nil
+}
+
+func (o *OwnedClient) containerSpec(spec ContainerSpec) ContainerSpec {
+	spec.Name = o.namePrefix + spec.Name
+	spec.Labels = o.labels(spec.Labels)
+	networks := make([]NetworkAttachment, len(spec.Networks))
+	for i, att := range spec.Networks {
+		if !predefinedNetworks[att.Network] {
+			att.Network = o.namePrefix + att.Network
+		}
+		networks[i] = att
+	}
+	spec.Networks = networks
+	deps := make([]string, len(spec.DependsOn))
+	for i, dep := range spec.DependsOn {
+		deps[i] = o.namePrefix + dep
+	}
+	spec.DependsOn = deps
+	if len(spec.DependsOnHealthy) > 0 {
+		healthy := make([]string, len(spec.DependsOnHealthy))
+		for i, dep := range spec.DependsOnHealthy {
+			healthy[i] = o.namePrefix + dep
+		}
+		spec.DependsOnHealthy = healthy
+	}
+	return spec
+}
+
+// StartContainer starts the container of the namespace.
+func (o *OwnedClient) StartContainer(ctx context.Context, name string) error {
+	id, err := o.container(ctx, name)
+	if err != nil {
+		return err
+	}
+	return o.c.startContainer(ctx, id)
+}
+
+// StopContainer stops the container of the namespace.
+func (o *OwnedClient) StopContainer(ctx context.Context, name string) error {
+	id, err := o.container(ctx, name)
+	if err != nil {
+		return err
+	}
+	return o.c.stopContainer(ctx, id)
+}
+
+// RemoveContainer removes the container of the namespace, running containers
+// are killed.
+func (o *OwnedClient) RemoveContainer(ctx context.Context, name string) error {
+	id, err := o.container(ctx, name)
+	if err != nil {
+		return err
+	}
+	return o.c.removeContainer(ctx, id, true)
+}
+
+// ContainerNames returns the names of all containers of the namespace.
+func (o *OwnedClient) ContainerNames(ctx context.Context) ([]string, error) {
+	containers, err := o.c.listContainers(ctx, true, o.Selector().filters())
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(containers))
+	for i, cs := range containers {
+		names[i] = strings.TrimPrefix(cs.Name(), o.namePrefix)
+	}
+	return names, nil
+}
+
+// container returns the ID of the named container if it is owned.
+func (o *OwnedClient) container(ctx context.Context, name string) (string, error) {
+	cj, err := o.c.inspectContainer(ctx, o.namePrefix+name)
+	if err != nil {
+		return "", err
+	}
+	if err := o.owns("container", name, cj.Config.Labels); err != nil {
+		return "", err
+	}
+	return cj.ID, nil
+}
+
+// CreateNetwork creates the network.
+func (o *OwnedClient) CreateNetwork(ctx context.Context, spec NetworkSpec) (string, error) {
+	spec.Name = o.namePrefix + spec.Name
+	spec.Labels = o.labels(spec.Labels)
+	res, err := o.c.createNetwork(ctx, spec.createBody())
+	if err != nil {
+		return "", fmt.Errorf("create network %s: %w", spec.Name, err)
+	}
+	return res.ID, nil
+}
+
+// RemoveNetwork removes the network of the namespace.
+func (o *OwnedClient) RemoveNetwork(ctx context.Context, name string) error {
+	n, err := o.c.inspectNetwork(ctx, o.namePrefix+name)
+	if err != nil {
+		return err
+	}
+	if err := o.owns("network", name, n.Labels); err != nil {
+		return err
+	}
+	return o.c.removeNetwork(ctx, n.ID)
+}
+
+// NetworkNames returns the names of all networks of the namespace.
+func (o *OwnedClient) NetworkNames(ctx context.Context) ([]string, error) {
+	networks, err := o.c.listNetworks(ctx, o.Selector().filters())
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(networks))
+	for i, n := range networks {
+		names[i] = strings.TrimPrefix(n.Name, o.namePrefix)
+	}
+	return names, nil
+}
+
+// CreateVolume creates the volume.
+func (o *OwnedClient) CreateVolume(ctx context.Context, spec VolumeSpec) error {
+	name := o.namePrefix + spec.Name
+	if _, err := o.c.createVolume(ctx, name, spec.Driver, o.labels(spec.Labels)); err != nil {
+		return fmt.Errorf("create volume %s: %w", name, err)
+	}
+	return nil
+}
+
+// RemoveVolume removes the volume of the namespace.
+func (o *OwnedClient) RemoveVolume(ctx context.Context, name string) error {
+	v, err := o.c.inspectVolume(ctx, o.namePrefix+name)
+	if err != nil {
+		return err
+	}
+	if err := o.owns("volume", name, v.Labels); err != nil {
+		return err
+	}
+	return o.c.removeVolume(ctx, v.Name, false)
+}
+
+// VolumeNames returns the names of all volumes of the namespace.
+func (o *OwnedClient) VolumeNames(ctx context.Context) ([]string, error) {
+	volumes, err := o.c.listVolumes(ctx, o.Selector().filters())
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(volumes))
+	for i, v := range volumes {
+		names[i] = strings.TrimPrefix(v.Name, o.namePrefix)
+	}
+	return names, nil
+}
+
+// RemoveAll removes all containers, networks and volumes of the namespace
+// like TeardownRun.
+func (o *OwnedClient) RemoveAll(ctx context.Context, opts ...ApplyOption) ([]Operation, error) {
+	return o.c.teardown(ctx, o.Selector().filters(), opts)
+}
+
+// labels returns a copy of the labels with the ownership label.
+func (o *OwnedClient) labels(labels map[string]string) map[string]string {
+	res := copyLabels(labels)
+	res[o.label] = o.value
+	return res
+}
+
+func (o *OwnedClient) owns(resource, name string, labels map[string]string) error {
+	if !o.Selector().Matches(labels) {
+		return fmt.Errorf("%s %s is not owned by namespace %s", resource, name, o.value)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_OwnedClient(t *testing.T) {
+	oc, err := client.Owned(Namespace{Name: "ci1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("container spec", func(t *testing.T) {
+		got := oc.containerSpec(ContainerSpec{
+			Name:      "plc",
+			Networks:  []NetworkAttachment{{Network: "bridge"}, {Network: "field"}},
+			DependsOn: []string{"db"},
+		})
+		want := ContainerSpec{
+			Name:      "ci1_plc",
+			Labels:    map[string]string{"com.grid-x.docker.namespace": "ci1"},
+			Networks:  []NetworkAttachment{{Network: "bridge"}, {Network: "ci1_field"}},
+			DependsOn: []string{"ci1_db"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("want %+v, got %+v", want, got)
+		}
+	})
+
+	t.Run("names", func(t *testing.T) {
+		srv.route(map[string]mockResponse{
+			"GET /containers/json": {Body: `[{"Id": "c1", "Names": ["/ci1_plc"]}]`},
+		})
+		defer srv.route(nil)
+
+		names, err := oc.ContainerNames(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(names, []string{"plc"}) {
+			t.Errorf("unexpected names %v", names)
+		}
+	})
+
+	t.Run("foreign container", func(t *testing.T) {
+		labels, _ := json.Marshal(map[string]string{"com.grid-x.docker.namespace": "other"})
+		srv.route(map[string]mockResponse{
+			"GET /containers/ci1_plc/json": {Body: `{"Id": "c1", "Config": {"Labels": ` + string(labels) + `}}`},
+			"DELETE /containers/c1":        {StatusCode: http.StatusNoContent},
+		})
+		defer srv.route(nil)
+
+		err := oc.RemoveContainer(context.Background(), "plc")
+		if err == nil || !strings.Contains(err.Error(), "not owned") {
+			t.Errorf("unexpected error %v", err)
+		}
+		if got := srv.Requests(); len(got) != 1 {
+			t.Errorf("container must not be removed: %v", got)
+		}
+	})
+}
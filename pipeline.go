@@ -0,0 +1,181 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PipelineOptions configures CreatePipeline.
+type PipelineOptions struct {
+	// Concurrency limits the number of containers set up in parallel.
+	// Defaults to 1.
+	Concurrency int
+	// RequestsPerSecond limits the rate of requests sent to the daemon by
+	// all workers together. Zero means no limit.
+	RequestsPerSecond float64
+}
+
+// CreatePipeline creates, connects and starts the containers in parallel.
+// A container is set up only after all containers named by its DependsOn
+// were started. Dependencies must be part of specs. The IDs of the created
+// containers are returned by name. If some containers fail, a BatchError
+// keyed by name is returned; containers depending on a failed container are
+// not created and fail as well.
+func (c *Client) CreatePipeline(ctx context.Context, specs []ContainerSpec, opts PipelineOptions) (map[string]string, error) {
+	if err := checkPipeline(specs); err != nil {
+		return nil, err
+	}
+	// with a concurrency of 1 the containers are set up one after the
+	// other, in an order which follows the dependencies
+	order, err := (&Spec{Containers: specs}).startOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		limit = newRateLimiter(opts.RequestsPerSecond)
+		mu    sync.Mutex
+		ids   = make(map[string]string, len(specs))
+	)
+	errs := forEachInGraph(ctx, order, graphOptions{
+		concurrency: opts.Concurrency,
+		deps:        func(cs ContainerSpec) []string { return cs.DependsOn },
+	}, func(cs ContainerSpec) error {
+		id, err := c.createPipelined(ctx, cs, limit)
+		if id != "" {
+			mu.Lock()
+			ids[cs.Name] = id
+			mu.Unlock()
+		}
+		return err
+	})
+	if len(errs) > 0 {
+		return ids, errs
+	}
+	return ids, nil
+}
+
+// createPipelined creates, connects and starts a single container. Every
+// request waits for the rate limiter first.
+func (c *Client) createPipelined(ctx context.Context, cs ContainerSpec, limit *rateLimiter) (string, error) {
+	body, err := cs.createBody()
+	if err != nil {
+		return "", err
+	}
+	if err := limit.wait(ctx); err != nil {
+		return "", err
+	}
+	res, err := c.createContainer(ctx, cs.Name, body)
+	if err != nil {
+		return "", fmt.Errorf("create container: %w", err)
+	}
+	for i, att := range cs.Networks {
+		if i == 0 {
+			continue
+		}
+		if err := limit.wait(ctx); err != nil {
+			return res.ID, err
+		}
+		if err := c.connectNetwork(ctx, att.Network, res.ID, att.endpointConfig()); err != nil {
+			return res.ID, fmt.Errorf("connect to %s: %w", att.Network, err)
+		}
+	}
+	if err := limit.wait(ctx); err != nil {
+		return res.ID, err
+	}
+	if err := c.startContainer(ctx, res.ID); err != nil {
+		return res.ID, fmt.Errorf("start container: %w", err)
+	}
+	return res.ID, nil
+}
+
+// checkPipeline verifies that the names are unique and the dependencies are
+// known and free of cycles.
+func checkPipeline(specs []ContainerSpec) error {
+	deps := make(map[string][]string, len(specs))
+	for _, cs := range specs {
+		if cs.Name == "" {
+			return fmt.Errorf("pipeline: container name must not be empty")
+		}
+		if _, ok := deps[cs.Name]; ok {
+			return fmt.Errorf("pipeline: duplicate container %s", cs.Name)
+		}
+		deps[cs.Name] = cs.DependsOn
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(specs))
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("pipeline: dependency cycle at container %s", name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range deps[name] {
+			if _, ok := deps[dep]; !ok {
+				return fmt.Errorf("pipeline: container %s depends on unknown container %s", name, dep)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, cs := range specs {
+		if err := visit(cs.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rateLimiter spaces requests evenly. A nil rateLimiter does not limit.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// wait blocks until the next request may be sent or the context is done.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	slot := l.next
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	d := time.Until(slot)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
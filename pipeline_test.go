@@ -0,0 +1,85 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_CreatePipeline(t *testing.T) {
+	specs := []ContainerSpec{
+		{Name: "web", Image: "nginx", DependsOn: []string{"db"}},
+		{Name: "db", Image: "postgres"},
+	}
+
+	t.Run("dependency order", func(t *testing.T) {
+		srv.route(map[string]mockResponse{
+			"POST /containers/create":   {StatusCode: http.StatusCreated, Body: `{"Id": "c1"}`},
+			"POST /containers/c1/start": {StatusCode: http.StatusNoContent},
+		})
+		defer srv.route(nil)
+
+		ids, err := client.CreatePipeline(context.Background(), specs, PipelineOptions{Concurrency: 4, RequestsPerSecond: 1000})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := map[string]string{"db": "c1", "web": "c1"}; !reflect.DeepEqual(ids, want) {
+			t.Errorf("want %v, got %v", want, ids)
+		}
+		want := []string{
+			"POST /containers/create",
+			"POST /containers/c1/start",
+			"POST /containers/create",
+			"POST /containers/c1/start",
+		}
+		if got := srv.Requests(); !reflect.DeepEqual(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("failed dependency", func(t *testing.T) {
+		srv.route(map[string]mockResponse{
+			"POST /containers/create": {StatusCode: http.StatusInternalServerError, Body: `{"message": "boom"}`},
+		})
+		defer srv.route(nil)
+
+		_, err := client.CreatePipeline(context.Background(), specs, PipelineOptions{})
+		be, ok := err.(BatchError)
+		if !ok || len(be) != 2 {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if !strings.Contains(be["web"].Error(), "dependency db failed") {
+			t.Errorf("unexpected error for web: %v", be["web"])
+		}
+		if got := srv.Requests(); len(got) != 1 {
+			t.Errorf("expected a single request, got %v", got)
+		}
+	})
+}
+
+func Test_checkPipeline(t *testing.T) {
+	tests := []struct {
+		name  string
+		specs []ContainerSpec
+		err   string
+	}{
+		{"cycle", []ContainerSpec{
+			{Name: "a", DependsOn: []string{"b"}},
+			{Name: "b", DependsOn: []string{"a"}},
+		}, "dependency cycle"},
+		{"unknown", []ContainerSpec{
+			{Name: "a", DependsOn: []string{"x"}},
+		}, "unknown container x"},
+		{"duplicate", []ContainerSpec{{Name: "a"}, {Name: "a"}}, "duplicate container a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPipeline(tt.specs)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("want error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
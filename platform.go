@@ -0,0 +1,105 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PlatformError is returned by VerifyImagePlatform if an image was built for
+// another platform than the wanted one. The daemon runs such images under
+// emulation if the host supports it, which makes them many times slower.
+type PlatformError struct {
+	Image string
+	// Want and Got are platforms as os/arch[/variant], e.g. linux/arm/v7.
+	Want string
+	Got  string
+}
+
+func (e *PlatformError) Error() string {
+	return fmt.Sprintf("image %s is built for %s, not %s: it would run under emulation",
+		e.Image, e.Got, e.Want)
+}
+
+// VerifyImagePlatform verifies that the local image was built for the
+// platform, e.g. "linux/arm64" or "linux/arm/v7". An empty platform stands
+// for the platform of the daemon. A mismatch is reported as *PlatformError.
+// The variant is only compared if both the image and the platform name it.
+func (c *Client) VerifyImagePlatform(ctx context.Context, ref, platform string) error {
+	if platform == "" {
+		v, err := c.Version(ctx)
+		if err != nil {
+			return err
+		}
+		platform = v.Os + "/" + v.Arch
+	}
+	want, err := parsePlatform(platform)
+	if err != nil {
+		return err
+	}
+	img, err := c.inspectImage(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("inspect image %s: %w", ref, err)
+	}
+	got := imagePlatform{os: img.Os, arch: normalizeArch(img.Architecture), variant: img.Variant}
+	if !want.matches(got) {
+		return &PlatformError{Image: ref, Want: want.String(), Got: got.String()}
+	}
+	return nil
+}
+
+// imagePlatform is a platform as os/arch[/variant].
+type imagePlatform struct {
+	os, arch, variant string
+}
+
+func parsePlatform(s string) (imagePlatform, error) {
+	ss := strings.Split(strings.ToLower(s), "/")
+	if len(ss) < 2 || len(ss) > 3 || ss[0] == "" || ss[1] == "" {
+		return imagePlatform{}, fmt.Errorf("invalid platform %q, want os/arch[/variant]", s)
+	}
+	p := imagePlatform{os: ss[0], arch: normalizeArch(ss[1])}
+	if len(ss) == 3 {
+		p.variant = ss[2]
+	}
+	return p, nil
+}
+
+func (p imagePlatform) String() string {
+	if p.variant == "" {
+		return p.os + "/" + p.arch
+	}
+	return p.os + "/" + p.arch + "/" + p.variant
+}
+
+// matches reports whether the image platform got can run natively on p.
+func (p imagePlatform) matches(got imagePlatform) bool {
+	if p.os != got.os || p.arch != got.arch {
+		return false
+	}
+	return p.variant == "" || got.variant == "" || normalizeVariant(p.arch, p.variant) == normalizeVariant(got.arch, got.variant)
+}
+
+// normalizeArch maps the architecture names of the kernel to the ones of Go
+// the registries use, e.g. x86_64 to amd64.
+func normalizeArch(arch string) string {
+	switch arch {
+	case "x86_64", "x86-64":
+		return "amd64"
+	case "aarch64":
+		return "arm64"
+	case "armhf", "armel":
+		return "arm"
+	case "i386", "i686":
+		return "386"
+	}
+	return arch
+}
+
+// normalizeVariant treats v8 as the only variant of arm64.
+func normalizeVariant(arch, variant string) string {
+	if arch == "arm64" && variant == "v8" {
+		return ""
+	}
+	return variant
+}
@@ -0,0 +1,52 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func Test_VerifyImagePlatform(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /version":                   {Body: `{"Version": "20.10.7", "Os": "linux", "Arch": "arm64"}`},
+		"GET /images/sim/plc:amd64/json": {Body: `{"Id": "sha256:1", "Os": "linux", "Architecture": "amd64"}`},
+		"GET /images/sim/plc:arm64/json": {Body: `{"Id": "sha256:2", "Os": "linux", "Architecture": "arm64", "Variant": "v8"}`},
+		"GET /images/sim/plc:armv7/json": {Body: `{"Id": "sha256:3", "Os": "linux", "Architecture": "arm", "Variant": "v7"}`},
+	})
+	defer srv.route(nil)
+
+	tt := []struct {
+		ref      string
+		platform string
+		mismatch bool
+		wantErr  bool
+	}{
+		{ref: "sim/plc:arm64"},
+		{ref: "sim/plc:amd64", mismatch: true},
+		{ref: "sim/plc:amd64", platform: "linux/x86_64"},
+		{ref: "sim/plc:arm64", platform: "linux/aarch64"},
+		{ref: "sim/plc:armv7", platform: "linux/arm/v7"},
+		{ref: "sim/plc:armv7", platform: "linux/arm"},
+		{ref: "sim/plc:armv7", platform: "linux/arm/v6", mismatch: true},
+		{ref: "sim/plc:armv7", platform: "linux", wantErr: true},
+		{ref: "sim/db", wantErr: true},
+	}
+	for _, tc := range tt {
+		err := client.VerifyImagePlatform(context.Background(), tc.ref, tc.platform)
+		var perr *PlatformError
+		switch {
+		case tc.mismatch && !errors.As(err, &perr):
+			t.Errorf("%s on %q: expected platform error, got %v", tc.ref, tc.platform, err)
+		case tc.wantErr && err == nil:
+			t.Errorf("%s on %q: expected error", tc.ref, tc.platform)
+		case !tc.mismatch && !tc.wantErr && err != nil:
+			t.Errorf("%s on %q: %v", tc.ref, tc.platform, err)
+		}
+	}
+
+	err := client.VerifyImagePlatform(context.Background(), "sim/plc:amd64", "")
+	want := "image sim/plc:amd64 is built for linux/amd64, not linux/arm64: it would run under emulation"
+	if err == nil || err.Error() != want {
+		t.Errorf("got: %v, want: %s", err, want)
+	}
+}
@@ -0,0 +1,231 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Plugin is a managed plugin installed on the daemon, e.g. a network or
+// volume driver.
+// docs.: https://docs.docker.com/engine/api/v1.41/#tag/Plugin
+type Plugin struct {
+	ID   string
+	Name string
+	// Reference is the remote reference the plugin was pulled from.
+	Reference string
+	Enabled   bool
+	// Capabilities are the interfaces the plugin implements, e.g.
+	// volumedriver or networkdriver.
+	Capabilities []string
+	Env          []string
+}
+
+// PluginPrivilege is a privilege a plugin requests on installation.
+type PluginPrivilege struct {
+	Name        string   `json:"Name"`
+	Description string   `json:"Description"`
+	Value       []string `json:"Value"`
+}
+
+// PluginInstallOptions configures InstallPlugin.
+type PluginInstallOptions struct {
+	// Alias is the local name of the plugin. Defaults to the remote
+	// reference.
+	Alias string
+	// Privileges are granted to the plugin. If nil, the privileges the
+	// plugin requests are only granted if GrantAll is set, otherwise the
+	// installation of a plugin requesting privileges fails.
+	Privileges []PluginPrivilege
+	GrantAll   bool
+	// Env sets the settings of the plugin as KEY=value before it is enabled.
+	Env []string
+	// Enable enables the plugin after the installation.
+	Enable bool
+}
+
+type pluginJSON struct {
+	ID              string `json:"Id"`
+	Name            string `json:"Name"`
+	Enabled         bool   `json:"Enabled"`
+	PluginReference string `json:"PluginReference"`
+	Settings        struct {
+		Env []string `json:"Env"`
+	} `json:"Settings"`
+	Config struct {
+		Interface struct {
+			Types []struct {
+				Capability string `json:"Capability"`
+			} `json:"Types"`
+		} `json:"Interface"`
+	} `json:"Config"`
+}
+
+func (p *pluginJSON) plugin() Plugin {
+	res := Plugin{
+		ID:        p.ID,
+		Name:      p.Name,
+		Reference: p.PluginReference,
+		Enabled:   p.Enabled,
+		Env:       p.Settings.Env,
+	}
+	for _, t := range p.Config.Interface.Types {
+		res.Capabilities = append(res.Capabilities, t.Capability)
+	}
+	return res
+}
+
+// ListPlugins lists the installed plugins. If capability is not empty, only
+// plugins with this capability, e.g. volumedriver, are returned.
+func (c *Client) ListPlugins(ctx context.Context, capability string) ([]Plugin, error) {
+	var filters map[string][]string
+	if capability != "" {
+		filters = map[string][]string{"capability": {capability}}
+	}
+	var res []pluginJSON
+	if err := c.doJSON(ctx, http.MethodGet, "plugins", filterQuery(filters), nil, &res, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("list plugins: %w", err)
+	}
+	plugins := make([]Plugin, 0, len(res))
+	for i := range res {
+		plugins = append(plugins, res[i].plugin())
+	}
+	return plugins, nil
+}
+
+// InspectPlugin returns the installed plugin with the given name.
+func (c *Client) InspectPlugin(ctx context.Context, name string) (*Plugin, error) {
+	var res pluginJSON
+	if err := c.doJSON(ctx, http.MethodGet, "plugins/"+pathRef(name)+"/json", nil, nil, &res, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("inspect plugin %s: %w", name, err)
+	}
+	p := res.plugin()
+	return &p, nil
+}
+
+// PluginPrivileges returns the privileges the plugin requests on
+// installation.
+func (c *Client) PluginPrivileges(ctx context.Context, remote string) ([]PluginPrivilege, error) {
+	var res []PluginPrivilege
+	q := url.Values{"remote": {remote}}
+	if err := c.doJSON(ctx, http.MethodGet, "plugins/privileges", q, nil, &res, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("plugin privileges %s: %w", remote, err)
+	}
+	return res, nil
+}
+
+// InstallPlugin pulls the plugin from its registry, configures and enables
+// it as set by the options. A plugin which is already installed under the
+// alias is not pulled again, so InstallPlugin may be used to provision hosts
+// repeatedly. Credentials are taken from the auth provider of the client.
+// The long timeout of the client applies to the pull, see SetTimeouts.
+func (c *Client) InstallPlugin(ctx context.Context, remote string, opts PluginInstallOptions) (*Plugin, error) {
+	name := opts.Alias
+	if name == "" {
+		name = remote
+	}
+	p, err := c.InspectPlugin(ctx, name)
+	if err != nil && !isNotFound(err) {
+		return nil, err
+	}
+	if p == nil {
+		if err := c.pullPlugin(ctx, remote, name, opts); err != nil {
+			return nil, fmt.Errorf("install plugin %s: %w", remote, err)
+		}
+		if len(opts.Env) > 0 {
+			if err := c.doJSON(ctx, http.MethodPost, "plugins/"+pathRef(name)+"/set", nil, opts.Env, nil, http.StatusNoContent); err != nil {
+				return nil, fmt.Errorf("configure plugin %s: %w", name, err)
+			}
+		}
+		if p, err = c.InspectPlugin(ctx, name); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Enable && !p.Enabled {
+		if err := c.EnablePlugin(ctx, name); err != nil {
+			return nil, err
+		}
+		p.Enabled = true
+	}
+	return p, nil
+}
+
+func (c *Client) pullPlugin(ctx context.Context, remote, name string, opts PluginInstallOptions) error {
+	privileges := opts.Privileges
+	if privileges == nil {
+		requested, err := c.PluginPrivileges(ctx, remote)
+		if err != nil {
+			return err
+		}
+		if len(requested) > 0 && !opts.GrantAll {
+			names := make([]string, 0, len(requested))
+			for _, p := range requested {
+				names = append(names, p.Name)
+			}
+			return fmt.Errorf("plugin requests privileges %s, grant them explicitly or set GrantAll", strings.Join(names, ", "))
+		}
+		privileges = requested
+	}
+	if privileges == nil {
+		privileges = []PluginPrivilege{}
+	}
+	b, err := json.Marshal(privileges)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	if c.auth != nil {
+		auth, err := c.auth.Auth(ctx, remote)
+		if err != nil {
+			return err
+		}
+		if auth != nil {
+			header.Set("X-Registry-Auth", auth.header())
+		}
+	}
+	q := url.Values{"remote": {remote}, "name": {name}}
+	r, err := c.streamHeader(ctx, http.MethodPost, "plugins/pull", q, bytes.NewReader(b), header)
+	if err != nil {
+		return err
+	}
+	defer drainClose(r.Body)
+	if err := checkResponse(r, http.StatusOK, http.StatusNoContent); err != nil {
+		return err
+	}
+	return readProgress(r.Body)
+}
+
+// EnablePlugin enables the installed plugin.
+func (c *Client) EnablePlugin(ctx context.Context, name string) error {
+	if err := c.doJSON(ctx, http.MethodPost, "plugins/"+pathRef(name)+"/enable", nil, nil, nil, http.StatusOK); err != nil {
+		return fmt.Errorf("enable plugin %s: %w", name, err)
+	}
+	return nil
+}
+
+// DisablePlugin disables the installed plugin. Plugins in use by containers,
+// networks or volumes can not be disabled.
+func (c *Client) DisablePlugin(ctx context.Context, name string) error {
+	if err := c.doJSON(ctx, http.MethodPost, "plugins/"+pathRef(name)+"/disable", nil, nil, nil, http.StatusOK); err != nil {
+		return fmt.Errorf("disable plugin %s: %w", name, err)
+	}
+	return nil
+}
+
+// RemovePlugin removes the plugin. With force an enabled plugin is disabled
+// first.
+func (c *Client) RemovePlugin(ctx context.Context, name string, force bool) error {
+	q := url.Values{}
+	if force {
+		q.Set("force", "1")
+	}
+	if err := c.doJSON(ctx, http.MethodDelete, "plugins/"+pathRef(name), q, nil, nil, http.StatusOK); err != nil {
+		return fmt.Errorf("remove plugin %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sshfsPlugin = `{"Id": "p1", "Name": "vieux/sshfs:latest", "Enabled": false, "PluginReference": "docker.io/vieux/sshfs:latest",
+	"Settings": {"Env": ["DEBUG=1"]}, "Config": {"Interface": {"Types": [{"Prefix": "docker", "Capability": "volumedriver", "Version": "1.0"}]}}}`
+
+func Test_InstallPlugin(t *testing.T) {
+	notFound := mockResponse{StatusCode: http.StatusNotFound, Body: `{"message": "plugin not found"}`}
+	routes := func() map[string]mockResponse {
+		inspect := notFound
+		inspect.Next = &mockResponse{Body: sshfsPlugin}
+		return map[string]mockResponse{
+			"GET /plugins/vieux/sshfs/json":    inspect,
+			"GET /plugins/privileges":          {Body: `[{"Name": "network", "Value": ["host"]}]`},
+			"POST /plugins/pull":               {Body: `{"status": "Download complete"}`},
+			"POST /plugins/vieux/sshfs/set":    {StatusCode: http.StatusNoContent},
+			"POST /plugins/vieux/sshfs/enable": {},
+		}
+	}
+	defer srv.route(nil)
+	ctx := context.Background()
+
+	srv.route(routes())
+	_, err := client.InstallPlugin(ctx, "vieux/sshfs", PluginInstallOptions{})
+	if err == nil || !strings.Contains(err.Error(), "network") {
+		t.Errorf("expected privileges to be refused, got %v", err)
+	}
+
+	srv.route(routes())
+	p, err := client.InstallPlugin(ctx, "vieux/sshfs", PluginInstallOptions{GrantAll: true, Env: []string{"DEBUG=1"}, Enable: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Plugin{
+		ID: "p1", Name: "vieux/sshfs:latest", Reference: "docker.io/vieux/sshfs:latest", Enabled: true,
+		Capabilities: []string{"volumedriver"}, Env: []string{"DEBUG=1"},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("want %+v, got %+v", want, p)
+	}
+	wantReqs := []string{
+		"GET /plugins/vieux/sshfs/json",
+		"GET /plugins/privileges",
+		"POST /plugins/pull",
+		"POST /plugins/vieux/sshfs/set",
+		"GET /plugins/vieux/sshfs/json",
+		"POST /plugins/vieux/sshfs/enable",
+	}
+	if reqs := srv.Requests(); !reflect.DeepEqual(reqs, wantReqs) {
+		t.Errorf("want requests %v, got %v", wantReqs, reqs)
+	}
+
+	// installed plugins are not pulled again
+	srv.route(map[string]mockResponse{"GET /plugins/vieux/sshfs/json": {Body: sshfsPlugin}})
+	if _, err := client.InstallPlugin(ctx, "vieux/sshfs", PluginInstallOptions{}); err != nil {
+		t.Error(err)
+	}
+	if reqs := srv.Requests(); len(reqs) != 1 {
+		t.Errorf("unexpected requests %v", reqs)
+	}
+}
+
+func Test_ListPlugins(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /plugins?" + filterQuery(map[string][]string{"capability": {"volumedriver"}}).Encode(): {Body: "[" + sshfsPlugin + "]"},
+	})
+	defer srv.route(nil)
+
+	plugins, err := client.ListPlugins(context.Background(), "volumedriver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plugins) != 1 || plugins[0].ID != "p1" || plugins[0].Enabled {
+		t.Errorf("unexpected plugins %+v", plugins)
+	}
+}
@@ -0,0 +1,99 @@
+package docker
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// portQuarantine is how long released ports are not handed out again by
+// AllocateHostPorts, which gives the daemon time to bind them.
+const portQuarantine = time.Minute
+
+// allocated holds the ports handed out by AllocateHostPorts, by the time
+// they may be handed out again. Held ports map to the zero time.
+var allocated = struct {
+	sync.Mutex
+	ports map[int]time.Time
+}{ports: make(map[int]time.Time)}
+
+// PortReservation holds free host ports by binding them, so nobody else can
+// take them until they are published by a container. Letting the daemon
+// choose random ports instead makes it hard to know them in advance, and
+// looking for free ports without holding them races with parallel runs.
+type PortReservation struct {
+	ports     []int
+	listeners []net.Listener
+}
+
+// AllocateHostPorts reserves n free TCP ports on all addresses of the host.
+// The daemon has to run on the local host. Release the reservation right
+// before the container publishing the ports is created.
+func AllocateHostPorts(n int) (*PortReservation, error) {
+	r := &PortReservation{}
+	allocated.Lock()
+	defer allocated.Unlock()
+	now := time.Now()
+	for port, until := range allocated.ports {
+		if !until.IsZero() && now.After(until) {
+			delete(allocated.ports, port)
+		}
+	}
+	for len(r.ports) < n {
+		l, err := net.Listen("tcp", ":0")
+		if err != nil {
+			r.release()
+			return nil, fmt.Errorf("allocate host ports: %w", err)
+		}
+		port := l.Addr().(*net.TCPAddr).Port
+		if _, ok := allocated.ports[port]; ok {
+			// held or released recently, the daemon may not have bound it; it is
+			// closed after the loop, so the next listener gets another port
+			defer l.Close()
+			continue
+		}
+		allocated.ports[port] = time.Time{}
+		r.ports = append(r.ports, port)
+		r.listeners = append(r.listeners, l)
+	}
+	return r, nil
+}
+
+// Ports returns the reserved ports.
+func (r *PortReservation) Ports() []int {
+	return append([]int(nil), r.ports...)
+}
+
+// Publish publishes the container ports, e.g. "502" or "53/udp", on the
+// reserved ports in the given order by adding them to the ports of the spec.
+// The reservation only holds TCP ports, UDP ports are very likely but not
+// certainly free as well.
+func (r *PortReservation) Publish(spec *ContainerSpec, containerPorts ...string) error {
+	if len(containerPorts) > len(r.ports) {
+		return fmt.Errorf("publish %d ports on %d reserved ports", len(containerPorts), len(r.ports))
+	}
+	for i, p := range containerPorts {
+		spec.Ports = append(spec.Ports, strconv.Itoa(r.ports[i])+":"+p)
+	}
+	return nil
+}
+
+// Release unbinds the ports, so the daemon can bind them. This process does
+// not hand them out again for a minute.
+func (r *PortReservation) Release() {
+	allocated.Lock()
+	defer allocated.Unlock()
+	r.release()
+}
+
+// release has to be called with the lock of allocated held.
+func (r *PortReservation) release() {
+	until := time.Now().Add(portQuarantine)
+	for i, l := range r.listeners {
+		l.Close()
+		allocated.ports[r.ports[i]] = until
+	}
+	r.listeners = nil
+}
@@ -0,0 +1,58 @@
+package docker
+
+import (
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func Test_AllocateHostPorts(t *testing.T) {
+	r, err := AllocateHostPorts(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ports := r.Ports()
+	seen := make(map[int]bool)
+	for _, p := range ports {
+		if p == 0 || seen[p] {
+			t.Fatalf("invalid ports %v", ports)
+		}
+		seen[p] = true
+	}
+	if l, err := net.Listen("tcp", ":"+strconv.Itoa(ports[0])); err == nil {
+		l.Close()
+		t.Error("reserved port is not held")
+	}
+
+	spec := ContainerSpec{Name: "plc", Ports: []string{"8080:80"}}
+	if err := r.Publish(&spec, "502", "53/udp"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"8080:80", strconv.Itoa(ports[0]) + ":502", strconv.Itoa(ports[1]) + ":53/udp"}
+	if !reflect.DeepEqual(spec.Ports, want) {
+		t.Errorf("got: %v, want: %v", spec.Ports, want)
+	}
+	if err := r.Publish(&spec, "1", "2", "3", "4"); err == nil {
+		t.Error("expected error for too many ports")
+	}
+
+	r.Release()
+	l, err := net.Listen("tcp", ":"+strconv.Itoa(ports[0]))
+	if err != nil {
+		t.Fatalf("released port is still held: %v", err)
+	}
+	l.Close()
+
+	// released ports are not handed out again right away
+	other, err := AllocateHostPorts(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Release()
+	for _, p := range other.Ports() {
+		if seen[p] {
+			t.Errorf("port %d was handed out twice", p)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultPortForwardImage is the image of the helper containers of
+	// PortForward.
+	DefaultPortForwardImage = "alpine/socat:1.7.4.4"
+	// LabelPortForward marks helper containers of PortForward, the value is
+	// the ID of the container forwarded to.
+	LabelPortForward = "com.grid-x.docker.portforward"
+)
+
+// PortForward forwards a local port to a port of a container.
+type PortForward struct {
+	// Addr is the local address connections are forwarded from, e.g.
+	// "127.0.0.1:41234".
+	Addr string
+	// HelperID is the ID of the helper container, empty if the port of the
+	// container is published.
+	HelperID string
+
+	parent *Client
+	ln     net.Listener
+	target string
+
+	mu    sync.Mutex
+	conns map[net.Conn]bool
+	wg    sync.WaitGroup
+}
+
+// PortForward listens on a free port of the loopback interface and forwards
+// the connections to the TCP port of the container, e.g. "502". If the port
+// is published, connections go to the published port. Otherwise a helper
+// container running socat is attached to a network of the container and
+// publishes the port instead. The forward lasts until it is closed.
+func (c *Client) PortForward(ctx context.Context, id, port string) (*PortForward, error) {
+	port = normalizePort(port)
+	if !strings.HasSuffix(port, "/tcp") {
+		return nil, fmt.Errorf("port forward: only tcp ports are supported, got %s", port)
+	}
+	cj, err := c.inspectContainer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("inspect container %s: %w", id, err)
+	}
+	if !cj.State.Running {
+		return nil, fmt.Errorf("port forward: container %s is not running", id)
+	}
+
+	f := &PortForward{parent: c, conns: make(map[net.Conn]bool)}
+	host := c.daemonHost()
+	for _, b := range cj.NetworkSettings.Ports[port] {
+		if b.HostPort == "" {
+			continue
+		}
+		switch ip := net.ParseIP(b.HostIP); {
+		case ip == nil || ip.IsUnspecified():
+			f.target = net.JoinHostPort(host, b.HostPort)
+		case !ip.IsLoopback() || host == "127.0.0.1":
+			f.target = net.JoinHostPort(b.HostIP, b.HostPort)
+		}
+		if f.target != "" {
+			break
+		}
+	}
+	if f.target == "" {
+		if err := f.runHelper(ctx, cj, strings.TrimSuffix(port, "/tcp")); err != nil {
+			return nil, fmt.Errorf("port forward to %s: %w", id, err)
+		}
+	}
+
+	f.ln, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		f.Close(context.Background())
+		return nil, fmt.Errorf("port forward: %w", err)
+	}
+	f.Addr = f.ln.Addr().String()
+	f.wg.Add(1)
+	go f.serve()
+	return f, nil
+}
+
+// runHelper starts the helper container forwarding its published port to
+// the address of the container on its first network, by name.
+func (f *PortForward) runHelper(ctx context.Context, cj *containerJSON, port string) error {
+	names := make([]string, 0, len(cj.NetworkSettings.Networks))
+	for name, ep := range cj.NetworkSettings.Networks {
+		if ep.IPAddress != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("container has no address to forward to")
+	}
+	sort.Strings(names)
+	network := names[0]
+	ip := cj.NetworkSettings.Networks[network].IPAddress
+
+	// the port of a local daemon is reachable on the loopback interface, so
+	// it is not exposed to the network
+	published := port
+	if f.parent.daemonHost() == "127.0.0.1" {
+		published = "127.0.0.1::" + port
+	}
+	spec := ContainerSpec{
+		Image:    DefaultPortForwardImage,
+		Cmd:      []string{"TCP-LISTEN:" + port + ",fork,reuseaddr", "TCP:" + net.JoinHostPort(ip, port)},
+		Labels:   map[string]string{LabelPortForward: cj.ID},
+		Ports:    []string{published},
+		Networks: []NetworkAttachment{{Network: network}},
+	}
+	// the helper is torn down with the run of the container
+	if run := cj.Config.Labels[LabelRun]; run != "" {
+		spec.Labels[LabelRun] = run
+	}
+	body, err := spec.createBody()
+	if err != nil {
+		return err
+	}
+	res, err := f.parent.createContainer(ctx, "", body)
+	if isNotFound(err) {
+		if err := f.parent.PullImage(ctx, spec.Image); err != nil {
+			return err
+		}
+		res, err = f.parent.createContainer(ctx, "", body)
+	}
+	if err != nil {
+		return fmt.Errorf("create helper: %w", err)
+	}
+	f.HelperID = res.ID
+
+	if err := f.parent.startContainer(ctx, res.ID); err != nil {
+		f.Close(context.Background())
+		return fmt.Errorf("start helper: %w", err)
+	}
+	hostPort, err := f.parent.HostPort(ctx, res.ID, port)
+	if err != nil {
+		f.Close(context.Background())
+		return err
+	}
+	f.target = net.JoinHostPort(f.parent.daemonHost(), hostPort)
+	return nil
+}
+
+// serve accepts connections until the listener is closed.
+func (f *PortForward) serve() {
+	defer f.wg.Done()
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		f.wg.Add(1)
+		go f.forward(conn)
+	}
+}
+
+// forward copies between the connection and a new one to the target until
+// either side is closed.
+func (f *PortForward) forward(conn net.Conn) {
+	defer f.wg.Done()
+	defer conn.Close()
+	target, err := net.DialTimeout("tcp", f.target, dialTimeout)
+	if err != nil {
+		return
+	}
+	defer target.Close()
+	if !f.track(conn, target) {
+		return
+	}
+	defer f.untrack(conn, target)
+
+	done := make(chan struct{}, 2)
+	cp := func(dst, src net.Conn) {
+		io.Copy(dst, src)
+		// pass on the end of the stream, but let the other direction finish
+		if tc, ok := dst.(*net.TCPConn); ok {
+			tc.CloseWrite()
+		}
+		done <- struct{}{}
+	}
+	go cp(target, conn)
+	go cp(conn, target)
+	<-done
+	<-done
+}
+
+// track registers connections to be closed by Close. It returns false if
+// the forward is closed already.
+func (f *PortForward) track(conns ...net.Conn) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.conns == nil {
+		return false
+	}
+	for _, c := range conns {
+		f.conns[c] = true
+	}
+	return true
+}
+
+func (f *PortForward) untrack(conns ...net.Conn) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range conns {
+		delete(f.conns, c)
+	}
+}
+
+// Close stops listening, closes the forwarded connections and removes the
+// helper container.
+func (f *PortForward) Close(ctx context.Context) error {
+	if f.ln != nil {
+		f.ln.Close()
+	}
+	f.mu.Lock()
+	for c := range f.conns {
+		c.Close()
+	}
+	f.conns = nil
+	f.mu.Unlock()
+	f.wg.Wait()
+
+	if f.HelperID == "" {
+		return nil
+	}
+	if err := f.parent.removeContainer(ctx, f.HelperID, true); err != nil && !isNotFound(err) {
+		return fmt.Errorf("remove port forward helper %s: %w", f.HelperID, err)
+	}
+	return nil
+}
+
+// daemonHost returns the host published ports of the daemon are reachable
+// at: the host of TCP daemons, the loopback address for sockets.
+func (c *Client) daemonHost() string {
+	if c.base == baseAddr {
+		return "127.0.0.1"
+	}
+	u, err := url.Parse(c.base)
+	if err != nil || u.Hostname() == "localhost" {
+		return "127.0.0.1"
+	}
+	return u.Hostname()
+}
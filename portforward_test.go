@@ -0,0 +1,92 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_PortForward(t *testing.T) {
+	// the echo server stands in for the published port
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	_, hostPort, _ := net.SplitHostPort(ln.Addr().String())
+
+	published := fmt.Sprintf(`{"Id": "c1", "Name": "/meter", "State": {"Status": "running", "Running": true},
+		"NetworkSettings": {"Ports": {"502/tcp": [{"HostIp": "0.0.0.0", "HostPort": %q}]}}}`, hostPort)
+	unpublished := `{"Id": "c1", "Name": "/meter", "State": {"Status": "running", "Running": true},
+		"Config": {"Labels": {"com.grid-x.docker.run": "r1"}},
+		"NetworkSettings": {"Networks": {"sim": {"IPAddress": "172.18.0.3"}}}}`
+	helper := fmt.Sprintf(`{"Id": "h1", "Name": "/helper", "State": {"Status": "running", "Running": true},
+		"NetworkSettings": {"Ports": {"502/tcp": [{"HostIp": "0.0.0.0", "HostPort": %q}]}}}`, hostPort)
+
+	for _, tc := range []struct {
+		name   string
+		routes map[string]mockResponse
+		helper string
+	}{
+		{"published", map[string]mockResponse{
+			"GET /containers/meter/json": {Body: published},
+		}, ""},
+		{"helper", map[string]mockResponse{
+			"GET /containers/meter/json": {Body: unpublished},
+			"POST /containers/create":    {StatusCode: http.StatusCreated, Body: `{"Id": "h1"}`},
+			"POST /containers/h1/start":  {StatusCode: http.StatusNoContent},
+			"GET /containers/h1/json":    {Body: helper},
+			"DELETE /containers/h1":      {StatusCode: http.StatusNoContent},
+		}, "h1"},
+	} {
+		srv.route(tc.routes)
+		f, err := client.PortForward(context.Background(), "meter", "502")
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if f.HelperID != tc.helper {
+			t.Errorf("%s: expected helper %q, got %q", tc.name, tc.helper, f.HelperID)
+		}
+		// the helper of the local daemon is published on the loopback interface
+		for i, r := range srv.Requests() {
+			if body := srv.Bodies()[i]; r == "POST /containers/create" && !strings.Contains(body, `"HostIp":"127.0.0.1"`) {
+				t.Errorf("%s: expected helper published on 127.0.0.1, got %s", tc.name, body)
+			}
+		}
+
+		conn, err := net.Dial("tcp", f.Addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Write([]byte("ping"))
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+			t.Errorf("%s: expected echo, got %q, %v", tc.name, buf, err)
+		}
+		if err := f.Close(context.Background()); err != nil {
+			t.Error(err)
+		}
+		// the forwarded connection is closed
+		if _, err := conn.Read(buf); err == nil {
+			t.Errorf("%s: expected closed connection", tc.name)
+		}
+		conn.Close()
+		srv.route(nil)
+	}
+}
@@ -0,0 +1,144 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// HostPort returns the port on the host the given port of the container is
+// published on. The port defaults to tcp, e.g. "5432" or "53/udp".
+func (c *Client) HostPort(ctx context.Context, id, port string) (string, error) {
+	cj, err := c.inspectContainer(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("inspect container %s: %w", id, err)
+	}
+	for _, b := range cj.NetworkSettings.Ports[normalizePort(port)] {
+		if b.HostPort != "" {
+			return b.HostPort, nil
+		}
+	}
+	return "", fmt.Errorf("port %s of container %s is not published", port, id)
+}
+
+// PortConflict is a host port requested by a container which is bound
+// already.
+type PortConflict struct {
+	// Port is the requested host port, e.g. "8080/tcp" or
+	// "127.0.0.1:502/tcp".
+	Port string
+	// Container is the name of the container holding the port. It is empty
+	// if another process on the host holds it.
+	Container string
+}
+
+// PortConflictError is returned by CheckPorts.
+type PortConflictError struct {
+	Container string
+	Conflicts []PortConflict
+}
+
+func (e *PortConflictError) Error() string {
+	ss := make([]string, len(e.Conflicts))
+	for i, pc := range e.Conflicts {
+		if pc.Container != "" {
+			ss[i] = fmt.Sprintf("host port %s is bound by container %s", pc.Port, pc.Container)
+		} else {
+			ss[i] = fmt.Sprintf("host port %s is in use on the host", pc.Port)
+		}
+	}
+	return fmt.Sprintf("container %s: %s", e.Container, strings.Join(ss, "; "))
+}
+
+// CheckPorts checks the host ports published by the spec against the ports
+// of the other running containers before the container is started, which
+// fails with a far less clear message of the daemon otherwise. With
+// checkHost the ports are also bound on this host for a moment, which finds
+// listeners outside of docker; this is only meaningful if the daemon runs
+// on the local host. Ports chosen by the daemon and port ranges are not
+// checked. Conflicts are returned as *PortConflictError.
+func (c *Client) CheckPorts(ctx context.Context, spec ContainerSpec, checkHost bool) error {
+	type hostPort struct {
+		ip, port, proto string
+	}
+	var wanted []hostPort
+	for _, p := range spec.Ports {
+		port, b, err := parsePortBinding(p)
+		if err != nil {
+			return err
+		}
+		if b.HostPort == "" || strings.Contains(b.HostPort, "-") {
+			continue
+		}
+		proto := port[strings.Index(port, "/")+1:]
+		wanted = append(wanted, hostPort{ip: b.HostIP, port: b.HostPort, proto: proto})
+	}
+	if len(wanted) == 0 {
+		return nil
+	}
+
+	containers, err := c.listContainers(ctx, false, nil)
+	if err != nil {
+		return fmt.Errorf("list containers: %w", err)
+	}
+	e := &PortConflictError{Container: spec.Name}
+	for _, w := range wanted {
+		name := w.port + "/" + w.proto
+		if w.ip != "" {
+			name = net.JoinHostPort(w.ip, w.port) + "/" + w.proto
+		}
+		holder, found := "", false
+		for _, cs := range containers {
+			if cs.Name() == spec.Name {
+				continue
+			}
+			for _, p := range cs.Ports {
+				if p.PublicPort != 0 && strconv.Itoa(int(p.PublicPort)) == w.port &&
+					p.Type == w.proto && ipsOverlap(p.IP, w.ip) {
+					holder, found = cs.Name(), true
+				}
+			}
+		}
+		if !found && checkHost && !hostPortFree(w.ip, w.port, w.proto) {
+			found = true
+		}
+		if found {
+			e.Conflicts = append(e.Conflicts, PortConflict{Port: name, Container: holder})
+		}
+	}
+	if len(e.Conflicts) > 0 {
+		return e
+	}
+	return nil
+}
+
+// ipsOverlap reports whether bindings to the addresses conflict. An empty
+// or unspecified address binds all addresses.
+func ipsOverlap(a, b string) bool {
+	wildcard := func(ip string) bool {
+		return ip == "" || net.ParseIP(ip).IsUnspecified()
+	}
+	return wildcard(a) || wildcard(b) || net.ParseIP(a).Equal(net.ParseIP(b))
+}
+
+// hostPortFree tries to bind the port on this host.
+func hostPortFree(ip, port, proto string) bool {
+	addr := net.JoinHostPort(ip, port)
+	switch proto {
+	case "tcp":
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return false
+		}
+		l.Close()
+	case "udp":
+		l, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return false
+		}
+		l.Close()
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package docker
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func Test_HostPort(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /containers/c1/json": {Body: `{"Id": "c1", "NetworkSettings": {"Ports": {"5432/tcp": [{"HostIp": "127.0.0.1", "HostPort": "32768"}], "53/udp": null}}}`},
+	})
+	defer srv.route(nil)
+
+	port, err := client.HostPort(context.Background(), "c1", "5432")
+	if err != nil || port != "32768" {
+		t.Errorf("unexpected result %s, %v", port, err)
+	}
+	if _, err := client.HostPort(context.Background(), "c1", "53/udp"); err == nil {
+		t.Error("expected error for unpublished port")
+	}
+}
+
+func Test_CheckPorts(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /containers/json": {Body: `[
+			{"Id": "c1", "Names": ["/web"], "Ports": [
+				{"IP": "0.0.0.0", "PrivatePort": 80, "PublicPort": 8080, "Type": "tcp"},
+				{"IP": "0.0.0.0", "PrivatePort": 9000, "PublicPort": 9000, "Type": "tcp"}
+			]},
+			{"Id": "c2", "Names": ["/plc"], "Ports": [{"IP": "0.0.0.0", "PrivatePort": 502, "PublicPort": 502, "Type": "tcp"}]}
+		]`},
+	})
+	defer srv.route(nil)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	_, taken, _ := net.SplitHostPort(l.Addr().String())
+
+	spec := ContainerSpec{Name: "plc", Ports: []string{"8080:80", "502:502", "9000:9000/udp", "80", "127.0.0.1:" + taken + ":80"}}
+	err = client.CheckPorts(context.Background(), spec, false)
+	pe, ok := err.(*PortConflictError)
+	if !ok {
+		t.Fatalf("got error: %v, want a PortConflictError", err)
+	}
+	want := []PortConflict{{Port: "8080/tcp", Container: "web"}}
+	if !reflect.DeepEqual(pe.Conflicts, want) {
+		t.Errorf("got: %+v, want: %+v", pe.Conflicts, want)
+	}
+
+	// only the taken port is bound on the host, others may need privileges
+	spec.Ports = []string{"8080:80", "127.0.0.1:" + taken + ":80"}
+	err = client.CheckPorts(context.Background(), spec, true)
+	wantMsg := "container plc: host port 8080/tcp is bound by container web; host port 127.0.0.1:" + taken + "/tcp is in use on the host"
+	if err == nil || err.Error() != wantMsg {
+		t.Errorf("got error: %v, want: %s", err, wantMsg)
+	}
+
+	if err := client.CheckPorts(context.Background(), ContainerSpec{Name: "x", Ports: []string{"8081:80"}}, false); err != nil {
+		t.Error(err)
+	}
+}
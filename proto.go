@@ -0,0 +1,102 @@
+package docker
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The BuildKit session speaks gRPC. Its messages are small enough to encode
+// and decode them by hand instead of depending on a protobuf library.
+// docs.: https://protobuf.dev/programming-guides/encoding/
+
+// Wire types of protobuf.
+const (
+	protoWireVarint = 0
+	protoWireI64    = 1
+	protoWireLen    = 2
+	protoWireI32    = 5
+)
+
+// protoField is a field of a decoded message. Bytes is set for
+// length-delimited fields, Varint for varints.
+type protoField struct {
+	Num    int
+	Wire   int
+	Varint uint64
+	Bytes  []byte
+}
+
+// parseProto splits a message into its fields.
+func parseProto(msg []byte) ([]protoField, error) {
+	var fields []protoField
+	for len(msg) > 0 {
+		tag, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return nil, fmt.Errorf("protobuf: invalid tag")
+		}
+		msg = msg[n:]
+		f := protoField{Num: int(tag >> 3), Wire: int(tag & 7)}
+		switch f.Wire {
+		case protoWireVarint:
+			f.Varint, n = binary.Uvarint(msg)
+			if n <= 0 {
+				return nil, fmt.Errorf("protobuf: invalid varint of field %d", f.Num)
+			}
+			msg = msg[n:]
+		case protoWireLen:
+			l, n := binary.Uvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < l {
+				return nil, fmt.Errorf("protobuf: invalid length of field %d", f.Num)
+			}
+			f.Bytes = msg[n : n+int(l)]
+			msg = msg[n+int(l):]
+		case protoWireI64, protoWireI32:
+			size := 8
+			if f.Wire == protoWireI32 {
+				size = 4
+			}
+			if len(msg) < size {
+				return nil, fmt.Errorf("protobuf: truncated field %d", f.Num)
+			}
+			msg = msg[size:]
+		default:
+			return nil, fmt.Errorf("protobuf: unsupported wire type %d of field %d", f.Wire, f.Num)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// protoString returns the last value of the string field num.
+func protoString(msg []byte, num int) (string, error) {
+	fields, err := parseProto(msg)
+	if err != nil {
+		return "", err
+	}
+	var s string
+	for _, f := range fields {
+		if f.Num == num && f.Wire == protoWireLen {
+			s = string(f.Bytes)
+		}
+	}
+	return s, nil
+}
+
+// protoVarint appends the varint field num to b.
+func protoVarint(b []byte, num int, v uint64) []byte {
+	b = appendUvarint(b, uint64(num)<<3|protoWireVarint)
+	return appendUvarint(b, v)
+}
+
+// protoBytes appends the length-delimited field num to b.
+func protoBytes(b []byte, num int, v []byte) []byte {
+	b = appendUvarint(b, uint64(num)<<3|protoWireLen)
+	b = appendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
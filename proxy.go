@@ -0,0 +1,74 @@
+package docker
+
+import (
+	"os"
+	"strings"
+)
+
+// ProxyEnv holds the proxy settings injected into containers, see
+// SetProxyEnv.
+type ProxyEnv struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+}
+
+// ProxyFromEnvironment returns the proxy settings of the current process
+// taken from HTTP_PROXY, HTTPS_PROXY and NO_PROXY or their lowercase
+// variants.
+func ProxyFromEnvironment() ProxyEnv {
+	return ProxyEnv{
+		HTTPProxy:  getenvAny("HTTP_PROXY", "http_proxy"),
+		HTTPSProxy: getenvAny("HTTPS_PROXY", "https_proxy"),
+		NoProxy:    getenvAny("NO_PROXY", "no_proxy"),
+	}
+}
+
+func getenvAny(names ...string) string {
+	for _, n := range names {
+		if v := os.Getenv(n); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// SetProxyEnv makes the client inject the proxy settings into the
+// environment of every container it creates, in upper and lower case as
+// tools differ in what they read. Variables set by the spec of a container
+// are kept. Empty settings are not injected, nil disables the injection.
+// SetProxyEnv has to be called before the client is used.
+// e.g.: p := ProxyFromEnvironment(); c.SetProxyEnv(&p)
+func (c *Client) SetProxyEnv(p *ProxyEnv) {
+	if p == nil {
+		c.proxyEnv = nil
+		return
+	}
+	cp := *p
+	c.proxyEnv = &cp
+}
+
+// inject adds the proxy settings to env, which holds KEY=value
+// pairs, in upper and lower case. A setting is skipped if env sets it
+// already in either case, tools disagree which one wins.
+func (p *ProxyEnv) inject(env []string) []string {
+	if p == nil {
+		return env
+	}
+	set := make(map[string]bool, len(env))
+	for _, e := range env {
+		set[strings.SplitN(e, "=", 2)[0]] = true
+	}
+	for _, v := range []struct{ name, value string }{
+		{"HTTP_PROXY", p.HTTPProxy},
+		{"HTTPS_PROXY", p.HTTPSProxy},
+		{"NO_PROXY", p.NoProxy},
+	} {
+		lower := strings.ToLower(v.name)
+		if v.value == "" || set[v.name] || set[lower] {
+			continue
+		}
+		env = append(env, v.name+"="+v.value, lower+"="+v.value)
+	}
+	return env
+}
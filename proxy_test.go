@@ -0,0 +1,63 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func Test_ProxyEnv(t *testing.T) {
+	for _, k := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy"} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Unsetenv(k)
+	}
+	os.Setenv("http_proxy", "http://proxy:3128")
+	os.Setenv("NO_PROXY", "localhost")
+
+	p := ProxyFromEnvironment()
+	if want := (ProxyEnv{HTTPProxy: "http://proxy:3128", NoProxy: "localhost"}); p != want {
+		t.Errorf("want %+v, got %+v", want, p)
+	}
+
+	// the spec sets no_proxy, NO_PROXY must not override it
+	got := p.inject([]string{"A=1", "no_proxy=plc"})
+	want := []string{"A=1", "no_proxy=plc", "HTTP_PROXY=http://proxy:3128", "http_proxy=http://proxy:3128"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	var none *ProxyEnv
+	if got := none.inject([]string{"A=1"}); !reflect.DeepEqual(got, []string{"A=1"}) {
+		t.Errorf("unexpected env %v", got)
+	}
+}
+
+func Test_SetProxy(t *testing.T) {
+	var host string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.URL.Host
+		w.Write([]byte("OK"))
+	}))
+	defer proxy.Close()
+
+	c, err := NewClientHost("tcp://lab-1:2375")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(proxy.URL)
+	if err := c.SetProxy(u); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if host != "lab-1:2375" {
+		t.Errorf("unexpected host %q", host)
+	}
+	if err := client.SetProxy(u); err == nil {
+		t.Error("expected error for socket client")
+	}
+}
@@ -0,0 +1,150 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// PullImage pulls the image from its registry. The reference may contain a
+// tag or a digest, the tag defaults to latest. Concurrent pulls of the same
+// reference are coalesced into a single request to the daemon and all
+// callers get its result. The shared pull is canceled once every caller has
+// given up. Rate limited pulls are handled as set by SetPullOptions.
+// The long timeout of the client applies to pulls, see SetTimeouts.
+// docs.: https://docs.docker.com/engine/api/v1.36/#operation/ImageCreate
+func (c *Client) PullImage(ctx context.Context, ref string) error {
+	if ref == "" {
+		return fmt.Errorf("pull: image must not be empty")
+	}
+	if err := c.pulls.do(ctx, ref, func(ctx context.Context) error {
+		return c.pullWithRetry(ctx, ref)
+	}); err != nil {
+		return fmt.Errorf("pull image %s: %w", ref, err)
+	}
+	return nil
+}
+
+// pullImage pulls the image with the credentials of the auth provider. If
+// the registry rejects cached credentials, they are refreshed once.
+func (c *Client) pullImage(ctx context.Context, ref string) error {
+	err := c.pullImageOnce(ctx, ref)
+	if inv, ok := c.auth.(AuthInvalidator); ok && err != nil && isUnauthorized(err) {
+		inv.Invalidate(ref)
+		err = c.pullImageOnce(ctx, ref)
+	}
+	return err
+}
+
+func (c *Client) pullImageOnce(ctx context.Context, ref string) error {
+	header := http.Header{}
+	if c.auth != nil {
+		auth, err := c.auth.Auth(ctx, ref)
+		if err != nil {
+			return err
+		}
+		if auth != nil {
+			header.Set("X-Registry-Auth", auth.header())
+		}
+	}
+
+	r, err := c.streamHeader(ctx, http.MethodPost, "images/create", pullQuery(ref), nil, header)
+	if err != nil {
+		return err
+	}
+	defer drainClose(r.Body)
+	if err := checkResponse(r, http.StatusOK); err != nil {
+		return err
+	}
+	return readProgress(r.Body)
+}
+
+// readProgress consumes the JSON progress messages sent by the daemon while
+// an image is pulled. Failures are reported within the stream after the
+// status code was already sent.
+func readProgress(r io.Reader) error {
+	return ReadJSONMessages(r, nil)
+}
+
+// pullQuery splits the reference into the fromImage and tag parameters.
+// References with digest are passed as they are.
+func pullQuery(ref string) url.Values {
+	q := url.Values{"fromImage": {ref}}
+	if strings.Contains(ref, "@") {
+		return q
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		q.Set("fromImage", ref[:i])
+		q.Set("tag", ref[i+1:])
+		return q
+	}
+	q.Set("tag", "latest")
+	return q
+}
+
+// flightGroup coalesces concurrent calls with the same key. The zero value
+// is ready to use.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+type flight struct {
+	done    chan struct{}
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// do calls fn once for all concurrent callers with the same key. fn runs
+// with its own context, which is canceled when the contexts of all callers
+// are done. A caller whose context is done returns its error immediately.
+// Canceled calls are forgotten, so later callers do not join them.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(context.Context) error) error {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flight)
+	}
+	f, ok := g.calls[key]
+	if !ok {
+		fctx, cancel := context.WithCancel(context.Background())
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = f
+		go func() {
+			f.err = fn(fctx)
+			cancel()
+			g.mu.Lock()
+			g.forget(key, f)
+			g.mu.Unlock()
+			close(f.done)
+		}()
+	}
+	f.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			f.cancel()
+			g.forget(key, f)
+		}
+		g.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// forget removes the call, so later callers start a new one. The caller has
+// to hold the lock.
+func (g *flightGroup) forget(key string, f *flight) {
+	if g.calls[key] == f {
+		delete(g.calls, key)
+	}
+}
@@ -0,0 +1,93 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_PullImage(t *testing.T) {
+	t.Run("coalesced", func(t *testing.T) {
+		srv.route(map[string]mockResponse{
+			"POST /images/create": {
+				Body:  `{"status": "Pulling from library/postgres"}` + "\n" + `{"status": "Download complete"}`,
+				Delay: 100 * time.Millisecond,
+			},
+		})
+		defer srv.route(nil)
+
+		var wg sync.WaitGroup
+		errs := make([]error, 5)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = client.PullImage(context.Background(), "postgres:12")
+			}(i)
+		}
+		wg.Wait()
+
+		for _, err := range errs {
+			if err != nil {
+				t.Error(err)
+			}
+		}
+		if got := srv.Requests(); len(got) != 1 {
+			t.Errorf("expected a single pull, got %v", got)
+		}
+	})
+
+	t.Run("error in stream", func(t *testing.T) {
+		srv.route(map[string]mockResponse{
+			"POST /images/create": {Body: `{"status": "Pulling"}` + "\n" + `{"error": "manifest unknown"}`},
+		})
+		defer srv.route(nil)
+
+		err := client.PullImage(context.Background(), "postgres:nope")
+		if err == nil || !strings.Contains(err.Error(), "manifest unknown") {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+}
+
+func Test_flightGroupCancel(t *testing.T) {
+	var g flightGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan struct{})
+	go func() {
+		cancel()
+	}()
+	err := g.do(ctx, "k", func(ctx context.Context) error {
+		<-ctx.Done()
+		close(canceled)
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error %v", err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("shared call was not canceled")
+	}
+}
+
+func Test_pullQuery(t *testing.T) {
+	tests := map[string]url.Values{
+		"postgres":                   {"fromImage": {"postgres"}, "tag": {"latest"}},
+		"postgres:12":                {"fromImage": {"postgres"}, "tag": {"12"}},
+		"localhost:5000/app":         {"fromImage": {"localhost:5000/app"}, "tag": {"latest"}},
+		"localhost:5000/app:v1":      {"fromImage": {"localhost:5000/app"}, "tag": {"v1"}},
+		"postgres@sha256:0123456789": {"fromImage": {"postgres@sha256:0123456789"}},
+	}
+	for ref, want := range tests {
+		if got := pullQuery(ref); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: want %v, got %v", ref, want, got)
+		}
+	}
+}
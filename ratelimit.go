@@ -0,0 +1,158 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrRateLimited is returned if the registry refused a pull because the pull
+// rate limit was reached, like Docker Hub does for anonymous and free users.
+var ErrRateLimited = errors.New("pull rate limit reached")
+
+// PullOptions changes how PullImage reacts to rate limits.
+type PullOptions struct {
+	// Retries is the number of retries of a rate limited pull.
+	Retries int
+	// Backoff is the wait time before the first retry, it is doubled for
+	// every further retry. Defaults to the policy of the client, see
+	// SetBackoff, or 10s.
+	Backoff time.Duration
+	// Mirror is the host of a registry mirroring Docker Hub, e.g.
+	// mirror.gcr.io. If the pull of a Docker Hub image is still rate limited
+	// after all retries, the image is pulled from the mirror and tagged with
+	// the original reference.
+	Mirror string
+}
+
+const defaultPullBackoff = 10 * time.Second
+
+// SetPullOptions sets the rate limit handling of PullImage. It has to be
+// called before the client is used.
+func (c *Client) SetPullOptions(opts PullOptions) {
+	c.pullOpts = opts
+}
+
+// isRateLimited reports whether the pull failed by a rate limit.
+func isRateLimited(err error) bool {
+	var e *APIError
+	if errors.As(err, &e) && e.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "toomanyrequests") || strings.Contains(msg, "rate limit")
+}
+
+// pullWithRetry pulls the image and retries rate limited pulls with
+// exponential backoff before falling back to the mirror.
+func (c *Client) pullWithRetry(ctx context.Context, ref string) error {
+	opts := c.pullOpts
+	backoff := c.backoffOr(ExponentialBackoff(defaultPullBackoff, 0, 0))
+	if opts.Backoff > 0 {
+		backoff = ExponentialBackoff(opts.Backoff, 0, 0)
+	}
+
+	err := c.pullImage(ctx, ref)
+	for i := 0; i < opts.Retries && err != nil && isRateLimited(err); i++ {
+		if err := sleepBackoff(ctx, backoff, i); err != nil {
+			return err
+		}
+		err = c.pullImage(ctx, ref)
+	}
+	if err == nil || !isRateLimited(err) {
+		return err
+	}
+
+	parsed := parseReference(ref)
+	if opts.Mirror == "" || parsed.Registry != dockerHub {
+		return fmt.Errorf("%w: %v", ErrRateLimited, err)
+	}
+	mirrored := opts.Mirror + "/" + parsed.Repository
+	if parsed.Digest != "" {
+		mirrored += "@" + parsed.Digest
+	} else {
+		mirrored += ":" + parsed.Tag
+	}
+	if merr := c.pullImage(ctx, mirrored); merr != nil {
+		return fmt.Errorf("%w: %v; mirror %s: %v", ErrRateLimited, err, opts.Mirror, merr)
+	}
+	if parsed.Digest != "" {
+		// images pulled by digest can not be tagged, they are only found by
+		// the mirror reference
+		return nil
+	}
+	repo := ref
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		repo = ref[:i]
+	}
+	return c.tagImage(ctx, mirrored, repo, parsed.Tag)
+}
+
+// RateLimit is the pull rate limit of Docker Hub.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	// Window is the period the limit applies to.
+	Window time.Duration
+}
+
+// HubRateLimit returns the current pull rate limit of Docker Hub for the
+// credentials of the TokenAuth, or for anonymous pulls if it has no user.
+// Checking the limit does not count as pull.
+// docs.: https://docs.docker.com/docker-hub/download-rate-limit/
+func (t *TokenAuth) HubRateLimit(ctx context.Context) (*RateLimit, error) {
+	const image = "ratelimitpreview/test"
+	ta := &TokenAuth{Username: t.Username, Password: t.Password, HTTP: t.HTTP}
+	auth, err := ta.Auth(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodHead, "https://"+dockerHubAPI+"/v2/"+image+"/manifests/latest", nil)
+	if err != nil {
+		return nil, err
+	}
+	if auth != nil && auth.RegistryToken != "" {
+		req.Header.Set("Authorization", "Bearer "+auth.RegistryToken)
+	}
+	r, err := ta.client().Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("rate limit: %w", err)
+	}
+	r.Body.Close()
+	if err := checkResponse(r, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("rate limit: %w", err)
+	}
+	return parseRateLimit(r.Header), nil
+}
+
+// parseRateLimit reads headers like "ratelimit-remaining: 76;w=21600". The
+// headers are missing for users without limit, the limit is -1 then.
+func parseRateLimit(h http.Header) *RateLimit {
+	rl := &RateLimit{Limit: -1, Remaining: -1}
+	parse := func(v string) int {
+		ss := strings.Split(v, ";")
+		n, err := strconv.Atoi(strings.TrimSpace(ss[0]))
+		if err != nil {
+			return -1
+		}
+		for _, s := range ss[1:] {
+			if w := strings.TrimPrefix(strings.TrimSpace(s), "w="); w != s {
+				if secs, err := strconv.Atoi(w); err == nil {
+					rl.Window = time.Duration(secs) * time.Second
+				}
+			}
+		}
+		return n
+	}
+	if v := h.Get("Ratelimit-Limit"); v != "" {
+		rl.Limit = parse(v)
+	}
+	if v := h.Get("Ratelimit-Remaining"); v != "" {
+		rl.Remaining = parse(v)
+	}
+	return rl
+}
@@ -0,0 +1,64 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_PullImageRateLimited(t *testing.T) {
+	limited := mockResponse{Body: `{"error": "toomanyrequests: You have reached your pull rate limit."}`}
+	defer client.SetPullOptions(PullOptions{})
+
+	t.Run("retries", func(t *testing.T) {
+		srv.route(map[string]mockResponse{"POST /images/create": limited})
+		defer srv.route(nil)
+		client.SetPullOptions(PullOptions{Retries: 2, Backoff: time.Millisecond})
+
+		err := client.PullImage(context.Background(), "postgres:12")
+		if !errors.Is(err, ErrRateLimited) {
+			t.Errorf("unexpected error %v", err)
+		}
+		if got := srv.Requests(); len(got) != 3 {
+			t.Errorf("expected 3 pulls, got %v", got)
+		}
+	})
+
+	t.Run("mirror", func(t *testing.T) {
+		srv.route(map[string]mockResponse{
+			"POST /images/create": limited,
+			"POST /images/create?fromImage=mirror.gcr.io%2Flibrary%2Fpostgres&tag=12": {Body: `{"status": "Downloaded"}`},
+			"POST /images/mirror.gcr.io/library/postgres:12/tag":                      {StatusCode: http.StatusCreated},
+		})
+		defer srv.route(nil)
+		client.SetPullOptions(PullOptions{Backoff: time.Millisecond, Mirror: "mirror.gcr.io"})
+
+		if err := client.PullImage(context.Background(), "postgres:12"); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{
+			"POST /images/create",
+			"POST /images/create",
+			"POST /images/mirror.gcr.io/library/postgres:12/tag",
+		}
+		if got := srv.Requests(); !reflect.DeepEqual(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+}
+
+func Test_parseRateLimit(t *testing.T) {
+	h := http.Header{}
+	h.Set("Ratelimit-Limit", "100;w=21600")
+	h.Set("Ratelimit-Remaining", "76;w=21600")
+	want := &RateLimit{Limit: 100, Remaining: 76, Window: 6 * time.Hour}
+	if got := parseRateLimit(h); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+	if got := parseRateLimit(http.Header{}); got.Limit != -1 || got.Remaining != -1 {
+		t.Errorf("expected unlimited, got %+v", got)
+	}
+}
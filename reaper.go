@@ -0,0 +1,154 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultReaperImage is the image of the reaper sidecar. It speaks the
+	// protocol of the testcontainers ryuk.
+	DefaultReaperImage = "testcontainers/ryuk:0.3.4"
+	// LabelReaper marks reaper containers.
+	LabelReaper = "com.grid-x.docker.reaper"
+
+	reaperPort = "8080/tcp"
+)
+
+// ReaperOptions configures StartReaper.
+type ReaperOptions struct {
+	// Image defaults to DefaultReaperImage.
+	Image string
+	// Socket is the path of the docker socket on the host which is mounted
+	// into the reaper. Defaults to /var/run/docker.sock.
+	Socket string
+	// Privileged is required on hosts with SELinux to access the socket.
+	Privileged bool
+}
+
+// Reaper removes the resources of a process once it died. It is a sidecar
+// container which holds a connection to the process: as soon as the
+// connection is closed, either by Close or because the process crashed, the
+// reaper removes all containers, networks and volumes matching the selector
+// and exits.
+type Reaper struct {
+	// ID of the reaper container.
+	ID   string
+	conn net.Conn
+}
+
+// StartReaper starts a reaper for the resources matching the selector. The
+// image is pulled if it is not available. The reaper's port is published on
+// the loopback interface, so the daemon has to run on the local host.
+// e.g.: r, err := c.StartReaper(ctx, RunSelector(run), ReaperOptions{})
+func (c *Client) StartReaper(ctx context.Context, selector Selector, opts ReaperOptions) (*Reaper, error) {
+	if len(selector) == 0 {
+		return nil, fmt.Errorf("reaper: selector must not be empty")
+	}
+	if opts.Image == "" {
+		opts.Image = DefaultReaperImage
+	}
+	if opts.Socket == "" {
+		opts.Socket = "/var/run/docker.sock"
+	}
+
+	body, err := ContainerSpec{
+		Image:      opts.Image,
+		Labels:     map[string]string{LabelReaper: "true"},
+		Ports:      []string{"127.0.0.1::" + reaperPort},
+		Mounts:     []string{opts.Socket + ":/var/run/docker.sock"},
+		Privileged: opts.Privileged,
+	}.createBody()
+	if err != nil {
+		return nil, fmt.Errorf("reaper: %w", err)
+	}
+	body.HostConfig.AutoRemove = true
+
+	res, err := c.createContainer(ctx, "", body)
+	if isNotFound(err) {
+		if err := c.PullImage(ctx, opts.Image); err != nil {
+			return nil, fmt.Errorf("reaper: %w", err)
+		}
+		res, err = c.createContainer(ctx, "", body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reaper: create container: %w", err)
+	}
+
+	r, err := func() (*Reaper, error) {
+		if err := c.startContainer(ctx, res.ID); err != nil {
+			return nil, fmt.Errorf("start container: %w", err)
+		}
+		cj, err := c.inspectContainer(ctx, res.ID)
+		if err != nil {
+			return nil, fmt.Errorf("inspect container: %w", err)
+		}
+		bindings := cj.NetworkSettings.Ports[reaperPort]
+		if len(bindings) == 0 {
+			return nil, fmt.Errorf("port %s is not published", reaperPort)
+		}
+		conn, err := dialReaper(ctx, net.JoinHostPort("127.0.0.1", bindings[0].HostPort))
+		if err != nil {
+			return nil, err
+		}
+		if err := registerReaper(conn, selector); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return &Reaper{ID: res.ID, conn: conn}, nil
+	}()
+	if err != nil {
+		// the context may be done already, the cleanup must not depend on it
+		c.removeContainer(context.Background(), res.ID, true)
+		return nil, fmt.Errorf("reaper: %w", err)
+	}
+	return r, nil
+}
+
+// Close closes the connection to the reaper, which then removes the
+// resources.
+func (r *Reaper) Close() error {
+	return r.conn.Close()
+}
+
+// dialReaper connects to the reaper. The connection is retried until the
+// reaper listens or the context is done.
+func dialReaper(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	for {
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			return conn, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("connect to %s: %w", addr, err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// registerReaper sends the label filters of the selector and waits for the
+// acknowledgement of the reaper.
+func registerReaper(conn net.Conn, selector Selector) error {
+	q := url.Values{"label": selector.filters()["label"]}
+	if _, err := fmt.Fprintf(conn, "%s\n", q.Encode()); err != nil {
+		return fmt.Errorf("register filters: %w", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("register filters: %w", err)
+	}
+	if strings.TrimSpace(line) != "ACK" {
+		return fmt.Errorf("register filters: unexpected answer %q", line)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_StartReaper(t *testing.T) {
+	// fake ryuk: acknowledges the filters and reports the closed connection
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	filters := make(chan string, 1)
+	closed := make(chan struct{})
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		filters <- line
+		fmt.Fprint(conn, "ACK\n")
+		ioutil.ReadAll(conn)
+		close(closed)
+	}()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	srv.route(map[string]mockResponse{
+		"POST /containers/create":   {StatusCode: http.StatusCreated, Body: `{"Id": "r1"}`},
+		"POST /containers/r1/start": {StatusCode: http.StatusNoContent},
+		"GET /containers/r1/json":   {Body: `{"Id": "r1", "NetworkSettings": {"Ports": {"8080/tcp": [{"HostIp": "127.0.0.1", "HostPort": "` + port + `"}]}}}`},
+	})
+	defer srv.route(nil)
+
+	r, err := client.StartReaper(context.Background(), RunSelector("r1"), ReaperOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ID != "r1" {
+		t.Errorf("unexpected ID %s", r.ID)
+	}
+	q, err := url.ParseQuery(strings.TrimSpace(<-filters))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{LabelRun + "=r1"}; !reflect.DeepEqual(q["label"], want) {
+		t.Errorf("want filters %q, got %q", want, q["label"])
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	<-closed
+}
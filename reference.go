@@ -0,0 +1,105 @@
+package docker
+
+import (
+	"net"
+	"strings"
+)
+
+const (
+	// dockerHub is the registry of images without registry host.
+	dockerHub = "docker.io"
+	// dockerHubAPI is the host serving the registry API of Docker Hub.
+	dockerHubAPI = "registry-1.docker.io"
+)
+
+// reference is a parsed image reference.
+type reference struct {
+	// Registry is the host of the registry, docker.io for Docker Hub.
+	Registry string
+	// Repository is the path within the registry, e.g. library/postgres.
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// parseReference splits an image reference like
+// "localhost:5000/sim/plc:1.0" or "postgres@sha256:...". The tag defaults
+// to latest if there is no digest.
+func parseReference(ref string) reference {
+	var r reference
+	if i := strings.Index(ref, "@"); i >= 0 {
+		ref, r.Digest = ref[:i], ref[i+1:]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref, r.Tag = ref[:i], ref[i+1:]
+	}
+	if r.Tag == "" && r.Digest == "" {
+		r.Tag = "latest"
+	}
+
+	// the first component is a registry if it looks like a host
+	if i := strings.Index(ref, "/"); i >= 0 {
+		host := ref[:i]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			r.Registry, r.Repository = host, ref[i+1:]
+		}
+	}
+	if r.Registry == "" {
+		r.Registry, r.Repository = dockerHub, ref
+	}
+	if r.Registry == dockerHub && !strings.Contains(r.Repository, "/") {
+		r.Repository = "library/" + r.Repository
+	}
+	return r
+}
+
+// apiHost returns the host serving the registry API.
+func (r reference) apiHost() string {
+	if r.Registry == dockerHub {
+		return dockerHubAPI
+	}
+	return r.Registry
+}
+
+// String returns the reference with registry, e.g.
+// "docker.io/library/postgres:13".
+func (r reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// tagOrDigest returns the digest of the reference, if it has one, else its
+// tag.
+func (r reference) tagOrDigest() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// url returns the URL of the registry API for the path within the
+// repository, e.g. "manifests/latest".
+func (r reference) url(path string) string {
+	return r.apiURL(r.Repository + "/" + path)
+}
+
+// apiURL returns the URL of the path of the registry API, e.g. "" for the
+// version check. Registries on loopback addresses are talked to by plain
+// HTTP.
+func (r reference) apiURL(path string) string {
+	scheme := "https"
+	host := r.apiHost()
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if ip := net.ParseIP(host); host == "localhost" || ip != nil && ip.IsLoopback() {
+		scheme = "http"
+	}
+	return scheme + "://" + r.apiHost() + "/v2/" + path
+}
@@ -0,0 +1,21 @@
+package docker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseReference(t *testing.T) {
+	tests := map[string]reference{
+		"postgres":               {Registry: "docker.io", Repository: "library/postgres", Tag: "latest"},
+		"grafana/grafana:7.0":    {Registry: "docker.io", Repository: "grafana/grafana", Tag: "7.0"},
+		"localhost:5000/sim/plc": {Registry: "localhost:5000", Repository: "sim/plc", Tag: "latest"},
+		"gcr.io/proj/app:v1":     {Registry: "gcr.io", Repository: "proj/app", Tag: "v1"},
+		"postgres@sha256:abc":    {Registry: "docker.io", Repository: "library/postgres", Digest: "sha256:abc"},
+	}
+	for ref, want := range tests {
+		if got := parseReference(ref); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: want %+v, got %+v", ref, want, got)
+		}
+	}
+}
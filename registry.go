@@ -0,0 +1,279 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RegistryAuth holds the credentials the daemon uses to access a registry.
+// Either username and password, an identity token or a bearer token for the
+// registry is set.
+// docs.: https://docs.docker.com/engine/api/v1.41/#section/Authentication
+type RegistryAuth struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	ServerAddress string `json:"serveraddress,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+	RegistryToken string `json:"registrytoken,omitempty"`
+}
+
+// header encodes the credentials for the X-Registry-Auth header.
+func (a *RegistryAuth) header() string {
+	b, _ := json.Marshal(a)
+	return base64.URLEncoding.EncodeToString(b)
+}
+
+// AuthProvider provides the credentials to pull the given image. A nil
+// RegistryAuth means the image is pulled anonymously.
+type AuthProvider interface {
+	Auth(ctx context.Context, image string) (*RegistryAuth, error)
+}
+
+// PushAuthProvider is implemented by AuthProviders whose credentials are
+// scoped to the action, like the tokens of TokenAuth. PushAuth provides the
+// credentials to push the given image.
+type PushAuthProvider interface {
+	PushAuth(ctx context.Context, image string) (*RegistryAuth, error)
+}
+
+// authFor returns the credentials of p for the actions on the image, e.g.
+// "pull" or "pull,push".
+func authFor(ctx context.Context, p AuthProvider, image, actions string) (*RegistryAuth, error) {
+	if pp, ok := p.(PushAuthProvider); ok && actions != "pull" {
+		return pp.PushAuth(ctx, image)
+	}
+	return p.Auth(ctx, image)
+}
+
+// AuthInvalidator is implemented by AuthProviders which cache credentials.
+// Invalidate is called if the registry rejected the credentials for the
+// image, the pull is retried once with fresh credentials.
+type AuthInvalidator interface {
+	Invalidate(image string)
+}
+
+// SetAuthProvider sets the provider of the credentials used by PullImage.
+// It has to be called before the client is used.
+func (c *Client) SetAuthProvider(p AuthProvider) {
+	c.auth = p
+}
+
+// isUnauthorized reports whether the registry rejected the credentials.
+func isUnauthorized(err error) bool {
+	var e *APIError
+	if errors.As(err, &e) && e.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unauthorized") || strings.Contains(msg, "authentication required")
+}
+
+// tokenExpiryMargin is the time before their expiry tokens are refreshed.
+const tokenExpiryMargin = 30 * time.Second
+
+// TokenAuth is an AuthProvider for registries using token authentication,
+// like Docker Hub. It answers the 401 challenge of the registry by fetching
+// a bearer token from the announced token service and caches the token per
+// repository and scope until shortly before it expires. Auth provides tokens
+// for pulling, PushAuth for pushing. Without username the tokens are
+// anonymous.
+type TokenAuth struct {
+	Username string
+	Password string
+	// HTTP is used to talk to the registry, defaults to a client with a
+	// timeout of 30s.
+	HTTP *http.Client
+
+	mu     sync.Mutex
+	tokens map[string]bearerToken
+}
+
+type bearerToken struct {
+	token   string
+	expires time.Time
+}
+
+// Auth implements AuthProvider.
+func (t *TokenAuth) Auth(ctx context.Context, image string) (*RegistryAuth, error) {
+	return t.auth(ctx, image, "pull")
+}
+
+// PushAuth implements PushAuthProvider.
+func (t *TokenAuth) PushAuth(ctx context.Context, image string) (*RegistryAuth, error) {
+	return t.auth(ctx, image, "pull,push")
+}
+
+// auth provides a token for the actions on the repository of the image,
+// e.g. "pull,push".
+func (t *TokenAuth) auth(ctx context.Context, image, actions string) (*RegistryAuth, error) {
+	ref := parseReference(image)
+	key := ref.apiHost() + "/" + ref.Repository + ":" + actions
+
+	t.mu.Lock()
+	tok, ok := t.tokens[key]
+	t.mu.Unlock()
+	if ok && time.Now().Add(tokenExpiryMargin).Before(tok.expires) {
+		return &RegistryAuth{RegistryToken: tok.token}, nil
+	}
+
+	tok, err := t.fetch(ctx, ref, actions)
+	if err != nil {
+		return nil, fmt.Errorf("registry token for %s: %w", image, err)
+	}
+	if tok.token == "" {
+		// the registry does not use token authentication
+		return t.basic(ref), nil
+	}
+	t.mu.Lock()
+	if t.tokens == nil {
+		t.tokens = make(map[string]bearerToken)
+	}
+	t.tokens[key] = tok
+	t.mu.Unlock()
+	return &RegistryAuth{RegistryToken: tok.token}, nil
+}
+
+// Invalidate implements AuthInvalidator.
+func (t *TokenAuth) Invalidate(image string) {
+	ref := parseReference(image)
+	key := ref.apiHost() + "/" + ref.Repository
+	t.mu.Lock()
+	delete(t.tokens, key+":pull")
+	delete(t.tokens, key+":pull,push")
+	t.mu.Unlock()
+}
+
+func (t *TokenAuth) basic(ref reference) *RegistryAuth {
+	if t.Username == "" {
+		return nil
+	}
+	return &RegistryAuth{Username: t.Username, Password: t.Password, ServerAddress: ref.Registry}
+}
+
+func (t *TokenAuth) client() *http.Client {
+	if t.HTTP != nil {
+		return t.HTTP
+	}
+	return &http.Client{Timeout: 30 * time.Second}
+}
+
+// fetch requests the challenge of the registry and a token for the actions
+// on the repository. The token is empty if the registry does not ask for a
+// bearer token or can not be asked, e.g. because it is only reachable by
+// the daemon.
+func (t *TokenAuth) fetch(ctx context.Context, ref reference, actions string) (bearerToken, error) {
+	req, err := http.NewRequest(http.MethodGet, ref.apiURL(""), nil)
+	if err != nil {
+		return bearerToken{}, err
+	}
+	r, err := t.client().Do(req.WithContext(ctx))
+	if err != nil {
+		return bearerToken{}, ctx.Err()
+	}
+	r.Body.Close()
+	if r.StatusCode != http.StatusUnauthorized {
+		return bearerToken{}, nil
+	}
+	scheme, params := parseChallenge(r.Header.Get("WWW-Authenticate"))
+	if !strings.EqualFold(scheme, "bearer") || params["realm"] == "" {
+		return bearerToken{}, nil
+	}
+	return requestToken(ctx, t.client(), params, []string{"repository:" + ref.Repository + ":" + actions},
+		t.Username, t.Password)
+}
+
+// requestToken requests a bearer token for the scopes from the token
+// service announced by the params of a challenge.
+func requestToken(ctx context.Context, hc *http.Client, params map[string]string, scopes []string, username, password string) (bearerToken, error) {
+	q := url.Values{"scope": scopes}
+	if params["service"] != "" {
+		q.Set("service", params["service"])
+	}
+	req, err := http.NewRequest(http.MethodGet, params["realm"]+"?"+q.Encode(), nil)
+	if err != nil {
+		return bearerToken{}, err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	r, err := hc.Do(req.WithContext(ctx))
+	if err != nil {
+		return bearerToken{}, err
+	}
+	defer r.Body.Close()
+	if err := checkResponse(r, http.StatusOK); err != nil {
+		return bearerToken{}, err
+	}
+
+	var res struct {
+		Token       string    `json:"token"`
+		AccessToken string    `json:"access_token"`
+		ExpiresIn   int       `json:"expires_in"`
+		IssuedAt    time.Time `json:"issued_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		return bearerToken{}, err
+	}
+	tok := bearerToken{token: res.Token}
+	if tok.token == "" {
+		tok.token = res.AccessToken
+	}
+	if tok.token == "" {
+		return bearerToken{}, fmt.Errorf("token service returned no token")
+	}
+	// tokens without expiry are valid for 60s by specification
+	if res.ExpiresIn == 0 {
+		res.ExpiresIn = 60
+	}
+	if res.IssuedAt.IsZero() {
+		res.IssuedAt = time.Now()
+	}
+	tok.expires = res.IssuedAt.Add(time.Duration(res.ExpiresIn) * time.Second)
+	return tok, nil
+}
+
+// parseChallenge parses a WWW-Authenticate header like
+// `Bearer realm="https://auth.docker.io/token",service="registry.docker.io"`.
+func parseChallenge(header string) (string, map[string]string) {
+	params := make(map[string]string)
+	header = strings.TrimSpace(header)
+	i := strings.Index(header, " ")
+	if i < 0 {
+		return header, params
+	}
+	scheme, rest := header[:i], header[i+1:]
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			end := strings.Index(rest, ",")
+			if end < 0 {
+				end = len(rest)
+			}
+			value, rest = rest[:end], rest[end:]
+		}
+		params[key] = value
+	}
+	return scheme, params
+}
@@ -0,0 +1,113 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_TokenAuth(t *testing.T) {
+	var fetched int32
+	var scopes []string
+	var reg *httptest.Server
+	reg = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, reg.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/token":
+			user, pass, _ := r.BasicAuth()
+			scope := r.URL.Query().Get("scope")
+			if !strings.HasPrefix(scope, "repository:sim/plc:") || user != "ci" || pass != "secret" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			scopes = append(scopes, scope)
+			n := atomic.AddInt32(&fetched, 1)
+			fmt.Fprintf(w, `{"token": "t%d", "expires_in": 300}`, n)
+		}
+	}))
+	defer reg.Close()
+
+	image := strings.TrimPrefix(reg.URL, "http://") + "/sim/plc:1.0"
+	ta := &TokenAuth{Username: "ci", Password: "secret", HTTP: reg.Client()}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		auth, err := ta.Auth(ctx, image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if auth.RegistryToken != "t1" {
+			t.Errorf("expected cached token t1, got %q", auth.RegistryToken)
+		}
+	}
+	ta.Invalidate(image)
+	if auth, err := ta.Auth(ctx, image); err != nil || auth.RegistryToken != "t2" {
+		t.Errorf("expected new token t2, got %+v, %v", auth, err)
+	}
+	// pushing needs a token of its own
+	if auth, err := ta.PushAuth(ctx, image); err != nil || auth.RegistryToken != "t3" {
+		t.Errorf("expected push token t3, got %+v, %v", auth, err)
+	}
+	want := []string{"repository:sim/plc:pull", "repository:sim/plc:pull", "repository:sim/plc:pull,push"}
+	if !reflect.DeepEqual(scopes, want) {
+		t.Errorf("got scopes %v, want %v", scopes, want)
+	}
+
+	// registries which can not be asked for a challenge get the credentials
+	reg.Close()
+	auth, err := ta.Auth(ctx, strings.TrimPrefix(reg.URL, "http://")+"/sim/meter:1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.Username != "ci" || auth.RegistryToken != "" {
+		t.Errorf("expected basic auth, got %+v", auth)
+	}
+}
+
+func Test_parseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope=x`)
+	want := map[string]string{"realm": "https://auth.docker.io/token", "service": "registry.docker.io", "scope": "x"}
+	if scheme != "Bearer" || !reflect.DeepEqual(params, want) {
+		t.Errorf("unexpected challenge %s %v", scheme, params)
+	}
+}
+
+// staleAuth counts the calls of an AuthProvider.
+type staleAuth struct {
+	auths, invalidations int
+}
+
+func (a *staleAuth) Auth(context.Context, string) (*RegistryAuth, error) {
+	a.auths++
+	return &RegistryAuth{RegistryToken: "stale"}, nil
+}
+
+func (a *staleAuth) Invalidate(string) { a.invalidations++ }
+
+func Test_PullImageUnauthorized(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"POST /images/create": {Body: `{"error": "unauthorized: authentication required"}`},
+	})
+	defer srv.route(nil)
+
+	auth := &staleAuth{}
+	client.SetAuthProvider(auth)
+	defer client.SetAuthProvider(nil)
+
+	if err := client.PullImage(context.Background(), "sim/plc"); err == nil {
+		t.Fatal("expected error")
+	}
+	if auth.auths != 2 || auth.invalidations != 1 {
+		t.Errorf("expected one retry with fresh credentials, got %+v", auth)
+	}
+	if got := srv.Requests(); len(got) != 2 {
+		t.Errorf("expected two pulls, got %v", got)
+	}
+}
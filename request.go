@@ -0,0 +1,215 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrInvalidName is returned for names and IDs which can not be used in a
+// request path or which the daemon would reject as container name.
+var ErrInvalidName = errors.New("invalid name")
+
+// containerName is the pattern of container names accepted by dockerd.
+var containerName = regexp.MustCompile(`^/?[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
+// validContainerName checks the name of a container before it is created.
+func validContainerName(name string) error {
+	if !containerName.MatchString(name) {
+		return fmt.Errorf("%w %q: only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed", ErrInvalidName, name)
+	}
+	return nil
+}
+
+// pathName escapes the name or ID of a container, network, volume etc. as a
+// single segment of a request path. The leading slash of container names
+// returned by the API is removed.
+func pathName(name string) string {
+	return url.PathEscape(strings.TrimPrefix(name, "/"))
+}
+
+// pathRef escapes an image or plugin reference for a request path. The
+// daemon matches the slashes of repositories, so they are kept and the
+// parts between them are escaped.
+func pathRef(ref string) string {
+	parts := strings.Split(ref, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
+
+// endpoint returns the URL of the path relative to the API base. Paths with
+// empty, "." or ".." segments are rejected, they are left by empty or
+// malicious names and would address another resource or endpoint.
+func (c *Client) endpoint(path string, query url.Values) (string, error) {
+	for _, seg := range strings.Split(path, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return "", fmt.Errorf("%w in request path %q", ErrInvalidName, path)
+		}
+	}
+	endpoint := c.base + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	return endpoint, nil
+}
+
+// isNotFound reports whether err was caused by a 404 response of the daemon.
+func isNotFound(err error) bool {
+	var e *APIError
+	return errors.As(err, &e) && e.StatusCode == http.StatusNotFound
+}
+
+// checkResponse verifies the status code of r. If it is not one of want, the
+// body is read to extract the daemon's error message and an *APIError is
+// returned.
+func checkResponse(r *http.Response, want ...int) error {
+	for _, w := range want {
+		if r.StatusCode == w {
+			return nil
+		}
+	}
+	msg := struct {
+		Message string `json:"message"`
+	}{}
+	b, _ := ioutil.ReadAll(io.LimitReader(r.Body, 64<<10))
+	if err := json.Unmarshal(b, &msg); err != nil {
+		msg.Message = string(bytes.TrimSpace(b))
+	}
+	e := &APIError{StatusCode: r.StatusCode, Want: want[0], Message: msg.Message}
+	if r.Request != nil {
+		e.Method = r.Request.Method
+		e.Path, e.Resource, e.ID = parseAPIPath(r.Request.URL)
+	}
+	return e
+}
+
+// do sends a request to the daemon. If in is not nil it is sent as JSON body.
+// The caller has to close the body of the returned response.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in interface{}) (*http.Response, error) {
+	if in == nil {
+		return c.send(ctx, method, path, query, nil, "")
+	}
+	b, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	return c.send(ctx, method, path, query, bytes.NewReader(b), "application/json")
+}
+
+// send sends a request with the given body and content type to the daemon.
+// The caller has to close the body of the returned response.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body io.Reader, contentType string) (*http.Response, error) {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	return c.sendHeader(ctx, method, path, query, body, header)
+}
+
+// sendHeader sends a request with the given body and header to the daemon,
+// with the timeout of the class of its method, see methodClass.
+// The caller has to close the body of the returned response.
+func (c *Client) sendHeader(ctx context.Context, method, path string, query url.Values, body io.Reader, header http.Header) (*http.Response, error) {
+	return c.sendTimeout(ctx, c.timeout(methodClass(method)), method, path, query, body, header)
+}
+
+// stream sends a GET request for a stream which stays open as long as the
+// context, e.g. events. No timeout of the client applies.
+// The caller has to close the body of the returned response.
+func (c *Client) stream(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	return c.sendTimeout(ctx, 0, http.MethodGet, path, query, nil, nil)
+}
+
+// streamHeader sends a request like sendHeader with the long timeout, for
+// requests which take as long as the daemon needs, e.g. builds.
+// The caller has to close the body of the returned response.
+func (c *Client) streamHeader(ctx context.Context, method, path string, query url.Values, body io.Reader, header http.Header) (*http.Response, error) {
+	return c.sendTimeout(ctx, c.timeout(TimeoutLong), method, path, query, body, header)
+}
+
+// sendTimeout sends a request with the timeout, zero means none.
+// The caller has to close the body of the returned response.
+func (c *Client) sendTimeout(ctx context.Context, timeout time.Duration, method, path string, query url.Values, body io.Reader, header http.Header) (*http.Response, error) {
+	endpoint, err := c.endpoint(path, query)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if method != http.MethodGet {
+		// the request may have changed the object even if it failed
+		defer c.cache.invalidatePath(path)
+	}
+	return c.timeoutClient(timeout).Do(req.WithContext(ctx))
+}
+
+// doJSON sends a request like do and checks the status code of the response
+// against want. If out is not nil, the response body is decoded into it.
+func (c *Client) doJSON(ctx context.Context, method, path string, query url.Values, in, out interface{}, want ...int) error {
+	if method == http.MethodGet && out != nil && c.cache.cacheable(path) {
+		return c.cachedJSON(ctx, path, query, out, want...)
+	}
+	r, err := c.do(ctx, method, path, query, in)
+	if err != nil {
+		return err
+	}
+	defer drainClose(r.Body)
+
+	if err := checkResponse(r, want...); err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	if c.strict != nil {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		if err := c.strict.check(path, b, out); err != nil {
+			return err
+		}
+		return json.Unmarshal(b, out)
+	}
+	return json.NewDecoder(r.Body).Decode(out)
+}
+
+// filterQuery encodes the given filters as the filters query parameter used
+// by the list endpoints of the engine API.
+func filterQuery(filters map[string][]string) url.Values {
+	q := url.Values{}
+	if len(filters) == 0 {
+		return q
+	}
+	b, _ := json.Marshal(filters)
+	q.Set("filters", string(b))
+	return q
+}
+
+// maxDrain limits how much of a response body drainClose reads. Longer
+// bodies are not worth the wait, their connection is closed instead.
+const maxDrain = 64 << 10
+
+// drainClose reads the rest of the body and closes it, so the connection is
+// returned to the pool of the transport and reused. It must not be used for
+// streams which do not end, like followed logs.
+func drainClose(body io.ReadCloser) {
+	io.Copy(ioutil.Discard, io.LimitReader(body, maxDrain))
+	body.Close()
+}
@@ -0,0 +1,70 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_PathEscaping(t *testing.T) {
+	var paths []string
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		if strings.HasSuffix(r.URL.Path, "/tag") {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer daemon.Close()
+	c, err := NewClientHost("tcp://" + strings.TrimPrefix(daemon.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		path string
+	}{
+		{"api name", func() error { return c.startContainer(ctx, "/sim-1") }, "/containers/sim-1/start"},
+		{"slash", func() error { return c.startContainer(ctx, "a/../../b") }, "/containers/a%2F..%2F..%2Fb/start"},
+		{"query", func() error { return c.removeNetwork(ctx, "net?force=1") }, "/networks/net%3Fforce=1"},
+		{"legacy", func() error { return c.DeleteContainer("x y") }, "/containers/x%20y"},
+		{"image", func() error { return c.tagImage(ctx, "registry:5000/sim/probe:1", "sim/probe", "latest") },
+			"/images/registry:5000/sim/probe:1/tag"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths = nil
+			if err := tt.call(); err != nil {
+				t.Fatal(err)
+			}
+			if len(paths) != 1 || paths[0] != tt.path {
+				t.Errorf("got %v, want %s", paths, tt.path)
+			}
+		})
+	}
+
+	for _, id := range []string{"", "..", ".", "/"} {
+		paths = nil
+		if err := c.startContainer(ctx, id); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("start %q: got %v, want ErrInvalidName", id, err)
+		}
+		if err := c.StartContainer(id); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("StartContainer %q: got %v, want ErrInvalidName", id, err)
+		}
+		if len(paths) != 0 {
+			t.Errorf("%q: sent %v", id, paths)
+		}
+	}
+	for _, name := range []string{"a", "-sim", "sim/1", "sim 1", "sim\n1"} {
+		if _, err := c.createContainer(ctx, name, &containerCreate{Image: "alpine"}); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("create %q: got %v, want ErrInvalidName", name, err)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// The actions of the events of type "daemon" passed to
+// RestartOptions.Notify.
+const (
+	// DaemonDown is emitted when the daemon could not be connected to.
+	DaemonDown = "down"
+	// DaemonUp is emitted when the daemon answers pings again.
+	DaemonUp = "up"
+)
+
+// RestartOptions configures how the client survives restarts of the
+// daemon, see SetRestartRecovery.
+type RestartOptions struct {
+	// MaxWait is how long the daemon is waited for after it went away,
+	// e.g. for an upgrade of the engine. Defaults to 2 minutes.
+	MaxWait time.Duration
+	// Backoff is the policy of the waits between pings. Defaults to the
+	// policy of the client, see SetBackoff, or exponential waits from
+	// 200ms up to 5s.
+	Backoff Backoff
+	// Notify is called with an event of type "daemon" and the action
+	// DaemonDown or DaemonUp when the daemon goes away and when it is back.
+	// It must not block.
+	Notify func(Event)
+}
+
+const (
+	defaultRestartMaxWait = 2 * time.Minute
+	restartPingTimeout    = 5 * time.Second
+)
+
+// SetRestartRecovery makes the client wait for a restarting daemon instead
+// of failing, nil disables it. When a request finds the socket missing, the
+// connection refused or closed without a response, the daemon is pinged
+// with backoff until it answers or MaxWait passed. Requests are held back
+// meanwhile and sent once it is up, the wait does not count against their
+// timeouts. The failed request is sent again if
+// the daemon has not received it or it does not change anything, i.e. GET
+// and HEAD requests; other requests fail with their error as the daemon
+// may have executed them. SetRestartRecovery has to be called before the
+// client is used.
+func (c *Client) SetRestartRecovery(opts *RestartOptions) {
+	if opts == nil {
+		c.restarts = nil
+		c.chainTransport()
+		return
+	}
+	w := &restartWaiter{maxWait: opts.MaxWait, backoff: opts.Backoff, notify: opts.Notify}
+	if w.maxWait <= 0 {
+		w.maxWait = defaultRestartMaxWait
+	}
+	if w.backoff == nil {
+		w.backoff = c.backoffOr(ExponentialBackoff(200*time.Millisecond, 5*time.Second, 0))
+	}
+	c.restarts = w
+	c.chainTransport()
+}
+
+// restartWaiter is a RoundTripper which holds requests back while the
+// daemon is down and sends them again once it is up.
+type restartWaiter struct {
+	next    http.RoundTripper
+	maxWait time.Duration
+	backoff Backoff
+	notify  func(Event)
+
+	mu sync.Mutex
+	// outage is not nil while the daemon is down.
+	outage *outage
+}
+
+// outage is a period the daemon is down, done is closed when it is over.
+// err is set if the daemon did not come back.
+type outage struct {
+	done chan struct{}
+	err  error
+}
+
+func (w *restartWaiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	for retried := false; ; retried = true {
+		if err := w.wait(req.Context()); err != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, err
+		}
+		res, err := w.next.RoundTrip(req)
+		if err == nil || req.Context().Err() != nil || !daemonGone(err) {
+			return res, err
+		}
+		w.down(req)
+		if retried || !notReceived(err) && req.Method != http.MethodGet && req.Method != http.MethodHead {
+			return nil, err
+		}
+		// requests with a body can only be sent again if it can be recreated
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return nil, err
+			}
+			b, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = b
+		}
+	}
+}
+
+// wait blocks while the daemon is down, the timeout of the request is
+// paused meanwhile. It fails if the daemon did not come back or the context
+// is done.
+func (w *restartWaiter) wait(ctx context.Context) error {
+	w.mu.Lock()
+	o := w.outage
+	w.mu.Unlock()
+	if o == nil {
+		return nil
+	}
+	// the outage does not count against the timeout of the request
+	defer pauseTimeout(ctx)()
+	select {
+	case <-o.done:
+		return o.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// down starts an outage unless one is going on already. The daemon is
+// pinged in the background until it is up again.
+func (w *restartWaiter) down(req *http.Request) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.outage != nil {
+		return
+	}
+	o := &outage{done: make(chan struct{})}
+	w.outage = o
+	w.emit(DaemonDown)
+	ping := *req.URL
+	ping.Path, ping.RawPath, ping.RawQuery = "/_ping", "", ""
+	go w.recover(o, ping.String())
+}
+
+// recover pings the daemon until it answers or MaxWait passed and ends the
+// outage.
+func (w *restartWaiter) recover(o *outage, endpoint string) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.maxWait)
+	defer cancel()
+	var err error
+	for n := 0; ; n++ {
+		if err = w.ping(ctx, endpoint); err == nil {
+			break
+		}
+		if sleepBackoff(ctx, w.backoff, n) != nil {
+			err = fmt.Errorf("%w: not back within %s: %v", ErrDaemonUnavailable, w.maxWait, err)
+			break
+		}
+	}
+
+	w.mu.Lock()
+	o.err = err
+	w.outage = nil
+	close(o.done)
+	if err == nil {
+		w.emit(DaemonUp)
+	}
+	w.mu.Unlock()
+}
+
+func (w *restartWaiter) ping(ctx context.Context, endpoint string) error {
+	ctx, cancel := context.WithTimeout(ctx, restartPingTimeout)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	res, err := w.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer drainClose(res.Body)
+	return checkResponse(res, http.StatusOK)
+}
+
+// emit has to be called with the lock held, so down and up are passed in
+// order.
+func (w *restartWaiter) emit(action string) {
+	if w.notify != nil {
+		w.notify(Event{Type: "daemon", Action: action, Time: time.Now()})
+	}
+}
+
+// daemonGone reports whether the request failed because the daemon went
+// away, e.g. while it restarts.
+func daemonGone(err error) bool {
+	return notReceived(err) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// notReceived reports whether the request failed before it reached the
+// daemon because the dial found its socket missing or the connection
+// refused. An open circuit breaker does not count, it does not tell whether
+// the daemon restarts.
+func notReceived(err error) bool {
+	var op *net.OpError
+	if !errors.As(err, &op) || op.Op != "dial" {
+		return false
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ENOENT)
+}
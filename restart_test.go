@@ -0,0 +1,148 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func Test_RestartRecovery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "docker.sock")
+	serve := func() *http.Server {
+		l, err := net.Listen("unix", sock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/_ping" {
+				w.Write([]byte("OK"))
+				return
+			}
+			w.Write([]byte(`{"Id": "c1"}`))
+		})}
+		go d.Serve(l)
+		return d
+	}
+
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	c := NewClient(sock)
+	// the outage lasts longer than the timeout of the requests
+	c.SetTimeouts(Timeouts{Fast: 50 * time.Millisecond, Medium: time.Second, Long: time.Second})
+	c.SetRestartRecovery(&RestartOptions{
+		MaxWait: 5 * time.Second,
+		Backoff: ConstantBackoff(20 * time.Millisecond),
+		Notify: func(e Event) {
+			mu.Lock()
+			events = append(events, e.Type+" "+e.Action)
+			mu.Unlock()
+		},
+	})
+	ctx := context.Background()
+
+	d := serve()
+	if _, err := c.inspectContainer(ctx, "c1"); err != nil {
+		t.Fatal(err)
+	}
+	// closing the listener removes the socket like a stopped daemon
+	d.Close()
+	restarted := make(chan *http.Server)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		restarted <- serve()
+	}()
+	if _, err := c.inspectContainer(ctx, "c1"); err != nil {
+		t.Fatalf("request during restart: %v", err)
+	}
+	d = <-restarted
+	defer d.Close()
+	if _, err := c.inspectContainer(ctx, "c1"); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"daemon down", "daemon up"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("got events %v, want %v", events, want)
+	}
+}
+
+func Test_RestartRecoveryGivesUp(t *testing.T) {
+	c := NewClient("/nonexistent/docker.sock")
+	c.SetRestartRecovery(&RestartOptions{MaxWait: 50 * time.Millisecond, Backoff: ConstantBackoff(10 * time.Millisecond)})
+	start := time.Now()
+	if _, err := c.Ping(context.Background()); !errors.Is(err, ErrDaemonUnavailable) {
+		t.Errorf("got %v, want ErrDaemonUnavailable", err)
+	}
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("gave up after %s, want to wait for the daemon", d)
+	}
+}
+
+func Test_RestartRecoveryResend(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		err     error
+		wantErr bool
+		// wantSent is the number of times the request is sent.
+		wantSent int
+	}{
+		{name: "refused", method: http.MethodPost, err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, wantSent: 2},
+		{name: "read after EOF", method: http.MethodGet, err: io.EOF, wantSent: 2},
+		{name: "change after EOF", method: http.MethodPost, err: io.EOF, wantErr: true, wantSent: 1},
+		{name: "other error", method: http.MethodGet, err: errors.New("boom"), wantErr: true, wantSent: 1},
+		{name: "breaker open", method: http.MethodGet, err: ErrDaemonUnavailable, wantErr: true, wantSent: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sent := 0
+			w := &restartWaiter{
+				maxWait: time.Second,
+				backoff: ConstantBackoff(time.Millisecond),
+				next: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+					ok := &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("OK")), Header: http.Header{}}
+					if r.URL.Path == "/_ping" {
+						return ok, nil
+					}
+					sent++
+					if sent == 1 {
+						return nil, tc.err
+					}
+					return ok, nil
+				}),
+			}
+			req, err := http.NewRequest(tc.method, "http://localhost/containers/c1/start", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := w.RoundTrip(req)
+			if err == nil {
+				res.Body.Close()
+			}
+			if (err != nil) != tc.wantErr {
+				t.Errorf("got error %v, want error: %v", err, tc.wantErr)
+			}
+			if sent != tc.wantSent {
+				t.Errorf("sent %d times, want %d", sent, tc.wantSent)
+			}
+		})
+	}
+}
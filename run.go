@@ -0,0 +1,205 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// WaitCondition defines what Run waits for after the container was started.
+type WaitCondition string
+
+const (
+	// WaitNone returns as soon as the container was started.
+	WaitNone WaitCondition = ""
+	// WaitHealthy waits until the healthcheck of the container passes. For
+	// containers without healthcheck it is sufficient that they run.
+	WaitHealthy WaitCondition = "healthy"
+	// WaitExited waits until the container exited.
+	WaitExited WaitCondition = "exited"
+)
+
+const defaultPollInterval = 500 * time.Millisecond
+
+// RunOptions configures Run.
+type RunOptions struct {
+	Wait WaitCondition
+	// PollInterval is the interval the state of the container is checked
+	// while waiting. It defaults to the policy of the client, see
+	// SetBackoff, or 500ms.
+	PollInterval time.Duration
+}
+
+// RunResult is returned by Run.
+type RunResult struct {
+	ID string
+	// Container is the handle of the container.
+	Container *Container
+	// Warnings are the warnings of the daemon about the creation of the
+	// container.
+	Warnings []string
+	// ExitCode is only set if Run waited for the exit of the container.
+	ExitCode int
+}
+
+// Run creates and starts a container and waits for the condition of the
+// options. Use the context to limit the time to wait. If anything fails, the
+// container is removed again.
+func (c *Client) Run(ctx context.Context, spec ContainerSpec, opts RunOptions) (*RunResult, error) {
+	poll := c.backoffOr(ConstantBackoff(defaultPollInterval))
+	if opts.PollInterval > 0 {
+		poll = ConstantBackoff(opts.PollInterval)
+	}
+	body, err := spec.createBody()
+	if err != nil {
+		return nil, fmt.Errorf("run %s: %w", spec.Image, err)
+	}
+	res, err := c.createContainer(ctx, spec.Name, body)
+	if err != nil {
+		return nil, fmt.Errorf("create container %s: %w", spec.Name, err)
+	}
+
+	result := &RunResult{ID: res.ID, Warnings: res.Warnings,
+		Container: &Container{ID: res.ID, Name: spec.Name, c: c}}
+	err = func() error {
+		for i, a := range spec.Networks {
+			if i == 0 {
+				continue
+			}
+			if err := c.connectNetwork(ctx, a.Network, res.ID, a.endpointConfig()); err != nil {
+				return fmt.Errorf("connect container to %s: %w", a.Network, err)
+			}
+		}
+		if err := c.startContainer(ctx, res.ID); err != nil {
+			return fmt.Errorf("start container: %w", err)
+		}
+		if opts.Wait == WaitNone {
+			return nil
+		}
+		cj, err := c.waitContainer(ctx, res.ID, opts.Wait, poll)
+		if err != nil {
+			return err
+		}
+		result.ExitCode = cj.State.ExitCode
+		return nil
+	}()
+	if err != nil {
+		// the context may be done already, the cleanup must not depend on it
+		c.removeContainer(context.Background(), res.ID, true)
+		return nil, fmt.Errorf("run %s: %w", spec.Image, err)
+	}
+	return result, nil
+}
+
+// waitContainer polls the state of the container until the condition is
+// reached. The waits between the polls follow the backoff.
+func (c *Client) waitContainer(ctx context.Context, id string, cond WaitCondition, backoff Backoff) (*containerJSON, error) {
+	for n := 0; ; n++ {
+		cj, err := c.inspectContainer(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		exited := !cj.State.Running && !cj.State.Restarting &&
+			(cj.State.Status == "exited" || cj.State.Status == "dead")
+
+		switch cond {
+		case WaitExited:
+			if exited {
+				return cj, nil
+			}
+		case WaitHealthy:
+			if exited {
+				return nil, fmt.Errorf("container exited with code %d before it was healthy", cj.State.ExitCode)
+			}
+			if cj.State.Running && (cj.State.Health == nil || cj.State.Health.Status == "healthy") {
+				return cj, nil
+			}
+		default:
+			return nil, fmt.Errorf("unknown wait condition %q", cond)
+		}
+
+		if err := sleepBackoff(ctx, backoff, n); err != nil {
+			return nil, fmt.Errorf("wait for container to be %s: %w", cond, err)
+		}
+	}
+}
+
+// WaitRemoved blocks until the daemon does not know the container anymore,
+// e.g. because it was started with AutoRemove or its removal is in progress.
+// This avoids conflicts when a container of the same name is created next.
+// A container which is gone already returns immediately. A timeout of zero
+// waits as long as the context. The removal is confirmed by inspecting the
+// container, as daemons with API < 1.30 ignore the removed condition of the
+// wait endpoint and return once the container stopped. Daemons which do not
+// know the condition are polled.
+func (c *Client) WaitRemoved(ctx context.Context, id string, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	q := url.Values{"condition": {"removed"}}
+	r, err := c.sendTimeout(ctx, 0, http.MethodPost, "containers/"+pathName(id)+"/wait", q, nil, nil)
+	if err != nil {
+		return fmt.Errorf("wait for removal of %s: %w", id, err)
+	}
+	defer drainClose(r.Body)
+	err = checkResponse(r, http.StatusOK)
+	var e *APIError
+	switch {
+	case isNotFound(err):
+		return nil
+	case err != nil && (!errors.As(err, &e) || e.StatusCode != http.StatusBadRequest):
+		return fmt.Errorf("wait for removal of %s: %w", id, err)
+	}
+
+	// the container is removed, stopped if the daemon ignored the condition
+	// or the condition is unknown to the daemon
+
+	backoff := c.backoffOr(ConstantBackoff(defaultPollInterval))
+	for n := 0; ; n++ {
+		_, err := c.inspectContainer(ctx, id)
+		if isNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("wait for removal of %s: %w", id, err)
+		}
+		if err := sleepBackoff(ctx, backoff, n); err != nil {
+			return fmt.Errorf("wait for removal of %s: %w", id, err)
+		}
+	}
+}
+
+// CaptureResult is returned by RunAndCapture.
+type CaptureResult struct {
+	ExitCode int
+	Stdout   []byte
+	Stderr   []byte
+}
+
+// RunAndCapture runs a container to completion and returns its exit code and
+// output. The container is removed afterwards. A non zero exit code is not
+// treated as error.
+func (c *Client) RunAndCapture(ctx context.Context, spec ContainerSpec) (*CaptureResult, error) {
+	res, err := c.Run(ctx, spec, RunOptions{Wait: WaitExited})
+	if err != nil {
+		return nil, err
+	}
+	defer c.removeContainer(context.Background(), res.ID, true)
+
+	var stdout, stderr bytes.Buffer
+	if err := c.Logs(ctx, res.ID, &stdout, &stderr); err != nil {
+		return nil, fmt.Errorf("run %s: %w", spec.Image, err)
+	}
+	return &CaptureResult{
+		ExitCode: res.ExitCode,
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+	}, nil
+}
@@ -0,0 +1,162 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_Run(t *testing.T) {
+	tt := []struct {
+		name     string
+		routes   map[string]mockResponse
+		opts     RunOptions
+		expect   []string
+		exitCode int
+		wantErr  bool
+	}{
+		{
+			name: "wait exited",
+			routes: map[string]mockResponse{
+				"POST /containers/create":   {StatusCode: http.StatusCreated, Body: `{"Id": "c1"}`},
+				"POST /containers/c1/start": {StatusCode: http.StatusNoContent},
+				"GET /containers/c1/json":   {Body: `{"Id": "c1", "State": {"Status": "exited", "ExitCode": 3}}`},
+			},
+			opts:     RunOptions{Wait: WaitExited},
+			expect:   []string{"POST /containers/create", "POST /containers/c1/start", "GET /containers/c1/json"},
+			exitCode: 3,
+		},
+		{
+			name: "cleanup on failure",
+			routes: map[string]mockResponse{
+				"POST /containers/create":   {StatusCode: http.StatusCreated, Body: `{"Id": "c1"}`},
+				"POST /containers/c1/start": {StatusCode: http.StatusNoContent},
+				"GET /containers/c1/json":   {Body: `{"Id": "c1", "State": {"Status": "exited", "ExitCode": 1}}`},
+				"DELETE /containers/c1":     {StatusCode: http.StatusNoContent},
+			},
+			opts: RunOptions{Wait: WaitHealthy},
+			expect: []string{
+				"POST /containers/create",
+				"POST /containers/c1/start",
+				"GET /containers/c1/json",
+				"DELETE /containers/c1",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			srv.route(tc.routes)
+			defer srv.route(nil)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			res, err := client.Run(ctx, ContainerSpec{Image: "busybox"}, tc.opts)
+			if err != nil && !tc.wantErr {
+				t.Fatal(err)
+			}
+			if err == nil && tc.wantErr {
+				t.Error("expected error")
+			}
+			if err == nil && res.ExitCode != tc.exitCode {
+				t.Errorf("got: %d, want: %d", res.ExitCode, tc.exitCode)
+			}
+			if got := srv.Requests(); !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("got: %v, want: %v", got, tc.expect)
+			}
+		})
+	}
+}
+
+func Test_RunAndCapture(t *testing.T) {
+	logs := string([]byte{1, 0, 0, 0, 0, 0, 0, 6}) + "hello\n" +
+		string([]byte{2, 0, 0, 0, 0, 0, 0, 5}) + "oops\n"
+	srv.route(map[string]mockResponse{
+		"POST /containers/create":   {StatusCode: http.StatusCreated, Body: `{"Id": "c1"}`},
+		"POST /containers/c1/start": {StatusCode: http.StatusNoContent},
+		"GET /containers/c1/json":   {Body: `{"Id": "c1", "State": {"Status": "exited", "ExitCode": 2}}`},
+		"GET /containers/c1/logs":   {Body: logs},
+		"DELETE /containers/c1":     {StatusCode: http.StatusNoContent},
+	})
+	defer srv.route(nil)
+
+	res, err := client.RunAndCapture(context.Background(), ContainerSpec{Image: "busybox"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := &CaptureResult{ExitCode: 2, Stdout: []byte("hello\n"), Stderr: []byte("oops\n")}
+	if !reflect.DeepEqual(res, expect) {
+		t.Errorf("got: %+v, want: %+v", res, expect)
+	}
+}
+
+func Test_WaitRemoved(t *testing.T) {
+	tt := []struct {
+		name    string
+		routes  map[string]mockResponse
+		expect  []string
+		wantErr bool
+	}{
+		{
+			name: "removed",
+			routes: map[string]mockResponse{
+				"POST /containers/c1/wait?condition=removed": {Body: `{"StatusCode": 0}`},
+			},
+			expect: []string{"POST /containers/c1/wait", "GET /containers/c1/json"},
+		},
+		{
+			name: "condition ignored",
+			routes: map[string]mockResponse{
+				"POST /containers/c1/wait?condition=removed": {Body: `{"StatusCode": 0}`},
+				"GET /containers/c1/json": {Body: `{"Id": "c1", "Name": "/plc", "State": {"Status": "exited"}}`,
+					Next: &mockResponse{StatusCode: http.StatusNotFound, Body: `{"message": "No such container: c1"}`}},
+			},
+			expect: []string{"POST /containers/c1/wait", "GET /containers/c1/json", "GET /containers/c1/json"},
+		},
+		{
+			name:   "gone already",
+			routes: map[string]mockResponse{},
+			expect: []string{"POST /containers/c1/wait"},
+		},
+		{
+			name: "old daemon",
+			routes: map[string]mockResponse{
+				"POST /containers/c1/wait": {StatusCode: http.StatusBadRequest, Body: `{"message": "invalid condition"}`},
+				"GET /containers/c1/json": {Body: `{"Id": "c1", "Name": "/plc", "State": {"Status": "removing"}}`,
+					Next: &mockResponse{StatusCode: http.StatusNotFound, Body: `{"message": "No such container: c1"}`}},
+			},
+			expect: []string{"POST /containers/c1/wait", "GET /containers/c1/json", "GET /containers/c1/json"},
+		},
+		{
+			name: "timeout",
+			routes: map[string]mockResponse{
+				"POST /containers/c1/wait": {Delay: 200 * time.Millisecond, Body: `{"StatusCode": 0}`},
+			},
+			expect:  []string{"POST /containers/c1/wait"},
+			wantErr: true,
+		},
+	}
+
+	c := NewClient(sockPath)
+	c.SetBackoff(ConstantBackoff(time.Millisecond))
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			srv.route(tc.routes)
+			defer srv.route(nil)
+
+			err := c.WaitRemoved(context.Background(), "c1", 50*time.Millisecond)
+			if err != nil && !tc.wantErr {
+				t.Error(err)
+			}
+			if err == nil && tc.wantErr {
+				t.Error("expected error")
+			}
+			if got := srv.Requests(); !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("got: %v, want: %v", got, tc.expect)
+			}
+		})
+	}
+}
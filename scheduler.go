@@ -0,0 +1,185 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Placement describes the needs of a container placed by a Scheduler.
+type Placement struct {
+	// Key identifies the simulated device. Placements with the same key
+	// stay on the same host as long as it is healthy. An empty key is
+	// never sticky.
+	Key string
+	// Memory in bytes and NanoCPUs (1e9 per CPU) are reserved on the host.
+	Memory   int64
+	NanoCPUs int64
+	// Constraints must match the engine labels of the host.
+	Constraints map[string]string
+}
+
+// Scheduler places containers on the healthy hosts of a Manager. The free
+// resources of a host are its memory and CPUs as reported by /info minus
+// the reservations of the placements made by the scheduler, containers
+// created otherwise are not taken into account. Among the hosts which
+// match the constraints and have enough resources left, the one with the
+// most free memory is chosen.
+type Scheduler struct {
+	m *Manager
+
+	mu         sync.Mutex
+	placements map[string]placed
+	reserved   map[string]resources
+}
+
+type placed struct {
+	host string
+	resources
+}
+
+type resources struct {
+	memory, nanoCPUs int64
+}
+
+// NewScheduler returns a scheduler for the hosts of the manager.
+func NewScheduler(m *Manager) *Scheduler {
+	return &Scheduler{
+		m:          m,
+		placements: make(map[string]placed),
+		reserved:   make(map[string]resources),
+	}
+}
+
+// Place returns the host for the placement and reserves its resources.
+// A placement whose key was placed already returns the same host again
+// without reserving twice, unless the host became unhealthy.
+func (s *Scheduler) Place(ctx context.Context, p Placement) (string, error) {
+	healthy := make(map[string]bool)
+	for _, name := range s.m.Healthy() {
+		healthy[name] = true
+	}
+
+	s.mu.Lock()
+	if prev, ok := s.placements[p.Key]; ok && p.Key != "" {
+		if healthy[prev.host] {
+			s.mu.Unlock()
+			return prev.host, nil
+		}
+		s.release(p.Key)
+	}
+	s.mu.Unlock()
+
+	free := make(map[string]resources)
+	for _, name := range sortedKeys(healthy) {
+		var info *Info
+		err := s.m.Do(ctx, name, func(ctx context.Context, c *Client) error {
+			var err error
+			info, err = c.Info(ctx)
+			return err
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", fmt.Errorf("place %s: %w", p.Key, ctx.Err())
+			}
+			continue
+		}
+		if !matchLabels(info.Labels, p.Constraints) {
+			continue
+		}
+		free[name] = resources{memory: info.MemTotal, nanoCPUs: int64(info.NCPU) * 1e9}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// the key may have been placed concurrently while the hosts were asked
+	if prev, ok := s.placements[p.Key]; ok && p.Key != "" {
+		if healthy[prev.host] {
+			return prev.host, nil
+		}
+		s.release(p.Key)
+	}
+	host := ""
+	var best resources
+	for _, name := range sortedKeys(free) {
+		r := free[name]
+		r.memory -= s.reserved[name].memory
+		r.nanoCPUs -= s.reserved[name].nanoCPUs
+		if r.memory < p.Memory || r.nanoCPUs < p.NanoCPUs {
+			continue
+		}
+		if host == "" || r.memory > best.memory {
+			host, best = name, r
+		}
+	}
+	if host == "" {
+		return "", fmt.Errorf("place %s: no healthy host with enough resources matches the constraints", p.Key)
+	}
+
+	r := s.reserved[host]
+	r.memory += p.Memory
+	r.nanoCPUs += p.NanoCPUs
+	s.reserved[host] = r
+	if p.Key != "" {
+		s.placements[p.Key] = placed{host: host, resources: resources{memory: p.Memory, nanoCPUs: p.NanoCPUs}}
+	}
+	return host, nil
+}
+
+// Release frees the resources reserved for the key and forgets its host.
+func (s *Scheduler) Release(key string) {
+	s.mu.Lock()
+	s.release(key)
+	s.mu.Unlock()
+}
+
+// release has to be called with the lock held.
+func (s *Scheduler) release(key string) {
+	p, ok := s.placements[key]
+	if !ok {
+		return
+	}
+	r := s.reserved[p.host]
+	r.memory -= p.memory
+	r.nanoCPUs -= p.nanoCPUs
+	s.reserved[p.host] = r
+	delete(s.placements, key)
+}
+
+// Run places the container and runs it on the chosen host. The host is
+// returned with the result. The reservation is kept even if the container
+// fails, call Release once the device is removed.
+func (s *Scheduler) Run(ctx context.Context, p Placement, spec ContainerSpec, opts RunOptions) (string, *RunResult, error) {
+	host, err := s.Place(ctx, p)
+	if err != nil {
+		return "", nil, err
+	}
+	var res *RunResult
+	err = s.m.Do(ctx, host, func(ctx context.Context, c *Client) error {
+		var err error
+		res, err = c.Run(ctx, spec, opts)
+		return err
+	})
+	if err != nil {
+		return host, nil, err
+	}
+	return host, res, nil
+}
+
+// matchLabels reports whether the key=value labels contain all constraints.
+func matchLabels(labels []string, constraints map[string]string) bool {
+	have := make(map[string]string, len(labels))
+	for _, l := range labels {
+		ss := strings.SplitN(l, "=", 2)
+		if len(ss) == 2 {
+			have[ss[0]] = ss[1]
+		}
+	}
+	for k, v := range constraints {
+		if got, ok := have[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
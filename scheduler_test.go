@@ -0,0 +1,87 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_Scheduler(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /info": {Body: `{"MemTotal": 8589934592, "NCPU": 4, "Labels": ["site=a"]}`},
+	})
+	defer srv.route(nil)
+	lab2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"MemTotal": 4294967296, "NCPU": 2, "Labels": ["site=b"]}`))
+	}))
+	defer lab2.Close()
+
+	m := NewManager()
+	if err := m.AddClient("lab-1", client); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add("lab-2", "tcp://"+strings.TrimPrefix(lab2.URL, "http://")); err != nil {
+		t.Fatal(err)
+	}
+	s := NewScheduler(m)
+	ctx := context.Background()
+
+	tests := []struct {
+		p    Placement
+		host string
+	}{
+		{p: Placement{Key: "d1", Memory: 6 << 30}, host: "lab-1"},
+		// sticky, nothing reserved twice
+		{p: Placement{Key: "d1", Memory: 6 << 30}, host: "lab-1"},
+		{p: Placement{Key: "d2", Memory: 4 << 30}, host: "lab-2"},
+		{p: Placement{Key: "d3", Memory: 1 << 30, Constraints: map[string]string{"site": "a"}}, host: "lab-1"},
+		{p: Placement{Key: "d4", NanoCPUs: 5e9}},
+		{p: Placement{Key: "d5", Memory: 1 << 30, Constraints: map[string]string{"site": "c"}}},
+	}
+	for _, tt := range tests {
+		host, err := s.Place(ctx, tt.p)
+		if host != tt.host || (err != nil) != (tt.host == "") {
+			t.Errorf("%s: want host %q, got %q, %v", tt.p.Key, tt.host, host, err)
+		}
+	}
+
+	s.Release("d1")
+	if host, err := s.Place(ctx, Placement{Key: "d6", Memory: 7 << 30}); err != nil || host != "lab-1" {
+		t.Errorf("unexpected placement %q, %v", host, err)
+	}
+}
+
+func Test_SchedulerConcurrentPlace(t *testing.T) {
+	lab := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/info" {
+			// keep the placements busy asking the host at the same time
+			time.Sleep(20 * time.Millisecond)
+		}
+		w.Write([]byte(`{"MemTotal": 8589934592, "NCPU": 4}`))
+	}))
+	defer lab.Close()
+	m := NewManager()
+	if err := m.Add("lab", "tcp://"+strings.TrimPrefix(lab.URL, "http://")); err != nil {
+		t.Fatal(err)
+	}
+	s := NewScheduler(m)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Place(context.Background(), Placement{Key: "d1", Memory: 1 << 30}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := s.reserved["lab"].memory; got != 1<<30 {
+		t.Errorf("reserved %d bytes, want the placement reserved once", got)
+	}
+}
@@ -0,0 +1,64 @@
+package docker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// BuildSecret is a secret which RUN instructions of a build mount by
+// --mount=type=secret,id=<ID>. It is read from the file Src or, if Src is
+// empty, from the environment variable Env, which defaults to ID. The
+// secret is only sent to the daemon when the build asks for it and is not
+// stored in the layers of the image.
+type BuildSecret struct {
+	ID  string
+	Src string
+	Env string
+}
+
+func (s BuildSecret) read() ([]byte, error) {
+	if s.Src != "" {
+		return ioutil.ReadFile(s.Src)
+	}
+	env := s.Env
+	if env == "" {
+		env = s.ID
+	}
+	v, ok := os.LookupEnv(env)
+	if !ok {
+		return nil, fmt.Errorf("secret %s: environment variable %s is not set", s.ID, env)
+	}
+	return []byte(v), nil
+}
+
+// addSecrets serves the secrets to BuildKit.
+func (s *buildSession) addSecrets(secrets []BuildSecret) error {
+	byID := make(map[string]BuildSecret, len(secrets))
+	for _, secret := range secrets {
+		if secret.ID == "" {
+			return fmt.Errorf("secret: missing id")
+		}
+		if _, ok := byID[secret.ID]; ok {
+			return fmt.Errorf("secret %s: duplicate id", secret.ID)
+		}
+		byID[secret.ID] = secret
+	}
+	// GetSecretRequest{ID = 1}, GetSecretResponse{Data = 1}
+	s.unary("/moby.buildkit.secrets.v1.Secrets/GetSecret", func(req []byte) ([]byte, error) {
+		id, err := protoString(req, 1)
+		if err != nil {
+			return nil, err
+		}
+		secret, ok := byID[id]
+		if !ok {
+			return nil, &grpcError{code: grpcNotFound, message: "secret " + id + " not found"}
+		}
+		data, err := secret.read()
+		if err != nil {
+			return nil, err
+		}
+		return protoBytes(nil, 1, data), nil
+	})
+	return nil
+}
@@ -0,0 +1,62 @@
+//go:build go1.24
+// +build go1.24
+
+package docker
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_BuildSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(src, []byte("s3cr3t"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id         string
+		wantStatus string
+		wantData   string
+	}{
+		{"token", "0", "s3cr3t"},
+		{"unknown", "5", ""},
+	}
+	for _, tt := range tests {
+		var status, data string
+		buildWithSession(t, BuildOptions{Secrets: []BuildSecret{{ID: "token", Src: src}}}, func(rt http.RoundTripper) {
+			body := bytes.NewReader(grpcFrame(protoBytes(nil, 1, []byte(tt.id))))
+			req, _ := http.NewRequest(http.MethodPost, "http://session/moby.buildkit.secrets.v1.Secrets/GetSecret", body)
+			req.Header.Set("Content-Type", "application/grpc")
+			r, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer r.Body.Close()
+			if b, _ := ioutil.ReadAll(r.Body); len(b) > 0 {
+				msg, err := readGRPCMessage(bytes.NewReader(b))
+				if err != nil {
+					t.Error(err)
+				}
+				data, _ = protoString(msg, 1)
+			}
+			status = r.Trailer.Get("Grpc-Status")
+		})
+		if status != tt.wantStatus {
+			t.Errorf("%s: want status %s, got %s", tt.id, tt.wantStatus, status)
+		}
+		if data != tt.wantData {
+			t.Errorf("%s: want secret %q, got %q", tt.id, tt.wantData, data)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package docker
+
+// Selector selects resources by their labels. A resource matches if it
+// carries all labels of the selector. An empty value matches every value of
+// the label.
+// e.g.: Selector{LabelRun: "sim-1", "com.example.role": ""}
+type Selector map[string]string
+
+// RunSelector selects all resources of the given run.
+func RunSelector(run string) Selector {
+	return Selector{LabelRun: run}
+}
+
+// filters converts the selector into the label filters of the engine API.
+func (s Selector) filters() map[string][]string {
+	if len(s) == 0 {
+		return nil
+	}
+	labels := make([]string, 0, len(s))
+	for k, v := range s {
+		if v == "" {
+			labels = append(labels, k)
+			continue
+		}
+		labels = append(labels, k+"="+v)
+	}
+	return map[string][]string{"label": labels}
+}
+
+// Matches reports whether the given labels are selected.
+func (s Selector) Matches(labels map[string]string) bool {
+	for k, v := range s {
+		got, ok := labels[k]
+		if !ok || (v != "" && got != v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,154 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServiceLogOptions configures ServiceLogs.
+type ServiceLogOptions struct {
+	// Follow keeps the stream open for new output until the context is
+	// done. Otherwise the long timeout of the client applies.
+	Follow bool
+	// Tail limits the output to the last lines, e.g. "100". Defaults to all.
+	Tail string
+	// Since only returns output written after the time.
+	Since time.Time
+	// PrefixTask prefixes every line by the task which wrote it as
+	// <service>.<slot>.<task id>, like docker service logs does.
+	PrefixTask bool
+}
+
+// ServiceLogs copies the output of all tasks of the service to stdout and
+// stderr. Either writer may be nil to discard the stream.
+// docs.: https://docs.docker.com/engine/api/v1.41/#operation/ServiceLogs
+func (c *Client) ServiceLogs(ctx context.Context, id string, opts ServiceLogOptions, stdout, stderr io.Writer) error {
+	q := url.Values{"stdout": {"1"}, "stderr": {"1"}}
+	if opts.Follow {
+		q.Set("follow", "1")
+	}
+	if opts.Tail != "" {
+		q.Set("tail", opts.Tail)
+	}
+	if !opts.Since.IsZero() {
+		q.Set("since", strconv.FormatInt(opts.Since.Unix(), 10))
+	}
+
+	var svc swarmService
+	if opts.PrefixTask {
+		// the details carry the task of every line
+		q.Set("details", "1")
+		if err := c.doJSON(ctx, http.MethodGet, "services/"+pathName(id), nil, nil, &svc, http.StatusOK); err != nil {
+			return fmt.Errorf("inspect service %s: %w", id, err)
+		}
+	}
+
+	timeout := c.timeout(TimeoutLong)
+	if opts.Follow {
+		timeout = 0
+	}
+	r, err := c.sendTimeout(ctx, timeout, http.MethodGet, "services/"+pathName(id)+"/logs", q, nil, nil)
+	if err != nil {
+		return fmt.Errorf("read logs of service %s: %w", id, err)
+	}
+	defer r.Body.Close()
+	if err := checkResponse(r, http.StatusOK); err != nil {
+		return fmt.Errorf("read logs of service %s: %w", id, err)
+	}
+
+	if opts.PrefixTask {
+		p := &taskPrefixer{c: c, ctx: ctx, service: svc.Spec.Name, names: make(map[string]string)}
+		if stdout != nil {
+			stdout = &lineWriter{w: stdout, fn: p.prefix}
+		}
+		if stderr != nil {
+			stderr = &lineWriter{w: stderr, fn: p.prefix}
+		}
+	}
+	if err := demuxStream(r.Body, stdout, stderr); err != nil {
+		return fmt.Errorf("read logs of service %s: %w", id, err)
+	}
+	return nil
+}
+
+// taskPrefixer replaces the details of a log line by the name of the task.
+type taskPrefixer struct {
+	c       *Client
+	ctx     context.Context
+	service string
+
+	mu    sync.Mutex
+	names map[string]string
+}
+
+// prefix rewrites a line like
+// "com.docker.swarm.node.id=n1,com.docker.swarm.task.id=t1 message".
+func (p *taskPrefixer) prefix(line []byte) []byte {
+	i := bytes.IndexByte(line, ' ')
+	if i < 0 {
+		return line
+	}
+	var task string
+	for _, kv := range strings.Split(string(line[:i]), ",") {
+		if v := strings.TrimPrefix(kv, "com.docker.swarm.task.id="); v != kv {
+			task = v
+		}
+	}
+	if task == "" {
+		return line
+	}
+	return append([]byte(p.name(task)+" | "), line[i+1:]...)
+}
+
+// name returns <service>.<slot>.<short task id> of the task.
+func (p *taskPrefixer) name(task string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if name, ok := p.names[task]; ok {
+		return name
+	}
+	short := task
+	if len(short) > 12 {
+		short = short[:12]
+	}
+	name := p.service + "." + short
+	var t struct {
+		Slot int `json:"Slot"`
+	}
+	if err := p.c.doJSON(p.ctx, http.MethodGet, "tasks/"+pathName(task), nil, nil, &t, http.StatusOK); err == nil && t.Slot > 0 {
+		name = fmt.Sprintf("%s.%d.%s", p.service, t.Slot, short)
+	}
+	p.names[task] = name
+	return name
+}
+
+// lineWriter calls fn for every complete line written and writes the result
+// to w. An incomplete last line is kept until it is completed.
+type lineWriter struct {
+	w   io.Writer
+	fn  func(line []byte) []byte
+	buf []byte
+}
+
+func (l *lineWriter) Write(b []byte) (int, error) {
+	l.buf = append(l.buf, b...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		line := l.fn(l.buf[:i:i])
+		l.buf = l.buf[i+1:]
+		if _, err := l.w.Write(append(line, '\n')); err != nil {
+			return len(b), err
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+)
+
+// frame returns a frame of a multiplexed stream.
+func frame(stream byte, payload string) string {
+	hdr := make([]byte, 8)
+	hdr[0] = stream
+	binary.BigEndian.PutUint32(hdr[4:], uint32(len(payload)))
+	return string(hdr) + payload
+}
+
+func Test_ServiceLogs(t *testing.T) {
+	details := "com.docker.swarm.node.id=n1,com.docker.swarm.service.id=s1,com.docker.swarm.task.id=t1234567890abcdef "
+	srv.route(map[string]mockResponse{
+		"GET /services/s1":             {Body: `{"ID": "s1", "Spec": {"Name": "plc"}}`},
+		"GET /tasks/t1234567890abcdef": {Body: `{"ID": "t1234567890abcdef", "Slot": 3}`},
+		"GET /services/s1/logs":        {Body: frame(1, details+"started\n") + frame(2, details+"warn") + frame(2, "ing\n")},
+	})
+	defer srv.route(nil)
+
+	var stdout, stderr bytes.Buffer
+	err := client.ServiceLogs(context.Background(), "s1", ServiceLogOptions{PrefixTask: true}, &stdout, &stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stdout.String(); got != "plc.3.t1234567890a | started\n" {
+		t.Errorf("unexpected stdout %q", got)
+	}
+	if got := stderr.String(); got != "plc.3.t1234567890a | warning\n" {
+		t.Errorf("unexpected stderr %q", got)
+	}
+}
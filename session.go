@@ -0,0 +1,116 @@
+package docker
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// Headers of the request which attaches a session to the daemon.
+const (
+	headerSessionID        = "X-Docker-Expose-Session-Uuid"
+	headerSessionName      = "X-Docker-Expose-Session-Name"
+	headerSessionSharedKey = "X-Docker-Expose-Session-Sharedkey"
+	headerSessionMethod    = "X-Docker-Expose-Session-Grpc-Method"
+)
+
+// Status codes of gRPC used by the session.
+const (
+	grpcOK            = 0
+	grpcNotFound      = 5
+	grpcUnimplemented = 12
+	grpcInternal      = 13
+)
+
+// grpcError is the status a unary handler fails with.
+type grpcError struct {
+	code    int
+	message string
+}
+
+func (e *grpcError) Error() string {
+	return fmt.Sprintf("grpc status %d: %s", e.code, e.message)
+}
+
+// buildSession is a BuildKit session. BuildKit calls back into the client
+// through it during a build, e.g. to fetch secrets. The daemon is the gRPC
+// client, the session serves the calls over HTTP/2 on the hijacked
+// connection of the session request.
+type buildSession struct {
+	id string
+	// sharedKey identifies state BuildKit keeps across sessions, e.g. the
+	// transferred context. It defaults to the ID.
+	sharedKey string
+	methods   map[string]http.HandlerFunc
+}
+
+func newBuildSession() *buildSession {
+	s := &buildSession{id: randomSuffix() + randomSuffix() + randomSuffix(), methods: make(map[string]http.HandlerFunc)}
+	// BuildKit closes sessions whose health check fails
+	s.unary("/grpc.health.v1.Health/Check", func([]byte) ([]byte, error) {
+		// status SERVING
+		return protoVarint(nil, 1, 1), nil
+	})
+	return s
+}
+
+// unary registers a handler of a unary gRPC method. fn gets the request
+// message and returns the response message.
+func (s *buildSession) unary(method string, fn func(req []byte) ([]byte, error)) {
+	s.methods[method] = func(w http.ResponseWriter, r *http.Request) {
+		req, err := readGRPCMessage(r.Body)
+		if err != nil {
+			writeGRPCStatus(w, grpcInternal, err.Error())
+			return
+		}
+		res, err := fn(req)
+		var gerr *grpcError
+		switch {
+		case errors.As(err, &gerr):
+			writeGRPCStatus(w, gerr.code, gerr.message)
+			return
+		case err != nil:
+			writeGRPCStatus(w, grpcInternal, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		w.WriteHeader(http.StatusOK)
+		w.Write(grpcFrame(res))
+		writeGRPCStatus(w, grpcOK, "")
+	}
+}
+
+// writeGRPCStatus finishes a gRPC call with the status in the trailers. If
+// no header was written yet, the response is trailers-only.
+func writeGRPCStatus(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+	if message != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", message)
+	}
+}
+
+// readGRPCMessage reads a single length-prefixed message. Compressed
+// messages are not supported, the session does not announce compression.
+func readGRPCMessage(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	if prefix[0] != 0 {
+		return nil, fmt.Errorf("compressed gRPC messages are not supported")
+	}
+	n := binary.BigEndian.Uint32(prefix[1:])
+	return ioutil.ReadAll(io.LimitReader(r, int64(n)))
+}
+
+// grpcFrame prefixes the message with its length.
+func grpcFrame(msg []byte) []byte {
+	b := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(b[1:], uint32(len(msg)))
+	return append(b, msg...)
+}
@@ -0,0 +1,85 @@
+//go:build go1.24
+// +build go1.24
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// runSession attaches the session to the daemon and serves the calls of BuildKit
+// until stop is called.
+func (c *Client) runSession(ctx context.Context, s *buildSession) (stop func(), err error) {
+	header := http.Header{
+		headerSessionID:        {s.id},
+		headerSessionName:      {"docker"},
+		headerSessionSharedKey: {s.id},
+	}
+	if s.sharedKey != "" {
+		header.Set(headerSessionSharedKey, s.sharedKey)
+	}
+	for m := range s.methods {
+		header.Add(headerSessionMethod, m)
+	}
+	conn, err := c.hijack(ctx, http.MethodPost, "session", nil, nil, header, "h2c")
+	if err != nil {
+		return nil, fmt.Errorf("start session: %w", err)
+	}
+
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	srv := &http.Server{
+		Protocols: &protocols,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h, ok := s.methods[r.URL.Path]
+			if !ok {
+				writeGRPCStatus(w, grpcUnimplemented, "unimplemented: "+r.URL.Path)
+				return
+			}
+			h(w, r)
+		}),
+	}
+	go srv.Serve(newConnListener(conn))
+	return func() {
+		srv.Close()
+		conn.Close()
+	}, nil
+}
+
+type sessionAddr struct{}
+
+func (sessionAddr) Network() string { return "session" }
+func (sessionAddr) String() string  { return "session" }
+
+// connListener is a net.Listener which accepts a single connection.
+type connListener struct {
+	conns chan net.Conn
+	once  sync.Once
+	done  chan struct{}
+}
+
+func newConnListener(conn net.Conn) *connListener {
+	l := &connListener{conns: make(chan net.Conn, 1), done: make(chan struct{})}
+	l.conns <- conn
+	return l
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *connListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr { return sessionAddr{} }
@@ -0,0 +1,15 @@
+//go:build !go1.24
+// +build !go1.24
+
+package docker
+
+import (
+	"context"
+	"fmt"
+)
+
+// runSession fails, serving HTTP/2 on the hijacked connection of a session
+// requires Go 1.24.
+func (c *Client) runSession(ctx context.Context, s *buildSession) (stop func(), err error) {
+	return nil, fmt.Errorf("start session: BuildKit sessions require Go 1.24")
+}
@@ -0,0 +1,76 @@
+//go:build go1.24
+// +build go1.24
+
+package docker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// sessionDaemon mocks the daemon side of a BuildKit session: it accepts the
+// session and makes its calls to the client before it answers the build.
+type sessionDaemon struct {
+	t    *testing.T
+	call func(rt http.RoundTripper)
+	done chan struct{}
+}
+
+func (d *sessionDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/session":
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			d.t.Error(err)
+			return
+		}
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: h2c\r\n\r\n")
+		buf.Flush()
+		go d.serve(conn)
+	case "/build":
+		if r.URL.Query().Get("version") != "2" || r.URL.Query().Get("session") == "" {
+			d.t.Errorf("unexpected build query %s", r.URL.RawQuery)
+		}
+		<-d.done
+		w.Write([]byte(`{"aux": "Cg=="}` + "\n" + `{"aux": {"ID": "sha256:abc"}}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (d *sessionDaemon) serve(conn net.Conn) {
+	defer close(d.done)
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	tr := &http.Transport{
+		Protocols: &protocols,
+		DialContext: func(context.Context, string, string) (net.Conn, error) {
+			return conn, nil
+		},
+	}
+	defer tr.CloseIdleConnections()
+	d.call(tr)
+}
+
+// buildWithSession builds with the options against a sessionDaemon which
+// makes the call.
+func buildWithSession(t *testing.T, opts BuildOptions, call func(rt http.RoundTripper)) {
+	d := &sessionDaemon{t: t, call: call, done: make(chan struct{})}
+	daemon := httptest.NewServer(d)
+	defer daemon.Close()
+	c, err := NewClientHost("tcp://" + strings.TrimPrefix(daemon.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := c.BuildImage(context.Background(), strings.NewReader(""), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "sha256:abc" {
+		t.Errorf("unexpected image ID %s", id)
+	}
+}
@@ -0,0 +1,108 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// shutdownKillWait is the time ShutdownAll waits for a container to exit
+// after it was killed.
+var shutdownKillWait = 5 * time.Second
+
+// ShutdownAll stops all running containers matching the selector in
+// parallel. Every container receives its stop signal, SIGTERM unless the
+// image or container defines another one. Containers which are still running
+// after the grace period are killed. The names of the containers which kept
+// running even after they were killed are returned. Failing requests are
+// returned as BatchError keyed by the container name.
+func (c *Client) ShutdownAll(ctx context.Context, selector Selector, grace time.Duration) ([]string, error) {
+	containers, err := c.listContainers(ctx, false, selector.filters())
+	if err != nil {
+		return nil, fmt.Errorf("list containers: %w", err)
+	}
+	names := make([]string, len(containers))
+	for i, cs := range containers {
+		names[i] = cs.Name()
+	}
+
+	var (
+		mu      sync.Mutex
+		refused []string
+	)
+	err = forEach(ctx, names, len(names), func(ctx context.Context, name string) error {
+		stopped, err := c.shutdown(ctx, name, grace)
+		if err != nil || stopped {
+			return err
+		}
+		mu.Lock()
+		refused = append(refused, name)
+		mu.Unlock()
+		return nil
+	})
+	sort.Strings(refused)
+	return refused, err
+}
+
+// shutdown sends the stop signal to the container and kills it if it did not
+// exit within the grace period. It reports whether the container stopped.
+func (c *Client) shutdown(ctx context.Context, id string, grace time.Duration) (bool, error) {
+	cj, err := c.inspectContainer(ctx, id)
+	if isNotFound(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	signal := cj.Config.StopSignal
+	if signal == "" {
+		signal = "SIGTERM"
+	}
+
+	for _, step := range []struct {
+		signal string
+		wait   time.Duration
+	}{
+		{signal, grace},
+		{"SIGKILL", shutdownKillWait},
+	} {
+		if err := c.killContainer(ctx, id, step.signal); isNotFound(err) {
+			return true, nil
+		} else if err != nil {
+			return false, fmt.Errorf("send %s: %w", step.signal, err)
+		}
+		stopped, err := c.waitStopped(ctx, id, step.wait)
+		if err != nil || stopped {
+			return stopped, err
+		}
+	}
+	return false, nil
+}
+
+// waitStopped waits at most d for the container to exit. It reports whether
+// the container exited; the error is only set if ctx is done or a request
+// failed.
+func (c *Client) waitStopped(ctx context.Context, id string, d time.Duration) (bool, error) {
+	wctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	interval := defaultPollInterval
+	if d/10 < interval {
+		interval = d / 10
+	}
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+	_, err := c.waitContainer(wctx, id, WaitExited, c.backoffOr(ConstantBackoff(interval)))
+	switch {
+	case err == nil, isNotFound(err):
+		return true, nil
+	case ctx.Err() != nil:
+		return false, ctx.Err()
+	case errors.Is(err, context.DeadlineExceeded):
+		return false, nil
+	}
+	return false, err
+}
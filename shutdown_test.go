@@ -0,0 +1,65 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_ShutdownAll(t *testing.T) {
+	list := `[{"Id": "c1", "Names": ["/plc"]}]`
+
+	t.Run("stopped by signal", func(t *testing.T) {
+		srv.route(map[string]mockResponse{
+			"GET /containers/json":      {Body: list},
+			"GET /containers/plc/json":  {Body: `{"Id": "c1", "State": {"Status": "exited"}, "Config": {"StopSignal": "SIGINT"}}`},
+			"POST /containers/plc/kill": {StatusCode: http.StatusNoContent},
+		})
+		defer srv.route(nil)
+
+		refused, err := client.ShutdownAll(context.Background(), RunSelector("r1"), time.Second)
+		if err != nil || len(refused) != 0 {
+			t.Fatalf("unexpected result %v, %v", refused, err)
+		}
+		want := []string{
+			"GET /containers/json",
+			"GET /containers/plc/json",
+			"POST /containers/plc/kill",
+			"GET /containers/plc/json",
+		}
+		if got := srv.Requests(); !reflect.DeepEqual(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("refused to die", func(t *testing.T) {
+		defer func(d time.Duration) { shutdownKillWait = d }(shutdownKillWait)
+		shutdownKillWait = 20 * time.Millisecond
+
+		srv.route(map[string]mockResponse{
+			"GET /containers/json":      {Body: list},
+			"GET /containers/plc/json":  {Body: `{"Id": "c1", "State": {"Status": "running", "Running": true}}`},
+			"POST /containers/plc/kill": {StatusCode: http.StatusNoContent},
+		})
+		defer srv.route(nil)
+
+		refused, err := client.ShutdownAll(context.Background(), RunSelector("r1"), 20*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(refused, []string{"plc"}) {
+			t.Errorf("unexpected refused containers %v", refused)
+		}
+		kills := 0
+		for _, r := range srv.Requests() {
+			if r == "POST /containers/plc/kill" {
+				kills++
+			}
+		}
+		if kills != 2 {
+			t.Errorf("expected stop signal and kill, got %d requests", kills)
+		}
+	})
+}
@@ -0,0 +1,276 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const snapshotManifestName = "snapshot.json"
+
+// snapshotManifest is stored as first file of a snapshot archive. It is
+// followed by one tar archive per volume named volumes/<name>.tar.
+type snapshotManifest struct {
+	Created    time.Time           `json:"created"`
+	Networks   []NetworkSpec       `json:"networks,omitempty"`
+	Volumes    []snapshotVolume    `json:"volumes,omitempty"`
+	Containers []snapshotContainer `json:"containers,omitempty"`
+}
+
+type snapshotVolume struct {
+	VolumeSpec
+	// Container and Path identify where the volume is mounted read-write,
+	// the content is copied through this mount.
+	Container string `json:"container"`
+	Path      string `json:"path"`
+}
+
+type snapshotContainer struct {
+	Spec ContainerSpec `json:"spec"`
+	// ImageDigest references the image by its content e.g.:
+	// "postgres@sha256:..." It is empty for images which were never pushed.
+	ImageDigest string `json:"image_digest,omitempty"`
+	Running     bool   `json:"running"`
+}
+
+// predefinedNetworks can not be created or removed.
+var predefinedNetworks = map[string]bool{"bridge": true, "host": true, "none": true}
+
+// SnapshotEnvironment writes a tar archive to w which records all containers
+// matching the selector: their configuration, the digests of their images,
+// the networks they are attached to and the content of their named volumes.
+// The archive can be used to rebuild the environment by RestoreEnvironment.
+// Containers should be stopped while their volumes are copied.
+func (c *Client) SnapshotEnvironment(ctx context.Context, selector Selector, w io.Writer) error {
+	containers, err := c.listContainers(ctx, true, selector.filters())
+	if err != nil {
+		return fmt.Errorf("list containers: %w", err)
+	}
+
+	var (
+		manifest = snapshotManifest{Created: time.Now().UTC()}
+		networks = make(map[string]bool)
+		volumes  = make(map[string]int)
+	)
+	for _, summary := range containers {
+		cj, err := c.inspectContainer(ctx, summary.ID)
+		if err != nil {
+			return fmt.Errorf("inspect container %s: %w", summary.Name(), err)
+		}
+		sc := snapshotContainer{Spec: specFromInspect(cj), Running: cj.State.Running}
+		if img, err := c.inspectImage(ctx, cj.Image); err == nil && len(img.RepoDigests) > 0 {
+			sc.ImageDigest = img.RepoDigests[0]
+		}
+		manifest.Containers = append(manifest.Containers, sc)
+
+		for name, ep := range cj.NetworkSettings.Networks {
+			if predefinedNetworks[name] || networks[name] {
+				continue
+			}
+			networks[name] = true
+			nw, err := c.inspectNetwork(ctx, ep.NetworkID)
+			if err != nil {
+				return fmt.Errorf("inspect network %s: %w", name, err)
+			}
+			manifest.Networks = append(manifest.Networks, networkSpecFromInspect(nw))
+		}
+
+		for _, m := range cj.Mounts {
+			if m.Type != "volume" {
+				continue
+			}
+			i, ok := volumes[m.Name]
+			if !ok {
+				v, err := c.inspectVolume(ctx, m.Name)
+				if err != nil {
+					return fmt.Errorf("inspect volume %s: %w", m.Name, err)
+				}
+				manifest.Volumes = append(manifest.Volumes, snapshotVolume{
+					VolumeSpec: VolumeSpec{Name: v.Name, Driver: v.Driver, Labels: v.Labels},
+				})
+				i = len(manifest.Volumes) - 1
+				volumes[m.Name] = i
+			}
+			if manifest.Volumes[i].Container == "" && m.RW {
+				manifest.Volumes[i].Container = sc.Spec.Name
+				manifest.Volumes[i].Path = m.Destination
+			}
+		}
+	}
+	sort.Slice(manifest.Networks, func(i, j int) bool {
+		return manifest.Networks[i].Name < manifest.Networks[j].Name
+	})
+
+	tw := tar.NewWriter(w)
+	b, err := json.MarshalIndent(&manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeTarFile(tw, snapshotManifestName, int64(len(b)), bytes.NewReader(b)); err != nil {
+		return err
+	}
+	for _, v := range manifest.Volumes {
+		if v.Container == "" {
+			continue
+		}
+		if err := c.snapshotVolume(ctx, tw, v); err != nil {
+			return fmt.Errorf("copy volume %s: %w", v.Name, err)
+		}
+	}
+	return tw.Close()
+}
+
+// snapshotVolume copies the content of the volume into the archive. The tar
+// stream of the daemon has to be buffered because the size of a tar entry
+// must be known in advance.
+func (c *Client) snapshotVolume(ctx context.Context, tw *tar.Writer, v snapshotVolume) error {
+	r, err := c.getArchive(ctx, v.Container, v.Path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tmp, err := ioutil.TempFile("", "docker-snapshot-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return writeTarFile(tw, "volumes/"+v.Name+".tar", size, tmp)
+}
+
+func writeTarFile(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw, r)
+	return err
+}
+
+// RestoreEnvironment rebuilds an environment from an archive written by
+// SnapshotEnvironment. Networks which already exist are reused. Containers
+// are created from the recorded image digests, so the images have to be
+// available on the daemon. Containers which were running are started after
+// their volumes were restored.
+func (c *Client) RestoreEnvironment(ctx context.Context, r io.Reader) error {
+	tr := tar.NewReader(r)
+	hdr, err := tr.Next()
+	if err != nil {
+		return fmt.Errorf("read snapshot: %w", err)
+	}
+	if hdr.Name != snapshotManifestName {
+		return fmt.Errorf("read snapshot: expected %s, got %s", snapshotManifestName, hdr.Name)
+	}
+	var manifest snapshotManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return fmt.Errorf("read snapshot: %w", err)
+	}
+
+	networks := make(map[string]string)
+	for _, n := range manifest.Networks {
+		if id, err := c.networkByName(ctx, n.Name); err == nil {
+			networks[n.Name] = id
+			continue
+		}
+		res, err := c.createNetwork(ctx, n.createBody())
+		if err != nil {
+			return fmt.Errorf("create network %s: %w", n.Name, err)
+		}
+		networks[n.Name] = res.ID
+	}
+
+	for _, v := range manifest.Volumes {
+		if _, err := c.createVolume(ctx, v.Name, v.Driver, v.Labels); err != nil {
+			return fmt.Errorf("create volume %s: %w", v.Name, err)
+		}
+	}
+
+	ids := make(map[string]string)
+	for _, sc := range manifest.Containers {
+		body, err := sc.Spec.createBody()
+		if err != nil {
+			return fmt.Errorf("container %s: %w", sc.Spec.Name, err)
+		}
+		if sc.ImageDigest != "" {
+			body.Image = sc.ImageDigest
+		}
+		res, err := c.createContainer(ctx, sc.Spec.Name, body)
+		if err != nil {
+			return fmt.Errorf("create container %s: %w", sc.Spec.Name, err)
+		}
+		ids[sc.Spec.Name] = res.ID
+
+		for i, a := range sc.Spec.Networks {
+			if i == 0 {
+				continue
+			}
+			nwid, ok := networks[a.Network]
+			if !ok {
+				nwid = a.Network
+			}
+			if err := c.connectNetwork(ctx, nwid, res.ID, a.endpointConfig()); err != nil {
+				return fmt.Errorf("connect container %s to %s: %w", sc.Spec.Name, a.Network, err)
+			}
+		}
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read snapshot: %w", err)
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(hdr.Name, "volumes/"), ".tar")
+		v := manifest.volume(name)
+		if v == nil {
+			return fmt.Errorf("read snapshot: unexpected file %s", hdr.Name)
+		}
+		if err := c.putArchive(ctx, ids[v.Container], path.Dir(v.Path), tr); err != nil {
+			return fmt.Errorf("restore volume %s: %w", name, err)
+		}
+	}
+
+	for _, sc := range manifest.Containers {
+		if !sc.Running {
+			continue
+		}
+		if err := c.startContainer(ctx, ids[sc.Spec.Name]); err != nil {
+			return fmt.Errorf("start container %s: %w", sc.Spec.Name, err)
+		}
+	}
+	return nil
+}
+
+func (m *snapshotManifest) volume(name string) *snapshotVolume {
+	for i := range m.Volumes {
+		if m.Volumes[i].Name == name {
+			return &m.Volumes[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func Test_SnapshotRestore(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /containers/json": {Body: `[{"Id": "c1", "Names": ["/plc"]}]`},
+		"GET /containers/c1/json": {Body: `{
+			"Id": "c1", "Name": "/plc", "Image": "sha256:abc",
+			"State": {"Running": true},
+			"Config": {"Image": "plc:latest", "Env": ["MODE=sim"]},
+			"Mounts": [{"Type": "volume", "Name": "data", "Destination": "/data", "RW": true}],
+			"NetworkSettings": {"Networks": {"subnet": {"NetworkID": "n1", "Aliases": ["plc", "c1"]}}}
+		}`},
+		"GET /images/sha256:abc/json": {Body: `{"RepoDigests": ["plc@sha256:1234"]}`},
+		"GET /networks/n1":            {Body: `{"Id": "n1", "Name": "subnet", "Driver": "bridge"}`},
+		"GET /volumes/data":           {Body: `{"Name": "data", "Driver": "local"}`},
+		"GET /containers/plc/archive": {Body: "volume content"},
+	})
+	defer srv.route(nil)
+
+	var buf bytes.Buffer
+	if err := client.SnapshotEnvironment(context.Background(), RunSelector("test"), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.route(map[string]mockResponse{
+		"GET /networks":              {Body: `[]`},
+		"POST /networks/create":      {StatusCode: http.StatusCreated, Body: `{"Id": "n2"}`},
+		"POST /volumes/create":       {StatusCode: http.StatusCreated, Body: `{"Name": "data"}`},
+		"POST /containers/create":    {StatusCode: http.StatusCreated, Body: `{"Id": "c2"}`},
+		"PUT /containers/c2/archive": {},
+		"POST /containers/c2/start":  {StatusCode: http.StatusNoContent},
+	})
+	if err := client.RestoreEnvironment(context.Background(), &buf); err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"GET /networks",
+		"POST /networks/create",
+		"POST /volumes/create",
+		"POST /containers/create",
+		"PUT /containers/c2/archive",
+		"POST /containers/c2/start",
+	}
+	if got := srv.Requests(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("got: %v, want: %v", got, expect)
+	}
+}
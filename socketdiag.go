@@ -0,0 +1,91 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// SocketError is returned if the socket of the daemon could not be
+// connected to because it is missing, the permissions deny the access or
+// the daemon does not listen. It tells what was found out about the cause,
+// which is not obvious from the error of the connection. Once the client
+// connected to the socket, failed connections are not diagnosed anymore.
+type SocketError struct {
+	Path string
+	// Err is the error of the connection.
+	Err error
+	// Diagnosis are the findings and hints, e.g. "user sim is not in group
+	// docker of the socket".
+	Diagnosis []string
+}
+
+func (e *SocketError) Error() string {
+	msg := fmt.Sprintf("connect to docker socket %s: %v", e.Path, e.Err)
+	if len(e.Diagnosis) > 0 {
+		msg += ": " + strings.Join(e.Diagnosis, "; ")
+	}
+	return msg
+}
+
+func (e *SocketError) Unwrap() error {
+	return e.Err
+}
+
+// diagnoseSocket returns a *SocketError for failed connections to the socket
+// at path which are caused by the setup of the host, other errors are
+// returned as they are.
+func diagnoseSocket(path string, err error) error {
+	e := &SocketError{Path: path, Err: err}
+	switch {
+	case errors.Is(err, syscall.ENOENT):
+		e.Diagnosis = append(e.Diagnosis, "the socket does not exist")
+		e.Diagnosis = append(e.Diagnosis, daemonHint(path)...)
+	case errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EPERM):
+		e.Diagnosis = append(e.Diagnosis, socketPermissions(path)...)
+	case errors.Is(err, syscall.ECONNREFUSED):
+		e.Diagnosis = append(e.Diagnosis, "the socket exists but nobody listens")
+		e.Diagnosis = append(e.Diagnosis, daemonHint(path)...)
+	default:
+		return err
+	}
+	return e
+}
+
+// daemonHint tells whether dockerd runs and points to the sockets which
+// exist instead of path, e.g. of a rootless daemon.
+func daemonHint(path string) []string {
+	var hints []string
+	switch running, known := dockerdRunning(); {
+	case !known:
+	case running:
+		hints = append(hints, "dockerd is running, check the path of the socket, e.g. DOCKER_HOST")
+	default:
+		hints = append(hints, "dockerd is not running, start it, e.g. with sudo systemctl start docker")
+	}
+	for _, s := range socketCandidates() {
+		if s == path {
+			continue
+		}
+		if fi, err := os.Stat(s); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			hints = append(hints, "a daemon socket exists at "+s)
+		}
+	}
+	return hints
+}
+
+// socketCandidates are the usual paths of the socket: of the system daemon,
+// a rootless daemon and Docker Desktop.
+func socketCandidates() []string {
+	candidates := []string{"/var/run/docker.sock"}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		candidates = append(candidates, filepath.Join(dir, "docker.sock"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(home, ".docker", "run", "docker.sock"))
+	}
+	return candidates
+}
@@ -0,0 +1,105 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func Test_SocketDiagnosis(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socketdiag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a socket which is left behind by a stopped daemon
+	stale := filepath.Join(dir, "stale.sock")
+	l, err := net.Listen("unix", stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+
+	tests := []struct {
+		name     string
+		sock     string
+		wantErr  error
+		wantDiag string
+	}{
+		{name: "missing", sock: filepath.Join(dir, "missing.sock"), wantErr: syscall.ENOENT, wantDiag: "the socket does not exist"},
+		{name: "stale", sock: stale, wantErr: syscall.ECONNREFUSED, wantDiag: "the socket exists but nobody listens"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewClient(tc.sock).Ping(context.Background())
+			var e *SocketError
+			if !errors.As(err, &e) {
+				t.Fatalf("got %v, want *SocketError", err)
+			}
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("got %v, want it to wrap %v", err, tc.wantErr)
+			}
+			if e.Path != tc.sock || len(e.Diagnosis) == 0 || e.Diagnosis[0] != tc.wantDiag {
+				t.Errorf("got %+v, want diagnosis %q", e, tc.wantDiag)
+			}
+		})
+	}
+
+	t.Run("connected before", func(t *testing.T) {
+		l, err := net.Listen("unix", filepath.Join(dir, "docker.sock"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("OK"))
+		})}
+		go d.Serve(l)
+		c := NewClient(l.Addr().String())
+		if _, err := c.Ping(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		d.Close()
+		c.transport.CloseIdleConnections()
+		_, err = c.Ping(context.Background())
+		var e *SocketError
+		if !errors.Is(err, syscall.ENOENT) || errors.As(err, &e) {
+			t.Errorf("got %v, want the error of the connection without diagnosis", err)
+		}
+	})
+
+	t.Run("permission denied", func(t *testing.T) {
+		l, err := net.Listen("unix", filepath.Join(dir, "denied.sock"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		path := l.Addr().String()
+		if err := os.Chmod(path, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		err = diagnoseSocket(path, &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.EACCES)})
+		var e *SocketError
+		if !errors.As(err, &e) || len(e.Diagnosis) < 2 {
+			t.Fatalf("got %v, want *SocketError with owner and cause", err)
+		}
+		if !strings.HasPrefix(e.Diagnosis[0], "the socket is owned by ") || !strings.HasSuffix(e.Diagnosis[0], "with mode Srw-------") {
+			t.Errorf("got %q, want owner and mode", e.Diagnosis[0])
+		}
+	})
+
+	t.Run("other error", func(t *testing.T) {
+		want := errors.New("boom")
+		if err := diagnoseSocket("/var/run/docker.sock", want); err != want {
+			t.Errorf("got %v, want the error unchanged", err)
+		}
+	})
+}
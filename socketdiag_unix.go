@@ -0,0 +1,91 @@
+//go:build !windows
+// +build !windows
+
+package docker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// socketPermissions explains why the access to the socket is denied: who
+// owns it and whether the user is allowed to connect by its group.
+func socketPermissions(path string) []string {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return []string{fmt.Sprintf("the socket can not be inspected: %v", err)}
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	owner := strconv.Itoa(int(st.Uid))
+	if u, err := user.LookupId(owner); err == nil {
+		owner = u.Username
+	}
+	gid := strconv.Itoa(int(st.Gid))
+	group := gid
+	if g, err := user.LookupGroupId(gid); err == nil {
+		group = g.Name
+	}
+	diag := []string{fmt.Sprintf("the socket is owned by %s:%s with mode %s", owner, group, fi.Mode())}
+
+	me := strconv.Itoa(os.Getuid())
+	if u, err := user.Current(); err == nil {
+		me = u.Username
+		if os.Getuid() == 0 {
+			return append(diag, "the process runs as root, the access is denied by a security module like SELinux or AppArmor")
+		}
+		if ids, err := u.GroupIds(); err == nil && !contains(ids, gid) {
+			return append(diag, fmt.Sprintf("user %s is not in group %s of the socket, add it with sudo usermod -aG %s %s and log in again",
+				me, group, group, me))
+		}
+	}
+	if !inProcessGroups(int(st.Gid)) {
+		return append(diag, fmt.Sprintf("user %s is in group %s, but this session is not, log in again or run newgrp %s",
+			me, group, group))
+	}
+	if fi.Mode().Perm()&0o060 != 0o060 {
+		return append(diag, fmt.Sprintf("group %s may not read and write the socket", group))
+	}
+	return diag
+}
+
+// inProcessGroups reports whether the process has the group.
+func inProcessGroups(gid int) bool {
+	if os.Getegid() == gid {
+		return true
+	}
+	groups, err := os.Getgroups()
+	if err != nil {
+		return false
+	}
+	for _, g := range groups {
+		if g == gid {
+			return true
+		}
+	}
+	return false
+}
+
+// dockerdRunning looks for dockerd in the processes of the host. known is
+// false if they can not be listed, e.g. on hosts without /proc.
+func dockerdRunning() (running, known bool) {
+	comms, err := filepath.Glob("/proc/[0-9]*/comm")
+	if err != nil || len(comms) == 0 {
+		return false, false
+	}
+	for _, c := range comms {
+		b, err := ioutil.ReadFile(c)
+		if err == nil && strings.TrimSpace(string(b)) == "dockerd" {
+			return true, true
+		}
+	}
+	return false, true
+}
@@ -0,0 +1,15 @@
+//go:build windows
+// +build windows
+
+package docker
+
+// socketPermissions is not supported on Windows, where the daemon listens
+// on a named pipe.
+func socketPermissions(path string) []string {
+	return nil
+}
+
+// dockerdRunning is not supported on Windows.
+func dockerdRunning() (running, known bool) {
+	return false, false
+}
@@ -0,0 +1,515 @@
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grid-x/docker/types"
+)
+
+// Labels which are set on every resource created by Apply.
+const (
+	// LabelRun holds the run of the Spec a resource belongs to.
+	LabelRun = "com.grid-x.docker.run"
+	// LabelConfigHash holds the hash of the spec a resource was created
+	// from. It is used to detect resources whose configuration drifted.
+	LabelConfigHash = "com.grid-x.docker.config-hash"
+)
+
+// Spec describes the desired state of an environment: the networks, volumes
+// and containers which shall exist and how the containers are connected.
+// Run identifies the environment. Every resource created by Apply carries it
+// in the LabelRun label, which allows to find the resources again.
+type Spec struct {
+	Run        string          `json:"run"`
+	Networks   []NetworkSpec   `json:"networks,omitempty"`
+	Volumes    []VolumeSpec    `json:"volumes,omitempty"`
+	Containers []ContainerSpec `json:"containers,omitempty"`
+}
+
+// NetworkSpec describes a network. Driver defaults to bridge. Subnet and
+// Gateway are optional and use the CIDR notation e.g.: "172.28.0.0/16".
+type NetworkSpec struct {
+	Name     string            `json:"name"`
+	Driver   string            `json:"driver,omitempty"`
+	Subnet   string            `json:"subnet,omitempty"`
+	Gateway  string            `json:"gateway,omitempty"`
+	Internal bool              `json:"internal,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+// VolumeSpec describes a named volume. Driver defaults to local.
+type VolumeSpec struct {
+	Name   string            `json:"name"`
+	Driver string            `json:"driver,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// ContainerSpec describes a container.
+// ExposedPorts shall be so specified: ["<port>/<tcp|udp>"]
+// Ports are published as: ["[[<hostIP>:]<hostPort>:]<port>[/<tcp|udp>]"]
+// If the host port is omitted a random port is used.
+// Mounts e.g.: ["/var/run/docker.sock:/var/run/docker.sock:ro", "data:/data"]
+// A mount source which is not a path refers to a named volume. Relative
+// paths like "./data" are only supported by LoadSpecFile, which resolves them
+// against the directory of the file.
+// The container is attached to Networks in the given order. DependsOn names
+// the containers which have to be started before this one. The containers
+// named by DependsOnHealthy also have to pass their healthcheck first, like
+// the condition service_healthy of compose files; a dependency without
+// healthcheck only has to run. Dependencies which are not part of the spec
+// refer to existing containers.
+type ContainerSpec struct {
+	Name         string              `json:"name"`
+	Image        string              `json:"image"`
+	Cmd          []string            `json:"cmd,omitempty"`
+	Entrypoint   []string            `json:"entrypoint,omitempty"`
+	Env          map[string]string   `json:"env,omitempty"`
+	Labels       map[string]string   `json:"labels,omitempty"`
+	ExposedPorts []string            `json:"exposed_ports,omitempty"`
+	Ports        []string            `json:"ports,omitempty"`
+	Mounts       []string            `json:"mounts,omitempty"`
+	Privileged   bool                `json:"privileged,omitempty"`
+	Networks     []NetworkAttachment `json:"networks,omitempty"`
+	DependsOn    []string            `json:"depends_on,omitempty"`
+	// DependsOnHealthy is only honored by Apply.
+	DependsOnHealthy []string `json:"depends_on_healthy,omitempty"`
+	// Annotations are OCI annotations passed to the runtime of the
+	// container. They require API version 1.43.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Healthcheck replaces the healthcheck of the image.
+	Healthcheck *Healthcheck `json:"healthcheck,omitempty"`
+}
+
+// Healthcheck is the healthcheck of a container. Test is given like in the
+// engine API: ["CMD", <args>...] runs the command, ["CMD-SHELL", <command>]
+// runs the command by the shell of the container and ["NONE"] disables the
+// healthcheck of the image. Interval, Timeout and StartPeriod are durations
+// like "10s". Zero values use the defaults of the daemon.
+type Healthcheck struct {
+	Test        []string `json:"test,omitempty"`
+	Interval    string   `json:"interval,omitempty"`
+	Timeout     string   `json:"timeout,omitempty"`
+	StartPeriod string   `json:"start_period,omitempty"`
+	Retries     int      `json:"retries,omitempty"`
+}
+
+// NetworkAttachment connects a container to the network with the given name.
+type NetworkAttachment struct {
+	Network     string   `json:"network"`
+	Aliases     []string `json:"aliases,omitempty"`
+	IPv4Address string   `json:"ipv4_address,omitempty"`
+}
+
+// Validate checks the spec for missing and duplicate names, for options
+// which can not be parsed and for dependency cycles.
+func (s *Spec) Validate() error {
+	if s.Run == "" {
+		return fmt.Errorf("spec: run must not be empty")
+	}
+	seen := map[string]bool{}
+	for i, n := range s.Networks {
+		if n.Name == "" {
+			return fmt.Errorf("spec: networks[%d]: missing name", i)
+		}
+		if seen["n/"+n.Name] {
+			return fmt.Errorf("spec: networks[%d]: duplicate name %s", i, n.Name)
+		}
+		seen["n/"+n.Name] = true
+	}
+	for i, v := range s.Volumes {
+		if v.Name == "" {
+			return fmt.Errorf("spec: volumes[%d]: missing name", i)
+		}
+		if seen["v/"+v.Name] {
+			return fmt.Errorf("spec: volumes[%d]: duplicate name %s", i, v.Name)
+		}
+		seen["v/"+v.Name] = true
+	}
+	for i, c := range s.Containers {
+		if c.Name == "" {
+			return fmt.Errorf("spec: containers[%d]: missing name", i)
+		}
+		if c.Image == "" {
+			return fmt.Errorf("spec: containers[%d] %s: missing image", i, c.Name)
+		}
+		if seen["c/"+c.Name] {
+			return fmt.Errorf("spec: containers[%d]: duplicate name %s", i, c.Name)
+		}
+		seen["c/"+c.Name] = true
+		if _, err := c.createBody(); err != nil {
+			return fmt.Errorf("spec: containers[%d] %s: %v", i, c.Name, err)
+		}
+	}
+	if _, err := s.startOrder(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// startOrder returns the containers ordered such that every container
+// follows its dependencies. Apart from that the order of the spec is kept.
+func (s *Spec) startOrder() ([]ContainerSpec, error) {
+	var (
+		order []ContainerSpec
+		state = make(map[string]int, len(s.Containers)) // 1: visiting, 2: done
+		visit func(cs *ContainerSpec, path []string) error
+	)
+	visit = func(cs *ContainerSpec, path []string) error {
+		path = append(path, cs.Name)
+		switch state[cs.Name] {
+		case 1:
+			return fmt.Errorf("spec: dependency cycle %s", strings.Join(path, " -> "))
+		case 2:
+			return nil
+		}
+		state[cs.Name] = 1
+		for _, dep := range cs.dependencies() {
+			if d := s.container(dep); d != nil {
+				if err := visit(d, path); err != nil {
+					return err
+				}
+			}
+		}
+		state[cs.Name] = 2
+		order = append(order, *cs)
+		return nil
+	}
+	for i := range s.Containers {
+		if err := visit(&s.Containers[i], nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// dependencies returns the names of all containers the container depends on.
+func (c ContainerSpec) dependencies() []string {
+	return append(append([]string(nil), c.DependsOn...), c.DependsOnHealthy...)
+}
+
+// hash returns a stable hash of the network configuration.
+func (n NetworkSpec) hash() string {
+	return hashOf(n)
+}
+
+// hash returns a stable hash of the container configuration. The network
+// attachments and dependencies are not part of it, because they can be
+// changed without recreating the container.
+func (c ContainerSpec) hash() string {
+	c.Networks = nil
+	c.DependsOn = nil
+	c.DependsOnHealthy = nil
+	return hashOf(c)
+}
+
+func hashOf(v interface{}) string {
+	b, _ := json.Marshal(v)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func (n NetworkSpec) createBody() *networkCreate {
+	body := &networkCreate{
+		Name:       n.Name,
+		Driver:     n.Driver,
+		Internal:   n.Internal,
+		Attachable: true,
+		Labels:     copyLabels(n.Labels),
+	}
+	if body.Driver == "" {
+		body.Driver = "bridge"
+	}
+	if n.Subnet != "" {
+		cfg := map[string]string{"Subnet": n.Subnet}
+		if n.Gateway != "" {
+			cfg["Gateway"] = n.Gateway
+		}
+		body.IPAM = &types.NetworkCreateIPAM{Config: []map[string]string{cfg}}
+	}
+	return body
+}
+
+// createBody converts the spec into the body of a create request. The
+// attachment to the first network is part of the request, all other networks
+// have to be connected afterwards.
+func (c ContainerSpec) createBody() (*containerCreate, error) {
+	body := &containerCreate{
+		Image:        c.Image,
+		Cmd:          c.Cmd,
+		Entrypoint:   c.Entrypoint,
+		Labels:       copyLabels(c.Labels),
+		ExposedPorts: make(map[string]struct{}),
+	}
+	body.HostConfig.Privileged = c.Privileged
+	body.HostConfig.Annotations = copyLabels(c.Annotations)
+
+	keys := make([]string, 0, len(c.Env))
+	for k := range c.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		body.Env = append(body.Env, k+"="+c.Env[k])
+	}
+
+	for _, p := range c.ExposedPorts {
+		body.ExposedPorts[normalizePort(p)] = struct{}{}
+	}
+
+	for _, p := range c.Ports {
+		port, binding, err := parsePortBinding(p)
+		if err != nil {
+			return nil, err
+		}
+		if body.HostConfig.PortBindings == nil {
+			body.HostConfig.PortBindings = make(map[string][]portBinding)
+		}
+		body.ExposedPorts[port] = struct{}{}
+		body.HostConfig.PortBindings[port] = append(body.HostConfig.PortBindings[port], binding)
+	}
+
+	for _, m := range c.Mounts {
+		mnt, err := parseMount(m)
+		if err != nil {
+			return nil, err
+		}
+		body.HostConfig.Mounts = append(body.HostConfig.Mounts, mnt)
+	}
+
+	if c.Healthcheck != nil {
+		hc, err := c.Healthcheck.config()
+		if err != nil {
+			return nil, err
+		}
+		body.Healthcheck = hc
+	}
+
+	if len(c.Networks) > 0 {
+		first := c.Networks[0]
+		body.HostConfig.NetworkMode = first.Network
+		body.NetworkingConfig = &types.NetworkingConfig{
+			EndpointsConfig: map[string]*endpointConfig{
+				first.Network: first.endpointConfig(),
+			},
+		}
+	}
+	return body, nil
+}
+
+// config converts the healthcheck into the configuration of the engine API.
+func (h *Healthcheck) config() (*healthConfig, error) {
+	hc := &healthConfig{Test: h.Test, Retries: h.Retries}
+	durations := []struct {
+		name string
+		s    string
+		d    *int64
+	}{
+		{"interval", h.Interval, &hc.Interval},
+		{"timeout", h.Timeout, &hc.Timeout},
+		{"start_period", h.StartPeriod, &hc.StartPeriod},
+	}
+	for _, d := range durations {
+		if d.s == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.s)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("healthcheck: invalid %s %q", d.name, d.s)
+		}
+		*d.d = int64(v)
+	}
+	return hc, nil
+}
+
+func (a NetworkAttachment) endpointConfig() *endpointConfig {
+	cfg := &endpointConfig{Aliases: a.Aliases}
+	if a.IPv4Address != "" {
+		cfg.IPAMConfig = &ipamConfig{IPv4Address: a.IPv4Address}
+	}
+	return cfg
+}
+
+// normalizePort appends the default protocol tcp if it is missing.
+func normalizePort(p string) string {
+	if strings.Contains(p, "/") {
+		return p
+	}
+	return p + "/tcp"
+}
+
+// parsePortBinding parses "[[<hostIP>:]<hostPort>:]<port>[/<proto>]".
+func parsePortBinding(s string) (string, portBinding, error) {
+	ss := strings.Split(s, ":")
+	switch len(ss) {
+	case 1:
+		return normalizePort(ss[0]), portBinding{}, nil
+	case 2:
+		return normalizePort(ss[1]), portBinding{HostPort: ss[0]}, nil
+	case 3:
+		return normalizePort(ss[2]), portBinding{HostIP: ss[0], HostPort: ss[1]}, nil
+	}
+	return "", portBinding{}, fmt.Errorf("invalid port binding %q", s)
+}
+
+// resolveMounts resolves the relative sources of bind mounts against dir.
+func (s *Spec) resolveMounts(dir string) {
+	for i := range s.Containers {
+		for j, m := range s.Containers[i].Mounts {
+			if !strings.HasPrefix(m, ".") {
+				continue
+			}
+			ss := strings.SplitN(m, ":", 2)
+			ss[0] = filepath.Join(dir, ss[0])
+			s.Containers[i].Mounts[j] = strings.Join(ss, ":")
+		}
+	}
+}
+
+// parseMount parses "<source>:<target>[:ro]". If source is not a path it is
+// the name of a volume. Relative paths are rejected, the daemon does not
+// know what they are relative to.
+func parseMount(s string) (mount, error) {
+	ss := strings.Split(s, ":")
+	if len(ss) < 2 || len(ss) > 3 || ss[0] == "" || ss[1] == "" {
+		return mount{}, fmt.Errorf("invalid mount %q", s)
+	}
+	if strings.HasPrefix(ss[0], ".") {
+		return mount{}, fmt.Errorf("relative bind mount %q needs the spec file location", s)
+	}
+	m := mount{Source: ss[0], Target: ss[1], Type: "volume"}
+	if filepath.IsAbs(ss[0]) {
+		m.Type = "bind"
+		m.Consistency = "default"
+	}
+	if len(ss) == 3 {
+		switch ss[2] {
+		case "ro":
+			m.ReadOnly = true
+		case "rw":
+		default:
+			return mount{}, fmt.Errorf("invalid mount mode %q", ss[2])
+		}
+	}
+	return m, nil
+}
+
+// specFromInspect reconstructs the spec of an existing container. Defaults
+// of the image, like its environment, become part of the spec.
+func specFromInspect(cj *containerJSON) ContainerSpec {
+	cs := ContainerSpec{
+		Name:        strings.TrimPrefix(cj.Name, "/"),
+		Image:       cj.Config.Image,
+		Cmd:         cj.Config.Cmd,
+		Entrypoint:  cj.Config.Entrypoint,
+		Labels:      cj.Config.Labels,
+		Privileged:  cj.HostConfig.Privileged,
+		Annotations: cj.HostConfig.Annotations,
+	}
+
+	if len(cj.Config.Env) > 0 {
+		cs.Env = parseEnv(cj.Config.Env)
+	}
+
+	for port := range cj.Config.ExposedPorts {
+		if _, ok := cj.HostConfig.PortBindings[port]; !ok {
+			cs.ExposedPorts = append(cs.ExposedPorts, port)
+		}
+	}
+	sort.Strings(cs.ExposedPorts)
+
+	for port, bindings := range cj.HostConfig.PortBindings {
+		for _, b := range bindings {
+			switch {
+			case b.HostIP != "":
+				cs.Ports = append(cs.Ports, b.HostIP+":"+b.HostPort+":"+port)
+			case b.HostPort != "":
+				cs.Ports = append(cs.Ports, b.HostPort+":"+port)
+			default:
+				cs.Ports = append(cs.Ports, port)
+			}
+		}
+	}
+	sort.Strings(cs.Ports)
+
+	for _, m := range cj.Mounts {
+		var src string
+		switch m.Type {
+		case "volume":
+			src = m.Name
+		case "bind":
+			src = m.Source
+		default:
+			continue
+		}
+		mnt := src + ":" + m.Destination
+		if !m.RW {
+			mnt += ":ro"
+		}
+		cs.Mounts = append(cs.Mounts, mnt)
+	}
+
+	names := make([]string, 0, len(cj.NetworkSettings.Networks))
+	for name := range cj.NetworkSettings.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ep := cj.NetworkSettings.Networks[name]
+		a := NetworkAttachment{Network: name}
+		for _, alias := range ep.Aliases {
+			// the daemon adds the short container ID as alias
+			if !strings.HasPrefix(cj.ID, alias) {
+				a.Aliases = append(a.Aliases, alias)
+			}
+		}
+		if ep.IPAMConfig != nil {
+			a.IPv4Address = ep.IPAMConfig.IPv4Address
+		}
+		cs.Networks = append(cs.Networks, a)
+	}
+	return cs
+}
+
+// networkSpecFromInspect reconstructs the spec of an existing network.
+func networkSpecFromInspect(n *networkSummary) NetworkSpec {
+	ns := NetworkSpec{
+		Name:     n.Name,
+		Driver:   n.Driver,
+		Internal: n.Internal,
+		Labels:   n.Labels,
+	}
+	if len(n.IPAM.Config) > 0 {
+		ns.Subnet = n.IPAM.Config[0].Subnet
+		ns.Gateway = n.IPAM.Config[0].Gateway
+	}
+	return ns
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	res := make(map[string]string, len(labels)+2)
+	for k, v := range labels {
+		res[k] = v
+	}
+	return res
+}
+
+// parseEnv splits the KEY=value entries of an environment into a map.
+// Entries without value are mapped to the empty string.
+func parseEnv(env []string) map[string]string {
+	res := make(map[string]string, len(env))
+	for _, kv := range env {
+		ss := strings.SplitN(kv, "=", 2)
+		if len(ss) == 1 {
+			ss = append(ss, "")
+		}
+		res[ss[0]] = ss[1]
+	}
+	return res
+}
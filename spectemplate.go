@@ -0,0 +1,108 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"text/template"
+)
+
+// LoadSpecTemplateFile reads the spec from the given file like LoadSpecFile,
+// but executes the file as Go template with the variables first. This allows
+// to instantiate one topology for several parallel runs or device counts:
+//
+//	run: sim-{{.Run}}
+//	containers:
+//	{{- range $i := seq .Devices}}
+//	  - name: device-{{$i}}
+//	    image: sim/device
+//	    env:
+//	      ADDRESS: 10.0.0.{{add $i 10}}
+//	{{- end}}
+//
+// Variables which are not set are an error. Besides the functions of
+// text/template the templates can use seq, add and quote, see SpecTemplate.
+// The lines of errors refer to the executed template.
+func LoadSpecTemplateFile(path string, vars map[string]interface{}) (*Spec, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b, err = SpecTemplate(filepath.Base(path), b, vars)
+	if err != nil {
+		return nil, err
+	}
+	return loadSpec(path, b)
+}
+
+// SpecTemplate executes the spec template with the variables and returns the
+// resulting document. The templates can use these functions:
+//
+//	seq N      the numbers 1 to N, e.g. for range loops
+//	add A B    the sum of two numbers
+//	quote S    S as quoted string, which is valid in YAML and JSON
+//
+// Numbers may also be passed as strings, e.g. variables from the command line.
+func SpecTemplate(name string, data []byte, vars map[string]interface{}) ([]byte, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(specFuncs).Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var specFuncs = template.FuncMap{
+	"seq": func(n interface{}) ([]int, error) {
+		i, err := templateInt(n)
+		if err != nil {
+			return nil, err
+		}
+		seq := make([]int, 0, i)
+		for k := 1; k <= i; k++ {
+			seq = append(seq, k)
+		}
+		return seq, nil
+	},
+	"add": func(a, b interface{}) (int, error) {
+		i, err := templateInt(a)
+		if err != nil {
+			return 0, err
+		}
+		k, err := templateInt(b)
+		if err != nil {
+			return 0, err
+		}
+		return i + k, nil
+	},
+	"quote": func(v interface{}) string {
+		// a JSON string is a valid double quoted YAML scalar as well
+		b, _ := json.Marshal(fmt.Sprint(v))
+		return string(b)
+	},
+}
+
+// templateInt converts numbers and numeric strings to int.
+func templateInt(v interface{}) (int, error) {
+	switch v := v.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		return int(v), nil
+	case string:
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", v)
+		}
+		return i, nil
+	}
+	return 0, fmt.Errorf("%v is not a number", v)
+}
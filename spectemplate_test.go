@@ -0,0 +1,94 @@
+package docker
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_LoadSpecTemplateFile(t *testing.T) {
+	spec, err := LoadSpecTemplateFile(testfileLocation+"spec.tmpl.yaml", map[string]interface{}{
+		"Run":     7,
+		"Devices": "2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := &Spec{
+		Run:      "sim-7",
+		Networks: []NetworkSpec{{Name: "field-7", Subnet: "10.7.0.0/24"}},
+		Containers: []ContainerSpec{
+			{
+				Name:     "meter-7-1",
+				Image:    "sim/meter",
+				Env:      map[string]string{"SERIAL": "M-001"},
+				Networks: []NetworkAttachment{{Network: "field-7", IPv4Address: "10.7.0.11"}},
+			},
+			{
+				Name:     "meter-7-2",
+				Image:    "sim/meter",
+				Env:      map[string]string{"SERIAL": "M-002"},
+				Networks: []NetworkAttachment{{Network: "field-7", IPv4Address: "10.7.0.12"}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(spec, expect) {
+		t.Errorf("got: %+v, want: %+v", spec, expect)
+	}
+}
+
+func Test_SpecTemplateErrors(t *testing.T) {
+	tt := []struct {
+		name    string
+		data    string
+		vars    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:    "missing variable",
+			data:    "run: {{.Run}}",
+			wantErr: `map has no entry for key "Run"`,
+		},
+		{
+			name:    "not a number",
+			data:    "{{range seq .N}}{{end}}",
+			vars:    map[string]interface{}{"N": "many"},
+			wantErr: `"many" is not a number`,
+		},
+		{
+			name:    "syntax",
+			data:    "run: {{.Run",
+			wantErr: "unclosed action",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := SpecTemplate("spec", []byte(tc.data), tc.vars)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("got error: %v, want: %s", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func Test_SpecTemplateQuote(t *testing.T) {
+	run := "sim \"7\"\x01é"
+	for _, data := range []string{`{"run": {{quote .Run}}}`, "run: {{quote .Run}}"} {
+		b, err := SpecTemplate("spec", []byte(data), map[string]interface{}{"Run": run})
+		if err != nil {
+			t.Fatal(err)
+		}
+		load := LoadSpecYAML
+		if strings.HasPrefix(data, "{") {
+			load = LoadSpecJSON
+		}
+		spec, err := load(b)
+		if err != nil {
+			t.Fatalf("%s: %v", b, err)
+		}
+		if spec.Run != run {
+			t.Errorf("got: %q, want: %q", spec.Run, run)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package docker
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// sshIDHeader is the gRPC metadata which selects the agent of a forwarding.
+const sshIDHeader = "buildkit.ssh.id"
+
+// BuildSSH forwards an SSH agent to RUN instructions which mount it by
+// --mount=type=ssh[,id=<ID>], e.g. to clone private git repositories. ID
+// defaults to default, Socket to the agent of SSH_AUTH_SOCK. Keys are never
+// sent to the daemon, the daemon only gets access to the agent during the
+// build.
+type BuildSSH struct {
+	ID     string
+	Socket string
+}
+
+// addSSH forwards the agents to BuildKit.
+func (s *buildSession) addSSH(agents []BuildSSH) error {
+	sockets := make(map[string]string, len(agents))
+	for _, a := range agents {
+		if a.ID == "" {
+			a.ID = "default"
+		}
+		if a.Socket == "" {
+			a.Socket = os.Getenv("SSH_AUTH_SOCK")
+			if a.Socket == "" {
+				return fmt.Errorf("ssh %s: SSH_AUTH_SOCK is not set", a.ID)
+			}
+		}
+		if _, ok := sockets[a.ID]; ok {
+			return fmt.Errorf("ssh %s: duplicate id", a.ID)
+		}
+		sockets[a.ID] = a.Socket
+	}
+
+	// CheckAgentRequest{ID = 1}, CheckAgentResponse{}
+	s.unary("/moby.sshforward.v1.SSH/CheckAgent", func(req []byte) ([]byte, error) {
+		id, err := protoString(req, 1)
+		if err != nil {
+			return nil, err
+		}
+		if id == "" {
+			id = "default"
+		}
+		socket, ok := sockets[id]
+		if !ok {
+			return nil, &grpcError{code: grpcNotFound, message: "ssh agent " + id + " not found"}
+		}
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return nil, err
+		}
+		conn.Close()
+		return nil, nil
+	})
+
+	// ForwardAgent streams BytesMessage{Data = 1} in both directions.
+	s.methods["/moby.sshforward.v1.SSH/ForwardAgent"] = func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(sshIDHeader)
+		if id == "" {
+			id = "default"
+		}
+		socket, ok := sockets[id]
+		if !ok {
+			writeGRPCStatus(w, grpcNotFound, "ssh agent "+id+" not found")
+			return
+		}
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			writeGRPCStatus(w, grpcInternal, err.Error())
+			return
+		}
+		defer conn.Close()
+		forwardStream(w, r, conn)
+	}
+	return nil
+}
+
+// forwardStream copies the messages of the gRPC stream to conn and the data
+// read from conn back as messages, until both directions are done.
+func forwardStream(w http.ResponseWriter, r *http.Request, conn net.Conn) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			msg, err := readGRPCMessage(r.Body)
+			if err != nil {
+				break
+			}
+			data, err := protoString(msg, 1)
+			if err != nil {
+				break
+			}
+			if _, err := io.WriteString(conn, data); err != nil {
+				break
+			}
+		}
+		// the agent answers outstanding requests and closes the connection
+		if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+			cw.CloseWrite()
+		} else {
+			conn.Close()
+		}
+	}()
+
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := conn.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(grpcFrame(protoBytes(nil, 1, buf[:n]))); werr != nil {
+				break
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	r.Body.Close()
+	wg.Wait()
+	writeGRPCStatus(w, grpcOK, "")
+}
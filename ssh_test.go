@@ -0,0 +1,68 @@
+//go:build go1.24
+// +build go1.24
+
+package docker
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_BuildSSH(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	// the agent echoes the requests
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	var got, status string
+	buildWithSession(t, BuildOptions{SSH: []BuildSSH{{ID: "git", Socket: socket}}}, func(rt http.RoundTripper) {
+		pr, pw := io.Pipe()
+		req, _ := http.NewRequest(http.MethodPost, "http://session/moby.sshforward.v1.SSH/ForwardAgent", pr)
+		req.Header.Set("Content-Type", "application/grpc")
+		req.Header.Set("buildkit.ssh.id", "git")
+		go pw.Write(grpcFrame(protoBytes(nil, 1, []byte("request-identities"))))
+		r, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer r.Body.Close()
+		msg, err := readGRPCMessage(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		got, _ = protoString(msg, 1)
+		pw.Close()
+		ioutil.ReadAll(r.Body)
+		status = r.Trailer.Get("Grpc-Status")
+	})
+	if got != "request-identities" || status != "0" {
+		t.Errorf("unexpected forwarding: got %q, status %q", got, status)
+	}
+}
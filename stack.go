@@ -0,0 +1,117 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+)
+
+// LabelStack is the label docker stack uses to mark the resources of a
+// stack.
+const LabelStack = "com.docker.stack.namespace"
+
+// StackDeploy deploys the compose file as swarm stack: every service becomes
+// a swarm service named <name>_<service>, networks are created as attachable
+// overlay networks. Like docker stack deploy, existing services are updated
+// and services of the stack which are not part of the compose file anymore
+// are removed. The deploy section of a service sets its replicas, placement
+// constraints and labels. The client has to talk to a manager node.
+func (c *Client) StackDeploy(ctx context.Context, name string, composeBytes []byte) ([]Operation, error) {
+	compose, err := LoadCompose(composeBytes)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := compose.Spec(name)
+	if err != nil {
+		return nil, err
+	}
+	var ops []Operation
+
+	for _, n := range spec.Networks {
+		if _, err := c.networkByName(ctx, n.Name); err == nil {
+			continue
+		}
+		if n.Driver == "" || n.Driver == "bridge" {
+			n.Driver = "overlay"
+		}
+		n.Labels = copyLabels(n.Labels)
+		n.Labels[LabelStack] = name
+		ops = append(ops, Operation{Action: "create", Resource: "network", Name: n.Name})
+		if _, err := c.createNetwork(ctx, n.createBody()); err != nil {
+			return ops, fmt.Errorf("create network %s: %w", n.Name, err)
+		}
+	}
+
+	services, err := stackServices(name, compose, spec)
+	if err != nil {
+		return ops, err
+	}
+	existing, err := c.ListServices(ctx, Selector{LabelStack: name})
+	if err != nil {
+		return ops, err
+	}
+	current := make(map[string]string, len(existing))
+	for _, s := range existing {
+		current[s.Spec.Name] = s.ID
+	}
+
+	for _, svc := range services {
+		id, ok := current[svc.Name]
+		delete(current, svc.Name)
+		if ok {
+			ops = append(ops, Operation{Action: "update", Resource: "service", Name: svc.Name})
+			if err := c.UpdateService(ctx, id, svc); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = append(ops, Operation{Action: "create", Resource: "service", Name: svc.Name})
+		if _, err := c.CreateService(ctx, svc); err != nil {
+			return ops, err
+		}
+	}
+	for _, svcName := range sortedKeys(current) {
+		ops = append(ops, Operation{Action: "remove", Resource: "service", Name: svcName})
+		if err := c.RemoveService(ctx, current[svcName]); err != nil {
+			return ops, err
+		}
+	}
+	return ops, nil
+}
+
+// stackServices converts the containers of the spec into service specs.
+func stackServices(name string, compose *Compose, spec *Spec) ([]ServiceSpec, error) {
+	var services []ServiceSpec
+	for _, svcName := range sortedKeys(compose.Services) {
+		svc := compose.Services[svcName]
+		if svc.ContainerName != "" {
+			return nil, fmt.Errorf("service %s: container_name is not supported by stacks", svcName)
+		}
+		cs := spec.container(name + "_" + svcName)
+		if cs == nil {
+			return nil, fmt.Errorf("service %s: missing in spec", svcName)
+		}
+		ss := ServiceSpec{
+			Name:   cs.Name,
+			Image:  cs.Image,
+			Cmd:    cs.Cmd,
+			Env:    cs.Env,
+			Labels: map[string]string{LabelStack: name},
+			Ports:  cs.Ports,
+			Mounts: cs.Mounts,
+		}
+		for _, a := range cs.Networks {
+			ss.Networks = append(ss.Networks, NetworkAttachment{Network: a.Network, Aliases: a.Aliases})
+		}
+		if d := svc.Deploy; d != nil {
+			ss.Replicas = d.Replicas
+			for k, v := range d.Labels {
+				ss.Labels[k] = v
+			}
+			if d.Placement != nil {
+				ss.Constraints = d.Placement.Constraints
+			}
+		}
+		services = append(services, ss)
+	}
+	return services, nil
+}
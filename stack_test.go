@@ -0,0 +1,90 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+const stackCompose = `
+services:
+  db:
+    image: postgres:9.4
+    networks: [field]
+    deploy:
+      replicas: 2
+      labels:
+        tier: data
+      placement:
+        constraints: ["node.role==worker"]
+  web:
+    image: nginx
+    ports: ["8080:80"]
+    networks: [field]
+networks:
+  field: {}
+`
+
+func Test_StackDeploy(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /networks":                      {Body: `[]`},
+		"POST /networks/create":              {StatusCode: http.StatusCreated, Body: `{"Id": "n1"}`},
+		"GET /services":                      {Body: `[{"ID": "s1", "Spec": {"Name": "sim_db"}}, {"ID": "s2", "Spec": {"Name": "sim_old"}}]`},
+		"GET /services/s1":                   {Body: `{"ID": "s1", "Version": {"Index": 3}}`},
+		"POST /services/s1/update?version=3": {Body: `{}`},
+		"POST /services/create":              {StatusCode: http.StatusCreated, Body: `{"ID": "s3"}`},
+		"DELETE /services/s2":                {},
+	})
+	defer srv.route(nil)
+
+	ops, err := client.StackDeploy(context.Background(), "sim", []byte(stackCompose))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Operation{
+		{Action: "create", Resource: "network", Name: "sim_field"},
+		{Action: "update", Resource: "service", Name: "sim_db"},
+		{Action: "create", Resource: "service", Name: "sim_web"},
+		{Action: "remove", Resource: "service", Name: "sim_old"},
+	}
+	if !reflect.DeepEqual(ops, want) {
+		t.Errorf("want %+v, got %+v", want, ops)
+	}
+}
+
+func Test_stackServices(t *testing.T) {
+	compose, err := LoadCompose([]byte(stackCompose))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := compose.Spec("sim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	services, err := stackServices("sim", compose, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	two := 2
+	want := []ServiceSpec{
+		{
+			Name:        "sim_db",
+			Image:       "postgres:9.4",
+			Labels:      map[string]string{LabelStack: "sim", "tier": "data"},
+			Replicas:    &two,
+			Networks:    []NetworkAttachment{{Network: "sim_field", Aliases: []string{"db"}}},
+			Constraints: []string{"node.role==worker"},
+		},
+		{
+			Name:     "sim_web",
+			Image:    "nginx",
+			Labels:   map[string]string{LabelStack: "sim"},
+			Ports:    []string{"8080:80"},
+			Networks: []NetworkAttachment{{Network: "sim_field", Aliases: []string{"web"}}},
+		},
+	}
+	if !reflect.DeepEqual(services, want) {
+		t.Errorf("want %+v, got %+v", want, services)
+	}
+}
@@ -0,0 +1,90 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/grid-x/docker/types"
+)
+
+// ContainerStats returns a sample of the resource usage of the container.
+// The daemon takes about a second to answer, it samples the CPU usage twice.
+func (c *Client) ContainerStats(ctx context.Context, id string) (*types.Stats, error) {
+	var res types.Stats
+	err := c.doJSON(ctx, http.MethodGet, "containers/"+pathName(id)+"/stats", url.Values{"stream": {"0"}}, nil, &res, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("stats of %s: %w", id, err)
+	}
+	return &res, nil
+}
+
+// StatsSample is the resource usage of a container derived from its stats,
+// computed like docker stats does.
+type StatsSample struct {
+	Time      time.Time `json:"time"`
+	Container string    `json:"container"`
+	// CPUPercent is the CPU usage in percent of one CPU, e.g. 200 for two
+	// fully used CPUs.
+	CPUPercent float64 `json:"cpu_percent"`
+	// CPUSeconds is the CPU time used since the container started.
+	CPUSeconds float64 `json:"cpu_seconds"`
+	// MemoryUsage excludes the inactive page cache.
+	MemoryUsage uint64 `json:"memory_usage"`
+	MemoryLimit uint64 `json:"memory_limit"`
+	// NetworkRx and NetworkTx are summed over all interfaces.
+	NetworkRx  uint64 `json:"network_rx"`
+	NetworkTx  uint64 `json:"network_tx"`
+	BlockRead  uint64 `json:"block_read"`
+	BlockWrite uint64 `json:"block_write"`
+	PIDs       uint64 `json:"pids"`
+}
+
+// NewStatsSample derives the sample of the container from its stats.
+func NewStatsSample(container string, s *types.Stats) StatsSample {
+	res := StatsSample{
+		Time:        s.Read,
+		Container:   container,
+		CPUSeconds:  float64(s.CPUStats.CPUUsage.TotalUsage) / 1e9,
+		MemoryUsage: memoryUsage(s.MemoryStats),
+		MemoryLimit: s.MemoryStats.Limit,
+		PIDs:        s.PidsStats.Current,
+	}
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	cpus := float64(s.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		res.CPUPercent = cpuDelta / systemDelta * cpus * 100
+	}
+	for _, n := range s.Networks {
+		res.NetworkRx += n.RxBytes
+		res.NetworkTx += n.TxBytes
+	}
+	for _, e := range s.BlkioStats.IOServiceBytesRecursive {
+		switch e.Op {
+		case "read", "Read":
+			res.BlockRead += e.Value
+		case "write", "Write":
+			res.BlockWrite += e.Value
+		}
+	}
+	return res
+}
+
+// memoryUsage returns the used memory without the inactive page cache, the
+// key differs between cgroup v1 and v2.
+func memoryUsage(m types.MemoryStats) uint64 {
+	cache, ok := m.Stats["total_inactive_file"]
+	if !ok {
+		cache = m.Stats["inactive_file"]
+	}
+	if cache > m.Usage {
+		return 0
+	}
+	return m.Usage - cache
+}
@@ -0,0 +1,83 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grid-x/docker/types"
+)
+
+const statsBody = `{
+	"read": "2021-06-01T12:00:00Z",
+	"cpu_stats": {"cpu_usage": {"total_usage": 3000000000}, "system_cpu_usage": 20000000000, "online_cpus": 4},
+	"precpu_stats": {"cpu_usage": {"total_usage": 2000000000}, "system_cpu_usage": 16000000000},
+	"memory_stats": {"usage": 1000, "limit": 4000, "stats": {"inactive_file": 200}},
+	"blkio_stats": {"io_service_bytes_recursive": [{"op": "read", "value": 10}, {"op": "write", "value": 20}]},
+	"pids_stats": {"current": 3},
+	"networks": {"eth0": {"rx_bytes": 100, "tx_bytes": 50}, "eth1": {"rx_bytes": 1, "tx_bytes": 2}}
+}`
+
+func Test_NewStatsSample(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /containers/c1/stats?stream=0": {Body: statsBody},
+	})
+	defer srv.route(nil)
+
+	stats, err := client.ContainerStats(context.Background(), "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStatsSample("plc", stats)
+	want := StatsSample{Time: time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC), Container: "plc",
+		CPUPercent: 100, CPUSeconds: 3, MemoryUsage: 800, MemoryLimit: 4000,
+		NetworkRx: 101, NetworkTx: 52, BlockRead: 10, BlockWrite: 20, PIDs: 3}
+	if !s.Time.Equal(want.Time) {
+		t.Errorf("want time %v, got %v", want.Time, s.Time)
+	}
+	s.Time = want.Time
+	if s != want {
+		t.Errorf("want %+v, got %+v", want, s)
+	}
+
+	if u := memoryUsage(types.MemoryStats{Usage: 100, Stats: map[string]uint64{"total_inactive_file": 300}}); u != 0 {
+		t.Errorf("expected usage 0, got %d", u)
+	}
+}
+
+func Test_StatsExporter(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /containers/json":              {Body: `[{"Id": "c2", "Names": ["/meter"]}, {"Id": "c1", "Names": ["/plc"]}]`},
+		"GET /containers/c1/stats?stream=0": {Body: statsBody},
+		"GET /containers/c2/stats?stream=0": {Body: statsBody},
+	})
+	defer srv.route(nil)
+
+	for _, tc := range []struct {
+		format StatsFormat
+		want   []string
+	}{
+		{StatsCSV, []string{
+			"time,container,cpu_percent,cpu_seconds,memory_usage,memory_limit,network_rx,network_tx,block_read,block_write,pids",
+			"2021-06-01T12:00:00Z,meter,100.000,3.000,800,4000,101,52,10,20,3",
+			"2021-06-01T12:00:00Z,plc,100.000,3.000,800,4000,101,52,10,20,3",
+		}},
+		{StatsJSONLines, []string{
+			`{"time":"2021-06-01T12:00:00Z","container":"meter","cpu_percent":100,"cpu_seconds":3,"memory_usage":800,"memory_limit":4000,"network_rx":101,"network_tx":52,"block_read":10,"block_write":20,"pids":3}`,
+			`{"time":"2021-06-01T12:00:00Z","container":"plc","cpu_percent":100,"cpu_seconds":3,"memory_usage":800,"memory_limit":4000,"network_rx":101,"network_tx":52,"block_read":10,"block_write":20,"pids":3}`,
+		}},
+	} {
+		var buf bytes.Buffer
+		e := NewStatsExporter(client, RunSelector("r1"), &buf, StatsExportOptions{Interval: time.Hour, Format: tc.format})
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		if err := e.Run(ctx); err != context.DeadlineExceeded {
+			t.Fatalf("%s: expected deadline exceeded, got %v", tc.format, err)
+		}
+		cancel()
+		if got := strings.Split(strings.TrimSpace(buf.String()), "\n"); strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("%s: want\n%s\ngot\n%s", tc.format, strings.Join(tc.want, "\n"), buf.String())
+		}
+	}
+}
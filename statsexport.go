@@ -0,0 +1,146 @@
+package docker
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// StatsFormat is the format of the time series written by a StatsExporter.
+type StatsFormat string
+
+const (
+	// StatsCSV writes a header followed by one row per sample.
+	StatsCSV StatsFormat = "csv"
+	// StatsJSONLines writes one JSON encoded StatsSample per line.
+	StatsJSONLines StatsFormat = "jsonl"
+)
+
+// DefaultStatsInterval is the interval at which a StatsExporter samples by
+// default.
+const DefaultStatsInterval = 5 * time.Second
+
+// StatsExportOptions configure a StatsExporter.
+type StatsExportOptions struct {
+	// Interval between samples, defaults to DefaultStatsInterval.
+	Interval time.Duration
+	// Format defaults to StatsCSV.
+	Format StatsFormat
+}
+
+// statsColumns is the CSV header.
+var statsColumns = []string{"time", "container", "cpu_percent", "cpu_seconds", "memory_usage", "memory_limit",
+	"network_rx", "network_tx", "block_read", "block_write", "pids"}
+
+// StatsExporter samples the resource usage of the running containers
+// matching a selector and writes it as time series. Every sample is a row
+// per container, the container is identified by its name.
+type StatsExporter struct {
+	c        *Client
+	selector Selector
+	w        io.Writer
+	opts     StatsExportOptions
+
+	csv *csv.Writer
+}
+
+// NewStatsExporter returns an exporter writing to w.
+func NewStatsExporter(c *Client, selector Selector, w io.Writer, opts StatsExportOptions) *StatsExporter {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultStatsInterval
+	}
+	if opts.Format == "" {
+		opts.Format = StatsCSV
+	}
+	return &StatsExporter{c: c, selector: selector, w: w, opts: opts}
+}
+
+// Run samples until the context is done and returns its error. Samples
+// failing to list the containers are skipped, as are containers which stop
+// between listing and sampling. Failing to write aborts the export.
+func (e *StatsExporter) Run(ctx context.Context) error {
+	if e.opts.Format != StatsCSV && e.opts.Format != StatsJSONLines {
+		return fmt.Errorf("unknown stats format %q", e.opts.Format)
+	}
+	t := time.NewTicker(e.opts.Interval)
+	defer t.Stop()
+	for {
+		if samples, err := e.sample(ctx); err == nil {
+			if err := e.write(samples); err != nil {
+				return fmt.Errorf("export stats: %w", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// sample samples all matching containers, ordered by name.
+func (e *StatsExporter) sample(ctx context.Context) ([]StatsSample, error) {
+	containers, err := e.c.listContainers(ctx, false, e.selector.filters())
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		samples []StatsSample
+	)
+	for _, cs := range containers {
+		wg.Add(1)
+		go func(id, name string) {
+			defer wg.Done()
+			s, err := e.c.ContainerStats(ctx, id)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			samples = append(samples, NewStatsSample(name, s))
+			mu.Unlock()
+		}(cs.ID, cs.Name())
+	}
+	wg.Wait()
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Container < samples[j].Container })
+	return samples, nil
+}
+
+func (e *StatsExporter) write(samples []StatsSample) error {
+	if e.opts.Format == StatsJSONLines {
+		enc := json.NewEncoder(e.w)
+		for _, s := range samples {
+			if err := enc.Encode(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if e.csv == nil {
+		e.csv = csv.NewWriter(e.w)
+		if err := e.csv.Write(statsColumns); err != nil {
+			return err
+		}
+	}
+	u := func(v uint64) string { return strconv.FormatUint(v, 10) }
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 3, 64) }
+	for _, s := range samples {
+		err := e.csv.Write([]string{s.Time.UTC().Format(time.RFC3339Nano), s.Container, f(s.CPUPercent),
+			f(s.CPUSeconds), u(s.MemoryUsage), u(s.MemoryLimit), u(s.NetworkRx), u(s.NetworkTx),
+			u(s.BlockRead), u(s.BlockWrite), u(s.PIDs)})
+		if err != nil {
+			return err
+		}
+	}
+	e.csv.Flush()
+	return e.csv.Error()
+}
@@ -0,0 +1,151 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// StrictOptions configures the strict mode of a client.
+type StrictOptions struct {
+	// Logf logs the unknown fields. Defaults to log.Printf.
+	Logf func(format string, args ...interface{})
+}
+
+// DriftError is returned in strict mode if a response of the daemon lacks
+// fields the client relies on, which usually means the daemon speaks a
+// different version of the API, e.g. a newer engine or Podman.
+type DriftError struct {
+	// Path is the path of the request.
+	Path string
+	// Missing are the missing fields as JSON paths, e.g. State.Status.
+	Missing []string
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("api drift in response of %s: missing required fields %s",
+		e.Path, strings.Join(e.Missing, ", "))
+}
+
+// SetStrict enables the strict mode for the client, nil disables it. In
+// strict mode every JSON response of the daemon is validated against the
+// types of the client: fields the client does not know are logged once per
+// type, missing fields the client can not work without fail the request
+// with a *DriftError. As the client only models the part of the API it
+// uses, unknown fields are expected, they hint at changes of the API
+// though. SetStrict has to be called before the client is used.
+func (c *Client) SetStrict(opts *StrictOptions) {
+	if opts == nil {
+		c.strict = nil
+		return
+	}
+	s := &strictMode{logf: opts.Logf, seen: make(map[string]bool)}
+	if s.logf == nil {
+		s.logf = log.Printf
+	}
+	c.strict = s
+}
+
+type strictMode struct {
+	logf func(format string, args ...interface{})
+
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// check validates the JSON body against the type out will be decoded into.
+func (s *strictMode) check(path string, body []byte, out interface{}) error {
+	var raw interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return err
+	}
+	t := reflect.TypeOf(out)
+	var unknown, missing []string
+	walkJSON(t, raw, "", &unknown, &missing)
+
+	s.mu.Lock()
+	for _, f := range unknown {
+		key := t.String() + " " + f
+		if !s.seen[key] {
+			s.seen[key] = true
+			s.logf("docker: unknown field %s in response of %s", f, path)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return &DriftError{Path: path, Missing: missing}
+	}
+	return nil
+}
+
+// walkJSON compares the decoded JSON value v with the type t and collects
+// the paths of fields t does not know and of required fields v lacks.
+// Fields are required if tagged with strict:"required".
+func walkJSON(t reflect.Type, v interface{}, path string, unknown, missing *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		// encoding/json matches names case insensitively
+		names := make(map[string]string, len(fields))
+		for name := range fields {
+			names[strings.ToLower(name)] = name
+		}
+		for k, fv := range obj {
+			name, ok := names[strings.ToLower(k)]
+			if !ok {
+				*unknown = append(*unknown, joinPath(path, k))
+				continue
+			}
+			walkJSON(fields[name].Type, fv, joinPath(path, k), unknown, missing)
+		}
+		for name, f := range fields {
+			if f.Tag.Get("strict") != "required" {
+				continue
+			}
+			if fv, ok := lookupFold(obj, name); !ok || fv == nil {
+				*missing = append(*missing, joinPath(path, name))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		arr, ok := v.([]interface{})
+		if !ok {
+			return
+		}
+		for _, e := range arr {
+			walkJSON(t.Elem(), e, path+"[]", unknown, missing)
+		}
+	case reflect.Map:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for _, e := range obj {
+			walkJSON(t.Elem(), e, joinPath(path, "*"), unknown, missing)
+		}
+	}
+}
+
+func lookupFold(obj map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := obj[name]; ok {
+		return v, true
+	}
+	for k, v := range obj {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,45 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func Test_StrictMode(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /containers/c1/json": {Body: `{"Id": "c1", "Name": "/plc", "Platform": "linux",
+			"State": {"Status": "running", "Running": true, "Oom": false}}`},
+		"GET /containers/c2/json": {Body: `{"Id": "c2", "State": {"Running": true}}`},
+	})
+	defer srv.route(nil)
+	defer client.SetStrict(nil)
+
+	var logged []string
+	client.SetStrict(&StrictOptions{Logf: func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.inspectContainer(ctx, "c1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{
+		"docker: unknown field Platform in response of containers/c1/json",
+		"docker: unknown field State.Oom in response of containers/c1/json",
+	}
+	// the order of the fields is random, every one is logged once
+	if len(logged) != 2 || !(reflect.DeepEqual(logged, want) || reflect.DeepEqual(logged, []string{want[1], want[0]})) {
+		t.Errorf("want %v, got %v", want, logged)
+	}
+
+	_, err := client.inspectContainer(ctx, "c2")
+	var drift *DriftError
+	if !errors.As(err, &drift) || !reflect.DeepEqual(drift.Missing, []string{"Name", "State.Status"}) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
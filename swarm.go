@@ -0,0 +1,358 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServiceSpec describes a swarm service. Ports are published by the routing
+// mesh as: ["[<publishedPort>:]<port>[/<tcp|udp>]"], Mounts are given like
+// for containers, host IPs of ports are not supported by the routing mesh.
+// Static IPv4 addresses of network attachments are not supported for
+// services. Replicas is the number of tasks, nil means one. Constraints
+// restrict the nodes the tasks are placed on, e.g.
+// ["node.labels.site==plant-1"].
+type ServiceSpec struct {
+	Name        string              `json:"name"`
+	Image       string              `json:"image"`
+	Cmd         []string            `json:"cmd,omitempty"`
+	Env         map[string]string   `json:"env,omitempty"`
+	Labels      map[string]string   `json:"labels,omitempty"`
+	Replicas    *int                `json:"replicas,omitempty"`
+	Networks    []NetworkAttachment `json:"networks,omitempty"`
+	Ports       []string            `json:"ports,omitempty"`
+	Mounts      []string            `json:"mounts,omitempty"`
+	Constraints []string            `json:"constraints,omitempty"`
+}
+
+// Service is a service of the swarm.
+type Service struct {
+	ID        string
+	Version   uint64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Spec      ServiceSpec
+}
+
+type swarmServiceSpec struct {
+	Name         string            `json:"Name"`
+	Labels       map[string]string `json:"Labels,omitempty"`
+	TaskTemplate struct {
+		ContainerSpec struct {
+			Image   string            `json:"Image"`
+			Args    []string          `json:"Args,omitempty"`
+			Env     []string          `json:"Env,omitempty"`
+			Labels  map[string]string `json:"Labels,omitempty"`
+			Mounts  []mount           `json:"Mounts,omitempty"`
+			Command []string          `json:"Command,omitempty"`
+		} `json:"ContainerSpec"`
+		Placement *struct {
+			Constraints []string `json:"Constraints,omitempty"`
+		} `json:"Placement,omitempty"`
+		Networks []swarmNetworkAttachment `json:"Networks,omitempty"`
+	} `json:"TaskTemplate"`
+	Mode struct {
+		Replicated *struct {
+			Replicas uint64 `json:"Replicas"`
+		} `json:"Replicated,omitempty"`
+	} `json:"Mode"`
+	EndpointSpec *struct {
+		Ports []swarmPort `json:"Ports,omitempty"`
+	} `json:"EndpointSpec,omitempty"`
+}
+
+type swarmNetworkAttachment struct {
+	Target  string   `json:"Target"`
+	Aliases []string `json:"Aliases,omitempty"`
+}
+
+type swarmPort struct {
+	Protocol      string `json:"Protocol"`
+	TargetPort    int    `json:"TargetPort"`
+	PublishedPort int    `json:"PublishedPort,omitempty"`
+	PublishMode   string `json:"PublishMode,omitempty"`
+}
+
+type swarmService struct {
+	ID      string `json:"ID"`
+	Version struct {
+		Index uint64 `json:"Index"`
+	} `json:"Version"`
+	CreatedAt time.Time        `json:"CreatedAt"`
+	UpdatedAt time.Time        `json:"UpdatedAt"`
+	Spec      swarmServiceSpec `json:"Spec"`
+}
+
+// engineSpec converts the spec into the service spec of the engine API.
+func (s ServiceSpec) engineSpec() (*swarmServiceSpec, error) {
+	var es swarmServiceSpec
+	es.Name = s.Name
+	es.Labels = s.Labels
+	cs := &es.TaskTemplate.ContainerSpec
+	cs.Image = s.Image
+	cs.Args = s.Cmd
+	keys := make([]string, 0, len(s.Env))
+	for k := range s.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		cs.Env = append(cs.Env, k+"="+s.Env[k])
+	}
+	for _, m := range s.Mounts {
+		mnt, err := parseMount(m)
+		if err != nil {
+			return nil, err
+		}
+		mnt.Consistency = ""
+		cs.Mounts = append(cs.Mounts, mnt)
+	}
+	if len(s.Constraints) > 0 {
+		es.TaskTemplate.Placement = &struct {
+			Constraints []string `json:"Constraints,omitempty"`
+		}{Constraints: s.Constraints}
+	}
+	for _, n := range s.Networks {
+		es.TaskTemplate.Networks = append(es.TaskTemplate.Networks,
+			swarmNetworkAttachment{Target: n.Network, Aliases: n.Aliases})
+	}
+	replicas := 1
+	if s.Replicas != nil {
+		if replicas = *s.Replicas; replicas < 0 {
+			return nil, fmt.Errorf("invalid number of replicas %d", replicas)
+		}
+	}
+	es.Mode.Replicated = &struct {
+		Replicas uint64 `json:"Replicas"`
+	}{Replicas: uint64(replicas)}
+
+	for _, p := range s.Ports {
+		port, binding, err := parsePortBinding(p)
+		if err != nil {
+			return nil, err
+		}
+		if binding.HostIP != "" {
+			return nil, fmt.Errorf("invalid port %q: the routing mesh publishes on all addresses", p)
+		}
+		ss := strings.SplitN(port, "/", 2)
+		target, err := strconv.Atoi(ss[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", p)
+		}
+		sp := swarmPort{Protocol: ss[1], TargetPort: target, PublishMode: "ingress"}
+		if binding.HostPort != "" {
+			if sp.PublishedPort, err = strconv.Atoi(binding.HostPort); err != nil {
+				return nil, fmt.Errorf("invalid port %q", p)
+			}
+		}
+		if es.EndpointSpec == nil {
+			es.EndpointSpec = &struct {
+				Ports []swarmPort `json:"Ports,omitempty"`
+			}{}
+		}
+		es.EndpointSpec.Ports = append(es.EndpointSpec.Ports, sp)
+	}
+	return &es, nil
+}
+
+// spec converts the engine service spec back. Mounts are not restored.
+func (es *swarmServiceSpec) spec() ServiceSpec {
+	cs := es.TaskTemplate.ContainerSpec
+	s := ServiceSpec{
+		Name:   es.Name,
+		Image:  cs.Image,
+		Cmd:    cs.Args,
+		Labels: es.Labels,
+	}
+	for _, e := range cs.Env {
+		if s.Env == nil {
+			s.Env = make(map[string]string)
+		}
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) == 2 {
+			s.Env[kv[0]] = kv[1]
+		} else {
+			s.Env[kv[0]] = ""
+		}
+	}
+	if es.Mode.Replicated != nil {
+		replicas := int(es.Mode.Replicated.Replicas)
+		s.Replicas = &replicas
+	}
+	if es.TaskTemplate.Placement != nil {
+		s.Constraints = es.TaskTemplate.Placement.Constraints
+	}
+	for _, n := range es.TaskTemplate.Networks {
+		s.Networks = append(s.Networks, NetworkAttachment{Network: n.Target, Aliases: n.Aliases})
+	}
+	if es.EndpointSpec != nil {
+		for _, p := range es.EndpointSpec.Ports {
+			port := fmt.Sprintf("%d/%s", p.TargetPort, p.Protocol)
+			if p.PublishedPort != 0 {
+				port = fmt.Sprintf("%d:%s", p.PublishedPort, port)
+			}
+			s.Ports = append(s.Ports, port)
+		}
+	}
+	return s
+}
+
+// CreateService creates a replicated service on the swarm and returns its
+// ID. The client has to talk to a manager node.
+// docs.: https://docs.docker.com/engine/api/v1.41/#operation/ServiceCreate
+func (c *Client) CreateService(ctx context.Context, spec ServiceSpec) (string, error) {
+	if spec.Name == "" || spec.Image == "" {
+		return "", fmt.Errorf("create service: name and image must not be empty")
+	}
+	body, err := spec.engineSpec()
+	if err != nil {
+		return "", fmt.Errorf("create service %s: %w", spec.Name, err)
+	}
+	var res createResponse
+	if err := c.doJSON(ctx, http.MethodPost, "services/create", nil, body, &res, http.StatusCreated); err != nil {
+		return "", fmt.Errorf("create service %s: %w", spec.Name, err)
+	}
+	return res.ID, nil
+}
+
+// ListServices returns the services matching the selector.
+func (c *Client) ListServices(ctx context.Context, selector Selector) ([]Service, error) {
+	var res []swarmService
+	if err := c.doJSON(ctx, http.MethodGet, "services", filterQuery(selector.filters()), nil, &res, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("list services: %w", err)
+	}
+	services := make([]Service, len(res))
+	for i, s := range res {
+		services[i] = Service{
+			ID:        s.ID,
+			Version:   s.Version.Index,
+			CreatedAt: s.CreatedAt,
+			UpdatedAt: s.UpdatedAt,
+			Spec:      s.Spec.spec(),
+		}
+	}
+	return services, nil
+}
+
+// UpdateService replaces the spec of the service identified by ID or name.
+// The tasks are updated by the rolling update policy of the service.
+func (c *Client) UpdateService(ctx context.Context, id string, spec ServiceSpec) error {
+	var cur swarmService
+	if err := c.doJSON(ctx, http.MethodGet, "services/"+pathName(id), nil, nil, &cur, http.StatusOK); err != nil {
+		return fmt.Errorf("inspect service %s: %w", id, err)
+	}
+	body, err := spec.engineSpec()
+	if err != nil {
+		return fmt.Errorf("update service %s: %w", id, err)
+	}
+	q := url.Values{"version": {strconv.FormatUint(cur.Version.Index, 10)}}
+	if err := c.doJSON(ctx, http.MethodPost, "services/"+pathName(cur.ID)+"/update", q, body, nil, http.StatusOK); err != nil {
+		return fmt.Errorf("update service %s: %w", id, err)
+	}
+	return nil
+}
+
+// RemoveService removes the service and all its tasks.
+func (c *Client) RemoveService(ctx context.Context, id string) error {
+	if err := c.doJSON(ctx, http.MethodDelete, "services/"+pathName(id), nil, nil, nil, http.StatusOK); err != nil {
+		return fmt.Errorf("remove service %s: %w", id, err)
+	}
+	return nil
+}
+
+// Node is a node of the swarm.
+type Node struct {
+	ID       string
+	Hostname string
+	// Role is manager or worker.
+	Role string
+	// Availability is active, pause or drain.
+	Availability string
+	// State is unknown, down, ready or disconnected.
+	State string
+	Addr  string
+	// Labels are the labels of the node set by swarm managers, EngineLabels
+	// those of the daemon configuration.
+	Labels       map[string]string
+	EngineLabels map[string]string
+	NanoCPUs     int64
+	MemoryBytes  int64
+	Leader       bool
+}
+
+type swarmNode struct {
+	ID   string `json:"ID"`
+	Spec struct {
+		Labels       map[string]string `json:"Labels"`
+		Role         string            `json:"Role"`
+		Availability string            `json:"Availability"`
+	} `json:"Spec"`
+	Description struct {
+		Hostname  string `json:"Hostname"`
+		Resources struct {
+			NanoCPUs    int64 `json:"NanoCPUs"`
+			MemoryBytes int64 `json:"MemoryBytes"`
+		} `json:"Resources"`
+		Engine struct {
+			Labels map[string]string `json:"Labels"`
+		} `json:"Engine"`
+	} `json:"Description"`
+	Status struct {
+		State string `json:"State"`
+		Addr  string `json:"Addr"`
+	} `json:"Status"`
+	ManagerStatus *struct {
+		Leader bool `json:"Leader"`
+	} `json:"ManagerStatus"`
+}
+
+func (n *swarmNode) node() Node {
+	return Node{
+		ID:           n.ID,
+		Hostname:     n.Description.Hostname,
+		Role:         n.Spec.Role,
+		Availability: n.Spec.Availability,
+		State:        n.Status.State,
+		Addr:         n.Status.Addr,
+		Labels:       n.Spec.Labels,
+		EngineLabels: n.Description.Engine.Labels,
+		NanoCPUs:     n.Description.Resources.NanoCPUs,
+		MemoryBytes:  n.Description.Resources.MemoryBytes,
+		Leader:       n.ManagerStatus != nil && n.ManagerStatus.Leader,
+	}
+}
+
+// ListNodes returns the nodes of the swarm whose node labels match the
+// selector.
+// docs.: https://docs.docker.com/engine/api/v1.41/#operation/NodeList
+func (c *Client) ListNodes(ctx context.Context, selector Selector) ([]Node, error) {
+	var filters map[string][]string
+	if f := selector.filters(); f != nil {
+		filters = map[string][]string{"node.label": f["label"]}
+	}
+	var res []swarmNode
+	if err := c.doJSON(ctx, http.MethodGet, "nodes", filterQuery(filters), nil, &res, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("list nodes: %w", err)
+	}
+	nodes := make([]Node, len(res))
+	for i := range res {
+		nodes[i] = res[i].node()
+	}
+	return nodes, nil
+}
+
+// InspectNode returns the node identified by ID or hostname.
+func (c *Client) InspectNode(ctx context.Context, id string) (*Node, error) {
+	var res swarmNode
+	if err := c.doJSON(ctx, http.MethodGet, "nodes/"+pathName(id), nil, nil, &res, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("inspect node %s: %w", id, err)
+	}
+	n := res.node()
+	return &n, nil
+}
@@ -0,0 +1,114 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func Test_ServiceSpecRoundTrip(t *testing.T) {
+	replicas := 50
+	spec := ServiceSpec{
+		Name:        "plc",
+		Image:       "sim/plc:1",
+		Cmd:         []string{"--slaves", "10"},
+		Env:         map[string]string{"MODE": "tcp"},
+		Labels:      map[string]string{LabelRun: "r1"},
+		Replicas:    &replicas,
+		Networks:    []NetworkAttachment{{Network: "field", Aliases: []string{"plc"}}},
+		Ports:       []string{"8502:502/tcp", "161/udp"},
+		Constraints: []string{"node.role==worker"},
+	}
+	es, err := spec.engineSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the engine spec has to survive the JSON round trip
+	b, _ := json.Marshal(es)
+	var decoded swarmServiceSpec
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.spec(); !reflect.DeepEqual(got, spec) {
+		t.Errorf("want %+v, got %+v", spec, got)
+	}
+}
+
+func Test_ServiceSpecDefaults(t *testing.T) {
+	es, err := ServiceSpec{Name: "plc", Image: "sim/plc"}.engineSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if es.Mode.Replicated.Replicas != 1 {
+		t.Errorf("want 1 replica by default, got %d", es.Mode.Replicated.Replicas)
+	}
+	zero := 0
+	if es, err := (ServiceSpec{Name: "plc", Image: "sim/plc", Replicas: &zero}).engineSpec(); err != nil || es.Mode.Replicated.Replicas != 0 {
+		t.Errorf("want 0 replicas, got %+v, %v", es, err)
+	}
+	if _, err := (ServiceSpec{Name: "plc", Image: "sim/plc", Ports: []string{"127.0.0.1:8502:502"}}).engineSpec(); err == nil {
+		t.Error("expected error for a host IP")
+	}
+}
+
+func Test_Services(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"POST /services/create":              {StatusCode: http.StatusCreated, Body: `{"ID": "s1"}`},
+		"GET /services":                      {Body: `[{"ID": "s1", "Version": {"Index": 7}, "Spec": {"Name": "plc", "Mode": {"Replicated": {"Replicas": 3}}}}]`},
+		"GET /services/plc":                  {Body: `{"ID": "s1", "Version": {"Index": 7}}`},
+		"POST /services/s1/update?version=7": {Body: `{}`},
+		"DELETE /services/s1":                {},
+	})
+	defer srv.route(nil)
+	ctx := context.Background()
+
+	replicas := 3
+	id, err := client.CreateService(ctx, ServiceSpec{Name: "plc", Image: "sim/plc", Replicas: &replicas})
+	if err != nil || id != "s1" {
+		t.Fatalf("unexpected result %s, %v", id, err)
+	}
+	services, err := client.ListServices(ctx, RunSelector("r1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || services[0].Version != 7 || *services[0].Spec.Replicas != 3 {
+		t.Errorf("unexpected services %+v", services)
+	}
+	replicas = 5
+	if err := client.UpdateService(ctx, "plc", ServiceSpec{Name: "plc", Image: "sim/plc", Replicas: &replicas}); err != nil {
+		t.Error(err)
+	}
+	if err := client.RemoveService(ctx, "s1"); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_Nodes(t *testing.T) {
+	node := `{"ID": "n1", "Spec": {"Labels": {"site": "plant-1"}, "Role": "manager", "Availability": "active"},
+		"Description": {"Hostname": "host1", "Resources": {"NanoCPUs": 4000000000, "MemoryBytes": 8589934592}},
+		"Status": {"State": "ready", "Addr": "10.0.0.1"}, "ManagerStatus": {"Leader": true}}`
+	srv.route(map[string]mockResponse{
+		"GET /nodes?" + filterQuery(map[string][]string{"node.label": {"site=plant-1"}}).Encode(): {Body: "[" + node + "]"},
+		"GET /nodes/n1": {Body: node},
+	})
+	defer srv.route(nil)
+
+	want := Node{
+		ID: "n1", Hostname: "host1", Role: "manager", Availability: "active", State: "ready",
+		Addr: "10.0.0.1", Labels: map[string]string{"site": "plant-1"},
+		NanoCPUs: 4e9, MemoryBytes: 8 << 30, Leader: true,
+	}
+	nodes, err := client.ListNodes(context.Background(), Selector{"site": "plant-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || !reflect.DeepEqual(nodes[0], want) {
+		t.Errorf("want %+v, got %+v", want, nodes)
+	}
+	n, err := client.InspectNode(context.Background(), "n1")
+	if err != nil || !reflect.DeepEqual(*n, want) {
+		t.Errorf("want %+v, got %+v, %v", want, n, err)
+	}
+}
@@ -0,0 +1,241 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Info describes the daemon and its host.
+// docs.: https://docs.docker.com/engine/api/v1.41/#operation/SystemInfo
+type Info struct {
+	ID                string `json:"ID"`
+	Name              string `json:"Name"`
+	ServerVersion     string `json:"ServerVersion"`
+	Containers        int    `json:"Containers"`
+	ContainersRunning int    `json:"ContainersRunning"`
+	ContainersPaused  int    `json:"ContainersPaused"`
+	ContainersStopped int    `json:"ContainersStopped"`
+	Images            int    `json:"Images"`
+	// Driver is the storage driver, e.g. overlay2.
+	Driver        string `json:"Driver"`
+	CgroupDriver  string `json:"CgroupDriver"`
+	CgroupVersion string `json:"CgroupVersion"`
+	// MemTotal is the memory of the host in bytes.
+	MemTotal        int64    `json:"MemTotal"`
+	NCPU            int      `json:"NCPU"`
+	OperatingSystem string   `json:"OperatingSystem"`
+	OSType          string   `json:"OSType"`
+	Architecture    string   `json:"Architecture"`
+	KernelVersion   string   `json:"KernelVersion"`
+	SecurityOptions []string `json:"SecurityOptions"`
+	// Labels are the engine labels of the daemon as key=value.
+	Labels []string `json:"Labels"`
+	Swarm  struct {
+		NodeID string `json:"NodeID"`
+		// LocalNodeState is one of inactive, pending, active, error and
+		// locked.
+		LocalNodeState   string `json:"LocalNodeState"`
+		ControlAvailable bool   `json:"ControlAvailable"`
+	} `json:"Swarm"`
+}
+
+// Info returns information about the daemon and its host.
+func (c *Client) Info(ctx context.Context) (*Info, error) {
+	var res Info
+	if err := c.doJSON(ctx, http.MethodGet, "info", nil, nil, &res, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("info: %w", err)
+	}
+	return &res, nil
+}
+
+// Version describes the versions of the daemon.
+// docs.: https://docs.docker.com/engine/api/v1.41/#operation/SystemVersion
+type Version struct {
+	// Version of the engine, e.g. 20.10.7.
+	Version string `json:"Version"`
+	// APIVersion is the highest version of the API the daemon supports,
+	// MinAPIVersion the lowest one.
+	APIVersion    string `json:"ApiVersion"`
+	MinAPIVersion string `json:"MinAPIVersion"`
+	GitCommit     string `json:"GitCommit"`
+	GoVersion     string `json:"GoVersion"`
+	Os            string `json:"Os"`
+	Arch          string `json:"Arch"`
+	KernelVersion string `json:"KernelVersion"`
+	Experimental  bool   `json:"Experimental"`
+}
+
+// Version returns the versions of the daemon.
+func (c *Client) Version(ctx context.Context) (*Version, error) {
+	var res Version
+	if err := c.doJSON(ctx, http.MethodGet, "version", nil, nil, &res, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("version: %w", err)
+	}
+	return &res, nil
+}
+
+// AtLeast reports whether the engine version is at least the given one.
+// e.g.: v.AtLeast("20.10") is required for host-gateway.
+func (v *Version) AtLeast(version string) bool {
+	return compareVersions(v.Version, version) >= 0
+}
+
+// APIAtLeast reports whether the daemon supports the given API version.
+func (v *Version) APIAtLeast(version string) bool {
+	return compareVersions(v.APIVersion, version) >= 0
+}
+
+// compareVersions compares dotted versions numerically. Missing parts count
+// as zero, suffixes like -ce or -beta1 are ignored.
+func compareVersions(a, b string) int {
+	as, bs := versionParts(a), versionParts(b)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(v string) []int {
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	var parts []int
+	for _, s := range strings.Split(v, ".") {
+		n, _ := strconv.Atoi(s)
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// DiskUsage is the disk space used by the daemon. Sizes are in bytes, a size
+// of -1 means it was not calculated.
+// docs.: https://docs.docker.com/engine/api/v1.41/#operation/SystemDataUsage
+type DiskUsage struct {
+	// LayersSize is the size of all image layers.
+	LayersSize int64             `json:"LayersSize"`
+	Images     []ImageUsage      `json:"Images"`
+	Containers []ContainerUsage  `json:"Containers"`
+	Volumes    []VolumeUsage     `json:"Volumes"`
+	BuildCache []BuildCacheUsage `json:"BuildCache"`
+}
+
+// ImageUsage is the disk usage of an image.
+type ImageUsage struct {
+	ID       string   `json:"Id"`
+	RepoTags []string `json:"RepoTags"`
+	Size     int64    `json:"Size"`
+	// SharedSize is the size of the layers shared with other images.
+	SharedSize int64 `json:"SharedSize"`
+	// Containers is the number of containers using the image.
+	Containers int `json:"Containers"`
+}
+
+// ContainerUsage is the disk usage of a container.
+type ContainerUsage struct {
+	ID    string   `json:"Id"`
+	Names []string `json:"Names"`
+	Image string   `json:"Image"`
+	State string   `json:"State"`
+	// SizeRw is the size of the files written by the container.
+	SizeRw     int64 `json:"SizeRw"`
+	SizeRootFs int64 `json:"SizeRootFs"`
+}
+
+// VolumeUsage is the disk usage of a volume.
+type VolumeUsage struct {
+	Name      string `json:"Name"`
+	Driver    string `json:"Driver"`
+	UsageData struct {
+		Size int64 `json:"Size"`
+		// RefCount is the number of containers using the volume.
+		RefCount int `json:"RefCount"`
+	} `json:"UsageData"`
+}
+
+// BuildCacheUsage is the disk usage of a build cache record.
+type BuildCacheUsage struct {
+	ID     string `json:"ID"`
+	Type   string `json:"Type"`
+	Size   int64  `json:"Size"`
+	InUse  bool   `json:"InUse"`
+	Shared bool   `json:"Shared"`
+}
+
+// DiskUsage returns the disk space used by images, containers, volumes and
+// the build cache. Calculating it can take a while on hosts with many
+// resources.
+func (c *Client) DiskUsage(ctx context.Context) (*DiskUsage, error) {
+	var res DiskUsage
+	if err := c.doJSON(ctx, http.MethodGet, "system/df", nil, nil, &res, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("disk usage: %w", err)
+	}
+	return &res, nil
+}
+
+// Total returns the disk space used in total.
+func (d *DiskUsage) Total() int64 {
+	total := d.LayersSize
+	for _, c := range d.Containers {
+		total += positive(c.SizeRw)
+	}
+	for _, v := range d.Volumes {
+		total += positive(v.UsageData.Size)
+	}
+	for _, b := range d.BuildCache {
+		if !b.Shared {
+			total += positive(b.Size)
+		}
+	}
+	return total
+}
+
+// Reclaimable returns the disk space which pruning could free: images and
+// volumes not used by any container, the files of stopped containers and
+// the unused build cache.
+func (d *DiskUsage) Reclaimable() int64 {
+	var total int64
+	for _, i := range d.Images {
+		if i.Containers == 0 {
+			total += positive(i.Size - positive(i.SharedSize))
+		}
+	}
+	for _, c := range d.Containers {
+		if c.State != "running" {
+			total += positive(c.SizeRw)
+		}
+	}
+	for _, v := range d.Volumes {
+		if v.UsageData.RefCount == 0 {
+			total += positive(v.UsageData.Size)
+		}
+	}
+	for _, b := range d.BuildCache {
+		if !b.InUse && !b.Shared {
+			total += positive(b.Size)
+		}
+	}
+	return total
+}
+
+// positive maps the unknown size -1 to 0.
+func positive(n int64) int64 {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
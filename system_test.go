@@ -0,0 +1,124 @@
+package docker
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func Test_Info(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /info": {Body: `{"ID": "abc", "ContainersRunning": 3, "Driver": "overlay2", "CgroupVersion": "2",
+			"MemTotal": 8589934592, "NCPU": 4, "Swarm": {"LocalNodeState": "inactive"}}`},
+	})
+	defer srv.route(nil)
+
+	info, err := client.Info(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ContainersRunning != 3 || info.Driver != "overlay2" || info.CgroupVersion != "2" ||
+		info.MemTotal != 8<<30 || info.NCPU != 4 || info.Swarm.LocalNodeState != "inactive" {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
+
+func Test_Version(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /version": {Body: `{"Version": "20.10.7", "ApiVersion": "1.41", "Os": "linux", "Arch": "amd64", "Experimental": true}`},
+	})
+	defer srv.route(nil)
+
+	v, err := client.Version(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.APIVersion != "1.41" || v.Os != "linux" || !v.Experimental {
+		t.Errorf("unexpected version %+v", v)
+	}
+	if !v.AtLeast("20.10") || v.AtLeast("20.10.8") || !v.APIAtLeast("1.40") || v.APIAtLeast("1.42") {
+		t.Error("unexpected version comparison")
+	}
+}
+
+func Test_compareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"20.10.7", "20.10", 1},
+		{"20.10", "20.10.0", 0},
+		{"19.03.13", "20.10", -1},
+		{"18.09.1-ce", "18.09.1", 0},
+		{"1.9", "1.10", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compare %s %s: want %d, got %d", tt.a, tt.b, tt.want, got)
+		}
+	}
+}
+
+func Test_DiskUsage(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /system/df": {Body: `{
+			"LayersSize": 1000,
+			"Images": [
+				{"Id": "i1", "Size": 600, "SharedSize": 100, "Containers": 0},
+				{"Id": "i2", "Size": 400, "SharedSize": 100, "Containers": 1}
+			],
+			"Containers": [
+				{"Id": "c1", "State": "running", "SizeRw": 10},
+				{"Id": "c2", "State": "exited", "SizeRw": 20}
+			],
+			"Volumes": [
+				{"Name": "v1", "UsageData": {"Size": 50, "RefCount": 0}},
+				{"Name": "v2", "UsageData": {"Size": -1, "RefCount": -1}}
+			],
+			"BuildCache": [{"ID": "b1", "Size": 5, "InUse": false}]
+		}`},
+	})
+	defer srv.route(nil)
+
+	du, err := client.DiskUsage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := du.Total(); got != 1085 {
+		t.Errorf("unexpected total %d", got)
+	}
+	if got := du.Reclaimable(); got != 575 {
+		t.Errorf("unexpected reclaimable size %d", got)
+	}
+}
+
+func Test_Ping(t *testing.T) {
+	header := map[string]string{
+		"Api-Version":     "1.41",
+		"Ostype":          "linux",
+		"Builder-Version": "2",
+		"Swarm":           "active/manager",
+	}
+	srv.route(map[string]mockResponse{
+		"GET /_ping":  {Body: "OK", Header: header},
+		"HEAD /_ping": {Header: header},
+	})
+	defer srv.route(nil)
+
+	want := &PingResult{
+		APIVersion:     "1.41",
+		OSType:         "linux",
+		BuilderVersion: "2",
+		SwarmNodeState: "active",
+		SwarmManager:   true,
+	}
+	for _, ping := range []func(context.Context) (*PingResult, error){client.Ping, client.PingHead} {
+		res, err := ping(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(res, want) {
+			t.Errorf("want %+v, got %+v", want, res)
+		}
+	}
+}
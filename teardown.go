@@ -0,0 +1,99 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TeardownRun removes every container, network and volume which carries the
+// LabelRun label of the given run. Containers are removed first (running ones
+// are killed), followed by networks and volumes, so no resource is still in
+// use when it is removed. Resources which are already gone are ignored.
+// TeardownRun does not stop at the first failure; all errors are returned
+// together. With the DryRun option the removals are only planned.
+func (c *Client) TeardownRun(ctx context.Context, runID string, opts ...ApplyOption) ([]Operation, error) {
+	if runID == "" {
+		return nil, fmt.Errorf("teardown: run must not be empty")
+	}
+	return c.teardown(ctx, RunSelector(runID).filters(), opts)
+}
+
+// teardown removes all containers, networks and volumes matching the
+// filters.
+func (c *Client) teardown(ctx context.Context, owned map[string][]string, opts []ApplyOption) ([]Operation, error) {
+	a := c.newApplier(opts)
+	var errs teardownErrors
+
+	containers, err := c.listContainers(ctx, true, owned)
+	if err != nil {
+		return a.ops, fmt.Errorf("list containers: %w", err)
+	}
+	for _, cs := range containers {
+		if err := a.removeContainer(ctx, cs); err != nil && !isNotFound(err) {
+			errs = append(errs, fmt.Errorf("remove container %s: %w", cs.Name(), err))
+		}
+	}
+
+	networks, err := c.listNetworks(ctx, owned)
+	if err != nil {
+		return a.ops, fmt.Errorf("list networks: %w", err)
+	}
+	for _, n := range networks {
+		if err := a.removeNetwork(ctx, n); err != nil && !isNotFound(err) {
+			errs = append(errs, fmt.Errorf("remove network %s: %w", n.Name, err))
+		}
+	}
+
+	volumes, err := c.listVolumes(ctx, owned)
+	if err != nil {
+		return a.ops, fmt.Errorf("list volumes: %w", err)
+	}
+	for _, v := range volumes {
+		v := v
+		err := a.run(Operation{Action: "remove", Resource: "volume", Name: v.Name}, func() error {
+			return c.removeVolume(ctx, v.Name, false)
+		})
+		if err != nil && !isNotFound(err) {
+			errs = append(errs, fmt.Errorf("remove volume %s: %w", v.Name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return a.ops, errs
+	}
+	return a.ops, nil
+}
+
+// teardownErrors collects the errors of a teardown.
+type teardownErrors []error
+
+func (e teardownErrors) Error() string {
+	ss := make([]string, len(e))
+	for i, err := range e {
+		ss[i] = err.Error()
+	}
+	return fmt.Sprintf("teardown failed: %s", strings.Join(ss, "; "))
+}
+
+// Is reports whether one of the errors matches the target, for errors.Is.
+func (e teardownErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors which matches the target, for
+// errors.As.
+func (e teardownErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func Test_TeardownRun(t *testing.T) {
+	tt := []struct {
+		name    string
+		routes  map[string]mockResponse
+		expect  []string
+		wantErr bool
+	}{
+		{
+			name: "expected",
+			routes: map[string]mockResponse{
+				"GET /containers/json":  {Body: `[{"Id": "c1", "Names": ["/plc"]}, {"Id": "c2", "Names": ["/gone"]}]`},
+				"DELETE /containers/c1": {StatusCode: http.StatusNoContent},
+				"DELETE /containers/c2": {StatusCode: http.StatusNotFound, Body: `{"message": "No such container: c2"}`},
+				"GET /networks":         {Body: `[{"Id": "n1", "Name": "subnet"}]`},
+				"GET /networks/n1":      {Body: `{"Id": "n1", "Containers": {}}`},
+				"DELETE /networks/n1":   {StatusCode: http.StatusNoContent},
+				"GET /volumes":          {Body: `{"Volumes": [{"Name": "data"}]}`},
+				"DELETE /volumes/data":  {StatusCode: http.StatusNoContent},
+			},
+			expect: []string{
+				"GET /containers/json",
+				"DELETE /containers/c1",
+				"DELETE /containers/c2",
+				"GET /networks",
+				"GET /networks/n1",
+				"DELETE /networks/n1",
+				"GET /volumes",
+				"DELETE /volumes/data",
+			},
+		},
+		{
+			name: "continue on error",
+			routes: map[string]mockResponse{
+				"GET /containers/json":  {Body: `[{"Id": "c1", "Names": ["/plc"]}]`},
+				"DELETE /containers/c1": {StatusCode: http.StatusConflict, Body: `{"message": "removal in progress"}`},
+				"GET /networks":         {Body: `[]`},
+				"GET /volumes":          {Body: `{"Volumes": []}`},
+			},
+			expect: []string{
+				"GET /containers/json",
+				"DELETE /containers/c1",
+				"GET /networks",
+				"GET /volumes",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			srv.route(tc.routes)
+			defer srv.route(nil)
+
+			_, err := client.TeardownRun(context.Background(), "test")
+			if err != nil && !tc.wantErr {
+				t.Error(err)
+			}
+			if err == nil && tc.wantErr {
+				t.Error("expected error")
+			}
+			// the errors of the removals can be inspected
+			var e *APIError
+			if tc.wantErr && (!errors.As(err, &e) || e.StatusCode != http.StatusConflict) {
+				t.Errorf("expected conflict, got %v", err)
+			}
+			if got := srv.Requests(); !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("got: %v, want: %v", got, tc.expect)
+			}
+		})
+	}
+}
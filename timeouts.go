@@ -0,0 +1,221 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TimeoutClass groups the requests to the daemon by how long it may take to
+// answer them.
+type TimeoutClass int
+
+const (
+	// TimeoutFast is the class of requests the daemon answers right away,
+	// e.g. pings, inspects and lists.
+	TimeoutFast TimeoutClass = iota
+	// TimeoutMedium is the class of requests which change the state of
+	// objects, e.g. to create, start, stop or remove containers.
+	TimeoutMedium
+	// TimeoutLong is the class of requests which transfer data or wait for
+	// a process, e.g. pulls, builds, logs, copies and execs. Streams which
+	// follow their source until the context is done, like events or
+	// followed logs, have no timeout at all.
+	TimeoutLong
+)
+
+// Timeouts are the timeouts of the classes of requests, see SetTimeouts.
+// Each covers the whole request including the read of the response body,
+// except for the time the request is held back for a restarting daemon, see
+// SetRestartRecovery. A zero timeout disables the timeout of its class.
+type Timeouts struct {
+	Fast   time.Duration
+	Medium time.Duration
+	Long   time.Duration
+}
+
+// DefaultTimeouts are the timeouts of new clients.
+var DefaultTimeouts = Timeouts{
+	Fast:   5 * time.Second,
+	Medium: 30 * time.Second,
+	Long:   30 * time.Minute,
+}
+
+// SetTimeouts sets the timeouts of the classes of requests, e.g. a longer
+// Medium timeout for slow embedded hosts or a shorter Long timeout on CI
+// machines. The deadline of the context of a request applies as well.
+// SetTimeouts has to be called before the client is used.
+func (c *Client) SetTimeouts(t Timeouts) {
+	c.timeouts = t
+}
+
+// timeout returns the timeout of the class.
+func (c *Client) timeout(class TimeoutClass) time.Duration {
+	switch class {
+	case TimeoutFast:
+		return c.timeouts.Fast
+	case TimeoutMedium:
+		return c.timeouts.Medium
+	default:
+		return c.timeouts.Long
+	}
+}
+
+// httpClient returns an HTTP client with the timeout of the class, sharing
+// the transport of the client.
+func (c *Client) httpClient(class TimeoutClass) *http.Client {
+	return c.timeoutClient(c.timeout(class))
+}
+
+// timeoutClient returns an HTTP client with the timeout, zero means none,
+// sharing the transport of the client. Unlike http.Client.Timeout, the
+// timeout can be paused, see pauseTimeout.
+func (c *Client) timeoutClient(timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		return &http.Client{Transport: c.http.Transport}
+	}
+	return &http.Client{Transport: &timeoutTransport{next: c.http.Transport, timeout: timeout}}
+}
+
+// timeoutTransport is a RoundTripper which cancels requests once their
+// timeout passed, including the read of the response body.
+type timeoutTransport struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := newTimeoutContext(req.Context(), t.timeout)
+	res, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		ctx.cancel(context.Canceled)
+		return nil, err
+	}
+	res.Body = &timeoutBody{ReadCloser: res.Body, ctx: ctx}
+	return res, nil
+}
+
+// timeoutBody ends the context of the request once the body is closed.
+type timeoutBody struct {
+	io.ReadCloser
+	ctx *timeoutContext
+}
+
+func (b *timeoutBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.ctx.cancel(context.Canceled)
+	return err
+}
+
+type timeoutContextKey struct{}
+
+// timeoutContext is the context of a request with a timeout which can be
+// paused, e.g. while the request is held back for a restarting daemon.
+type timeoutContext struct {
+	context.Context
+	done chan struct{}
+
+	mu       sync.Mutex
+	err      error
+	timer    *time.Timer
+	deadline time.Time
+	// left is the time left of the timeout while it is paused.
+	left time.Duration
+}
+
+func newTimeoutContext(parent context.Context, timeout time.Duration) *timeoutContext {
+	c := &timeoutContext{Context: parent, done: make(chan struct{}), deadline: time.Now().Add(timeout)}
+	c.mu.Lock()
+	c.timer = time.AfterFunc(timeout, func() { c.cancel(context.DeadlineExceeded) })
+	c.mu.Unlock()
+	if err := parent.Err(); err != nil {
+		c.cancel(err)
+		return c
+	}
+	go func() {
+		select {
+		case <-parent.Done():
+			c.cancel(parent.Err())
+		case <-c.done:
+		}
+	}()
+	return c
+}
+
+func (c *timeoutContext) Deadline() (time.Time, bool) {
+	c.mu.Lock()
+	deadline := c.deadline
+	if c.left > 0 {
+		deadline = time.Now().Add(c.left)
+	}
+	c.mu.Unlock()
+	if d, ok := c.Context.Deadline(); ok && d.Before(deadline) {
+		return d, true
+	}
+	return deadline, true
+}
+
+func (c *timeoutContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *timeoutContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *timeoutContext) Value(key interface{}) interface{} {
+	if key == (timeoutContextKey{}) {
+		return c
+	}
+	return c.Context.Value(key)
+}
+
+func (c *timeoutContext) cancel(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	c.timer.Stop()
+	close(c.done)
+}
+
+// pauseTimeout stops the timeout of the request of the context until the
+// returned function is called. Contexts without timeout are not affected.
+func pauseTimeout(ctx context.Context) (resume func()) {
+	c, ok := ctx.Value(timeoutContextKey{}).(*timeoutContext)
+	if !ok {
+		return func() {}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil || c.left > 0 || !c.timer.Stop() {
+		return func() {}
+	}
+	c.left = time.Until(c.deadline)
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.err == nil {
+			c.deadline = time.Now().Add(c.left)
+			c.timer.Reset(c.left)
+		}
+		c.left = 0
+	}
+}
+
+// methodClass returns the class of requests by their method: reads are fast,
+// changes are of medium duration.
+func methodClass(method string) TimeoutClass {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return TimeoutFast
+	default:
+		return TimeoutMedium
+	}
+}
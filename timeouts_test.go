@@ -0,0 +1,76 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_Timeouts(t *testing.T) {
+	delay := 100 * time.Millisecond
+	srv.route(map[string]mockResponse{
+		"GET /containers/c1/json":   {Body: `{"Id": "c1"}`, Delay: delay},
+		"POST /containers/c1/start": {StatusCode: http.StatusNoContent, Delay: delay},
+		"POST /images/create":       {Body: `{"status": "Downloaded newer image"}`, Delay: delay},
+	})
+	defer srv.route(nil)
+
+	ctx := context.Background()
+	inspect := func(c *Client) error {
+		_, err := c.inspectContainer(ctx, "c1")
+		return err
+	}
+	start := func(c *Client) error {
+		return c.startContainer(ctx, "c1")
+	}
+	pull := func(c *Client) error {
+		return c.pullImageOnce(ctx, "busybox:latest")
+	}
+
+	tt := []struct {
+		name     string
+		timeouts Timeouts
+		// expired are the requests which are expected to time out
+		expired [3]bool
+	}{
+		{
+			name:     "defaults",
+			timeouts: DefaultTimeouts,
+		},
+		{
+			name:     "fast",
+			timeouts: Timeouts{Fast: 10 * time.Millisecond, Medium: time.Second, Long: time.Second},
+			expired:  [3]bool{true, false, false},
+		},
+		{
+			name:     "medium",
+			timeouts: Timeouts{Fast: time.Second, Medium: 10 * time.Millisecond, Long: time.Second},
+			expired:  [3]bool{false, true, false},
+		},
+		{
+			name:     "long",
+			timeouts: Timeouts{Fast: time.Second, Medium: time.Second, Long: 10 * time.Millisecond},
+			expired:  [3]bool{false, false, true},
+		},
+		{
+			name: "disabled",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewClient(sockPath)
+			c.SetTimeouts(tc.timeouts)
+			for i, fn := range []func(*Client) error{inspect, start, pull} {
+				err := fn(c)
+				if tc.expired[i] && err == nil {
+					t.Errorf("request %d: expected timeout", i)
+				}
+				if !tc.expired[i] && err != nil {
+					t.Errorf("request %d: %v", i, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+)
+
+// Topology builds a spec step by step. Up creates the networks and
+// containers in the order they were added.
+// e.g.:
+//
+//	ops, err := c.NewTopology("sim-1").
+//	    Network("subnet1", WithSubnet("172.28.0.0/16")).
+//	    Container("plc-1", "plc:latest", OnNetwork("subnet1", Alias("plc"))).
+//	    Up(ctx)
+type Topology struct {
+	c    *Client
+	spec Spec
+	err  error
+}
+
+// NetworkOption configures a network of a topology.
+type NetworkOption func(*NetworkSpec)
+
+// ContainerOption configures a container of a topology.
+type ContainerOption func(*ContainerSpec)
+
+// AttachOption configures the attachment of a container to a network.
+type AttachOption func(*NetworkAttachment)
+
+// NewTopology starts a new topology for the given run.
+func (c *Client) NewTopology(run string) *Topology {
+	return &Topology{c: c, spec: Spec{Run: run}}
+}
+
+// Network adds a network.
+func (t *Topology) Network(name string, opts ...NetworkOption) *Topology {
+	ns := NetworkSpec{Name: name}
+	for _, opt := range opts {
+		opt(&ns)
+	}
+	t.spec.Networks = append(t.spec.Networks, ns)
+	return t
+}
+
+// Volume adds a named volume.
+func (t *Topology) Volume(name string) *Topology {
+	t.spec.Volumes = append(t.spec.Volumes, VolumeSpec{Name: name})
+	return t
+}
+
+// Container adds a container. All networks it is attached to have to be
+// added before.
+func (t *Topology) Container(name, image string, opts ...ContainerOption) *Topology {
+	cs := ContainerSpec{Name: name, Image: image}
+	for _, opt := range opts {
+		opt(&cs)
+	}
+	for _, a := range cs.Networks {
+		if t.err == nil && t.spec.network(a.Network) == nil {
+			t.err = fmt.Errorf("topology: container %s: network %s has not been added", name, a.Network)
+		}
+	}
+	t.spec.Containers = append(t.spec.Containers, cs)
+	return t
+}
+
+// Spec returns the spec built so far.
+func (t *Topology) Spec() (*Spec, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	spec := t.spec
+	return &spec, spec.Validate()
+}
+
+// Up applies the topology. If it fails, the resources created by Up are
+// removed again. Resources are not restored though: changed resources stay
+// recreated, and resources of the run which are not part of the topology
+// anymore stay removed if Up failed while or after removing them.
+func (t *Topology) Up(ctx context.Context) ([]Operation, error) {
+	spec, err := t.Spec()
+	if err != nil {
+		return nil, err
+	}
+	ops, err := t.c.Apply(ctx, spec, Rollback(RollbackRemove))
+	if err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+// Down removes all resources of the topology's run.
+func (t *Topology) Down(ctx context.Context) ([]Operation, error) {
+	return t.c.TeardownRun(ctx, t.spec.Run)
+}
+
+// WithDriver sets the driver of a network.
+func WithDriver(driver string) NetworkOption {
+	return func(n *NetworkSpec) {
+		n.Driver = driver
+	}
+}
+
+// WithSubnet sets the subnet of a network in CIDR notation.
+func WithSubnet(subnet string) NetworkOption {
+	return func(n *NetworkSpec) {
+		n.Subnet = subnet
+	}
+}
+
+// WithGateway sets the gateway of a network.
+func WithGateway(gateway string) NetworkOption {
+	return func(n *NetworkSpec) {
+		n.Gateway = gateway
+	}
+}
+
+// WithInternal restricts external access to a network.
+func WithInternal() NetworkOption {
+	return func(n *NetworkSpec) {
+		n.Internal = true
+	}
+}
+
+// OnNetwork attaches a container to the network.
+func OnNetwork(network string, opts ...AttachOption) ContainerOption {
+	return func(c *ContainerSpec) {
+		a := NetworkAttachment{Network: network}
+		for _, opt := range opts {
+			opt(&a)
+		}
+		c.Networks = append(c.Networks, a)
+	}
+}
+
+// Alias adds a DNS alias of the container on the network.
+func Alias(alias string) AttachOption {
+	return func(a *NetworkAttachment) {
+		a.Aliases = append(a.Aliases, alias)
+	}
+}
+
+// WithIPv4Address assigns a static address on the network.
+func WithIPv4Address(addr string) AttachOption {
+	return func(a *NetworkAttachment) {
+		a.IPv4Address = addr
+	}
+}
+
+// WithCmd overwrites the command of the image.
+func WithCmd(cmd ...string) ContainerOption {
+	return func(c *ContainerSpec) {
+		c.Cmd = cmd
+	}
+}
+
+// WithEnv sets an environment variable.
+func WithEnv(key, value string) ContainerOption {
+	return func(c *ContainerSpec) {
+		if c.Env == nil {
+			c.Env = make(map[string]string)
+		}
+		c.Env[key] = value
+	}
+}
+
+// WithLabel sets a label.
+func WithLabel(key, value string) ContainerOption {
+	return func(c *ContainerSpec) {
+		if c.Labels == nil {
+			c.Labels = make(map[string]string)
+		}
+		c.Labels[key] = value
+	}
+}
+
+// WithPort publishes a port: "[[<hostIP>:]<hostPort>:]<port>[/<tcp|udp>]"
+func WithPort(port string) ContainerOption {
+	return func(c *ContainerSpec) {
+		c.Ports = append(c.Ports, port)
+	}
+}
+
+// WithMount adds a mount: "<source>:<target>[:ro]"
+func WithMount(mount string) ContainerOption {
+	return func(c *ContainerSpec) {
+		c.Mounts = append(c.Mounts, mount)
+	}
+}
+
+// WithPrivileged runs the container in privileged mode.
+func WithPrivileged() ContainerOption {
+	return func(c *ContainerSpec) {
+		c.Privileged = true
+	}
+}
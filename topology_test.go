@@ -0,0 +1,90 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func Test_TopologyUp(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /volumes":            {Body: `{"Volumes": []}`},
+		"GET /networks":           {Body: `[]`},
+		"POST /networks/create":   {StatusCode: http.StatusCreated, Body: `{"Id": "n1"}`},
+		"GET /containers/json":    {Body: `[]`},
+		"POST /containers/create": {StatusCode: http.StatusCreated, Body: `{"Id": "c1"}`},
+		"POST /containers/c1/start": {StatusCode: http.StatusInternalServerError,
+			Body: `{"message": "port is already allocated"}`},
+		"DELETE /containers/c1": {StatusCode: http.StatusNoContent},
+		"GET /networks/n1":      {Body: `{"Id": "n1", "Containers": {}}`},
+		"DELETE /networks/n1":   {StatusCode: http.StatusNoContent},
+	})
+	defer srv.route(nil)
+
+	_, err := client.NewTopology("test").
+		Network("subnet1", WithSubnet("172.28.0.0/16")).
+		Container("plc-1", "plc:latest", OnNetwork("subnet1", Alias("plc")), WithPort("8080:80")).
+		Up(context.Background())
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	expect := []string{
+		"GET /volumes",
+		"GET /networks",
+		"POST /networks/create",
+		"GET /containers/json",
+		"POST /containers/create",
+		"POST /containers/c1/start",
+		"DELETE /containers/c1",
+		"GET /networks/n1",
+		"DELETE /networks/n1",
+	}
+	if got := srv.Requests(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("got: %v, want: %v", got, expect)
+	}
+}
+
+// Test_TopologyUpForeignResources checks that the rollback leaves resources
+// alone which Up did not create: a container whose name is taken and a
+// volume which existed before.
+func Test_TopologyUpForeignResources(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /volumes":         {Body: `{"Volumes": []}`},
+		"GET /volumes/data":    {Body: `{"Name": "data"}`},
+		"GET /networks":        {Body: `[]`},
+		"GET /containers/json": {Body: `[]`},
+		"POST /containers/create": {StatusCode: http.StatusConflict,
+			Body: `{"message": "Conflict. The container name \"/plc\" is already in use"}`},
+	})
+	defer srv.route(nil)
+
+	_, err := client.NewTopology("test").
+		Volume("data").
+		Container("plc", "plc:latest", WithMount("data:/data")).
+		Up(context.Background())
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	expect := []string{
+		"GET /volumes",
+		"GET /volumes/data",
+		"GET /networks",
+		"GET /containers/json",
+		"POST /containers/create",
+	}
+	if got := srv.Requests(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("got: %v, want: %v", got, expect)
+	}
+}
+
+func Test_TopologyUnknownNetwork(t *testing.T) {
+	_, err := client.NewTopology("test").
+		Container("plc-1", "plc:latest", OnNetwork("subnet1")).
+		Spec()
+	if err == nil {
+		t.Error("expected error")
+	}
+}
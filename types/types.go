@@ -0,0 +1,364 @@
+// Package types holds the types of the engine API used by the docker
+// package. They mirror the subset of the API the package needs, so
+// downstream code can accept and return them without declaring its own
+// copies.
+// docs.: https://docs.docker.com/engine/api/v1.41/
+//
+// Fields tagged with strict:"required" are enforced by the strict mode of the
+// client.
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+// ContainerSummary is an element of the container list.
+type ContainerSummary struct {
+	ID     string            `json:"Id" strict:"required"`
+	Names  []string          `json:"Names" strict:"required"`
+	Image  string            `json:"Image"`
+	State  string            `json:"State"`
+	Status string            `json:"Status"`
+	Labels map[string]string `json:"Labels"`
+	Ports  []Port            `json:"Ports"`
+}
+
+// Port is a port of a listed container. PublicPort is only set if the port
+// is published on the host.
+type Port struct {
+	IP          string `json:"IP"`
+	PrivatePort uint16 `json:"PrivatePort"`
+	PublicPort  uint16 `json:"PublicPort"`
+	// Type is tcp, udp or sctp.
+	Type string `json:"Type"`
+}
+
+// Name returns the primary name of the container without the leading slash.
+func (s ContainerSummary) Name() string {
+	if len(s.Names) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(s.Names[0], "/")
+}
+
+// ContainerState is the state of an inspected container.
+type ContainerState struct {
+	Status     string  `json:"Status" strict:"required"`
+	Running    bool    `json:"Running" strict:"required"`
+	Paused     bool    `json:"Paused"`
+	Restarting bool    `json:"Restarting"`
+	OOMKilled  bool    `json:"OOMKilled"`
+	Dead       bool    `json:"Dead"`
+	Pid        int     `json:"Pid"`
+	ExitCode   int     `json:"ExitCode"`
+	Error      string  `json:"Error"`
+	StartedAt  string  `json:"StartedAt"`
+	FinishedAt string  `json:"FinishedAt"`
+	Health     *Health `json:"Health"`
+}
+
+// Health is the result of the healthcheck of a container.
+type Health struct {
+	// Status is one of starting, healthy and unhealthy.
+	Status        string `json:"Status"`
+	FailingStreak int    `json:"FailingStreak"`
+}
+
+// ContainerConfig is the configuration a container was created with.
+type ContainerConfig struct {
+	Hostname     string              `json:"Hostname"`
+	User         string              `json:"User"`
+	WorkingDir   string              `json:"WorkingDir"`
+	Tty          bool                `json:"Tty"`
+	Image        string              `json:"Image"`
+	Cmd          []string            `json:"Cmd"`
+	Entrypoint   []string            `json:"Entrypoint"`
+	Env          []string            `json:"Env"`
+	Labels       map[string]string   `json:"Labels"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+	StopSignal   string              `json:"StopSignal"`
+}
+
+// EndpointSettings describes the attachment of a container to a network.
+type EndpointSettings struct {
+	NetworkID         string      `json:"NetworkID"`
+	EndpointID        string      `json:"EndpointID"`
+	IPAMConfig        *IPAMConfig `json:"IPAMConfig"`
+	Aliases           []string    `json:"Aliases"`
+	IPAddress         string      `json:"IPAddress"`
+	GlobalIPv6Address string      `json:"GlobalIPv6Address"`
+}
+
+// PortBinding is the host address a port of a container is published on.
+type PortBinding struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+// MountPoint is a mount of an inspected container.
+type MountPoint struct {
+	// Type is one of bind, volume, tmpfs and npipe.
+	Type string `json:"Type"`
+	// Name and Driver are only set for volumes.
+	Name   string `json:"Name"`
+	Driver string `json:"Driver"`
+	// Source is the path on the host, for volumes the path of their data.
+	Source      string `json:"Source"`
+	Destination string `json:"Destination"`
+	// Mode holds the options of the mount, e.g. "ro" or "z".
+	Mode        string `json:"Mode"`
+	RW          bool   `json:"RW"`
+	Propagation string `json:"Propagation"`
+}
+
+// NetworkSettings holds the networks and published ports of a container.
+type NetworkSettings struct {
+	// Networks are the attachments by network name.
+	Networks map[string]*EndpointSettings `json:"Networks"`
+	// Ports are the bindings by port, e.g. 80/tcp.
+	Ports map[string][]PortBinding `json:"Ports"`
+}
+
+// ContainerJSON is the result of inspecting a container.
+type ContainerJSON struct {
+	ID              string          `json:"Id" strict:"required"`
+	Name            string          `json:"Name" strict:"required"`
+	Image           string          `json:"Image"`
+	RestartCount    int             `json:"RestartCount"`
+	State           ContainerState  `json:"State" strict:"required"`
+	Config          ContainerConfig `json:"Config"`
+	HostConfig      HostConfig      `json:"HostConfig"`
+	Mounts          []MountPoint    `json:"Mounts"`
+	NetworkSettings NetworkSettings `json:"NetworkSettings"`
+}
+
+// Mount is a mount of a container to create.
+type Mount struct {
+	Target      string `json:"Target"`
+	Source      string `json:"Source"`
+	ReadOnly    bool   `json:"ReadOnly"`
+	Type        string `json:"Type"`
+	Consistency string `json:"Consistency,omitempty"`
+}
+
+// HostConfig is the host specific configuration of a container.
+type HostConfig struct {
+	Mounts         []Mount                  `json:"Mounts,omitempty"`
+	PortBindings   map[string][]PortBinding `json:"PortBindings,omitempty"`
+	NetworkMode    string                   `json:"NetworkMode,omitempty"`
+	Privileged     bool                     `json:"Privileged,omitempty"`
+	AutoRemove     bool                     `json:"AutoRemove,omitempty"`
+	ReadonlyRootfs bool                     `json:"ReadonlyRootfs,omitempty"`
+	CapAdd         []string                 `json:"CapAdd,omitempty"`
+	CapDrop        []string                 `json:"CapDrop,omitempty"`
+	// Annotations are passed to the OCI runtime, they require API version
+	// 1.43.
+	Annotations map[string]string `json:"Annotations,omitempty"`
+	LogConfig   *LogConfig        `json:"LogConfig,omitempty"`
+}
+
+// LogConfig selects the log driver of a container, e.g. json-file or
+// journald.
+type LogConfig struct {
+	Type   string            `json:"Type,omitempty"`
+	Config map[string]string `json:"Config,omitempty"`
+}
+
+// EndpointConfig configures the attachment of a container to a network.
+type EndpointConfig struct {
+	Aliases    []string    `json:"Aliases,omitempty"`
+	IPAMConfig *IPAMConfig `json:"IPAMConfig,omitempty"`
+}
+
+// IPAMConfig holds the static addresses of a container in a network.
+type IPAMConfig struct {
+	IPv4Address string `json:"IPv4Address,omitempty"`
+	IPv6Address string `json:"IPv6Address,omitempty"`
+}
+
+// NetworkingConfig holds the network a container is attached to on
+// creation.
+type NetworkingConfig struct {
+	EndpointsConfig map[string]*EndpointConfig `json:"EndpointsConfig"`
+}
+
+// ContainerCreate is the body of a request to create a container.
+type ContainerCreate struct {
+	Hostname         string              `json:"Hostname,omitempty"`
+	User             string              `json:"User,omitempty"`
+	WorkingDir       string              `json:"WorkingDir,omitempty"`
+	Tty              bool                `json:"Tty,omitempty"`
+	StopSignal       string              `json:"StopSignal,omitempty"`
+	Image            string              `json:"Image"`
+	Cmd              []string            `json:"Cmd,omitempty"`
+	Entrypoint       []string            `json:"Entrypoint,omitempty"`
+	Env              []string            `json:"Env,omitempty"`
+	Labels           map[string]string   `json:"Labels,omitempty"`
+	ExposedPorts     map[string]struct{} `json:"ExposedPorts,omitempty"`
+	Healthcheck      *HealthConfig       `json:"Healthcheck,omitempty"`
+	HostConfig       HostConfig          `json:"HostConfig"`
+	NetworkingConfig *NetworkingConfig   `json:"NetworkingConfig,omitempty"`
+}
+
+// HealthConfig is the healthcheck of a container to create. The durations
+// are in nanoseconds, zero means the default of the daemon.
+type HealthConfig struct {
+	Test        []string `json:"Test,omitempty"`
+	Interval    int64    `json:"Interval,omitempty"`
+	Timeout     int64    `json:"Timeout,omitempty"`
+	StartPeriod int64    `json:"StartPeriod,omitempty"`
+	Retries     int      `json:"Retries,omitempty"`
+}
+
+// NetworkSummary is an element of the network list and the result of
+// inspecting a network.
+type NetworkSummary struct {
+	ID         string            `json:"Id" strict:"required"`
+	Name       string            `json:"Name" strict:"required"`
+	Driver     string            `json:"Driver"`
+	Internal   bool              `json:"Internal"`
+	Attachable bool              `json:"Attachable"`
+	EnableIPv6 bool              `json:"EnableIPv6"`
+	Labels     map[string]string `json:"Labels"`
+	IPAM       IPAM              `json:"IPAM"`
+	// Containers are the attached containers by ID. Only set by inspect.
+	Containers map[string]NetworkContainer `json:"Containers"`
+}
+
+// IPAM is the address configuration of a network.
+type IPAM struct {
+	Config []IPAMPool `json:"Config"`
+}
+
+// IPAMPool is an address range of a network.
+type IPAMPool struct {
+	Subnet  string `json:"Subnet"`
+	Gateway string `json:"Gateway"`
+}
+
+// NetworkContainer is a container attached to an inspected network.
+type NetworkContainer struct {
+	Name string `json:"Name"`
+}
+
+// NetworkCreate is the body of a request to create a network.
+type NetworkCreate struct {
+	Name       string             `json:"Name"`
+	Driver     string             `json:"Driver"`
+	Internal   bool               `json:"Internal"`
+	Attachable bool               `json:"Attachable"`
+	Labels     map[string]string  `json:"Labels,omitempty"`
+	IPAM       *NetworkCreateIPAM `json:"IPAM,omitempty"`
+}
+
+// NetworkCreateIPAM is the address configuration of a network to create,
+// e.g. {"Subnet": "172.28.0.0/16", "Gateway": "172.28.0.1"}.
+type NetworkCreateIPAM struct {
+	Config []map[string]string `json:"Config"`
+}
+
+// Volume is a named volume.
+type Volume struct {
+	Name       string            `json:"Name" strict:"required"`
+	Driver     string            `json:"Driver"`
+	Mountpoint string            `json:"Mountpoint"`
+	Labels     map[string]string `json:"Labels"`
+}
+
+// ImageInspect is the result of inspecting an image.
+type ImageInspect struct {
+	ID           string   `json:"Id" strict:"required"`
+	RepoTags     []string `json:"RepoTags"`
+	RepoDigests  []string `json:"RepoDigests"`
+	Architecture string   `json:"Architecture"`
+	Os           string   `json:"Os"`
+	Variant      string   `json:"Variant"`
+	Size         int64    `json:"Size"`
+	// Config is the default configuration of containers of the image.
+	Config ContainerConfig `json:"Config"`
+	RootFS RootFS          `json:"RootFS"`
+}
+
+// RootFS lists the layers of an image.
+type RootFS struct {
+	Type string `json:"Type"`
+	// Layers are the digests of the uncompressed layers, from the base
+	// layer up.
+	Layers []string `json:"Layers"`
+}
+
+// CreateResponse is the response of the daemon to a create request.
+type CreateResponse struct {
+	ID       string   `json:"Id" strict:"required"`
+	Warnings []string `json:"Warnings"`
+	// Warning is the warning of a network creation, the daemon does not
+	// send a list for networks.
+	Warning string `json:"Warning,omitempty"`
+}
+
+// Stats is a sample of the resource usage of a container.
+type Stats struct {
+	Read time.Time `json:"read"`
+	// PreCPUStats are the CPU stats of the previous sample, the CPU usage
+	// is the difference.
+	CPUStats    CPUStats                `json:"cpu_stats"`
+	PreCPUStats CPUStats                `json:"precpu_stats"`
+	MemoryStats MemoryStats             `json:"memory_stats"`
+	BlkioStats  BlkioStats              `json:"blkio_stats"`
+	PidsStats   PidsStats               `json:"pids_stats"`
+	Networks    map[string]NetworkStats `json:"networks"`
+}
+
+// CPUStats is the CPU time used by a container and the host.
+type CPUStats struct {
+	CPUUsage CPUUsage `json:"cpu_usage"`
+	// SystemUsage is the CPU time of the host in nanoseconds.
+	SystemUsage uint64 `json:"system_cpu_usage"`
+	OnlineCPUs  uint32 `json:"online_cpus"`
+}
+
+// CPUUsage is the CPU time used by a container in nanoseconds.
+type CPUUsage struct {
+	TotalUsage  uint64   `json:"total_usage"`
+	PercpuUsage []uint64 `json:"percpu_usage"`
+}
+
+// MemoryStats is the memory usage of a container in bytes. MaxUsage is only
+// reported for cgroup v1.
+type MemoryStats struct {
+	Usage    uint64            `json:"usage"`
+	MaxUsage uint64            `json:"max_usage"`
+	Limit    uint64            `json:"limit"`
+	Stats    map[string]uint64 `json:"stats"`
+}
+
+// BlkioStats is the block IO of a container.
+type BlkioStats struct {
+	IOServiceBytesRecursive []BlkioStatEntry `json:"io_service_bytes_recursive"`
+}
+
+// BlkioStatEntry is the block IO of an operation on a device, e.g. read.
+type BlkioStatEntry struct {
+	Major uint64 `json:"major"`
+	Minor uint64 `json:"minor"`
+	Op    string `json:"op"`
+	Value uint64 `json:"value"`
+}
+
+// PidsStats is the number of processes of a container.
+type PidsStats struct {
+	Current uint64 `json:"current"`
+}
+
+// NetworkStats is the network IO of an interface of a container.
+type NetworkStats struct {
+	RxBytes   uint64 `json:"rx_bytes"`
+	RxPackets uint64 `json:"rx_packets"`
+	RxErrors  uint64 `json:"rx_errors"`
+	RxDropped uint64 `json:"rx_dropped"`
+	TxBytes   uint64 `json:"tx_bytes"`
+	TxPackets uint64 `json:"tx_packets"`
+	TxErrors  uint64 `json:"tx_errors"`
+	TxDropped uint64 `json:"tx_dropped"`
+}
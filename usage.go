@@ -0,0 +1,87 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// UsageReport is the resource usage of the containers of a run.
+type UsageReport struct {
+	Created    time.Time                `json:"created"`
+	Containers []ContainerResourceUsage `json:"containers"`
+}
+
+// ContainerResourceUsage is the resource usage and the outcome of a container.
+type ContainerResourceUsage struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Image        string    `json:"image"`
+	Running      bool      `json:"running"`
+	ExitCode     int       `json:"exit_code"`
+	OOMKilled    bool      `json:"oom_killed"`
+	RestartCount int       `json:"restart_count"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	// The resource usage is only known for running containers. PeakMemory
+	// is the maximum usage reported by cgroup v1, cgroup v2 only reports
+	// the current usage.
+	PeakMemory uint64  `json:"peak_memory"`
+	CPUSeconds float64 `json:"cpu_seconds"`
+	NetworkRx  uint64  `json:"network_rx"`
+	NetworkTx  uint64  `json:"network_tx"`
+}
+
+// UsageReport gathers the usage of all containers matching the selector,
+// ordered by name. The daemon only reports the resource usage of running
+// containers, so the report has to be taken before the containers of the
+// run are stopped. Stopped containers contribute their exit code and
+// restarts.
+func (c *Client) UsageReport(ctx context.Context, selector Selector) (*UsageReport, error) {
+	containers, err := c.listContainers(ctx, true, selector.filters())
+	if err != nil {
+		return nil, fmt.Errorf("list containers: %w", err)
+	}
+	report := &UsageReport{Created: time.Now().UTC(), Containers: make([]ContainerResourceUsage, 0, len(containers))}
+	for _, cs := range containers {
+		cj, err := c.inspectContainer(ctx, cs.ID)
+		if isNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("inspect container %s: %w", cs.Name(), err)
+		}
+		u := ContainerResourceUsage{
+			ID:           cj.ID,
+			Name:         cs.Name(),
+			Image:        cj.Config.Image,
+			Running:      cj.State.Running,
+			ExitCode:     cj.State.ExitCode,
+			OOMKilled:    cj.State.OOMKilled,
+			RestartCount: cj.RestartCount,
+		}
+		u.StartedAt = parseStateTime(cj.State.StartedAt)
+		if !cj.State.Running {
+			u.FinishedAt = parseStateTime(cj.State.FinishedAt)
+		}
+		if cj.State.Running {
+			stats, err := c.ContainerStats(ctx, cs.ID)
+			if err != nil && !isNotFound(err) {
+				return nil, err
+			}
+			if stats != nil {
+				s := NewStatsSample(u.Name, stats)
+				u.PeakMemory = stats.MemoryStats.MaxUsage
+				if u.PeakMemory < s.MemoryUsage {
+					u.PeakMemory = s.MemoryUsage
+				}
+				u.CPUSeconds, u.NetworkRx, u.NetworkTx = s.CPUSeconds, s.NetworkRx, s.NetworkTx
+			}
+		}
+		report.Containers = append(report.Containers, u)
+	}
+	sort.Slice(report.Containers, func(i, j int) bool {
+		return report.Containers[i].Name < report.Containers[j].Name
+	})
+	return report, nil
+}
@@ -0,0 +1,40 @@
+package docker
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_UsageReport(t *testing.T) {
+	srv.route(map[string]mockResponse{
+		"GET /containers/json": {Body: `[{"Id": "c1", "Names": ["/plc"]}, {"Id": "c2", "Names": ["/meter"]}]`},
+		"GET /containers/c1/json": {Body: `{"Id": "c1", "Name": "/plc", "RestartCount": 2,
+			"Config": {"Image": "sim/plc"},
+			"State": {"Status": "running", "Running": true, "StartedAt": "2021-06-01T12:00:00Z",
+				"FinishedAt": "0001-01-01T00:00:00Z"}}`},
+		"GET /containers/c2/json": {Body: `{"Id": "c2", "Name": "/meter", "Config": {"Image": "sim/meter"},
+			"State": {"Status": "exited", "ExitCode": 137, "OOMKilled": true,
+				"StartedAt": "2021-06-01T12:00:00Z", "FinishedAt": "2021-06-01T12:05:00Z"}}`},
+		"GET /containers/c1/stats?stream=0": {Body: statsBody},
+	})
+	defer srv.route(nil)
+
+	report, err := client.UsageReport(context.Background(), RunSelector("r1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Containers) != 2 {
+		t.Fatalf("expected 2 containers, got %+v", report.Containers)
+	}
+	meter, plc := report.Containers[0], report.Containers[1]
+	if meter.Name != "meter" || meter.ExitCode != 137 || !meter.OOMKilled || meter.Running ||
+		!meter.FinishedAt.Equal(time.Date(2021, 6, 1, 12, 5, 0, 0, time.UTC)) || meter.CPUSeconds != 0 {
+		t.Errorf("unexpected usage of meter %+v", meter)
+	}
+	if plc.Name != "plc" || plc.Image != "sim/plc" || plc.RestartCount != 2 || !plc.Running ||
+		!plc.FinishedAt.IsZero() || plc.PeakMemory != 800 || plc.CPUSeconds != 3 ||
+		plc.NetworkRx != 101 || plc.NetworkTx != 52 {
+		t.Errorf("unexpected usage of plc %+v", plc)
+	}
+}
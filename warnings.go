@@ -0,0 +1,29 @@
+package docker
+
+import "strings"
+
+// CreateResult is the result of a create request: the ID of the created
+// object and the warnings of the daemon. Warnings often explain why a
+// container misbehaves later, e.g. a memory limit which is ignored because
+// the kernel lacks the cgroup.
+type CreateResult struct {
+	ID       string
+	Warnings []string
+}
+
+// SetWarningLog sets the function the warnings of all create requests of
+// the client are logged by, e.g. log.Printf. nil disables the logging,
+// which is the default. SetWarningLog has to be called before the client is
+// used.
+func (c *Client) SetWarningLog(logf func(format string, args ...interface{})) {
+	c.warnf = logf
+}
+
+// logWarnings logs the warnings of the creation of the object, e.g.
+// "container plc".
+func (c *Client) logWarnings(object string, warnings []string) {
+	if c.warnf == nil || len(warnings) == 0 {
+		return
+	}
+	c.warnf("docker: warnings creating %s: %s", object, strings.Join(warnings, "; "))
+}
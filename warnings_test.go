@@ -0,0 +1,61 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func Test_CreateWarnings(t *testing.T) {
+	warning := "Your kernel does not support memory limit capabilities or the cgroup is not mounted. Limitation discarded."
+	srv.route(map[string]mockResponse{
+		"POST /containers/create?name=plc": {StatusCode: http.StatusCreated,
+			Body: fmt.Sprintf(`{"Id": "c1", "Warnings": [%q]}`, warning)},
+		"POST /networks/create": {StatusCode: http.StatusCreated,
+			Body: `{"Id": "n1", "Warning": "network with name sim already exists"}`},
+		"POST /containers/c1/start": {StatusCode: http.StatusNoContent},
+	})
+	defer srv.route(nil)
+
+	c := NewClient(sockPath)
+	var logged []string
+	c.SetWarningLog(func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	})
+
+	res, err := c.CreateContainerWithWarnings("plc", "sim/plc", nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &CreateResult{ID: "c1", Warnings: []string{warning}}
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("got: %+v, want: %+v", res, want)
+	}
+	nres, err := c.CreateNetworkWithWarnings("sim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = &CreateResult{ID: "n1", Warnings: []string{"network with name sim already exists"}}
+	if !reflect.DeepEqual(nres, want) {
+		t.Errorf("got: %+v, want: %+v", nres, want)
+	}
+
+	run, err := c.Run(context.Background(), ContainerSpec{Name: "plc", Image: "sim/plc"}, RunOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(run.Warnings, []string{warning}) {
+		t.Errorf("got warnings: %v, want: %v", run.Warnings, []string{warning})
+	}
+
+	wantLogged := []string{
+		"docker: warnings creating container plc: " + warning,
+		"docker: warnings creating network sim: network with name sim already exists",
+		"docker: warnings creating container plc: " + warning,
+	}
+	if !reflect.DeepEqual(logged, wantLogged) {
+		t.Errorf("got logged: %q, want: %q", logged, wantLogged)
+	}
+}
@@ -0,0 +1,478 @@
+package docker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// This file contains a parser for the subset of YAML which is commonly used
+// in configuration files: block mappings and sequences, plain and quoted
+// scalars, literal (|) and folded (>) block scalars, single line flow
+// collections and comments. Anchors, aliases, tags and multiple documents are
+// not supported. The parser exists to keep the package free of external
+// dependencies.
+
+type yamlKind int
+
+const (
+	yamlNull yamlKind = iota
+	yamlScalar
+	yamlMap
+	yamlSeq
+)
+
+// yamlNode is a node of a parsed document. The line is 1-based; it is 0 for
+// nodes which do not stem from a YAML document.
+type yamlNode struct {
+	kind   yamlKind
+	line   int
+	value  string
+	quoted bool
+	keys   []string
+	values []*yamlNode
+}
+
+func (n *yamlNode) get(key string) *yamlNode {
+	for i, k := range n.keys {
+		if k == key {
+			return n.values[i]
+		}
+	}
+	return nil
+}
+
+type yamlLine struct {
+	no      int
+	indent  int
+	content string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	raw   []string
+	pos   int
+}
+
+// parseYAML parses a single YAML document.
+func parseYAML(data []byte) (*yamlNode, error) {
+	p := &yamlParser{raw: strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")}
+	for i, l := range p.raw {
+		if strings.HasPrefix(strings.TrimLeft(l, " "), "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		content := strings.TrimSpace(stripComment(l))
+		if content == "" || content == "---" {
+			continue
+		}
+		if content == "..." || strings.HasPrefix(content, "%") {
+			return nil, fmt.Errorf("line %d: directives and document markers are not supported", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{
+			no:      i + 1,
+			indent:  len(l) - len(strings.TrimLeft(l, " ")),
+			content: content,
+		})
+	}
+	if len(p.lines) == 0 {
+		return &yamlNode{kind: yamlNull, line: 1}, nil
+	}
+	n, err := p.parseBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].no)
+	}
+	return n, nil
+}
+
+// stripComment removes a trailing comment which is not part of a quoted
+// scalar. Quotes only quote if they start a scalar, an apostrophe within a
+// plain scalar like "it's" does not.
+func stripComment(l string) string {
+	var quote byte
+	for i := 0; i < len(l); i++ {
+		switch ch := l[i]; {
+		case quote == '"' && ch == '\\':
+			i++
+		case quote == '\'' && ch == '\'' && i+1 < len(l) && l[i+1] == '\'':
+			i++
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case (ch == '"' || ch == '\'') && startsScalar(l[:i]):
+			quote = ch
+		case ch == '#' && (i == 0 || l[i-1] == ' '):
+			return l[:i]
+		}
+	}
+	return l
+}
+
+// startsScalar reports whether a scalar starts after the text: at the start
+// of the line, after "key: ", "- ", "[", "{" or ",".
+func startsScalar(before string) bool {
+	s := strings.TrimRight(before, " ")
+	if s == "" {
+		return true
+	}
+	switch s[len(s)-1] {
+	case '[', '{', ',':
+		return true
+	case ':':
+		return len(s) < len(before)
+	case '-':
+		// the dash of a sequence item, not one within a plain scalar
+		return len(s) < len(before) && (len(s) == 1 || s[len(s)-2] == ' ')
+	}
+	return false
+}
+
+func (p *yamlParser) parseBlock(indent int) (*yamlNode, error) {
+	l := p.lines[p.pos]
+	if isSeqItem(l.content) {
+		return p.parseSeq(indent)
+	}
+	if _, _, ok := splitKey(l.content); ok {
+		return p.parseMap(indent)
+	}
+	p.pos++
+	return parseScalar(l.content, l.no)
+}
+
+func isSeqItem(s string) bool {
+	return s == "-" || strings.HasPrefix(s, "- ")
+}
+
+// splitKey splits "key: value" into key and value.
+func splitKey(s string) (string, string, bool) {
+	if strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{") {
+		return "", "", false
+	}
+	start := 0
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		end := strings.IndexByte(s[1:], s[0])
+		if end < 0 {
+			return "", "", false
+		}
+		start = end + 2
+	}
+	for i := start; i < len(s); i++ {
+		if s[i] != ':' || (i+1 < len(s) && s[i+1] != ' ') {
+			continue
+		}
+		key := strings.TrimSpace(s[:i])
+		if start > 0 {
+			key = unquote(key)
+		}
+		return key, strings.TrimSpace(s[i+1:]), true
+	}
+	return "", "", false
+}
+
+func (p *yamlParser) parseMap(indent int) (*yamlNode, error) {
+	n := &yamlNode{kind: yamlMap, line: p.lines[p.pos].no}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", l.no)
+		}
+		if isSeqItem(l.content) {
+			break
+		}
+		key, value, ok := splitKey(l.content)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", l.no)
+		}
+		if n.get(key) != nil {
+			return nil, fmt.Errorf("line %d: duplicate key %q", l.no, key)
+		}
+		p.pos++
+
+		v, err := p.parseValue(value, l, indent)
+		if err != nil {
+			return nil, err
+		}
+		n.keys = append(n.keys, key)
+		n.values = append(n.values, v)
+	}
+	return n, nil
+}
+
+// parseValue parses the value following a key or a sequence dash of line l.
+func (p *yamlParser) parseValue(value string, l yamlLine, indent int) (*yamlNode, error) {
+	switch {
+	case value == "|" || value == ">" || strings.HasPrefix(value, "|-") || strings.HasPrefix(value, ">-"):
+		return p.parseBlockScalar(value, l, indent), nil
+	case value != "":
+		return parseScalar(value, l.no)
+	case p.pos >= len(p.lines):
+		return &yamlNode{kind: yamlNull, line: l.no}, nil
+	}
+	next := p.lines[p.pos]
+	switch {
+	case next.indent > indent:
+		return p.parseBlock(next.indent)
+	case next.indent == indent && isSeqItem(next.content):
+		// sequences may start at the indentation of their key
+		return p.parseSeq(indent)
+	}
+	return &yamlNode{kind: yamlNull, line: l.no}, nil
+}
+
+func (p *yamlParser) parseSeq(indent int) (*yamlNode, error) {
+	n := &yamlNode{kind: yamlSeq, line: p.lines[p.pos].no}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent != indent || !isSeqItem(l.content) {
+			if l.indent > indent {
+				return nil, fmt.Errorf("line %d: unexpected indentation", l.no)
+			}
+			break
+		}
+
+		rest := strings.TrimSpace(strings.TrimPrefix(l.content, "-"))
+		if rest == "" {
+			p.pos++
+			v, err := p.parseValue("", l, indent)
+			if err != nil {
+				return nil, err
+			}
+			n.values = append(n.values, v)
+			continue
+		}
+
+		_, _, isKey := splitKey(rest)
+		if isKey || isSeqItem(rest) {
+			// the item is a nested collection starting on the same line:
+			// continue parsing with the content as line of its own.
+			p.lines[p.pos] = yamlLine{
+				no:      l.no,
+				indent:  indent + len(l.content) - len(rest),
+				content: rest,
+			}
+			v, err := p.parseBlock(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			n.values = append(n.values, v)
+			continue
+		}
+
+		p.pos++
+		v, err := p.parseValue(rest, l, indent)
+		if err != nil {
+			return nil, err
+		}
+		n.values = append(n.values, v)
+	}
+	return n, nil
+}
+
+// parseBlockScalar parses a literal or folded block scalar. The raw lines are
+// used because comments and blank lines are part of the content.
+func (p *yamlParser) parseBlockScalar(header string, l yamlLine, indent int) *yamlNode {
+	var lines []string
+	end := len(p.raw)
+	// the scalar ends with the first non blank line which is not indented
+	// more than the key.
+	for i := l.no; i < len(p.raw); i++ {
+		raw := p.raw[i]
+		if strings.TrimSpace(raw) != "" && len(raw)-len(strings.TrimLeft(raw, " ")) <= indent {
+			end = i
+			break
+		}
+		end = i + 1
+	}
+	blockIndent := -1
+	for _, raw := range p.raw[l.no:end] {
+		if strings.TrimSpace(raw) == "" {
+			lines = append(lines, "")
+			continue
+		}
+		if blockIndent < 0 {
+			blockIndent = len(raw) - len(strings.TrimLeft(raw, " "))
+		}
+		if len(raw) >= blockIndent {
+			raw = raw[blockIndent:]
+		}
+		lines = append(lines, raw)
+	}
+	for p.pos < len(p.lines) && p.lines[p.pos].no <= end {
+		p.pos++
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var value string
+	if header[0] == '|' {
+		value = strings.Join(lines, "\n")
+	} else {
+		value = foldLines(lines)
+	}
+	if !strings.HasSuffix(header, "-") && value != "" {
+		value += "\n"
+	}
+	return &yamlNode{kind: yamlScalar, line: l.no, value: value, quoted: true}
+}
+
+func foldLines(lines []string) string {
+	var b strings.Builder
+	for i, l := range lines {
+		switch {
+		case i == 0:
+		case l == "" || lines[i-1] == "":
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(' ')
+		}
+		b.WriteString(l)
+	}
+	return b.String()
+}
+
+// parseScalar parses a scalar or a single line flow collection.
+func parseScalar(s string, line int) (*yamlNode, error) {
+	// an empty scalar, e.g. in {a: } or [a, , b], is null
+	if s == "" {
+		return &yamlNode{kind: yamlNull, line: line}, nil
+	}
+	if strings.HasPrefix(s, "&") || strings.HasPrefix(s, "*") || strings.HasPrefix(s, "!") {
+		return nil, fmt.Errorf("line %d: anchors, aliases and tags are not supported", line)
+	}
+	if strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{") {
+		f := &flowParser{s: s, line: line}
+		n, err := f.parse()
+		if err != nil {
+			return nil, err
+		}
+		if f.skipSpace(); f.pos != len(s) {
+			return nil, fmt.Errorf("line %d: unexpected %q after flow collection", line, s[f.pos:])
+		}
+		return n, nil
+	}
+	if s[0] == '"' || s[0] == '\'' {
+		if len(s) < 2 || s[len(s)-1] != s[0] {
+			return nil, fmt.Errorf("line %d: unterminated quoted scalar", line)
+		}
+		return &yamlNode{kind: yamlScalar, line: line, value: unquote(s), quoted: true}, nil
+	}
+	if s == "~" || s == "null" || s == "Null" || s == "NULL" {
+		return &yamlNode{kind: yamlNull, line: line}, nil
+	}
+	return &yamlNode{kind: yamlScalar, line: line, value: s}, nil
+}
+
+// unquote removes the quotes of a single or double quoted scalar.
+func unquote(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+	switch s[0] {
+	case '\'':
+		return strings.Replace(s[1:len(s)-1], "''", "'", -1)
+	case '"':
+		if v, err := strconv.Unquote(s); err == nil {
+			return v
+		}
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// flowParser parses single line flow collections like [a, "b"] or {a: b}.
+type flowParser struct {
+	s    string
+	pos  int
+	line int
+}
+
+func (f *flowParser) skipSpace() {
+	for f.pos < len(f.s) && f.s[f.pos] == ' ' {
+		f.pos++
+	}
+}
+
+func (f *flowParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", f.line, fmt.Sprintf(format, args...))
+}
+
+func (f *flowParser) parse() (*yamlNode, error) {
+	f.skipSpace()
+	if f.pos >= len(f.s) {
+		return nil, f.errorf("unterminated flow collection")
+	}
+	switch f.s[f.pos] {
+	case '[':
+		return f.parseCollection(']')
+	case '{':
+		return f.parseCollection('}')
+	case '"', '\'':
+		q := f.s[f.pos]
+		end := f.pos + 1
+		for end < len(f.s) && (f.s[end] != q || (q == '"' && f.s[end-1] == '\\')) {
+			end++
+		}
+		if end >= len(f.s) {
+			return nil, f.errorf("unterminated quoted scalar")
+		}
+		v := unquote(f.s[f.pos : end+1])
+		f.pos = end + 1
+		return &yamlNode{kind: yamlScalar, line: f.line, value: v, quoted: true}, nil
+	}
+	start := f.pos
+	for f.pos < len(f.s) && !strings.ContainsRune(",]}", rune(f.s[f.pos])) &&
+		!(f.s[f.pos] == ':' && (f.pos+1 == len(f.s) || f.s[f.pos+1] == ' ')) {
+		f.pos++
+	}
+	return parseScalar(strings.TrimSpace(f.s[start:f.pos]), f.line)
+}
+
+func (f *flowParser) parseCollection(end byte) (*yamlNode, error) {
+	n := &yamlNode{kind: yamlSeq, line: f.line}
+	if end == '}' {
+		n.kind = yamlMap
+	}
+	f.pos++
+	for {
+		f.skipSpace()
+		if f.pos >= len(f.s) {
+			return nil, f.errorf("unterminated flow collection")
+		}
+		if f.s[f.pos] == end {
+			f.pos++
+			return n, nil
+		}
+
+		v, err := f.parse()
+		if err != nil {
+			return nil, err
+		}
+		if n.kind == yamlMap {
+			f.skipSpace()
+			if f.pos >= len(f.s) || f.s[f.pos] != ':' {
+				return nil, f.errorf("expected ':' in flow mapping")
+			}
+			f.pos++
+			key := v.value
+			if v, err = f.parse(); err != nil {
+				return nil, err
+			}
+			n.keys = append(n.keys, key)
+		}
+		n.values = append(n.values, v)
+
+		f.skipSpace()
+		if f.pos < len(f.s) && f.s[f.pos] == ',' {
+			f.pos++
+		} else if f.pos >= len(f.s) || f.s[f.pos] != end {
+			return nil, f.errorf("expected ',' or %q in flow collection", end)
+		}
+	}
+}